@@ -0,0 +1,54 @@
+package acceptance_test
+
+import (
+	"github.com/epinio/epinio/acceptance/helpers/catalog"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Admin", func() {
+	var (
+		org     string
+		appName string
+	)
+
+	BeforeEach(func() {
+		org = catalog.NewOrgName()
+		env.SetupAndTargetOrg(org)
+
+		appName = catalog.NewAppName()
+	})
+
+	Describe("blobs", func() {
+		It("lists the staged blob of an application", func() {
+			env.MakeApp(appName, 1, false)
+			defer env.DeleteApp(appName)
+
+			out, err := env.Epinio("", "admin", "blobs", "list")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`%s.*%s.*staged`, org, appName))
+		})
+
+		It("removes the blobs of a deleted application", func() {
+			env.MakeApp(appName, 1, false)
+			env.DeleteApp(appName)
+
+			out, err := env.Epinio("", "admin", "blobs", "list")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).ToNot(MatchRegexp(`%s.*%s`, org, appName))
+		})
+
+		It("prunes the blobs", func() {
+			out, err := env.Epinio("", "admin", "blobs", "prune", "--keep", "1")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp("Source blobs pruned|No blobs to prune"))
+		})
+
+		It("rejects a negative retention", func() {
+			out, err := env.Epinio("", "admin", "blobs", "prune", "--keep", "-1")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("must not be negative"))
+		})
+	})
+})
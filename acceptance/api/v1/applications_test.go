@@ -273,13 +273,44 @@ var _ = Describe("Apps API Application Endpoints", func() {
 				defer response.Body.Close()
 				bodyBytes, err := ioutil.ReadAll(response.Body)
 				Expect(err).ToNot(HaveOccurred(), string(bodyBytes))
-				Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+				Expect(response.StatusCode).To(Equal(http.StatusAccepted), string(bodyBytes))
 
 				var importResponse models.ImportGitResponse
 				err = json.Unmarshal(bodyBytes, &importResponse)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(importResponse.BlobUID).ToNot(BeEmpty())
-				Expect(importResponse.BlobUID).To(MatchRegexp(".+-.+-.+-.+-.+"))
+				Expect(importResponse.JobID).ToNot(BeEmpty())
+
+				By("waiting for the import job")
+				var job models.JobResponse
+				Eventually(func() string {
+					response, err := env.Curl("GET",
+						serverURL+"/"+v1.Routes.Path("JobShow", org, importResponse.JobID),
+						strings.NewReader(""))
+					Expect(err).ToNot(HaveOccurred())
+					defer response.Body.Close()
+					bodyBytes, err := ioutil.ReadAll(response.Body)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+
+					err = json.Unmarshal(bodyBytes, &job)
+					Expect(err).ToNot(HaveOccurred())
+					return job.Status
+				}, "2m", "2s").Should(Equal(models.JobSucceeded), job.Error)
+
+				Expect(job.BlobUID).ToNot(BeEmpty())
+				Expect(job.BlobUID).To(MatchRegexp(".+-.+-.+-.+-.+"))
+			})
+		})
+
+		Describe("GET /namespaces/:org/jobs/:id", func() {
+			It("returns a 404 when the job does not exist", func() {
+				response, err := env.Curl("GET",
+					serverURL+"/"+v1.Routes.Path("JobShow", org, "bogus"),
+					strings.NewReader(""))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response).ToNot(BeNil())
+				defer response.Body.Close()
+				Expect(response.StatusCode).To(Equal(http.StatusNotFound))
 			})
 		})
 
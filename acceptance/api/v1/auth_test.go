@@ -0,0 +1,98 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Auth API Endpoints", func() {
+	// requestToken asks the server for a token for the credentials
+	requestToken := func(username, password string) *http.Response {
+		body, err := json.Marshal(models.AuthTokenRequest{Username: username, Password: password})
+		Expect(err).ToNot(HaveOccurred())
+		request, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/auth/token", serverURL), strings.NewReader(string(body)))
+		Expect(err).ToNot(HaveOccurred())
+		response, err := env.Client().Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		return response
+	}
+
+	// curlWithToken issues a request authenticated by the token
+	curlWithToken := func(method, uri, token string) *http.Response {
+		request, err := http.NewRequest(method, uri, strings.NewReader(""))
+		Expect(err).ToNot(HaveOccurred())
+		request.Header.Set("Authorization", "Bearer "+token)
+		response, err := env.Client().Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		return response
+	}
+
+	It("rejects bad credentials", func() {
+		response := requestToken(env.EpinioUser, "bogus")
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("rejects bad tokens", func() {
+		response := curlWithToken("GET", fmt.Sprintf("%s/api/v1/namespaces", serverURL), "bogus")
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("issues tokens which can be used and revoked", func() {
+		response := requestToken(env.EpinioUser, env.EpinioPassword)
+		defer response.Body.Close()
+		bodyBytes, err := ioutil.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+
+		var tokenResponse models.AuthTokenResponse
+		err = json.Unmarshal(bodyBytes, &tokenResponse)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tokenResponse.Token).ToNot(BeEmpty())
+
+		response = curlWithToken("GET", fmt.Sprintf("%s/api/v1/namespaces", serverURL), tokenResponse.Token)
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		response = curlWithToken("DELETE", fmt.Sprintf("%s/api/v1/auth/token", serverURL), tokenResponse.Token)
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		response = curlWithToken("GET", fmt.Sprintf("%s/api/v1/namespaces", serverURL), tokenResponse.Token)
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("refreshes tokens, revoking the old one", func() {
+		response := requestToken(env.EpinioUser, env.EpinioPassword)
+		defer response.Body.Close()
+		var tokenResponse models.AuthTokenResponse
+		err := json.NewDecoder(response.Body).Decode(&tokenResponse)
+		Expect(err).ToNot(HaveOccurred())
+
+		response = curlWithToken("POST", fmt.Sprintf("%s/api/v1/auth/token/refresh", serverURL), tokenResponse.Token)
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		var refreshed models.AuthTokenResponse
+		err = json.NewDecoder(response.Body).Decode(&refreshed)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(refreshed.Token).ToNot(Equal(tokenResponse.Token))
+
+		response = curlWithToken("GET", fmt.Sprintf("%s/api/v1/namespaces", serverURL), tokenResponse.Token)
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusUnauthorized))
+
+		response = curlWithToken("GET", fmt.Sprintf("%s/api/v1/namespaces", serverURL), refreshed.Token)
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+	})
+})
@@ -0,0 +1,125 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/epinio/epinio/acceptance/helpers/catalog"
+	"github.com/epinio/epinio/helpers/randstr"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Users API Endpoints", func() {
+	var org, otherOrg, username, password string
+
+	// curlAs issues a request with the credentials of the test user
+	curlAs := func(method, uri string, body io.Reader) *http.Response {
+		request, err := http.NewRequest(method, uri, body)
+		Expect(err).ToNot(HaveOccurred())
+		request.SetBasicAuth(username, password)
+		response, err := env.Client().Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		return response
+	}
+
+	BeforeEach(func() {
+		org = catalog.NewOrgName()
+		env.SetupAndTargetOrg(org)
+		otherOrg = catalog.NewOrgName()
+		env.SetupAndTargetOrg(otherOrg)
+
+		suffix, err := randstr.Hex16()
+		Expect(err).ToNot(HaveOccurred())
+		username = "user-" + suffix
+		password = "password-" + suffix
+
+		out, err := env.Epinio("", "user", "add", username, "--password", password)
+		Expect(err).ToNot(HaveOccurred(), out)
+		out, err = env.Epinio("", "user", "grant", username, org, "--role", models.RoleViewer)
+		Expect(err).ToNot(HaveOccurred(), out)
+	})
+
+	AfterEach(func() {
+		out, err := env.Epinio("", "user", "remove", username)
+		Expect(err).ToNot(HaveOccurred(), out)
+		out, err = env.Epinio("", "namespace", "delete", "-f", otherOrg)
+		Expect(err).ToNot(HaveOccurred(), out)
+		out, err = env.Epinio("", "namespace", "delete", "-f", org)
+		Expect(err).ToNot(HaveOccurred(), out)
+	})
+
+	It("rejects bad credentials", func() {
+		password = "bogus"
+		response := curlAs("GET", fmt.Sprintf("%s/api/v1/namespaces", serverURL), strings.NewReader(""))
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("lists only the namespaces the user has a role in", func() {
+		response := curlAs("GET", fmt.Sprintf("%s/api/v1/namespaces", serverURL), strings.NewReader(""))
+		defer response.Body.Close()
+		bodyBytes, err := ioutil.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+
+		var namespaces models.NamespaceList
+		err = json.Unmarshal(bodyBytes, &namespaces)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(namespaces).To(HaveLen(1))
+		Expect(namespaces[0].Name).To(Equal(org))
+	})
+
+	It("allows a viewer to read the namespace", func() {
+		response := curlAs("GET", fmt.Sprintf("%s/api/v1/namespaces/%s/applications", serverURL, org), strings.NewReader(""))
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("forbids a viewer to modify the namespace", func() {
+		response := curlAs("POST", fmt.Sprintf("%s/api/v1/namespaces/%s/applications", serverURL, org),
+			strings.NewReader(`{"name":"app"}`))
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	It("forbids a viewer to read secrets of the namespace", func() {
+		response := curlAs("GET", fmt.Sprintf("%s/api/v1/namespaces/%s/services/%s", serverURL, org, "db"), strings.NewReader(""))
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	It("forbids access to other namespaces", func() {
+		response := curlAs("GET", fmt.Sprintf("%s/api/v1/namespaces/%s/applications", serverURL, otherOrg), strings.NewReader(""))
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	It("allows a developer to modify the namespace", func() {
+		out, err := env.Epinio("", "user", "grant", username, org, "--role", models.RoleDeveloper)
+		Expect(err).ToNot(HaveOccurred(), out)
+
+		response := curlAs("POST", fmt.Sprintf("%s/api/v1/namespaces/%s/applications", serverURL, org),
+			strings.NewReader(`{"name":"app"}`))
+		defer response.Body.Close()
+		bodyBytes, err := ioutil.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusCreated), string(bodyBytes))
+	})
+
+	It("forbids non-admins to manage users and namespaces", func() {
+		response := curlAs("GET", fmt.Sprintf("%s/api/v1/users", serverURL), strings.NewReader(""))
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+
+		response = curlAs("POST", fmt.Sprintf("%s/api/v1/namespaces", serverURL), strings.NewReader(`{"name":"forbidden"}`))
+		defer response.Body.Close()
+		Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+	})
+})
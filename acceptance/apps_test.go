@@ -2,7 +2,9 @@ package acceptance_test
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"path"
@@ -48,6 +50,69 @@ var _ = Describe("Apps", func() {
 			Expect(out).To(MatchRegexp("Ok"))
 		})
 
+		It("respects the health check", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio("", "app", "update", appName,
+				"--health-check-path", "/", "--health-check-initial-delay", "5")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = helpers.Kubectl("get", "deployment", "--namespace", org, appName,
+				"-o", "jsonpath={.spec.template.spec.containers[0].readinessProbe.httpGet.path}")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(Equal("/"))
+
+			out, err = env.Epinio("", "app", "show", appName)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Health Check\s*\|\s*http 8080/, timeout 1s, initial delay 5s`))
+			Expect(out).To(MatchRegexp(`Rollout\s*\|\s*max surge 25%, max unavailable 0`))
+		})
+
+		It("rejects a bad health check", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio("", "app", "update", appName, "--health-check", "exec")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("unknown health check type 'exec'"))
+		})
+
+		It("sets and removes compute resources", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio("", "app", "update", appName, "--memory", "256Mi", "--cpu", "100m")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = helpers.Kubectl("get", "deployment", "--namespace", org, appName,
+				"-o", "jsonpath={.spec.template.spec.containers[0].resources}")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring(`"limits":{"memory":"256Mi"}`))
+			Expect(out).To(ContainSubstring(`"requests":{"cpu":"100m","memory":"256Mi"}`))
+
+			out, err = env.Epinio("", "app", "show", appName)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Memory\s*\|\s*request 256Mi, limit 256Mi`))
+			Expect(out).To(MatchRegexp(`CPU\s*\|\s*request 100m, limit none`))
+
+			out, err = env.Epinio("", "app", "update", appName, "--cpu", "0")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio("", "app", "show", appName)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Memory\s*\|\s*request 256Mi, limit 256Mi`))
+			Expect(out).To(MatchRegexp(`CPU\s*\|\s*request none, limit none`))
+		})
+
+		It("rejects a request above the limit", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio("", "app", "update", appName, "--memory", "256Mi")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio("", "app", "update", appName, "--memory", "1Gi", "--memory-limit", "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("memory request 1Gi exceeds the limit 256Mi"))
+		})
+
 		Context("with service", func() {
 			var serviceName string
 
@@ -162,6 +227,156 @@ var _ = Describe("Apps", func() {
 		})
 	})
 
+	When("pushing with a staging strategy", func() {
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		strategyOf := func() string {
+			out, err := helpers.Kubectl("get", "pipelinerun",
+				"--namespace", deployments.TektonStagingNamespace,
+				"-l", fmt.Sprintf("app.kubernetes.io/name=%s", appName),
+				"-o", "jsonpath={.items[0].metadata.labels.epinio\\.suse\\.org/staging-strategy}")
+			Expect(err).ToNot(HaveOccurred(), out)
+			return out
+		}
+
+		It("detects a Dockerfile and builds it", func() {
+			appDir := "../assets/dockerfile-sample-app"
+			pushLog, err := env.Epinio(appDir, "apps", "push", appName)
+			Expect(err).ToNot(HaveOccurred(), pushLog)
+			Expect(pushLog).To(MatchRegexp(`Staging Strategy: .*dockerfile`))
+			Expect(strategyOf()).To(Equal("dockerfile"))
+		})
+
+		It("stages a prebuilt image", func() {
+			pushLog, err := env.Epinio("", "apps", "push", appName,
+				"--docker-image-url", dockerImageURL,
+				"--staging-strategy", "image")
+			Expect(err).ToNot(HaveOccurred(), pushLog)
+			Expect(strategyOf()).To(Equal("image"))
+		})
+
+		It("reports the failing step of a failed staging", func() {
+			appDir, err := ioutil.TempDir("", "epinio-failing-dockerfile")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(appDir)
+
+			err = ioutil.WriteFile(path.Join(appDir, "Dockerfile"),
+				[]byte("FROM alpine:3.14\nRUN exit 3\n"), 0600)
+			Expect(err).ToNot(HaveOccurred())
+
+			out, err := env.Epinio(appDir, "apps", "push", appName)
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Task: .*stage`))
+			Expect(out).To(MatchRegexp(`Step: .*build`))
+			Expect(out).To(ContainSubstring("Check that the Dockerfile builds"))
+			Expect(out).ToNot(ContainSubstring("Internal Server Error"))
+		})
+
+		It("reports a crashing application without waiting for the timeout", func() {
+			defer env.DeleteApp(appName)
+
+			// The busybox shell exits right away, the pod keeps crashing
+			out, err := env.Epinio("", "apps", "push", appName,
+				"--docker-image-url", "busybox",
+				"--staging-strategy", "image")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("application rollout failed"))
+			Expect(out).To(ContainSubstring("CrashLoopBackOff"))
+		})
+
+		It("rejects an unknown strategy", func() {
+			out, err := env.Epinio("", "apps", "push", appName,
+				"--staging-strategy", "bogus")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("unknown staging strategy 'bogus'"))
+		})
+	})
+
+	Describe("push --dry-run", func() {
+		It("lists the sources to upload, without the ignored ones", func() {
+			appDir, err := ioutil.TempDir("", "epinio-dry-run")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(appDir)
+
+			for file, contents := range map[string]string{
+				"index.php":     "<?php echo 'Hello';",
+				".env":          "SECRET=1",
+				".epinioignore": ".env\n",
+			} {
+				err = ioutil.WriteFile(path.Join(appDir, file), []byte(contents), 0600)
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			out, err := env.Epinio(appDir, "apps", "push", appName, "--dry-run")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("index.php"))
+			Expect(out).ToNot(ContainSubstring(".env"))
+			Expect(out).To(ContainSubstring("Dry run, nothing pushed"))
+
+			out, err = env.Epinio("", "app", "show", appName)
+			Expect(err).To(HaveOccurred(), out)
+		})
+	})
+
+	Describe("stage cancel", func() {
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("cancels the staging in flight", func() {
+			appDir, err := ioutil.TempDir("", "epinio-slow-dockerfile")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(appDir)
+
+			err = ioutil.WriteFile(path.Join(appDir, "Dockerfile"),
+				[]byte("FROM alpine:3.14\nRUN sleep 600\n"), 0600)
+			Expect(err).ToNot(HaveOccurred())
+
+			p, err := proc.Get(appDir, testenv.EpinioBinaryPath(), "apps", "push", appName)
+			Expect(err).NotTo(HaveOccurred())
+			defer func() {
+				if p.Process != nil {
+					p.Process.Kill()
+				}
+			}()
+			go p.Run()
+
+			By("waiting for the staging to start")
+			Eventually(func() string {
+				out, _ := env.Epinio("", "app", "show", appName)
+				return out
+			}, "5m").Should(MatchRegexp(`Staging`))
+
+			By("cancelling the staging")
+			out, err := env.Epinio("", "app", "stage", "cancel", appName)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Staging cancelled"))
+
+			By("checking that the pipelinerun is done")
+			Eventually(func() string {
+				out, _ := helpers.Kubectl("get", "pipelinerun",
+					"--namespace", deployments.TektonStagingNamespace,
+					"-l", fmt.Sprintf("app.kubernetes.io/name=%s", appName),
+					"-o", "jsonpath={.items[0].status.conditions[0].reason}")
+				return out
+			}, "2m").Should(Equal("PipelineRunCancelled"))
+
+			out, err = env.Epinio("", "app", "show", appName)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).ToNot(MatchRegexp(`Staging`))
+		})
+
+		It("reports an application which is not staging", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio("", "app", "stage", "cancel", appName)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("The application is not staging"))
+		})
+	})
+
 	When("pushing an app multiple times", func() {
 		var (
 			timeout  = 30 * time.Second
@@ -193,6 +408,16 @@ var _ = Describe("Apps", func() {
 			env.DeleteApp(appName)
 		})
 
+		It("skips the upload of unchanged sources", func() {
+			out, err := act()
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).ToNot(ContainSubstring("Sources unchanged"))
+
+			out, err = act()
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Sources unchanged, skipping upload"))
+		})
+
 		It("honours the given instance count", func() {
 			By("pushing without instance count", func() {
 				out, err := act()
@@ -247,6 +472,60 @@ var _ = Describe("Apps", func() {
 				Expect(out).To(MatchRegexp("Reusing cache layer"))
 			})
 		})
+		When("managing the cache", func() {
+			AfterEach(func() {
+				env.DeleteApp(appName)
+			})
+
+			It("shows the cache and its claim", func() {
+				out, err := env.Epinio("", "app", "cache", "show", appName)
+				Expect(err).ToNot(HaveOccurred(), out)
+				Expect(out).To(MatchRegexp(`Size.*1Gi \(default\)`))
+				Expect(out).To(MatchRegexp(`Claim.*%s`, names.GenerateResourceName(org, appName)))
+			})
+
+			It("recreates the claim with the configured size", func() {
+				out, err := env.Epinio("", "app", "cache", "config", appName, "--size", "2Gi")
+				Expect(err).ToNot(HaveOccurred(), out)
+				Expect(out).To(ContainSubstring("The existing cache does not match, and was removed"))
+				Expect(out).To(MatchRegexp(`Size.*2Gi`))
+
+				out, err = push()
+				Expect(err).ToNot(HaveOccurred(), out)
+
+				out, err = helpers.Kubectl("get", "pvc", "-n",
+					deployments.TektonStagingNamespace, names.GenerateResourceName(org, appName),
+					"-o", "jsonpath={.spec.resources.requests.storage}")
+				Expect(err).ToNot(HaveOccurred(), out)
+				Expect(out).To(Equal("2Gi"))
+			})
+
+			It("rejects a bad size and an unknown storage class", func() {
+				out, err := env.Epinio("", "app", "cache", "config", appName, "--size", "lots")
+				Expect(err).To(HaveOccurred(), out)
+				Expect(out).To(ContainSubstring("bad cache size 'lots'"))
+
+				out, err = env.Epinio("", "app", "cache", "config", appName, "--storage-class", "no-such-class")
+				Expect(err).To(HaveOccurred(), out)
+				Expect(out).To(ContainSubstring("storage class 'no-such-class' does not exist"))
+			})
+
+			It("clears the cache", func() {
+				out, err := env.Epinio("", "app", "cache", "clear", appName)
+				Expect(err).ToNot(HaveOccurred(), out)
+				Expect(out).To(ContainSubstring("Build cache cleared"))
+
+				out, err = push()
+				Expect(err).ToNot(HaveOccurred(), out)
+				Expect(out).ToNot(MatchRegexp("Reusing cache layer"))
+			})
+
+			It("lists the cache across namespaces", func() {
+				out, err := env.Epinio("", "app", "cache", "list")
+				Expect(err).ToNot(HaveOccurred(), out)
+				Expect(out).To(MatchRegexp(`%s.*%s.*%s`, org, appName, names.GenerateResourceName(org, appName)))
+			})
+		})
 		When("deleting the app", func() {
 			It("deletes the cache PVC too", func() {
 				out, err := helpers.Kubectl("get", "pvc", "-n",
@@ -434,6 +713,153 @@ var _ = Describe("Apps", func() {
 		})
 	})
 
+	Describe("manifest", func() {
+		var manifestDir string
+
+		BeforeEach(func() {
+			var err error
+			manifestDir, err = ioutil.TempDir("", "epinio-manifest")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			env.DeleteApp(appName)
+			os.RemoveAll(manifestDir)
+		})
+
+		It("pushes the app as declared by the manifest", func() {
+			manifestPath := path.Join(manifestDir, "epinio.yml")
+			err := ioutil.WriteFile(manifestPath, []byte(fmt.Sprintf(`
+name: %s
+instances: 2
+environment:
+  MYVAR: fromthemanifest
+`, appName)), 0600)
+			Expect(err).ToNot(HaveOccurred())
+
+			out, err := env.Epinio("", "apps", "push",
+				"--manifest", manifestPath,
+				"--docker-image-url", dockerImageURL)
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			Eventually(func() string {
+				out, err := env.Epinio("", "app", "show", appName)
+				ExpectWithOffset(1, err).ToNot(HaveOccurred(), out)
+				return out
+			}, "1m").Should(MatchRegexp(`Status\s*\|\s*2\/2\s*\|`))
+
+			out, err = env.Epinio("", "app", "env", "show", appName, "MYVAR")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`fromthemanifest`))
+		})
+
+		It("lets options override the manifest", func() {
+			manifestPath := path.Join(manifestDir, "epinio.yml")
+			err := ioutil.WriteFile(manifestPath, []byte("instances: 2\n"), 0600)
+			Expect(err).ToNot(HaveOccurred())
+
+			out, err := env.Epinio("", "apps", "push", appName,
+				"--manifest", manifestPath,
+				"--docker-image-url", dockerImageURL,
+				"--instances", "1")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			Eventually(func() string {
+				out, err := env.Epinio("", "app", "show", appName)
+				ExpectWithOffset(1, err).ToNot(HaveOccurred(), out)
+				return out
+			}, "1m").Should(MatchRegexp(`Status\s*\|\s*1\/1\s*\|`))
+		})
+
+		It("exports the configuration of an app", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			manifestPath := path.Join(manifestDir, "exported.yml")
+			out, err := env.Epinio("", "app", "manifest", appName, manifestPath)
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			content, err := ioutil.ReadFile(manifestPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(MatchRegexp("name: " + appName))
+			Expect(string(content)).To(MatchRegexp("instances: 1"))
+		})
+	})
+
+	Describe("releases", func() {
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("records each deploy and rolls back to a previous release", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio("", "apps", "push", appName,
+				"--docker-image-url", "epinio/sample-app")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio("", "app", "releases", appName)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`\| 1 +\| ` + dockerImageURL))
+			Expect(out).To(MatchRegexp(`\| 2 +\| epinio/sample-app`))
+
+			out, err = env.Epinio("", "app", "rollback", appName)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Rolled back application"))
+
+			out, err = env.Epinio("", "app", "releases", appName)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`\| 3 \(rollback to 1\) +\| ` + dockerImageURL))
+		})
+
+		It("fails for an unknown release", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio("", "app", "rollback", appName, "42")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Release '42' does not exist"))
+		})
+	})
+
+	Describe("routes", func() {
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("adds and removes custom routes", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			customRoute := appName + ".custom.example.com/path"
+
+			out, err := env.Epinio("", "app", "route", "add", appName, customRoute)
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio("", "app", "route", "list", appName)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring(customRoute))
+
+			Eventually(func() string {
+				out, err := env.Epinio("", "app", "show", appName)
+				ExpectWithOffset(1, err).ToNot(HaveOccurred(), out)
+				return out
+			}, "1m").Should(ContainSubstring(appName + ".custom.example.com"))
+
+			out, err = env.Epinio("", "app", "route", "remove", appName, customRoute)
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio("", "app", "route", "list", appName)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).ToNot(ContainSubstring(customRoute))
+		})
+
+		It("rejects a bad route", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio("", "app", "route", "add", appName, "bad_host.example.com")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("bad route"))
+		})
+	})
+
 	Describe("update", func() {
 		AfterEach(func() {
 			env.DeleteApp(appName)
@@ -460,6 +886,154 @@ var _ = Describe("Apps", func() {
 			}, "1m").Should(MatchRegexp(`Status\s*\|\s*3\/3\s*\|`))
 		})
 
+		It("autoscales, refusing manual changes of the instances", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio("", "app", "autoscale", appName, "--min", "2", "--max", "4", "--cpu-percent", "70")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("autoscaling requires a CPU request"))
+
+			out, err = env.Epinio("", "app", "update", appName, "--cpu", "100m")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio("", "app", "autoscale", appName, "--min", "2", "--max", "4", "--cpu-percent", "70")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Application autoscaled"))
+
+			out, err = helpers.Kubectl("get", "hpa", "--namespace", org, appName,
+				"-o", "jsonpath={.spec.minReplicas} {.spec.maxReplicas} {.spec.targetCPUUtilizationPercentage}")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(Equal("2 4 70"))
+
+			Eventually(func() string {
+				out, err := env.Epinio("", "app", "show", appName)
+				ExpectWithOffset(1, err).ToNot(HaveOccurred(), out)
+
+				return out
+			}, "2m").Should(MatchRegexp(`Replicas\s*\|\s*2 current, 2 desired`))
+
+			out, err = env.Epinio("", "app", "show", appName)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Autoscale\s*\|\s*min 2, max 4, target CPU 70%`))
+
+			out, err = env.Epinio("", "app", "update", appName, "-i", "3")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("application is autoscaled"))
+
+			out, err = env.Epinio("", "app", "update", appName, "--cpu", "0")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("autoscaling requires a CPU request"))
+
+			out, err = env.Epinio("", "app", "autoscale", appName, "--disable")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = helpers.Kubectl("get", "hpa", "--namespace", org, appName)
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("NotFound"))
+
+			out, err = env.Epinio("", "app", "update", appName, "-i", "3")
+			Expect(err).ToNot(HaveOccurred(), out)
+		})
+
+		It("restarts, stops, and starts the application", func() {
+			env.MakeDockerImageApp(appName, 2, dockerImageURL)
+
+			out, err := env.Epinio("", "app", "restart", appName)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Application restarted"))
+
+			out, err = helpers.Kubectl("get", "deployment", "--namespace", org, appName,
+				"-o", `jsonpath={.spec.template.metadata.annotations.epinio\.suse\.org/restarted-at}`)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).ToNot(BeEmpty())
+
+			out, err = env.Epinio("", "app", "stop", appName)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Application stopped"))
+
+			out, err = helpers.Kubectl("get", "deployment", "--namespace", org, appName,
+				"-o", "jsonpath={.spec.replicas}")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(Equal("0"))
+
+			out, err = env.Epinio("", "app", "show", appName)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Status\s*\|\s*stopped\s*\|`))
+			Expect(out).To(MatchRegexp(`Desired Instances\s*\|\s*2\s*\|`))
+
+			out, err = env.Epinio("", "app", "restart", appName)
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("application is stopped"))
+
+			out, err = env.Epinio("", "app", "start", appName)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Application started"))
+
+			Eventually(func() string {
+				out, err := env.Epinio("", "app", "show", appName)
+				ExpectWithOffset(1, err).ToNot(HaveOccurred(), out)
+
+				return out
+			}, "1m").Should(MatchRegexp(`Status\s*\|\s*2\/2\s*\|`))
+		})
+
+		It("runs commands in the instances of the application", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			Eventually(func() string {
+				out, err := env.Epinio("", "app", "show", appName)
+				ExpectWithOffset(1, err).ToNot(HaveOccurred(), out)
+
+				return out
+			}, "1m").Should(MatchRegexp(`Status\s*\|\s*1\/1\s*\|`))
+
+			out, err := env.Epinio("", "app", "exec", appName, "--", "echo", "hello from the app")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("hello from the app"))
+
+			out, err = env.Epinio("", "app", "exec", appName, "--", "sh", "-c", "exit 3")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("exit code 3"))
+
+			out, err = env.Epinio("", "app", "exec", appName, "--instance", "1", "--", "echo")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("application has no instance 1"))
+		})
+
+		It("forwards a local port to the application", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			Eventually(func() string {
+				out, err := env.Epinio("", "app", "show", appName)
+				ExpectWithOffset(1, err).ToNot(HaveOccurred(), out)
+
+				return out
+			}, "1m").Should(MatchRegexp(`Status\s*\|\s*1\/1\s*\|`))
+
+			p, err := proc.Get("", testenv.EpinioBinaryPath(), "app", "port-forward", appName, "18080:8080")
+			Expect(err).NotTo(HaveOccurred())
+
+			defer func() {
+				if p.Process != nil {
+					p.Process.Kill()
+				}
+			}()
+			go p.Run()
+
+			Eventually(func() int {
+				resp, err := http.Get("http://localhost:18080/")
+				if err != nil {
+					return 0
+				}
+				resp.Body.Close()
+				return resp.StatusCode
+			}, "30s", "1s").Should(Equal(http.StatusOK))
+
+			out, err := env.Epinio("", "app", "port-forward", appName, "--instance", "1", "18081:8080")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("application has no instance 1"))
+		})
+
 		Context("with service", func() {
 			var serviceName string
 
@@ -536,6 +1110,24 @@ var _ = Describe("Apps", func() {
 			}, "1m").Should(MatchRegexp(`Status .*\|.* 1\/1`))
 		})
 
+		It("shows the instances of an app", func() {
+			Eventually(func() string {
+				out, err := env.Epinio("", "app", "show", appName)
+				Expect(err).ToNot(HaveOccurred(), out)
+				return out
+			}, "1m").Should(MatchRegexp(`Status .*\|.* 1\/1`))
+
+			podName, err := helpers.Kubectl("get", "pods", "--namespace", org,
+				"--selector", "app.kubernetes.io/component=application,app.kubernetes.io/name="+appName,
+				"-o", "jsonpath={.items[0].metadata.name}")
+			Expect(err).ToNot(HaveOccurred(), podName)
+
+			out, err := env.Epinio("", "app", "show", appName)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Instances:"))
+			Expect(out).To(MatchRegexp(podName + `\s*\|\s*Running\s*\|\s*true\s*\|\s*0\s*\|`))
+		})
+
 		Describe("no instances", func() {
 			BeforeEach(func() {
 				out, err := env.Epinio("", "app", "update", appName, "--instances", "0")
@@ -633,6 +1225,38 @@ var _ = Describe("Apps", func() {
 			Expect(out).ToNot(MatchRegexp(`linkerd-.*`))
 		})
 
+		It("selects and filters the logs", func() {
+			podNames := env.GetPodNames(appName, org)
+
+			out, err := env.Epinio("", "app", "logs", appName, "--tail", "1", "--output", "json")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).ToNot(ContainSubstring("Streaming application logs"))
+
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			Expect(lines).To(HaveLen(1))
+			var logLine map[string]string
+			Expect(json.Unmarshal([]byte(lines[0]), &logLine)).To(Succeed())
+			Expect(podNames).To(ContainElement(logLine["PodName"]))
+
+			out, err = env.Epinio("", "app", "logs", appName, "--include", "^no line matches this$")
+			Expect(err).ToNot(HaveOccurred(), out)
+			for _, podName := range podNames {
+				Expect(out).ToNot(ContainSubstring(podName))
+			}
+
+			out, err = env.Epinio("", "app", "logs", appName, "--instance", "0", "--timestamps")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}`))
+
+			out, err = env.Epinio("", "app", "logs", appName, "--instance", "1")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("application has no instance 1"))
+
+			out, err = env.Epinio("", "app", "logs", appName, "--include", "(")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("bad include pattern"))
+		})
+
 		It("follows logs", func() {
 			p, err := proc.Get("", testenv.EpinioBinaryPath(), "app", "logs", "--follow", appName)
 			Expect(err).NotTo(HaveOccurred())
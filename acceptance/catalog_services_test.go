@@ -0,0 +1,109 @@
+package acceptance_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/epinio/epinio/acceptance/helpers/catalog"
+	"github.com/epinio/epinio/acceptance/testenv"
+	"github.com/epinio/epinio/helpers"
+	"github.com/epinio/epinio/internal/names"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// The broker is the fake broker from assets/fake-broker, pushed as an
+// epinio app, and registered with the service catalog.
+var _ = Describe("Catalog services", func() {
+	var org, brokerApp, brokerName, serviceName, appName string
+	dockerImageURL := "splatform/sample-app"
+
+	BeforeEach(func() {
+		out, err := helpers.Kubectl("get", "crd", "clusterservicebrokers.servicecatalog.k8s.io")
+		if err != nil {
+			Skip("the service catalog is not installed: " + out)
+		}
+
+		org = catalog.NewOrgName()
+		brokerApp = catalog.NewAppName()
+		brokerName = "fake-broker-" + brokerApp
+		serviceName = catalog.NewServiceName()
+		appName = catalog.NewAppName()
+		env.SetupAndTargetOrg(org)
+
+		currentDir, err := os.Getwd()
+		Expect(err).ToNot(HaveOccurred())
+		env.MakeAppWithDir(brokerApp, 1, false, path.Join(currentDir, testenv.AssetPath("fake-broker")))
+
+		broker, err := ioutil.TempFile("", "fake-broker-*.yaml")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.Remove(broker.Name())
+
+		_, err = fmt.Fprintf(broker, `apiVersion: servicecatalog.k8s.io/v1beta1
+kind: ClusterServiceBroker
+metadata:
+  name: %s
+spec:
+  url: http://%s.%s.svc.cluster.local:8080
+`, brokerName, names.ServiceName(brokerApp), org)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(broker.Close()).To(Succeed())
+
+		out, err = helpers.Kubectl("apply", "-f", broker.Name())
+		Expect(err).ToNot(HaveOccurred(), out)
+
+		Eventually(func() string {
+			out, err := env.Epinio("", "service", "catalog")
+			Expect(err).ToNot(HaveOccurred(), out)
+			return out
+		}, "2m").Should(MatchRegexp("fake-service"))
+	})
+
+	AfterEach(func() {
+		out, err := helpers.Kubectl("delete", "clusterservicebroker", brokerName, "--ignore-not-found")
+		Expect(err).ToNot(HaveOccurred(), out)
+		env.CleanupApp(brokerApp)
+	})
+
+	It("lists the classes and plans of the brokers", func() {
+		out, err := env.Epinio("", "service", "catalog")
+		Expect(err).ToNot(HaveOccurred(), out)
+		Expect(out).To(MatchRegexp(`fake-service.*\|.*A fake service, for testing.*\|.*` + brokerName))
+		Expect(out).To(MatchRegexp(`small.*\|.*yes.*\|.*A small fake service`))
+		Expect(out).To(MatchRegexp(`large.*\|.*\|.*A large fake service`))
+	})
+
+	It("rejects unknown plans", func() {
+		out, err := env.Epinio("", "service", "create-catalog", serviceName, "fake-service", "bogus")
+		Expect(err).To(HaveOccurred(), out)
+		Expect(out).To(MatchRegexp("plan"))
+	})
+
+	It("provisions, binds, and deletes a service", func() {
+		out, err := env.Epinio("", "service", "create-catalog", serviceName, "fake-service", "small",
+			"--params", `{"user":"fake"}`)
+		Expect(err).ToNot(HaveOccurred(), out)
+
+		out, err = env.Epinio("", "service", "show", serviceName)
+		Expect(err).ToNot(HaveOccurred(), out)
+		Expect(out).To(MatchRegexp(`Class: .*fake-service`))
+		Expect(out).To(MatchRegexp(`Plan: .*small`))
+		Expect(out).To(MatchRegexp(`user.*\|.*fake`))
+
+		env.MakeDockerImageApp(appName, 1, dockerImageURL)
+		env.BindAppService(appName, serviceName, org)
+
+		out, err = helpers.Kubectl("get", "secret", "--namespace", org,
+			fmt.Sprintf("svc.org-%s.svc-%s.app-%s", org, serviceName, appName),
+			"-o", "jsonpath={.data.user}")
+		Expect(err).ToNot(HaveOccurred(), out)
+		Expect(out).ToNot(BeEmpty())
+
+		env.UnbindAppService(appName, serviceName, org)
+		env.DeleteApp(appName)
+		env.DeleteService(serviceName)
+	})
+})
@@ -1,7 +1,10 @@
 package acceptance_test
 
 import (
+	"fmt"
+
 	"github.com/epinio/epinio/acceptance/helpers/catalog"
+	"github.com/epinio/epinio/helpers"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -13,6 +16,16 @@ var _ = Describe("Services", func() {
 	var serviceName2 string
 	dockerImageURL := "splatform/sample-app"
 
+	bindingSecret := func(org, service, app string) string {
+		n, err := helpers.Kubectl("get", "secret", "--namespace", org,
+			fmt.Sprintf("svc.org-%s.svc-%s.app-%s", org, service, app),
+			"-o", "jsonpath={.metadata.name}")
+		if err != nil {
+			return ""
+		}
+		return n
+	}
+
 	BeforeEach(func() {
 		org = catalog.NewOrgName()
 		serviceName1 = catalog.NewServiceName()
@@ -83,6 +96,9 @@ var _ = Describe("Services", func() {
 			Expect(out).To(MatchRegexp("Service Removed"))
 
 			env.VerifyAppServiceNotbound(appName, serviceName1, org, 1)
+			Eventually(func() string {
+				return bindingSecret(org, serviceName1, appName)
+			}, "1m").Should(BeEmpty())
 
 			// And check non-presence
 			Eventually(func() string {
@@ -93,6 +109,52 @@ var _ = Describe("Services", func() {
 		})
 	})
 
+	Describe("service update", func() {
+		var appName string
+		BeforeEach(func() {
+			appName = catalog.NewAppName()
+
+			env.MakeService(serviceName1)
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+			env.BindAppService(appName, serviceName1, org)
+		})
+
+		AfterEach(func() {
+			env.CleanupApp(appName)
+			env.CleanupService(serviceName1)
+		})
+
+		It("changes the service and restarts the bound applications", func() {
+			out, err := env.Epinio("", "service", "update", serviceName1,
+				"--set", "password=secret", "--unset", "username", "--one-at-a-time")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp("Service Updated"))
+			Expect(out).To(MatchRegexp(appName))
+
+			out, err = env.Epinio("", "service", "show", serviceName1)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`password.*\|.*secret`))
+			Expect(out).ToNot(MatchRegexp(`username`))
+
+			out, err = helpers.Kubectl("get", "secret", "--namespace", org,
+				bindingSecret(org, serviceName1, appName),
+				"-o", "jsonpath={.data.password} {.data.username}")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(Equal("c2VjcmV0 ")) // base64 of "secret"
+
+			out, err = helpers.Kubectl("get", "deployment", "--namespace", org, appName,
+				"-o", `jsonpath={.spec.template.metadata.annotations.epinio\.suse\.org/restarted-at}`)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).ToNot(BeEmpty())
+		})
+
+		It("requires a change", func() {
+			out, err := env.Epinio("", "service", "update", serviceName1)
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp("nothing to update"))
+		})
+	})
+
 	Describe("service bind", func() {
 		var appName string
 		BeforeEach(func() {
@@ -110,6 +172,30 @@ var _ = Describe("Services", func() {
 		It("binds a service to the application deployment", func() {
 			env.BindAppService(appName, serviceName1, org)
 		})
+
+		It("gives each application its own binding secret", func() {
+			otherApp := catalog.NewAppName()
+			env.MakeDockerImageApp(otherApp, 1, dockerImageURL)
+			defer env.CleanupApp(otherApp)
+
+			env.BindAppService(appName, serviceName1, org)
+			env.BindAppService(otherApp, serviceName1, org)
+
+			for _, app := range []string{appName, otherApp} {
+				secret := bindingSecret(org, serviceName1, app)
+				Expect(secret).ToNot(BeEmpty())
+
+				out, err := helpers.Kubectl("get", "deployment", "--namespace", org, app,
+					"-o", "jsonpath={.spec.template.spec.volumes[*].secret.secretName}")
+				Expect(err).ToNot(HaveOccurred(), out)
+				Expect(out).To(ContainSubstring(secret))
+
+				out, err = helpers.Kubectl("get", "secret", "--namespace", org, secret,
+					"-o", "jsonpath={.metadata.ownerReferences[0].name} {.data.username}")
+				Expect(err).ToNot(HaveOccurred(), out)
+				Expect(out).To(MatchRegexp(app + ` .+`))
+			}
+		})
 	})
 
 	Describe("service unbind", func() {
@@ -130,6 +216,16 @@ var _ = Describe("Services", func() {
 		It("unbinds a service from the application deployment", func() {
 			env.UnbindAppService(appName, serviceName1, org)
 		})
+
+		It("removes the binding secret of the application", func() {
+			Expect(bindingSecret(org, serviceName1, appName)).ToNot(BeEmpty())
+
+			env.UnbindAppService(appName, serviceName1, org)
+
+			Eventually(func() string {
+				return bindingSecret(org, serviceName1, appName)
+			}, "1m").Should(BeEmpty())
+		})
 	})
 
 	Describe("service", func() {
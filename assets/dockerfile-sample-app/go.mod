@@ -0,0 +1,3 @@
+module github.com/epinio/epinio/dockerfile-sample-app
+
+go 1.16
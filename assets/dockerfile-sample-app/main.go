@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+)
+
+const INDEX = `<!DOCTYPE html>
+<html>
+  <head>
+    <title>Built From A Dockerfile</title>
+  </head>
+  <body>
+    <p>Built from a Dockerfile</p>
+  </body>
+</html>`
+
+func main() {
+	http.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, INDEX)
+	})
+
+	log.Fatal(http.ListenAndServe(":"+os.Getenv("PORT"), nil))
+}
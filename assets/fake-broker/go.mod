@@ -0,0 +1,3 @@
+module github.com/epinio/epinio/fake-broker
+
+go 1.16
@@ -0,0 +1,128 @@
+// The fake broker is a minimal Open Service Broker, for the testing of
+// catalog services. It offers the class `fake-service` with the plans
+// `small` and `large`. Provisioning and binding complete synchronously.
+// The credentials of a binding contain the parameters of the instance,
+// and the IDs of instance and binding.
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+var catalog = map[string]interface{}{
+	"services": []interface{}{
+		map[string]interface{}{
+			"id":          "fake-service-id",
+			"name":        "fake-service",
+			"description": "A fake service, for testing",
+			"bindable":    true,
+			"plans": []interface{}{
+				map[string]interface{}{
+					"id":          "fake-plan-small-id",
+					"name":        "small",
+					"description": "A small fake service",
+					"free":        true,
+				},
+				map[string]interface{}{
+					"id":          "fake-plan-large-id",
+					"name":        "large",
+					"description": "A large fake service",
+					"free":        false,
+				},
+			},
+		},
+	},
+}
+
+type broker struct {
+	mu        sync.Mutex
+	instances map[string]map[string]interface{}
+}
+
+func main() {
+	b := &broker{instances: map[string]map[string]interface{}{}}
+
+	http.HandleFunc("/v2/catalog", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, catalog)
+	})
+	http.HandleFunc("/v2/service_instances/", b.serviceInstances)
+
+	log.Fatal(http.ListenAndServe(":"+os.Getenv("PORT"), nil))
+}
+
+// serviceInstances handles /v2/service_instances/:id and
+// /v2/service_instances/:id/service_bindings/:binding
+func (b *broker) serviceInstances(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v2/service_instances/"), "/")
+	instanceID := parts[0]
+
+	log.Printf("%s %s", r.Method, r.URL.Path)
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		var request struct {
+			Parameters map[string]interface{} `json:"parameters"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			respond(w, http.StatusBadRequest, map[string]interface{}{"description": err.Error()})
+			return
+		}
+
+		b.mu.Lock()
+		b.instances[instanceID] = request.Parameters
+		b.mu.Unlock()
+
+		respond(w, http.StatusCreated, map[string]interface{}{})
+
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		b.mu.Lock()
+		_, ok := b.instances[instanceID]
+		delete(b.instances, instanceID)
+		b.mu.Unlock()
+
+		if !ok {
+			respond(w, http.StatusGone, map[string]interface{}{})
+			return
+		}
+		respond(w, http.StatusOK, map[string]interface{}{})
+
+	case len(parts) == 3 && parts[1] == "service_bindings" && r.Method == http.MethodPut:
+		b.mu.Lock()
+		params, ok := b.instances[instanceID]
+		b.mu.Unlock()
+
+		if !ok {
+			respond(w, http.StatusNotFound, map[string]interface{}{"description": "unknown instance"})
+			return
+		}
+
+		credentials := map[string]interface{}{
+			"instance": instanceID,
+			"binding":  parts[2],
+		}
+		for k, v := range params {
+			credentials[k] = v
+		}
+
+		respond(w, http.StatusCreated, map[string]interface{}{"credentials": credentials})
+
+	case len(parts) == 3 && parts[1] == "service_bindings" && r.Method == http.MethodDelete:
+		respond(w, http.StatusOK, map[string]interface{}{})
+
+	default:
+		respond(w, http.StatusNotFound, map[string]interface{}{"description": "unknown endpoint"})
+	}
+}
+
+func respond(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Print(err)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/spf13/viper"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 type Epinio struct {
@@ -33,11 +34,10 @@ type Epinio struct {
 var _ kubernetes.Deployment = &Epinio{}
 
 const (
-	EpinioDeploymentID  = "epinio"
-	epinioServerYaml    = "epinio/server.yaml"
-	epinioRolesYAML     = "epinio/roles.yaml"
-	epinioBasicAuthYaml = "epinio/basicauth.yaml"
-	applicationCRDYaml  = "epinio/app-crd.yaml"
+	EpinioDeploymentID = "epinio"
+	epinioServerYaml   = "epinio/server.yaml"
+	epinioRolesYAML    = "epinio/roles.yaml"
+	applicationCRDYaml = "epinio/app-crd.yaml"
 )
 
 func (k Epinio) ID() string {
@@ -72,16 +72,6 @@ func (k Epinio) Delete(ctx context.Context, c *kubernetes.Cluster, ui *termui.UI
 		return errors.Wrap(err, fmt.Sprintf("Deleting %s failed:\n%s", epinioRolesYAML, out))
 	}
 
-	// (yyy) Note: We ignore deletion errors due to a mising
-	// Middleware CRD. That indicates that a traefik v1 controller
-	// was used, and the object was not applied. See also (xxx).
-
-	if out, err := helpers.KubectlDeleteEmbeddedYaml(epinioBasicAuthYaml, true); err != nil {
-		if !strings.Contains(out, `no matches for kind "Middleware"`) {
-			return errors.Wrap(err, fmt.Sprintf("Deleting %s failed:\n%s", epinioServerYaml, out))
-		}
-	}
-
 	message := "Deleting Epinio namespace " + EpinioDeploymentID
 	_, err = helpers.WaitForCommandCompletion(ui, message,
 		func() (string, error) {
@@ -247,26 +237,8 @@ func (k Epinio) Upgrade(ctx context.Context, c *kubernetes.Cluster, ui *termui.U
 }
 
 // Replaces ##current_epinio_version## with version.Version and applies the embedded yaml
-func (k Epinio) applyEpinioConfigYaml(ctx context.Context, c *kubernetes.Cluster, ui *termui.UI, auth auth.PasswordAuth, issuer string, nodePort bool) (string, error) {
-	// (xxx) Apply traefik v2 middleware. This will fail for a
-	// traefik v1 controller.  Ignore error if it was due due to a
-	// missing Middleware CRD. That indicates presence of the
-	// traefik v1 controller. See also (yyy).
-
-	yamlPathOnDisk, err := helpers.ExtractFile(epinioBasicAuthYaml)
-	if err != nil {
-		return "", errors.New("Failed to extract embedded file: " + epinioBasicAuthYaml + " - " + err.Error())
-	}
-	defer os.Remove(yamlPathOnDisk)
-
-	out, err := helpers.Kubectl("apply",
-		"--namespace", EpinioDeploymentID,
-		"--filename", yamlPathOnDisk)
-	if err != nil && !strings.Contains(out, `no matches for kind "Middleware"`) {
-		return "", err
-	}
-
-	yamlPathOnDisk, err = helpers.ExtractFile(epinioServerYaml)
+func (k Epinio) applyEpinioConfigYaml(ctx context.Context, c *kubernetes.Cluster, ui *termui.UI, apiAuth auth.PasswordAuth, issuer string, nodePort bool) (string, error) {
+	yamlPathOnDisk, err := helpers.ExtractFile(epinioServerYaml)
 
 	if err != nil {
 		return "", errors.New("Failed to extract embedded file: " + epinioServerYaml + " - " + err.Error())
@@ -278,19 +250,28 @@ func (k Epinio) applyEpinioConfigYaml(ctx context.Context, c *kubernetes.Cluster
 		return "", err
 	}
 
-	htpasswd, err := auth.Htpassword()
+	// The API user is the initial admin user of epinio. See internal/users for the
+	// management of users.
+	if errs := validation.IsDNS1123Label(apiAuth.Username); len(errs) > 0 {
+		return "", fmt.Errorf("bad API user name '%s': %s", apiAuth.Username, strings.Join(errs, ", "))
+	}
+
+	hash, err := auth.HashBcrypt(apiAuth.Password)
 	if err != nil {
 		return "", err
 	}
-	encodedCredentials := base64.StdEncoding.EncodeToString([]byte(htpasswd))
-	encodedUser := base64.StdEncoding.EncodeToString([]byte(auth.Username))
-	encodedPass := base64.StdEncoding.EncodeToString([]byte(auth.Password))
+	encodedHash := base64.StdEncoding.EncodeToString([]byte(hash))
+	encodedUser := base64.StdEncoding.EncodeToString([]byte(apiAuth.Username))
+	encodedPass := base64.StdEncoding.EncodeToString([]byte(apiAuth.Password))
 
 	re := regexp.MustCompile(`##current_epinio_version##`)
 	renderedFileContents := re.ReplaceAll(fileContents, []byte(version.Version))
 
-	re = regexp.MustCompile(`##api_credentials##`)
-	renderedFileContents = re.ReplaceAll(renderedFileContents, []byte(encodedCredentials))
+	re = regexp.MustCompile(`##api_user_name##`)
+	renderedFileContents = re.ReplaceAll(renderedFileContents, []byte(apiAuth.Username))
+
+	re = regexp.MustCompile(`##api_password_hash##`)
+	renderedFileContents = re.ReplaceAll(renderedFileContents, []byte(encodedHash))
 
 	re = regexp.MustCompile(`##api_user##`)
 	renderedFileContents = re.ReplaceAll(renderedFileContents, []byte(encodedUser))
@@ -346,14 +327,6 @@ func (k *Epinio) createIngress(ctx context.Context, c *kubernetes.Cluster, subdo
 				Namespace: EpinioDeploymentID,
 				Annotations: map[string]string{
 					"kubernetes.io/ingress.class": "traefik",
-					// Traefik v1 annotations for ingress with basic auth.
-					// See `assets/embedded-files/epinio/server.yaml` for
-					// the definition of the secret.
-					"ingress.kubernetes.io/auth-type":   "basic",
-					"ingress.kubernetes.io/auth-secret": "epinio-api-auth-secret",
-					// Traefik v2 annotation for ingress with basic auth.
-					// The name of the middleware is `(namespace)-(object)@kubernetescrd`.
-					"traefik.ingress.kubernetes.io/router.middlewares": EpinioDeploymentID + "-epinio-api-auth@kubernetescrd",
 					// Traefik v1/v2 tls annotations.
 					"traefik.ingress.kubernetes.io/router.entrypoints": "websecure",
 					"traefik.ingress.kubernetes.io/router.tls":         "true",
@@ -28,10 +28,33 @@ import (
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	"github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	yaml2 "sigs.k8s.io/yaml"
 )
 
+// stagingTasks lists the tasks of the staging pipelines which push the
+// application image to the registry, with the step doing so, and the
+// directory in which the image of that step looks for trusted certificates.
+// See applyTektonStaging.
+var stagingTasks = []struct {
+	yamlPath string
+	step     string
+	certDir  string
+}{
+	{tektonStagingYamlPath, "create", "/etc/ssl/certs"},
+	{tektonKanikoYamlPath, "build", "/kaniko/ssl/certs"},
+	{tektonSkopeoYamlPath, "copy", "/etc/pki/tls/certs"},
+}
+
+// stagingPipelineYamlPaths lists the pipelines of the staging strategies
+// beyond buildpacks, whose pipeline is applied with the common tasks, see
+// tektonPipelineYamlPath.
+var stagingPipelineYamlPaths = []string{
+	"tekton/stage-pipeline-dockerfile.yaml",
+	"tekton/stage-pipeline-image.yaml",
+}
+
 type Tekton struct {
 	Debug               bool
 	Secrets             []string
@@ -51,6 +74,8 @@ const (
 	tektonStagingYamlPath         = "tekton/buildpacks-task.yaml"
 	tektonAWSYamlPath             = "tekton/aws-cli-0.2.yaml"
 	tektonPipelineYamlPath        = "tekton/stage-pipeline.yaml"
+	tektonKanikoYamlPath          = "tekton/kaniko-task.yaml"
+	tektonSkopeoYamlPath          = "tekton/skopeo-task.yaml"
 	S3ConnectionDetailsSecret     = "epinio-s3-connection-details" // nolint:gosec
 )
 
@@ -353,29 +378,11 @@ func getRegistryCAHash(ctx context.Context, c *kubernetes.Cluster) (string, erro
 	return hash, nil
 }
 
+// applyTektonStaging installs the tasks and pipelines of the staging
+// strategies. The tasks pushing to the registry are made to trust its CA.
 func applyTektonStaging(ctx context.Context, c *kubernetes.Cluster, ui *termui.UI) error {
 	var caHash string
 
-	yamlPathOnDisk, err := helpers.ExtractFile(tektonStagingYamlPath)
-	if err != nil {
-		return errors.New("Failed to extract embedded file: " + tektonStagingYamlPath + " - " + err.Error())
-	}
-	defer os.Remove(yamlPathOnDisk)
-
-	fileContents, err := ioutil.ReadFile(yamlPathOnDisk)
-	if err != nil {
-		return err
-	}
-
-	tektonTask := &v1beta1.Task{}
-	err = yaml2.Unmarshal(fileContents, tektonTask, func(opt *json.Decoder) *json.Decoder {
-		opt.UseNumber()
-		return opt
-	})
-	if err != nil {
-		return errors.Wrapf(err, "failed to unmarshal task %s", string(fileContents))
-	}
-
 	// TODO this workaround is only needed for untrusted certs.
 	//  Once we can reach Tekton via linkerd, blocked by
 	//  https://github.com/tektoncd/catalog/issues/757, we can remove the
@@ -383,7 +390,8 @@ func applyTektonStaging(ctx context.Context, c *kubernetes.Cluster, ui *termui.U
 
 	// Add volume and volume mount of registry-certs for local deployment
 	// since tekton should trust the registry-certs.
-	err = retry.Do(func() error {
+	err := retry.Do(func() error {
+		var err error
 		caHash, err = getRegistryCAHash(ctx, c)
 		return err
 	},
@@ -400,6 +408,46 @@ func applyTektonStaging(ctx context.Context, c *kubernetes.Cluster, ui *termui.U
 		return errors.Wrapf(err, "Failed to get registry CA from %s namespace", TektonStagingNamespace)
 	}
 
+	for _, task := range stagingTasks {
+		err := applyStagingTask(ctx, c, task.yamlPath, task.step, task.certDir, caHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, yamlPath := range stagingPipelineYamlPaths {
+		if out, err := helpers.KubectlApplyEmbeddedYaml(yamlPath); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Installing %s failed:\n%s", yamlPath, out))
+		}
+	}
+
+	return nil
+}
+
+// applyStagingTask creates or updates the task from the embedded yaml
+// file. With a registry CA (hash) it is mounted into the certificate
+// directory of the named step.
+func applyStagingTask(ctx context.Context, c *kubernetes.Cluster, yamlPath, step, certDir, caHash string) error {
+	yamlPathOnDisk, err := helpers.ExtractFile(yamlPath)
+	if err != nil {
+		return errors.New("Failed to extract embedded file: " + yamlPath + " - " + err.Error())
+	}
+	defer os.Remove(yamlPathOnDisk)
+
+	fileContents, err := ioutil.ReadFile(yamlPathOnDisk)
+	if err != nil {
+		return err
+	}
+
+	tektonTask := &v1beta1.Task{}
+	err = yaml2.Unmarshal(fileContents, tektonTask, func(opt *json.Decoder) *json.Decoder {
+		opt.UseNumber()
+		return opt
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to unmarshal task %s", string(fileContents))
+	}
+
 	if caHash != "" {
 		volume := corev1.Volume{
 			Name: "registry-certs",
@@ -413,12 +461,12 @@ func applyTektonStaging(ctx context.Context, c *kubernetes.Cluster, ui *termui.U
 
 		volumeMount := corev1.VolumeMount{
 			Name:      "registry-certs",
-			MountPath: fmt.Sprintf("%s/%s", "/etc/ssl/certs", caHash),
+			MountPath: fmt.Sprintf("%s/%s", certDir, caHash),
 			SubPath:   "ca.crt",
 			ReadOnly:  true,
 		}
-		for stepIndex, step := range tektonTask.Spec.Steps {
-			if step.Name == "create" {
+		for stepIndex, taskStep := range tektonTask.Spec.Steps {
+			if taskStep.Name == step {
 				tektonTask.Spec.Steps[stepIndex].VolumeMounts = append(tektonTask.Spec.Steps[stepIndex].VolumeMounts, volumeMount)
 				break
 			}
@@ -429,10 +477,24 @@ func applyTektonStaging(ctx context.Context, c *kubernetes.Cluster, ui *termui.U
 	if err != nil {
 		return errors.Wrapf(err, "failed getting tekton Task clientSet")
 	}
+	tasks := clientSet.TektonV1beta1().Tasks(TektonStagingNamespace)
 
-	_, err = clientSet.TektonV1beta1().Tasks(TektonStagingNamespace).Create(ctx, tektonTask, metav1.CreateOptions{})
+	_, err = tasks.Create(ctx, tektonTask, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		// Upgrade. Replace the spec of the existing task.
+		existing, err := tasks.Get(ctx, tektonTask.Name, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed getting tekton Task %s", tektonTask.Name)
+		}
+		existing.Spec = tektonTask.Spec
+		_, err = tasks.Update(ctx, existing, metav1.UpdateOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed updating tekton Task %s", tektonTask.Name)
+		}
+		return nil
+	}
 	if err != nil {
-		return errors.Wrapf(err, "failed creating tekton Task")
+		return errors.Wrapf(err, "failed creating tekton Task %s", tektonTask.Name)
 	}
 
 	return nil
@@ -12,6 +12,7 @@ require (
 	github.com/go-git/go-git/v5 v5.4.2
 	github.com/go-logr/logr v0.4.0
 	github.com/go-logr/stdr v0.4.0
+	github.com/google/go-containerregistry v0.4.1-0.20210128200529-19c2b639fab1
 	github.com/google/uuid v1.1.2
 	github.com/gorilla/websocket v1.4.2
 	github.com/julienschmidt/httprouter v1.3.0
@@ -38,6 +39,7 @@ require (
 	k8s.io/apiextensions-apiserver v0.20.4
 	k8s.io/apimachinery v0.20.5
 	k8s.io/client-go v0.20.5
+	knative.dev/pkg v0.0.0-20210127163530-0d31134d5f4e
 	sigs.k8s.io/application v0.8.3
 	sigs.k8s.io/yaml v1.2.0
 )
@@ -5,6 +5,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -35,7 +39,9 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	typedbatchv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
 	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
 
 	// https://github.com/kubernetes/client-go/issues/345
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
@@ -544,6 +550,45 @@ func (c *Cluster) GetPodEvents(ctx context.Context, namespace, podName string) (
 	return strings.Join(events, "\n"), nil
 }
 
+// GetPodWarningEvents returns the most recent warning events of the named
+// pod, at most limit of them, oldest first. Each event is rendered as its
+// reason and message.
+func (c *Cluster) GetPodWarningEvents(ctx context.Context, namespace, podName string, limit int) ([]string, error) {
+	eventList, err := c.Kubectl.CoreV1().Events(namespace).List(ctx,
+		metav1.ListOptions{
+			FieldSelector: fmt.Sprintf("involvedObject.name=%s,type=%s", podName, v1.EventTypeWarning),
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	items := eventList.Items
+	sort.SliceStable(items, func(i, j int) bool {
+		return eventTime(items[i]).Before(eventTime(items[j]))
+	})
+	if len(items) > limit {
+		items = items[len(items)-limit:]
+	}
+
+	events := []string{}
+	for _, event := range items {
+		events = append(events, fmt.Sprintf("%s: %s", event.Reason, event.Message))
+	}
+
+	return events, nil
+}
+
+// eventTime returns the time an event was last seen
+func eventTime(event v1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.FirstTimestamp.Time
+}
+
 func (c *Cluster) Exec(namespace, podName, containerName string, command, stdin string) (string, string, error) {
 	var stdout, stderr bytes.Buffer
 	stdinput := bytes.NewBuffer([]byte(stdin))
@@ -616,7 +661,8 @@ func (c *Cluster) GetVersion() (string, error) {
 	return v.String(), nil
 }
 
-// ListIngressRoutes returns a list of all routes for ingresses in `namespace` with the given selector
+// ListIngressRoutes returns the routes of the named ingress in `namespace`, one
+// for each path of its rules, in the form HOST[/PATH]. The path `/` is left out.
 func (c *Cluster) ListIngressRoutes(ctx context.Context, namespace, name string) ([]string, error) {
 	ingress, err := c.Kubectl.NetworkingV1().Ingresses(namespace).Get(
 		ctx, name, metav1.GetOptions{})
@@ -627,7 +673,17 @@ func (c *Cluster) ListIngressRoutes(ctx context.Context, namespace, name string)
 	result := []string{}
 
 	for _, rule := range ingress.Spec.Rules {
-		result = append(result, rule.Host)
+		if rule.HTTP == nil {
+			result = append(result, rule.Host)
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			if path.Path == "" || path.Path == "/" {
+				result = append(result, rule.Host)
+			} else {
+				result = append(result, rule.Host+path.Path)
+			}
+		}
 	}
 
 	return result, nil
@@ -655,8 +711,6 @@ func (c *Cluster) execPod(namespace, podName, containerName string,
 		"-c",
 		command,
 	}
-	req := c.Kubectl.CoreV1().RESTClient().Post().Resource("pods").Name(podName).
-		Namespace(namespace).SubResource("exec")
 	option := &v1.PodExecOptions{
 		Container: containerName,
 		Command:   cmd,
@@ -668,6 +722,38 @@ func (c *Cluster) execPod(namespace, podName, containerName string,
 	if stdin == nil {
 		option.Stdin = false
 	}
+	return c.execStream(namespace, podName, option, remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+}
+
+// ExecTTY runs the command in the container of the pod, with a TTY
+// attached to stdin and stdout. The TTY is resized to the sizes read
+// from the queue, if any. It returns when the command exits, with an
+// error carrying the exit code of failed commands.
+func (c *Cluster) ExecTTY(namespace, podName, containerName string, command []string,
+	stdin io.Reader, stdout io.Writer, sizes remotecommand.TerminalSizeQueue) error {
+	option := &v1.PodExecOptions{
+		Container: containerName,
+		Command:   command,
+		Stdin:     true,
+		Stdout:    true,
+		TTY:       true,
+	}
+	return c.execStream(namespace, podName, option, remotecommand.StreamOptions{
+		Stdin:             stdin,
+		Stdout:            stdout,
+		Tty:               true,
+		TerminalSizeQueue: sizes,
+	})
+}
+
+func (c *Cluster) execStream(namespace, podName string, option *v1.PodExecOptions,
+	streams remotecommand.StreamOptions) error {
+	req := c.Kubectl.CoreV1().RESTClient().Post().Resource("pods").Name(podName).
+		Namespace(namespace).SubResource("exec")
 	req.VersionedParams(
 		option,
 		scheme.ParameterCodec,
@@ -676,16 +762,95 @@ func (c *Cluster) execPod(namespace, podName, containerName string,
 	if err != nil {
 		return err
 	}
-	err = exec.Stream(remotecommand.StreamOptions{
-		Stdin:  stdin,
-		Stdout: stdout,
-		Stderr: stderr,
-	})
+
+	return exec.Stream(streams)
+}
+
+// portForwardResponseTimeout bounds the wait for the end of the pod's
+// data, after the end of the connection's data.
+const portForwardResponseTimeout = 30 * time.Second
+
+// PortForward connects to the port of the pod, through the API server,
+// and copies the data between the connection and the port, until the
+// port closes. The end of the connection's data is passed on to the pod,
+// whose remaining data is still copied, for up to the
+// portForwardResponseTimeout.
+func (c *Cluster) PortForward(namespace, podName string, port int, conn io.ReadWriter) error {
+	req := c.Kubectl.CoreV1().RESTClient().Post().Resource("pods").Name(podName).
+		Namespace(namespace).SubResource("portforward")
+
+	transport, upgrader, err := spdy.RoundTripperFor(c.RestConfig)
 	if err != nil {
 		return err
 	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", req.URL())
+	streamConn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return errors.Wrap(err, "connecting to the pod")
+	}
+	defer streamConn.Close()
 
-	return nil
+	headers := http.Header{}
+	headers.Set(v1.StreamType, v1.StreamTypeError)
+	headers.Set(v1.PortHeader, strconv.Itoa(port))
+	headers.Set(v1.PortForwardRequestIDHeader, "0")
+	errorStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		return errors.Wrap(err, "creating the error stream")
+	}
+	// Nothing is written to the error stream
+	errorStream.Close()
+
+	errorChan := make(chan error, 1)
+	go func() {
+		message, err := ioutil.ReadAll(errorStream)
+		switch {
+		case err != nil:
+			errorChan <- errors.Wrap(err, "reading the error stream")
+		case len(message) > 0:
+			errorChan <- errors.New(string(message))
+		}
+		close(errorChan)
+	}()
+
+	headers.Set(v1.StreamType, v1.StreamTypeData)
+	dataStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		return errors.Wrap(err, "creating the data stream")
+	}
+
+	localDone := make(chan struct{})
+	remoteDone := make(chan struct{})
+
+	go func() {
+		// nolint:errcheck // the end of the copy is all that matters
+		io.Copy(conn, dataStream)
+		close(remoteDone)
+	}()
+
+	go func() {
+		// Tell the pod that no more data is coming
+		defer dataStream.Close()
+
+		// nolint:errcheck // the end of the copy is all that matters
+		io.Copy(dataStream, conn)
+		close(localDone)
+	}()
+
+	select {
+	case <-remoteDone:
+		return <-errorChan
+	case <-localDone:
+	}
+
+	// The connection sent all its data, wait for the response of the pod
+	select {
+	case <-remoteDone:
+		return <-errorChan
+	case <-time.After(portForwardResponseTimeout):
+		// The error stream ends with the stream connection
+		return nil
+	}
 }
 
 // NamespaceExistsAndOwned checks if the namespace exists
@@ -87,6 +87,9 @@ func FetchLogs(ctx context.Context, logChan chan ContainerLogLine, wg *sync.Wait
 	}
 
 	for _, pod := range podList.Items {
+		if !config.PodQuery.MatchString(pod.Name) {
+			continue
+		}
 		for _, c := range pod.Spec.Containers {
 			if !acceptable(c) {
 				continue
@@ -1,31 +1,103 @@
 package helpers
 
 import (
+	"archive/tar"
+	"bufio"
+	"io"
 	"io/ioutil"
+	"os"
 	"path"
+	"path/filepath"
+	"strings"
 
-	"github.com/mholt/archiver/v3"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
 	"github.com/pkg/errors"
 )
 
-func Tar(dir string) (string, string, error) {
-	files, err := ioutil.ReadDir(dir)
-	if err != nil {
-		return "", "", errors.Wrap(err, "cannot read the apps source files")
-	}
-	sources := []string{}
-	for _, f := range files {
-		// The FileInfo entries returned by ReadDir provide
-		// only the base name of the file or directory they
-		// are for. We have to add back the path of the
-		// application directory to get the proper paths to
-		// the files and directories to assemble in the
-		// tarball.
-		// Ignore git config files in the app sources.
-		if f.Name() == ".git" || f.Name() == ".gitignore" || f.Name() == ".gitmodules" || f.Name() == ".gitconfig" || f.Name() == ".git-credentials" {
-			continue
+// IgnoreFile is the name of the files listing the application sources
+// to leave out of the upload, in gitignore syntax. A directory without
+// such a file uses the patterns of its .gitignore instead.
+const IgnoreFile = ".epinioignore"
+
+// alwaysIgnored are the names of the files and directories which are
+// never uploaded, at any depth.
+var alwaysIgnored = map[string]bool{
+	".git":             true,
+	".gitignore":       true,
+	".gitmodules":      true,
+	".gitconfig":       true,
+	".git-credentials": true,
+	IgnoreFile:         true,
+}
+
+// SourceFile is a file or directory of the application sources.
+type SourceFile struct {
+	// Path is relative to the application directory, slash separated
+	Path string
+	Info os.FileInfo
+}
+
+// Sources returns the files and directories of the application sources
+// in dir, except for the ignored ones, see IgnoreFile. Directories are
+// listed before their contents.
+func Sources(dir string) ([]SourceFile, error) {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read the apps source files")
+	}
+
+	patterns, err := ignorePatterns(dir, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := []SourceFile{}
+	err = filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if file == dir {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
 		}
-		sources = append(sources, path.Join(dir, f.Name()))
+		rel = filepath.ToSlash(rel)
+		segments := strings.Split(rel, "/")
+
+		if alwaysIgnored[info.Name()] || gitignore.NewMatcher(patterns).Match(segments, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			patterns, err = ignorePatterns(file, segments, patterns)
+			if err != nil {
+				return err
+			}
+		}
+
+		sources = append(sources, SourceFile{Path: rel, Info: info})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read the apps source files")
+	}
+
+	return sources, nil
+}
+
+// Tar creates a tarball of the application sources in dir, see Sources.
+// It returns the temporary directory holding the tarball, and the path of
+// the tarball.
+func Tar(dir string) (string, string, error) {
+	sources, err := Sources(dir)
+	if err != nil {
+		return "", "", err
 	}
 
 	// create a tmpDir - tarball dir and POST
@@ -35,10 +107,101 @@ func Tar(dir string) (string, string, error) {
 	}
 
 	tarball := path.Join(tmpDir, "blob.tar")
-	err = archiver.Archive(sources, tarball)
+	err = archive(dir, sources, tarball)
 	if err != nil {
 		return tmpDir, "", errors.Wrap(err, "can't create archive")
 	}
 
 	return tmpDir, tarball, nil
 }
+
+// archive writes the sources found in dir into the tarball
+func archive(dir string, sources []SourceFile, tarball string) error {
+	out, err := os.Create(tarball)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	t := tar.NewWriter(out)
+	for _, source := range sources {
+		err := archiveFile(t, filepath.Join(dir, filepath.FromSlash(source.Path)), source)
+		if err != nil {
+			return err
+		}
+	}
+
+	return t.Close()
+}
+
+// archiveFile writes a single source into the tarball. The file is the
+// absolute path of the source, symlinks are read from it and archived as
+// links.
+func archiveFile(t *tar.Writer, file string, source SourceFile) error {
+	link := ""
+	if source.Info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(file)
+		if err != nil {
+			return errors.Wrapf(err, "%s: readlink", source.Path)
+		}
+		link = filepath.ToSlash(target)
+	}
+
+	hdr, err := tar.FileInfoHeader(source.Info, link)
+	if err != nil {
+		return errors.Wrapf(err, "%s: making header", source.Path)
+	}
+	hdr.Name = source.Path
+	if source.Info.IsDir() {
+		hdr.Name += "/"
+	}
+
+	if err := t.WriteHeader(hdr); err != nil {
+		return errors.Wrapf(err, "%s: writing header", source.Path)
+	}
+
+	if !source.Info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(t, f)
+	return errors.Wrapf(err, "%s: copying contents", source.Path)
+}
+
+// ignorePatterns adds the patterns of the ignore file in dir to the
+// patterns, scoped to the directory, i.e. the domain. The IgnoreFile is
+// preferred over the .gitignore.
+func ignorePatterns(dir string, domain []string, patterns []gitignore.Pattern) ([]gitignore.Pattern, error) {
+	for _, name := range []string{IgnoreFile, ".gitignore"} {
+		f, err := os.Open(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read %s", name)
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			patterns = append(patterns, gitignore.ParsePattern(line, domain))
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, errors.Wrapf(err, "cannot read %s", name)
+		}
+
+		return patterns, nil
+	}
+
+	return patterns, nil
+}
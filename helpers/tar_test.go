@@ -0,0 +1,131 @@
+package helpers_test
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/epinio/epinio/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sources", func() {
+	var dir string
+
+	write := func(file, contents string) {
+		file = filepath.Join(dir, file)
+		Expect(os.MkdirAll(filepath.Dir(file), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(file, []byte(contents), 0644)).To(Succeed())
+	}
+
+	paths := func() []string {
+		sources, err := Sources(dir)
+		Expect(err).ToNot(HaveOccurred())
+		result := []string{}
+		for _, source := range sources {
+			if !source.Info.IsDir() {
+				result = append(result, source.Path)
+			}
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "epinio-test")
+		Expect(err).ToNot(HaveOccurred())
+
+		write("index.js", "app")
+		write("lib/util.js", "util")
+		write(".env", "SECRET=1")
+		write("node_modules/dep/index.js", "dep")
+		write(".git/HEAD", "ref")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("skips the git files, and nothing else, without ignore files", func() {
+		Expect(paths()).To(ConsistOf(".env", "index.js", "lib/util.js", "node_modules/dep/index.js"))
+	})
+
+	It("honours the patterns of the .epinioignore", func() {
+		write(IgnoreFile, "# local files\nnode_modules/\n.env\n")
+		Expect(paths()).To(ConsistOf("index.js", "lib/util.js"))
+	})
+
+	It("honours .epinioignore files in subdirectories, for their directory only", func() {
+		write("lib/"+IgnoreFile, "*.js\n")
+		Expect(paths()).To(ConsistOf(".env", "index.js", "node_modules/dep/index.js"))
+	})
+
+	It("honours negated patterns", func() {
+		write(IgnoreFile, "*.js\n!index.js\n")
+		Expect(paths()).To(ConsistOf(".env", "index.js", "node_modules/dep/index.js"))
+	})
+
+	It("falls back to the .gitignore", func() {
+		write(".gitignore", "node_modules\n")
+		Expect(paths()).To(ConsistOf(".env", "index.js", "lib/util.js"))
+	})
+
+	It("prefers the .epinioignore over the .gitignore", func() {
+		write(".gitignore", "node_modules\n")
+		write(IgnoreFile, ".env\n")
+		Expect(paths()).To(ConsistOf("index.js", "lib/util.js", "node_modules/dep/index.js"))
+	})
+
+	It("archives the sources only", func() {
+		write(IgnoreFile, "node_modules\n.env\n")
+
+		tmpDir, tarball, err := Tar(dir)
+		defer os.RemoveAll(tmpDir)
+		Expect(err).ToNot(HaveOccurred())
+
+		f, err := os.Open(tarball)
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+
+		names := []string{}
+		r := tar.NewReader(f)
+		for {
+			hdr, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).ToNot(HaveOccurred())
+			names = append(names, hdr.Name)
+		}
+		Expect(names).To(ConsistOf("index.js", "lib/", "lib/util.js"))
+	})
+
+	It("archives symlinks as links", func() {
+		Expect(os.Symlink("index.js", filepath.Join(dir, "main.js"))).To(Succeed())
+
+		tmpDir, tarball, err := Tar(dir)
+		defer os.RemoveAll(tmpDir)
+		Expect(err).ToNot(HaveOccurred())
+
+		f, err := os.Open(tarball)
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+
+		links := map[string]string{}
+		r := tar.NewReader(f)
+		for {
+			hdr, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).ToNot(HaveOccurred())
+			if hdr.Typeflag == tar.TypeSymlink {
+				links[hdr.Name] = hdr.Linkname
+			}
+		}
+		Expect(links).To(Equal(map[string]string{"main.js": "index.js"}))
+	})
+})
@@ -0,0 +1,93 @@
+// Package wsstream provides a byte stream on top of a websocket
+// connection, for tunnelling TCP connections through the API server.
+package wsstream
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/gorilla/websocket"
+)
+
+// EndOfData is the text message announcing that the peer sends no more
+// data. The other direction of the connection stays open, like for the
+// half-close of a TCP connection.
+const EndOfData = "eof"
+
+// Stream reads the binary messages of the websocket connection as one
+// continuous stream of bytes, and writes each chunk of data as a binary
+// message. Other text messages are ignored. The end of the stream is the
+// EndOfData message, or the close of the connection by the peer. Errors
+// are reported as the text of the close message.
+type Stream struct {
+	conn   *websocket.Conn
+	reader io.Reader
+	eof    bool
+}
+
+// New returns the stream of the connection. It supports one reader and
+// one writer at a time.
+func New(conn *websocket.Conn) *Stream {
+	return &Stream{conn: conn}
+}
+
+// Read reads from the binary messages of the connection. It returns
+// io.EOF after the EndOfData message, or when the peer closed the
+// connection normally, and the text of the close message as error, if
+// there is one.
+func (s *Stream) Read(p []byte) (int, error) {
+	for {
+		if s.eof {
+			return 0, io.EOF
+		}
+
+		if s.reader != nil {
+			n, err := s.reader.Read(p)
+			if err == io.EOF {
+				s.reader = nil
+				if n == 0 {
+					continue
+				}
+				err = nil
+			}
+			return n, err
+		}
+
+		kind, reader, err := s.conn.NextReader()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) && closeErr.Code == websocket.CloseNormalClosure {
+				if closeErr.Text != "" {
+					return 0, errors.New(closeErr.Text)
+				}
+				return 0, io.EOF
+			}
+			return 0, err
+		}
+		switch kind {
+		case websocket.BinaryMessage:
+			s.reader = reader
+		case websocket.TextMessage:
+			message, err := ioutil.ReadAll(reader)
+			if err != nil {
+				return 0, err
+			}
+			s.eof = string(message) == EndOfData
+		}
+	}
+}
+
+// Write writes the data as a binary message
+func (s *Stream) Write(p []byte) (int, error) {
+	if err := s.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// CloseWrite sends the EndOfData message. The stream is not written to
+// after that, but still read from.
+func (s *Stream) CloseWrite() error {
+	return s.conn.WriteMessage(websocket.TextMessage, []byte(EndOfData))
+}
@@ -0,0 +1,13 @@
+package wsstream_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestWsstream(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Wsstream Suite")
+}
@@ -0,0 +1,92 @@
+package wsstream_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/epinio/epinio/helpers/wsstream"
+	"github.com/gorilla/websocket"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream", func() {
+	var server *httptest.Server
+
+	// serve runs the handler on the server side of a websocket
+	// connection, and returns the client side
+	serve := func(handler func(conn *websocket.Conn)) *websocket.Conn {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upgrader := websocket.Upgrader{}
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			handler(conn)
+		}))
+
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		Expect(err).ToNot(HaveOccurred())
+		return conn
+	}
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("reads the binary messages as one stream, until the close", func() {
+		conn := serve(func(conn *websocket.Conn) {
+			stream := wsstream.New(conn)
+			_, _ = stream.Write([]byte("hello "))
+			_ = conn.WriteMessage(websocket.TextMessage, []byte("ignored"))
+			_, _ = stream.Write([]byte("world"))
+			_ = conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		})
+		defer conn.Close()
+
+		data, err := ioutil.ReadAll(wsstream.New(conn))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("hello world"))
+	})
+
+	It("reports the text of the close message as error", func() {
+		conn := serve(func(conn *websocket.Conn) {
+			_, _ = wsstream.New(conn).Write([]byte("partial"))
+			_ = conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, "connection refused"))
+		})
+		defer conn.Close()
+
+		data, err := ioutil.ReadAll(wsstream.New(conn))
+		Expect(err).To(MatchError("connection refused"))
+		Expect(string(data)).To(Equal("partial"))
+	})
+
+	It("ends the stream of the peer at its end of data, keeping the other direction", func() {
+		conn := serve(func(conn *websocket.Conn) {
+			stream := wsstream.New(conn)
+			request, err := ioutil.ReadAll(stream)
+			if err != nil {
+				return
+			}
+			_, _ = stream.Write([]byte("got " + string(request)))
+			_ = conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		})
+		defer conn.Close()
+
+		stream := wsstream.New(conn)
+		_, err := stream.Write([]byte("request"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stream.CloseWrite()).To(Succeed())
+
+		data, err := ioutil.ReadAll(stream)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("got request"))
+	})
+})
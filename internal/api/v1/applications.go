@@ -3,8 +3,11 @@ package v1
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"regexp"
+	"strconv"
 	"sync"
 	"time"
 
@@ -14,11 +17,15 @@ import (
 	"github.com/epinio/epinio/internal/application"
 	"github.com/epinio/epinio/internal/duration"
 	"github.com/epinio/epinio/internal/organizations"
+	"github.com/epinio/epinio/internal/routes"
 	"github.com/epinio/epinio/internal/services"
+	"github.com/epinio/epinio/internal/staging"
+	"github.com/epinio/epinio/internal/users"
 	"github.com/epinio/epinio/pkg/api/core/v1/models"
 	"github.com/gorilla/websocket"
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // ApplicationsController represents all functionality of the API related to applications
@@ -102,6 +109,26 @@ func (hc ApplicationsController) Create(w http.ResponseWriter, r *http.Request)
 		return MultiError{theIssues}
 	}
 
+	_, err = routes.FromStrings(createRequest.Configuration.Routes)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	apierr := appRoutesClaimed(ctx, cluster, appRef, createRequest.Configuration.Routes)
+	if apierr != nil {
+		return apierr
+	}
+
+	apierr = healthValidate(createRequest.Configuration)
+	if apierr != nil {
+		return apierr
+	}
+
+	apierr = resourcesValidate(createRequest.Configuration)
+	if apierr != nil {
+		return apierr
+	}
+
 	// Arguments found OK, now we can modify the system state
 
 	err = application.Create(ctx, cluster, appRef, username)
@@ -133,6 +160,40 @@ func (hc ApplicationsController) Create(w http.ResponseWriter, r *http.Request)
 		return InternalError(err)
 	}
 
+	// Save routes, if any
+	if len(createRequest.Configuration.Routes) > 0 {
+		err = application.RoutesSet(ctx, cluster, appRef,
+			createRequest.Configuration.Routes)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	// Save compute resources, if any
+	if createRequest.Configuration.Resources != nil {
+		err = application.ResourcesSet(ctx, cluster, appRef,
+			models.AppResources{}.Merge(*createRequest.Configuration.Resources))
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	// Save health check and rollout strategy, if any
+	if createRequest.Configuration.HealthCheck != nil {
+		err = application.HealthCheckSet(ctx, cluster, appRef,
+			*createRequest.Configuration.HealthCheck)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+	if createRequest.Configuration.Rollout != nil {
+		err = application.RolloutSet(ctx, cluster, appRef,
+			*createRequest.Configuration.Rollout)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
 	err = jsonResponse(w, models.ResponseOK)
 	if err != nil {
 		return InternalError(err)
@@ -159,6 +220,7 @@ func (hc ApplicationsController) FullIndex(w http.ResponseWriter, r *http.Reques
 	if err != nil {
 		return InternalError(err)
 	}
+	orgList = allowedNamespaces(r, orgList)
 
 	var allApps models.AppList
 
@@ -220,7 +282,8 @@ func (hc ApplicationsController) Index(w http.ResponseWriter, r *http.Request) A
 }
 
 // Show handles the API endpoint GET /namespaces/:org/applications/:app
-// It returns the details of the specified application.
+// It returns the details of the specified application, including the
+// instances of its workload.
 func (hc ApplicationsController) Show(w http.ResponseWriter, r *http.Request) APIErrors {
 	ctx := r.Context()
 	params := httprouter.ParamsFromContext(ctx)
@@ -250,6 +313,16 @@ func (hc ApplicationsController) Show(w http.ResponseWriter, r *http.Request) AP
 		return AppIsNotKnown(appName)
 	}
 
+	app.StagingID, err = staging.InFlight(ctx, cluster, app.Meta)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	// Errors leave the instances unknown. The status reports the replicas
+	if app.Workload != nil && app.Workload.Active {
+		app.Workload.Instances, _ = application.NewWorkload(cluster, app.Meta).Instances(ctx)
+	}
+
 	err = jsonResponse(w, app)
 	if err != nil {
 		return InternalError(err)
@@ -348,6 +421,21 @@ func (hc ApplicationsController) Update(w http.ResponseWriter, r *http.Request)
 		return NewBadRequest("instances param should be integer equal or greater than zero")
 	}
 
+	_, err = routes.FromStrings(updateRequest.Routes)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	apierr := healthValidate(updateRequest)
+	if apierr != nil {
+		return apierr
+	}
+
+	apierr = resourcesValidate(updateRequest)
+	if apierr != nil {
+		return apierr
+	}
+
 	app, err := application.Lookup(ctx, cluster, org, appName)
 	if err != nil {
 		return InternalError(err)
@@ -359,15 +447,32 @@ func (hc ApplicationsController) Update(w http.ResponseWriter, r *http.Request)
 	if updateRequest.Instances != nil {
 		desired := *updateRequest.Instances
 
+		autoscale, err := application.Autoscale(ctx, cluster, app.Meta)
+		if err != nil {
+			return InternalError(err)
+		}
+		if autoscale != nil {
+			return autoscaled(appName)
+		}
+
 		// Save to configuration
-		err := application.ScalingSet(ctx, cluster, app.Meta, desired)
+		err = application.ScalingSet(ctx, cluster, app.Meta, desired)
 		if err != nil {
 			return InternalError(err)
 		}
 
-		// Restart workload, if any
-		if app.Workload != nil {
+		stopped, err := application.Stopped(ctx, cluster, app.Meta)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		// Restart workload, if any. A stopped workload picks the
+		// instances up when started.
+		if app.Workload != nil && !stopped {
 			err = application.NewWorkload(cluster, app.Meta).Scale(ctx, desired)
+			if errors.Is(err, application.ErrAutoscaled) {
+				return autoscaled(appName)
+			}
 			if err != nil {
 				return InternalError(err)
 			}
@@ -450,6 +555,121 @@ func (hc ApplicationsController) Update(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	if updateRequest.Routes != nil {
+		apierr := appRoutesClaimed(ctx, cluster, app.Meta, updateRequest.Routes)
+		if apierr != nil {
+			return apierr
+		}
+
+		err := application.RoutesSet(ctx, cluster, app.Meta, updateRequest.Routes)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		// For this read the new set of routes back, as the empty set means the default route
+		newRoutes, err := application.Routes(ctx, cluster, app.Meta)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		// Update ingress and certificates of the workload, if any
+		if app.Workload != nil {
+			applicationCR, err := application.Get(ctx, cluster, app.Meta)
+			if err != nil {
+				return InternalError(err)
+			}
+			owner := metav1.OwnerReference{
+				APIVersion: applicationCR.GetAPIVersion(),
+				Kind:       applicationCR.GetKind(),
+				Name:       applicationCR.GetName(),
+				UID:        applicationCR.GetUID(),
+			}
+
+			_, err = appRoutesSync(ctx, cluster, app.Meta, owner, username)
+			if err != nil {
+				return InternalError(err)
+			}
+		}
+
+		err = appRoutesCertificatesDelete(ctx, cluster, app.Meta, app.Configuration.Routes, newRoutes)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	if updateRequest.Resources != nil {
+		current, err := application.Resources(ctx, cluster, app.Meta)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		// The merged resources are validated again, as a request may now
+		// exceed a limit set by an earlier update
+		resources := current.Merge(*updateRequest.Resources)
+		err = application.ResourcesValidate(resources)
+		if err != nil {
+			return NewBadRequest(err.Error())
+		}
+
+		// The autoscaler needs the CPU request
+		if resources.CPURequest == "" {
+			autoscale, err := application.Autoscale(ctx, cluster, app.Meta)
+			if err != nil {
+				return InternalError(err)
+			}
+			if autoscale != nil {
+				return NewBadRequest(application.ErrNoCPURequest.Error(),
+					fmt.Sprintf("disable autoscaling first, with `epinio app autoscale %s --disable`", appName))
+			}
+		}
+
+		// Save to configuration
+		err = application.ResourcesSet(ctx, cluster, app.Meta, resources)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		// Update the workload, if any
+		if app.Workload != nil {
+			err = application.NewWorkload(cluster, app.Meta).ResourcesChange(ctx, resources)
+			if err != nil {
+				return InternalError(err)
+			}
+		}
+	}
+
+	if updateRequest.HealthCheck != nil || updateRequest.Rollout != nil {
+		if updateRequest.HealthCheck != nil {
+			err := application.HealthCheckSet(ctx, cluster, app.Meta, *updateRequest.HealthCheck)
+			if err != nil {
+				return InternalError(err)
+			}
+		}
+		if updateRequest.Rollout != nil {
+			err := application.RolloutSet(ctx, cluster, app.Meta, *updateRequest.Rollout)
+			if err != nil {
+				return InternalError(err)
+			}
+		}
+
+		// Update the workload, if any. For this read the complete settings back
+		if app.Workload != nil {
+			healthCheck, err := application.HealthCheck(ctx, cluster, app.Meta)
+			if err != nil {
+				return InternalError(err)
+			}
+			rollout, err := application.Rollout(ctx, cluster, app.Meta)
+			if err != nil {
+				return InternalError(err)
+			}
+
+			err = application.NewWorkload(cluster, app.Meta).HealthChange(ctx, healthCheck, rollout)
+			if err != nil {
+				return InternalError(err)
+			}
+		}
+	}
+
 	err = jsonResponse(w, models.ResponseOK)
 	if err != nil {
 		return InternalError(err)
@@ -463,7 +683,8 @@ func (hc ApplicationsController) Update(w http.ResponseWriter, r *http.Request)
 // deployment to be complete), before it returns. An exception is if
 // the application does not become running without
 // `duration.ToAppBuilt()` (default: 10 minutes). In that case it
-// returns with an error after that time.
+// returns with an error after that time. A failed rollout, e.g. due to
+// a crashing pod, is reported right away, with the reason.
 func (hc ApplicationsController) Running(w http.ResponseWriter, r *http.Request) APIErrors {
 	ctx := r.Context()
 	params := httprouter.ParamsFromContext(ctx)
@@ -499,8 +720,13 @@ func (hc ApplicationsController) Running(w http.ResponseWriter, r *http.Request)
 			"", http.StatusBadRequest)
 	}
 
-	err = cluster.WaitForDeploymentCompleted(
-		ctx, nil, org, appName, duration.ToAppBuilt())
+	err = application.NewWorkload(cluster, app.Meta).WaitForRollout(ctx, duration.ToAppBuilt())
+	var failure *application.RolloutError
+	if errors.As(err, &failure) {
+		return NewAPIError(fmt.Sprintf("application rollout failed, %s", failure),
+			fmt.Sprintf("see the logs with `epinio app logs %s`", appName),
+			http.StatusUnprocessableEntity)
+	}
 	if err != nil {
 		return InternalError(err)
 	}
@@ -516,7 +742,10 @@ func (hc ApplicationsController) Running(w http.ResponseWriter, r *http.Request)
 // and                            GET /namespaces/:org/staging/:stage_id/logs
 // It arranges for the logs of the specified application to be
 // streamed over a websocket. Dependent on the endpoint this may be
-// either regular logs, or the app's staging logs.
+// either regular logs, or the app's staging logs. The query parameters
+// select and filter the logs, see logParameters. The `instance` query
+// parameter selects the instance by index, as for Exec, without
+// requiring it to be running.
 func (hc ApplicationsController) Logs(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	params := httprouter.ParamsFromContext(ctx)
@@ -574,6 +803,35 @@ func (hc ApplicationsController) Logs(w http.ResponseWriter, r *http.Request) {
 	queryValues := r.URL.Query()
 	followStr := queryValues.Get("follow")
 
+	logParams, apierr := logParameters(r)
+	if apierr != nil {
+		jsonErrorResponse(w, apierr)
+		return
+	}
+
+	index, apierr := instanceParam(r)
+	if apierr != nil {
+		jsonErrorResponse(w, apierr)
+		return
+	}
+	if index >= 0 {
+		if appName == "" {
+			jsonErrorResponse(w, NewBadRequest("the staging logs have no instances to select"))
+			return
+		}
+
+		instances, err := application.NewWorkload(cluster, models.NewAppRef(appName, org)).Instances(ctx)
+		if err != nil {
+			jsonErrorResponse(w, InternalError(err))
+			return
+		}
+		if index >= len(instances) {
+			jsonErrorResponse(w, NewBadRequest(fmt.Sprintf("application has no instance %d, it has %d instances", index, len(instances))))
+			return
+		}
+		logParams.Instance = instances[index].Name
+	}
+
 	log.Info("processed query", "values", queryValues)
 	log.Info("upgrade to web socket")
 
@@ -593,7 +851,7 @@ func (hc ApplicationsController) Logs(w http.ResponseWriter, r *http.Request) {
 	log.Info("streaming begin")
 
 	hc.conn = conn
-	err = hc.streamPodLogs(ctx, org, appName, stageID, cluster, follow)
+	err = hc.streamPodLogs(ctx, org, appName, stageID, cluster, follow, logParams)
 	if err != nil {
 		log.V(1).Error(err, "error occurred after upgrading the websockets connection")
 		return
@@ -602,6 +860,53 @@ func (hc ApplicationsController) Logs(w http.ResponseWriter, r *http.Request) {
 	log.Info("streaming completed")
 }
 
+// logParameters returns the settings for application.Logs from the query
+// parameters of the request:
+// - since: the duration to reach into the past, e.g. 1h, at least 1s
+// - tail: the number of most recent lines of each container
+// - include, exclude: regular expressions filtering the lines, repeatable
+// - timestamps: `true` to prefix the lines with their time
+func logParameters(r *http.Request) (application.LogParameters, APIErrors) {
+	query := r.URL.Query()
+	params := application.LogParameters{
+		Timestamps: query.Get("timestamps") == "true",
+	}
+
+	if value := query.Get("since"); value != "" {
+		since, err := time.ParseDuration(value)
+		if err != nil || since < time.Second {
+			return params, NewBadRequest(fmt.Sprintf("bad since '%s', expected a duration of at least 1s, e.g. 1h", value))
+		}
+		params.Since = since
+	}
+
+	if value := query.Get("tail"); value != "" {
+		tail, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || tail < 0 {
+			return params, NewBadRequest(fmt.Sprintf("bad tail '%s', expected a number from 0", value))
+		}
+		params.Tail = &tail
+	}
+
+	for _, pattern := range query["include"] {
+		rex, err := regexp.Compile(pattern)
+		if err != nil {
+			return params, NewBadRequest(fmt.Sprintf("bad include pattern '%s': %s", pattern, err.Error()))
+		}
+		params.Include = append(params.Include, rex)
+	}
+
+	for _, pattern := range query["exclude"] {
+		rex, err := regexp.Compile(pattern)
+		if err != nil {
+			return params, NewBadRequest(fmt.Sprintf("bad exclude pattern '%s': %s", pattern, err.Error()))
+		}
+		params.Exclude = append(params.Exclude, rex)
+	}
+
+	return params, nil
+}
+
 // streamPodLogs sends the logs of any containers matching orgName, appName
 // and stageID to hc.conn (websockets) until ctx is Done or the connection is
 // closed.
@@ -617,7 +922,7 @@ func (hc ApplicationsController) Logs(w http.ResponseWriter, r *http.Request) {
 // connection is closed. In any case it will call the cancel func that will stop
 // all the children go routines described above and then will wait for their parent
 // go routine to stop too (using another WaitGroup).
-func (hc ApplicationsController) streamPodLogs(ctx context.Context, orgName, appName, stageID string, cluster *kubernetes.Cluster, follow bool) error {
+func (hc ApplicationsController) streamPodLogs(ctx context.Context, orgName, appName, stageID string, cluster *kubernetes.Cluster, follow bool, params application.LogParameters) error {
 	logger := tracelog.NewLogger().WithName("streamer-to-websockets").V(1)
 	logChan := make(chan tailer.ContainerLogLine)
 	logCtx, logCancelFunc := context.WithCancel(ctx)
@@ -631,7 +936,7 @@ func (hc ApplicationsController) streamPodLogs(ctx context.Context, orgName, app
 		}()
 
 		var tailWg sync.WaitGroup
-		err := application.Logs(logCtx, logChan, &tailWg, cluster, follow, appName, stageID, orgName)
+		err := application.Logs(logCtx, logChan, &tailWg, cluster, follow, appName, stageID, orgName, params)
 		if err != nil {
 			logger.Error(err, "setting up log routines failed")
 		}
@@ -745,12 +1050,73 @@ func (hc ApplicationsController) Delete(w http.ResponseWriter, r *http.Request)
 	return nil
 }
 
-// GetUsername returns the username from the header
+// GetUsername returns the name of the user authenticated for the request
 func GetUsername(r *http.Request) (string, error) {
-	username := r.Header.Get("X-Webauth-User")
-	if len(username) <= 0 {
-		return "", errors.New("username not found in the header")
+	user, ok := users.FromContext(r.Context())
+	if !ok || len(user.Username) <= 0 {
+		return "", errors.New("username not found in the request")
+	}
+
+	return user.Username, nil
+}
+
+// resourcesValidate checks the compute resource settings of the
+// configuration, if any. The removal of a resource, i.e. the value "0",
+// is accepted.
+func resourcesValidate(configuration models.ApplicationUpdateRequest) APIErrors {
+	if configuration.Resources != nil {
+		if err := application.ResourcesValidate(models.AppResources{}.Merge(*configuration.Resources)); err != nil {
+			return NewBadRequest(err.Error())
+		}
+	}
+	return nil
+}
+
+// healthValidate checks the health check and rollout settings of the
+// configuration, if any.
+func healthValidate(configuration models.ApplicationUpdateRequest) APIErrors {
+	if configuration.HealthCheck != nil {
+		if err := application.HealthCheckValidate(*configuration.HealthCheck); err != nil {
+			return NewBadRequest(err.Error())
+		}
+	}
+	if configuration.Rollout != nil {
+		if err := application.RolloutValidate(*configuration.Rollout); err != nil {
+			return NewBadRequest(err.Error())
+		}
+	}
+	return nil
+}
+
+// autoscaled returns the error for manual changes of the instances of an
+// autoscaled application.
+func autoscaled(appName string) APIErrors {
+	return NewBadRequest(application.ErrAutoscaled.Error(),
+		fmt.Sprintf("disable autoscaling first, with `epinio app autoscale %s --disable`", appName))
+}
+
+// knownApp returns the reference of the named application, after
+// checking that it and its namespace exist.
+func knownApp(ctx context.Context, cluster *kubernetes.Cluster, org, appName string) (models.AppRef, APIErrors) {
+	app := models.NewAppRef(appName, org)
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return app, InternalError(err)
+	}
+
+	if !exists {
+		return app, OrgIsNotKnown(org)
+	}
+
+	exists, err = application.Exists(ctx, cluster, app)
+	if err != nil {
+		return app, InternalError(err)
+	}
+
+	if !exists {
+		return app, AppIsNotKnown(appName)
 	}
 
-	return username, nil
+	return app, nil
 }
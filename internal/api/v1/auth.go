@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/tokens"
+	"github.com/epinio/epinio/internal/users"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+)
+
+// AuthController represents all functionality of the API related to API tokens
+type AuthController struct {
+}
+
+// Token handles the API endpoint /auth/token (POST).
+// It returns a new token for the user whose credentials are in the request body.
+// This endpoint does not require authentication.
+func (ac AuthController) Token(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var tokenRequest models.AuthTokenRequest
+	err = json.Unmarshal(bodyBytes, &tokenRequest)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	user, err := users.Authenticate(ctx, cluster, tokenRequest.Username, tokenRequest.Password)
+	if err == users.ErrBadCredentials {
+		return UserNotAuthenticated()
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+
+	token, claims, err := tokens.Issue(ctx, cluster, user.Username)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, models.AuthTokenResponse{
+		Token:   token,
+		Expires: claims.ExpiresAt(),
+	})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Refresh handles the API endpoint /auth/token/refresh (POST).
+// It returns a new token for the authenticated user. The token
+// authenticating the request, if any, is revoked.
+func (ac AuthController) Refresh(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	username, err := GetUsername(r)
+	if err != nil {
+		return UserNotFound()
+	}
+
+	token, claims, err := tokens.Issue(ctx, cluster, username)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if oldClaims, ok := requestTokenClaims(r); ok {
+		err = tokens.Revoke(ctx, cluster, oldClaims)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	err = jsonResponse(w, models.AuthTokenResponse{
+		Token:   token,
+		Expires: claims.ExpiresAt(),
+	})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Revoke handles the API endpoint /auth/token (DELETE).
+// It revokes the token authenticating the request.
+func (ac AuthController) Revoke(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	claims, ok := requestTokenClaims(r)
+	if !ok {
+		return NewBadRequest("Request is not authenticated by a token")
+	}
+
+	err = tokens.Revoke(ctx, cluster, claims)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, models.ResponseOK)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
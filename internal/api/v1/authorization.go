@@ -0,0 +1,200 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/routes"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/auth"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/epinio/epinio/internal/tokens"
+	"github.com/epinio/epinio/internal/users"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/julienschmidt/httprouter"
+)
+
+// adminRoutes lists the routes which are restricted to admins. All other
+// routes touching a namespace (`:org`) are authorized against the role
+// of the user in that namespace, see authorize.
+var adminRoutes = map[string]struct{}{
+	"NamespaceCreate": {},
+	"NamespaceDelete": {},
+	"Users":           {},
+	"UserCreate":      {},
+	"UserDelete":      {},
+	"UserGrant":       {},
+	"UserRevoke":      {},
+	"Caches":          {},
+	"Blobs":           {},
+	"BlobsPrune":      {},
+}
+
+// writeRoutes lists the GET routes which require write access to the
+// namespace, as they give access to the insides of applications, or to
+// the values of their environment and of services, i.e. to secrets.
+var writeRoutes = map[string]struct{}{
+	"AppExec":        {},
+	"AppPortForward": {},
+	"EnvList":        {},
+	"EnvShow":        {},
+	"ServiceShow":    {},
+}
+
+// publicRoutes lists the routes which do not require authentication.
+var publicRoutes = map[string]struct{}{
+	"AuthToken": {},
+}
+
+// Authenticate is the middleware authenticating the requests to the server,
+// either by a bearer token issued by the server, see tokens.Issue, or by
+// basic auth against the users known to epinio. The user is recorded in the
+// context of the request, for authorization by the handlers. Requests without
+// proper credentials are rejected.
+func Authenticate(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := tracelog.Logger(ctx)
+
+		cluster, err := kubernetes.GetCluster(ctx)
+		if err != nil {
+			jsonErrorResponse(w, InternalError(err))
+			return
+		}
+
+		var user models.User
+		if token := bearerToken(r); token != "" {
+			claims, err := tokens.Verify(ctx, cluster, token)
+			if err != nil {
+				if err == auth.ErrBadToken || err == auth.ErrTokenExpired || err == tokens.ErrTokenRevoked {
+					log.Info("token authentication failed", "reason", err.Error())
+					jsonErrorResponse(w, UserNotAuthenticated())
+					return
+				}
+				jsonErrorResponse(w, InternalError(err))
+				return
+			}
+
+			// The user is loaded for every request, for the current roles,
+			// and to reject the tokens of removed users.
+			user, err = users.Get(ctx, cluster, claims.Username)
+			if err != nil {
+				if err == users.ErrUserNotFound {
+					jsonErrorResponse(w, UserNotAuthenticated())
+					return
+				}
+				jsonErrorResponse(w, InternalError(err))
+				return
+			}
+
+			ctx = context.WithValue(ctx, tokenClaimsKey{}, claims)
+		} else {
+			username, password, ok := r.BasicAuth()
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Basic realm="epinio"`)
+				jsonErrorResponse(w, UserNotAuthenticated())
+				return
+			}
+
+			user, err = users.Authenticate(ctx, cluster, username, password)
+			if err != nil {
+				if err == users.ErrBadCredentials {
+					log.Info("authentication failed", "user", username)
+					w.Header().Set("WWW-Authenticate", `Basic realm="epinio"`)
+					jsonErrorResponse(w, UserNotAuthenticated())
+					return
+				}
+				jsonErrorResponse(w, InternalError(err))
+				return
+			}
+		}
+
+		h.ServeHTTP(w, r.WithContext(users.WithUser(ctx, user)))
+	})
+}
+
+// tokenClaimsKey is the type of the key under which the claims of the
+// token authenticating a request are stored in the request's context.
+type tokenClaimsKey struct{}
+
+// requestTokenClaims returns the claims of the token the request was
+// authenticated with, if any.
+func requestTokenClaims(r *http.Request) (auth.TokenClaims, bool) {
+	claims, ok := r.Context().Value(tokenClaimsKey{}).(auth.TokenClaims)
+	return claims, ok
+}
+
+// bearerToken returns the bearer token of the request, if any.
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return ""
+	}
+	return strings.TrimPrefix(header, "Bearer ")
+}
+
+// AdminOnly is the middleware restricting the handler to admins. It
+// expects the user to be authenticated already.
+func AdminOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := users.FromContext(r.Context())
+		if !ok || !user.Admin {
+			jsonErrorResponse(w, UserNotAllowed())
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// authorize wraps the handler of the named route with the check that the
+// authenticated user is allowed to use the route. Admin routes are checked
+// against the admin flag of the user. Routes touching a namespace require
+// read access for GET requests, and write access for anything else, and
+// for the write routes. The remaining routes are open to all users. Their
+// handlers filter the results, see allowedNamespaces.
+func authorize(name string, route routes.Route) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := users.FromContext(r.Context())
+		if !ok {
+			jsonErrorResponse(w, UserNotAuthenticated())
+			return
+		}
+
+		if _, ok := adminRoutes[name]; ok {
+			if !user.Admin {
+				jsonErrorResponse(w, UserNotAllowed())
+				return
+			}
+		} else if org := httprouter.ParamsFromContext(r.Context()).ByName("org"); org != "" {
+			_, write := writeRoutes[name]
+			write = write || route.Method != http.MethodGet
+			if !users.Allowed(user, org, write) {
+				jsonErrorResponse(w, UserNotAllowed())
+				return
+			}
+		}
+
+		route.Handler(w, r)
+	}
+}
+
+// allowedNamespaces returns the namespaces the user of the request is
+// allowed to read.
+func allowedNamespaces(r *http.Request, orgs []organizations.Organization) []organizations.Organization {
+	user, ok := users.FromContext(r.Context())
+	if !ok {
+		return nil
+	}
+
+	result := []organizations.Organization{}
+	for _, org := range orgs {
+		if users.Allowed(user, org.Name, false) {
+			result = append(result, org)
+		}
+	}
+
+	return result
+}
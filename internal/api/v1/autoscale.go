@@ -0,0 +1,155 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Autoscale handles the API endpoint PUT /namespaces/:org/applications/:app/autoscale
+// It replaces the autoscaling settings of the named application. A deployed
+// application gets an autoscaler for its deployment, or the autoscaler is
+// updated. Manual changes of the instances are refused from then on. The
+// instances have to request CPU, as the target utilization is relative to it.
+func (hc ApplicationsController) Autoscale(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var autoscale models.AppAutoscale
+	err = json.Unmarshal(bodyBytes, &autoscale)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	err = application.AutoscaleValidate(autoscale)
+	if err != nil {
+		return NewBadRequest(err.Error())
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	appRef, apierr := knownApp(ctx, cluster, org, appName)
+	if apierr != nil {
+		return apierr
+	}
+
+	resources, err := application.Resources(ctx, cluster, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+	if resources.CPURequest == "" {
+		return noCPURequest(appName)
+	}
+
+	err = application.AutoscaleSet(ctx, cluster, appRef, &autoscale)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	app, err := application.Lookup(ctx, cluster, org, appName)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	stopped, err := application.Stopped(ctx, cluster, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	// A stopped workload gets its autoscaler when started
+	if app.Workload != nil && !stopped {
+		err = application.NewWorkload(cluster, appRef).AutoscaleChange(ctx, &autoscale)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	err = jsonResponse(w, models.ResponseOK)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// AutoscaleDisable handles the API endpoint DELETE /namespaces/:org/applications/:app/autoscale
+// It disables the autoscaling of the named application. A deployed
+// application's autoscaler is deleted, and the deployment returns to the
+// configured instances.
+func (hc ApplicationsController) AutoscaleDisable(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	appRef, apierr := knownApp(ctx, cluster, org, appName)
+	if apierr != nil {
+		return apierr
+	}
+
+	err = application.AutoscaleSet(ctx, cluster, appRef, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	app, err := application.Lookup(ctx, cluster, org, appName)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	stopped, err := application.Stopped(ctx, cluster, appRef)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	// A stopped workload has no autoscaler, and stays stopped
+	if app.Workload != nil && !stopped {
+		workload := application.NewWorkload(cluster, appRef)
+
+		err = workload.AutoscaleChange(ctx, nil)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		err = workload.Scale(ctx, *app.Configuration.Instances)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	err = jsonResponse(w, models.ResponseOK)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// noCPURequest returns the error for autoscaling an application whose
+// instances do not request CPU.
+func noCPURequest(appName string) APIErrors {
+	return NewBadRequest(application.ErrNoCPURequest.Error(),
+		fmt.Sprintf("set one first, with `epinio app update %s --cpu 250m`", appName))
+}
@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/spf13/viper"
+)
+
+// BlobsController represents all functionality of the API related to the source blobs
+type BlobsController struct {
+}
+
+// Index handles the API endpoint GET /blobs
+// It returns the source blobs in the S3 storage, with their state per the
+// server's retention policy.
+func (hc BlobsController) Index(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	blobs, err := application.Blobs(ctx, cluster, blobRetention())
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, blobs)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Prune handles the API endpoint POST /blobs/prune
+// It removes the expired and orphaned source blobs from the S3 storage.
+// The request may override the server's retention.
+func (hc BlobsController) Prune(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var req models.BlobPruneRequest
+	if len(bodyBytes) > 0 {
+		err = json.Unmarshal(bodyBytes, &req)
+		if err != nil {
+			return BadRequest(err)
+		}
+	}
+	if req.Keep < 0 {
+		return NewBadRequest("the number of blobs to keep must not be negative")
+	}
+
+	keep := req.Keep
+	if keep == 0 {
+		keep = blobRetention()
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	response, err := application.BlobsPrune(ctx, cluster, keep)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, response)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// blobRetention returns the number of source blobs to keep per application, as configured for the server
+func blobRetention() int {
+	if keep := viper.GetInt("blob-retention"); keep > 0 {
+		return keep
+	}
+	return models.DefaultBlobRetention
+}
@@ -0,0 +1,184 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/staging"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Cache handles the API endpoint GET /namespaces/:org/applications/:app/cache
+// It returns the settings of the named application's build cache, and the
+// claim holding it, if any.
+func (hc ApplicationsController) Cache(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	app, apierr := knownApp(ctx, cluster, org, appName)
+	if apierr != nil {
+		return apierr
+	}
+
+	cache, err := application.Cache(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, cache)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// CacheUpdate handles the API endpoint PATCH /namespaces/:org/applications/:app/cache
+// It replaces the settings of the named application's build cache. A claim
+// not matching the new settings is deleted, i.e. the next staging starts
+// with an empty cache.
+func (hc ApplicationsController) CacheUpdate(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var config models.AppCacheConfig
+	err = json.Unmarshal(bodyBytes, &config)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	app, apierr := knownApp(ctx, cluster, org, appName)
+	if apierr != nil {
+		return apierr
+	}
+
+	err = application.CacheConfigCheck(ctx, cluster, config)
+	if err != nil {
+		return NewBadRequest(err.Error())
+	}
+
+	apierr = cacheNotStaging(ctx, cluster, app)
+	if apierr != nil {
+		return apierr
+	}
+
+	_, err = application.CacheSet(ctx, cluster, app, config)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	cache, err := application.Cache(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, cache)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// CacheClear handles the API endpoint DELETE /namespaces/:org/applications/:app/cache
+// It removes the contents of the named application's build cache. The
+// settings and the claim are kept.
+func (hc ApplicationsController) CacheClear(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	app, apierr := knownApp(ctx, cluster, org, appName)
+	if apierr != nil {
+		return apierr
+	}
+
+	apierr = cacheNotStaging(ctx, cluster, app)
+	if apierr != nil {
+		return apierr
+	}
+
+	_, err = application.CacheClear(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, models.ResponseOK)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Caches handles the API endpoint GET /caches
+// It returns the claims holding the build caches of all applications, across
+// namespaces, including the orphaned claims of deleted applications.
+func (hc ApplicationsController) Caches(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	caches, err := application.Caches(ctx, cluster)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, caches)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// cacheNotStaging rejects changes to the build cache of an application
+// while it is staging, as the staging run is using the cache.
+func cacheNotStaging(ctx context.Context, cluster *kubernetes.Cluster, app models.AppRef) APIErrors {
+	inFlight, err := staging.InFlight(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if inFlight != "" {
+		return NewBadRequest("application is staging, its build cache is in use",
+			fmt.Sprintf("wait for staging %s to complete, or cancel it with `epinio app stage cancel %s`", inFlight, app.Name))
+	}
+
+	return nil
+}
@@ -1,14 +1,19 @@
 package v1
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/auth"
 	"github.com/epinio/epinio/internal/names"
+	"github.com/epinio/epinio/internal/routes"
 	"github.com/julienschmidt/httprouter"
+	"github.com/spf13/viper"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
@@ -16,6 +21,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
+	"github.com/epinio/epinio/deployments"
 	"github.com/epinio/epinio/helpers/kubernetes"
 	"github.com/epinio/epinio/helpers/tracelog"
 	"github.com/epinio/epinio/internal/domain"
@@ -35,6 +41,10 @@ type deployParam struct {
 	Owner       metav1.OwnerReference
 	Environment models.EnvVariableList
 	Services    application.AppServiceBindList
+	Git         *models.GitRef
+	HealthCheck models.HealthCheck
+	Rollout     models.Rollout
+	Resources   v1.ResourceRequirements
 }
 
 // Deploy handles the API endpoint /orgs/:org/applications/:app/deploy
@@ -74,13 +84,95 @@ func (hc ApplicationsController) Deploy(w http.ResponseWriter, r *http.Request)
 		return InternalError(err, "failed to get access to a kube client")
 	}
 
+	log.Info("deploying app", "org", org, "app", req.App)
+
+	gitRef, apierr := stageGitRef(ctx, cluster, req.App, req.Stage.ID)
+	if apierr != nil {
+		return apierr
+	}
+
+	release, route, apierr := deployApp(ctx, cluster, req.App, username, models.AppRelease{
+		ImageURL: req.ImageURL,
+		StageID:  req.Stage.ID,
+		Git:      gitRef,
+	})
+	if apierr != nil {
+		return apierr
+	}
+
+	// Delete previous pipelineruns except for the current one
+	if req.Stage.ID != "" {
+		if err := application.Unstage(ctx, cluster, req.App, req.Stage.ID); err != nil {
+			return InternalError(err)
+		}
+	}
+
+	resp := models.DeployResponse{
+		Route:   route,
+		Release: release.Number,
+	}
+	err = jsonResponse(w, resp)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// stageGitRef returns the repository and commit the sources of the staging
+// run were imported from, as recorded by the import, see importGit. The
+// result is nil for sources which were not imported from git, and for
+// unknown runs.
+func stageGitRef(ctx context.Context, cluster *kubernetes.Cluster, app models.AppRef, stageID string) (*models.GitRef, APIErrors) {
+	if stageID == "" {
+		return nil, nil
+	}
+
+	tc, err := cluster.ClientTekton()
+	if err != nil {
+		return nil, InternalError(err, "failed to get access to a tekton client")
+	}
+
+	run, err := tc.PipelineRuns(deployments.TektonStagingNamespace).Get(ctx, stageID, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, InternalError(err)
+	}
+
+	if run.Labels["app.kubernetes.io/name"] != app.Name || run.Labels["app.kubernetes.io/part-of"] != app.Org {
+		return nil, NewBadRequest(fmt.Sprintf("stage '%s' does not belong to the application", stageID))
+	}
+
+	blobUID := run.Labels[models.EpinioStageBlobUIDLabel]
+	if blobUID == "" {
+		return nil, nil
+	}
+
+	gitRef, err := application.BlobGit(ctx, cluster, blobUID)
+	if err != nil {
+		return nil, InternalError(err, "failed to read the source blob")
+	}
+
+	return gitRef, nil
+}
+
+// deployApp creates or updates the deployment, service and ingress (kube) resources
+// for the app, using the image, stage and git reference of the release, and the
+// current configuration of the app. Success is recorded as a new release of the
+// app, with a snapshot of that configuration. The new release and the app's route
+// are returned.
+func deployApp(ctx context.Context, cluster *kubernetes.Cluster, app models.AppRef, username string, release models.AppRelease) (models.AppRelease, string, APIErrors) {
+	log := tracelog.Logger(ctx)
+
 	// check application resource
-	applicationCR, err := application.Get(ctx, cluster, req.App)
+	applicationCR, err := application.Get(ctx, cluster, app)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			return AppIsNotKnown("cannot deploy app, application resource is missing")
+			return release, "", AppIsNotKnown("cannot deploy app, application resource is missing")
 		}
-		return InternalError(err, "failed to get the application resource")
+		return release, "", InternalError(err, "failed to get the application resource")
 	}
 	owner := metav1.OwnerReference{
 		APIVersion: applicationCR.GetAPIVersion(),
@@ -89,114 +181,167 @@ func (hc ApplicationsController) Deploy(w http.ResponseWriter, r *http.Request)
 		UID:        applicationCR.GetUID(),
 	}
 
+	// the routes may have been claimed since they were set
+	specs, err := application.Routes(ctx, cluster, app)
+	if err != nil {
+		return release, "", InternalError(err, "failed to access application's routes")
+	}
+	if apierr := appRoutesClaimed(ctx, cluster, app, specs); apierr != nil {
+		return release, "", apierr
+	}
+
 	// determine number of desired instances
-	instances, err := application.Scaling(ctx, cluster, req.App)
+	instances, err := application.Scaling(ctx, cluster, app)
 	if err != nil {
-		return InternalError(err, "failed to access application's desired instances")
+		return release, "", InternalError(err, "failed to access application's desired instances")
+	}
+
+	// determine autoscaling, if any. The autoscaler decides the instances
+	autoscale, err := application.Autoscale(ctx, cluster, app)
+	if err != nil {
+		return release, "", InternalError(err, "failed to access application's autoscaling")
+	}
+	if autoscale != nil {
+		instances = autoscale.Min
+	}
+
+	// A deployment starts a stopped application
+	stopped, err := application.Stopped(ctx, cluster, app)
+	if err != nil {
+		return release, "", InternalError(err, "failed to access application's state")
 	}
 
 	// determine runtime environment, if any
-	environment, err := application.Environment(ctx, cluster, req.App)
+	environment, err := application.Environment(ctx, cluster, app)
 	if err != nil {
-		return InternalError(err, "failed to access application's runtime environment")
+		return release, "", InternalError(err, "failed to access application's runtime environment")
 	}
 
 	// determine bound services, if any
-	services, err := application.BoundServices(ctx, cluster, req.App)
+	services, err := application.BoundServices(ctx, cluster, app)
 	if err != nil {
-		return InternalError(err, "failed to access application's bound services")
+		return release, "", InternalError(err, "failed to access application's bound services")
 	}
 
-	bindings, err := application.ToBinds(ctx, services, req.App.Name, username)
+	bindings, err := application.ToBinds(ctx, services, app.Name, username)
 	if err != nil {
-		return InternalError(err, "failed to process application's bound services")
+		return release, "", InternalError(err, "failed to process application's bound services")
 	}
 
-	route, err := domain.AppDefaultRoute(ctx, req.App.Name)
+	// determine health check and rollout strategy
+	healthCheck, err := application.HealthCheck(ctx, cluster, app)
 	if err != nil {
-		return InternalError(err)
+		return release, "", InternalError(err, "failed to access application's health check")
+	}
+
+	rollout, err := application.Rollout(ctx, cluster, app)
+	if err != nil {
+		return release, "", InternalError(err, "failed to access application's rollout strategy")
+	}
+
+	// determine compute resources
+	appResources, err := application.Resources(ctx, cluster, app)
+	if err != nil {
+		return release, "", InternalError(err, "failed to access application's compute resources")
+	}
+
+	resources, err := application.ResourceRequirements(appResources)
+	if err != nil {
+		return release, "", InternalError(err, "failed to process application's compute resources")
 	}
 
 	deployParams := deployParam{
-		AppRef:      req.App,
+		AppRef:      app,
 		Owner:       owner,
 		Environment: environment,
 		Services:    bindings,
 		Instances:   instances,
-		ImageURL:    req.ImageURL,
+		ImageURL:    release.ImageURL,
 		Username:    username,
+		Git:         release.Git,
+		HealthCheck: healthCheck,
+		Rollout:     rollout,
+		Resources:   resources,
 	}
 
-	log.Info("deploying app", "org", org, "app", req.App)
-	deployment := newAppDeployment(req.Stage.ID, deployParams)
+	deployment := newAppDeployment(release.StageID, deployParams)
 	deployment.SetOwnerReferences([]metav1.OwnerReference{owner})
-	if _, err := cluster.Kubectl.AppsV1().Deployments(req.App.Org).Create(ctx, deployment, metav1.CreateOptions{}); err != nil {
+	if _, err := cluster.Kubectl.AppsV1().Deployments(app.Org).Create(ctx, deployment, metav1.CreateOptions{}); err != nil {
 		if apierrors.IsAlreadyExists(err) {
-			if _, err := cluster.Kubectl.AppsV1().Deployments(req.App.Org).Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
-				return InternalError(err)
+			// Keep the instances chosen by the autoscaler, if any
+			if autoscale != nil && !stopped {
+				current, err := cluster.Kubectl.AppsV1().Deployments(app.Org).Get(ctx, deployment.Name, metav1.GetOptions{})
+				if err != nil {
+					return release, "", InternalError(err)
+				}
+				deployment.Spec.Replicas = current.Spec.Replicas
+			}
+			if _, err := cluster.Kubectl.AppsV1().Deployments(app.Org).Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+				return release, "", InternalError(err)
 			}
 		} else {
-			return InternalError(err)
+			return release, "", InternalError(err)
+		}
+	}
+
+	err = application.NewWorkload(cluster, app).AutoscaleChange(ctx, autoscale)
+	if err != nil {
+		return release, "", InternalError(err, "failed to update application's autoscaler")
+	}
+
+	if stopped {
+		err = application.StoppedSet(ctx, cluster, app, false)
+		if err != nil {
+			return release, "", InternalError(err, "failed to start the application")
 		}
 	}
 
-	log.Info("deploying app service", "org", org, "app", req.App)
+	log.Info("deploying app service", "org", app.Org, "app", app)
 
-	svc := newAppService(req.App, username)
+	svc := newAppService(app, username)
 
 	log.Info("app service", "name", svc.ObjectMeta.Name)
 
 	svc.SetOwnerReferences([]metav1.OwnerReference{owner})
-	if _, err := cluster.Kubectl.CoreV1().Services(req.App.Org).Create(ctx, svc, metav1.CreateOptions{}); err != nil {
+	if _, err := cluster.Kubectl.CoreV1().Services(app.Org).Create(ctx, svc, metav1.CreateOptions{}); err != nil {
 		if apierrors.IsAlreadyExists(err) {
-			service, err := cluster.Kubectl.CoreV1().Services(req.App.Org).Get(ctx, svc.Name, metav1.GetOptions{})
+			service, err := cluster.Kubectl.CoreV1().Services(app.Org).Get(ctx, svc.Name, metav1.GetOptions{})
 			if err != nil {
-				return InternalError(err)
+				return release, "", InternalError(err)
 			}
 
 			svc.ResourceVersion = service.ResourceVersion
 			svc.Spec.ClusterIP = service.Spec.ClusterIP
-			if _, err := cluster.Kubectl.CoreV1().Services(req.App.Org).Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
-				return InternalError(err)
+			if _, err := cluster.Kubectl.CoreV1().Services(app.Org).Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
+				return release, "", InternalError(err)
 			}
 		} else {
-			return InternalError(err)
+			return release, "", InternalError(err)
 		}
 	}
 
-	log.Info("deploying app ingress", "org", org, "app", req.App, "", route)
-
-	ing := newAppIngress(req.App, route, username)
-
-	log.Info("app ingress", "name", ing.ObjectMeta.Name)
+	log.Info("deploying app ingress", "org", app.Org, "app", app)
 
-	ing.SetOwnerReferences([]metav1.OwnerReference{owner})
-	if _, err := cluster.Kubectl.NetworkingV1().Ingresses(req.App.Org).Create(ctx, ing, metav1.CreateOptions{}); err != nil {
-		if apierrors.IsAlreadyExists(err) {
-			if _, err := cluster.Kubectl.NetworkingV1().Ingresses(req.App.Org).Update(ctx, ing, metav1.UpdateOptions{}); err != nil {
-				return InternalError(err)
-			}
-		} else {
-			return InternalError(err)
-		}
+	route, err := appRoutesSync(ctx, cluster, app, owner, username)
+	if err != nil {
+		return release, "", InternalError(err)
 	}
 
-	// Delete previous pipelineruns except for the current one
-	if req.Stage.ID != "" {
-		if err := application.Unstage(ctx, cluster, req.App, req.Stage.ID); err != nil {
-			return InternalError(err)
-		}
+	// Record the release
+	release.Environment = environment
+	release.Services = []string{}
+	for _, service := range services {
+		release.Services = append(release.Services, service.Name())
 	}
+	release.Username = username
+	release.Created = time.Now().UTC()
 
-	resp := models.DeployResponse{
-		Route: route,
-	}
-	err = jsonResponse(w, resp)
+	release, err = application.ReleaseAdd(ctx, cluster, app, release)
 	if err != nil {
-		return InternalError(err)
+		return release, "", InternalError(err, "failed to record the release")
 	}
 
-	return nil
+	return release, route, nil
 }
 
 // newAppDeployment is a helper that creates the kube deployment resource for the app
@@ -213,9 +358,18 @@ func newAppDeployment(stageID string, deployParams deployParam) *appsv1.Deployme
 		labels["epinio.suse.org/stage-id"] = stageID
 	}
 
+	readiness, liveness := application.HealthProbes(deployParams.HealthCheck)
+
+	annotations := map[string]string{}
+	if deployParams.Git != nil {
+		annotations[models.EpinioGitURLAnnotation] = deployParams.Git.URL
+		annotations[models.EpinioGitCommitAnnotation] = deployParams.Git.Revision
+	}
+
 	return &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: deployParams.Name,
+			Name:        deployParams.Name,
+			Annotations: annotations,
 			Labels: map[string]string{
 				"app.kubernetes.io/name":       deployParams.Name,
 				"app.kubernetes.io/part-of":    deployParams.Org,
@@ -226,6 +380,7 @@ func newAppDeployment(stageID string, deployParams deployParam) *appsv1.Deployme
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: &deployParams.Instances,
+			Strategy: application.RolloutStrategy(deployParams.Rollout),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{
 					"app.kubernetes.io/name": deployParams.Name,
@@ -251,8 +406,11 @@ func newAppDeployment(stageID string, deployParams deployParam) *appsv1.Deployme
 									ContainerPort: 8080,
 								},
 							},
-							Env:          deployParams.Environment.ToEnvVarArray(deployParams.AppRef),
-							VolumeMounts: deployParams.Services.ToMountsArray(),
+							Env:            deployParams.Environment.ToEnvVarArray(deployParams.AppRef),
+							VolumeMounts:   deployParams.Services.ToMountsArray(),
+							Resources:      deployParams.Resources,
+							ReadinessProbe: readiness,
+							LivenessProbe:  liveness,
 						},
 					},
 				},
@@ -297,10 +455,202 @@ func newAppService(app models.AppRef, username string) *v1.Service {
 	}
 }
 
-// newAppIngress is a helper that creates the kube ingress resource for the app
-func newAppIngress(appRef models.AppRef, route, username string) *networkingv1.Ingress {
+// appRoutesSync creates or updates the kube ingress resource for the app, with a rule
+// for each of its routes, and ensures that a certificate exists for each of the hosts
+// of these routes. It returns the first route of the app.
+func appRoutesSync(ctx context.Context, cluster *kubernetes.Cluster, app models.AppRef, owner metav1.OwnerReference, username string) (string, error) {
+	log := tracelog.Logger(ctx)
+
+	specs, err := application.Routes(ctx, cluster, app)
+	if err != nil {
+		return "", err
+	}
+
+	appRoutes, err := routes.FromStrings(specs)
+	if err != nil {
+		return "", err
+	}
+
+	certNames := map[string]string{}
+	for _, host := range routes.Hosts(appRoutes) {
+		certName, err := routeCertName(ctx, app, host)
+		if err != nil {
+			return "", err
+		}
+		certNames[host] = certName
+
+		cert := auth.CertParam{
+			Name:      certName,
+			Namespace: app.Org,
+			Host:      host,
+			Issuer:    viper.GetString("tls-issuer"),
+		}
+
+		log.Info("app cert", "host", cert.Host, "issuer", cert.Issuer)
+
+		err = auth.CreateCertificate(ctx, cluster, cert, &owner)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	ing := newAppIngress(app, appRoutes, certNames, username)
+
+	log.Info("app ingress", "name", ing.ObjectMeta.Name)
+
+	ing.SetOwnerReferences([]metav1.OwnerReference{owner})
+	if _, err := cluster.Kubectl.NetworkingV1().Ingresses(app.Org).Create(ctx, ing, metav1.CreateOptions{}); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return "", err
+		}
+		if _, err := cluster.Kubectl.NetworkingV1().Ingresses(app.Org).Update(ctx, ing, metav1.UpdateOptions{}); err != nil {
+			return "", err
+		}
+	}
+
+	return appRoutes[0].String(), nil
+}
+
+// appRoutesClaimed checks that the hosts of the routes are not claimed by
+// any ingress other than the app's own, in any namespace. Without this
+// check an app could take over the host of another app, of another
+// tenant. An empty list checks the default route of the app.
+func appRoutesClaimed(ctx context.Context, cluster *kubernetes.Cluster, app models.AppRef, specs []string) APIErrors {
+	if len(specs) == 0 {
+		route, err := domain.AppDefaultRoute(ctx, app.Name)
+		if err != nil {
+			return InternalError(err)
+		}
+		specs = []string{route}
+	}
+
+	appRoutes, err := routes.FromStrings(specs)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	ingresses, err := cluster.Kubectl.NetworkingV1().Ingresses("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	claimed := map[string]struct{}{}
+	for _, ingress := range ingresses.Items {
+		if ingress.Namespace == app.Org && ingress.Name == names.IngressName(app.Name) {
+			continue
+		}
+		for _, rule := range ingress.Spec.Rules {
+			claimed[rule.Host] = struct{}{}
+		}
+	}
+
+	for _, host := range routes.Hosts(appRoutes) {
+		if _, ok := claimed[host]; ok {
+			return RouteIsClaimed(host)
+		}
+	}
+
+	return nil
+}
+
+// appRoutesCertificatesDelete removes the certificates for the hosts of the old
+// routes of the app which are not used by its new routes anymore.
+func appRoutesCertificatesDelete(ctx context.Context, cluster *kubernetes.Cluster, app models.AppRef, oldRoutes, newRoutes []string) error {
+	oldParsed, err := routes.FromStrings(oldRoutes)
+	if err != nil {
+		return err
+	}
+	newParsed, err := routes.FromStrings(newRoutes)
+	if err != nil {
+		return err
+	}
+
+	keep := map[string]struct{}{}
+	for _, host := range routes.Hosts(newParsed) {
+		keep[host] = struct{}{}
+	}
+
+	for _, host := range routes.Hosts(oldParsed) {
+		if _, ok := keep[host]; ok {
+			continue
+		}
+
+		certName, err := routeCertName(ctx, app, host)
+		if err != nil {
+			return err
+		}
+
+		err = auth.DeleteCertificate(ctx, cluster, app.Org, certName)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// routeCertName returns the name of the certificate for the given host of an app
+// route. The certificate for the default route is named after the app, as it is
+// created by staging.
+func routeCertName(ctx context.Context, app models.AppRef, host string) (string, error) {
+	defaultRoute, err := domain.AppDefaultRoute(ctx, app.Name)
+	if err != nil {
+		return "", err
+	}
+
+	if host == defaultRoute {
+		return app.Name, nil
+	}
+
+	return names.GenerateResourceName(app.Name, host), nil
+}
+
+// newAppIngress is a helper that creates the kube ingress resource for the app,
+// with a rule for each host of the app routes, and a path for each of the routes.
+// The certificate secrets for the hosts are derived from the certificate names.
+func newAppIngress(appRef models.AppRef, appRoutes []routes.Route, certNames map[string]string, username string) *networkingv1.Ingress {
 	pathTypeImplementationSpecific := networkingv1.PathTypeImplementationSpecific
 
+	rules := []networkingv1.IngressRule{}
+	tls := []networkingv1.IngressTLS{}
+
+	for _, host := range routes.Hosts(appRoutes) {
+		paths := []networkingv1.HTTPIngressPath{}
+		for _, route := range appRoutes {
+			if route.Host != host {
+				continue
+			}
+			paths = append(paths, networkingv1.HTTPIngressPath{
+				Backend: networkingv1.IngressBackend{
+					Service: &networkingv1.IngressServiceBackend{
+						Name: names.ServiceName(appRef.Name),
+						Port: networkingv1.ServiceBackendPort{
+							Number: 8080,
+						},
+					},
+				},
+				Path:     route.Path,
+				PathType: &pathTypeImplementationSpecific,
+			})
+		}
+
+		rules = append(rules, networkingv1.IngressRule{
+			Host: host,
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: paths,
+				},
+			},
+		})
+
+		tls = append(tls, networkingv1.IngressTLS{
+			Hosts: []string{
+				host,
+			},
+			SecretName: fmt.Sprintf("%s-tls", certNames[host]),
+		})
+	}
+
 	return &networkingv1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: names.IngressName(appRef.Name),
@@ -318,37 +668,8 @@ func newAppIngress(appRef models.AppRef, route, username string) *networkingv1.I
 			},
 		},
 		Spec: networkingv1.IngressSpec{
-			Rules: []networkingv1.IngressRule{
-				{
-					Host: route,
-					IngressRuleValue: networkingv1.IngressRuleValue{
-						HTTP: &networkingv1.HTTPIngressRuleValue{
-							Paths: []networkingv1.HTTPIngressPath{
-								{
-									Backend: networkingv1.IngressBackend{
-										Service: &networkingv1.IngressServiceBackend{
-											Name: names.ServiceName(appRef.Name),
-											Port: networkingv1.ServiceBackendPort{
-												Number: 8080,
-											},
-										},
-									},
-									Path:     "/",
-									PathType: &pathTypeImplementationSpecific,
-								},
-							},
-						},
-					},
-				},
-			},
-			TLS: []networkingv1.IngressTLS{
-				{
-					Hosts: []string{
-						route,
-					},
-					SecretName: fmt.Sprintf("%s-tls", appRef.Name),
-				},
-			},
+			Rules: rules,
+			TLS:   tls,
 		},
 	}
 }
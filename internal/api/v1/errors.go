@@ -107,14 +107,46 @@ func NewNotFoundError(msg string, details ...string) APIError {
 	return NewAPIError(msg, strings.Join(details, ", "), http.StatusNotFound)
 }
 
-// UserNotFound constructs an API error for when the user name is not found in the header
+// UserNotFound constructs an API error for when the user is not found in the request context
 func UserNotFound() APIError {
 	return NewAPIError(
-		"User not found in the request header",
+		"User not found in the request",
 		"",
 		http.StatusBadRequest)
 }
 
+// UserNotAuthenticated constructs an API error for when the request carries no or bad credentials
+func UserNotAuthenticated() APIError {
+	return NewAPIError(
+		"Not authenticated",
+		"",
+		http.StatusUnauthorized)
+}
+
+// UserNotAllowed constructs an API error for when the user lacks the role required by the request
+func UserNotAllowed() APIError {
+	return NewAPIError(
+		"Not allowed",
+		"",
+		http.StatusForbidden)
+}
+
+// UserIsNotKnown constructs an API error for when the desired user does not exist
+func UserIsNotKnown(user string) APIError {
+	return NewAPIError(
+		fmt.Sprintf("User '%s' does not exist", user),
+		"",
+		http.StatusNotFound)
+}
+
+// UserAlreadyKnown constructs an API error for when we have a conflict with an existing user
+func UserAlreadyKnown(user string) APIError {
+	return NewAPIError(
+		fmt.Sprintf("User '%s' already exists", user),
+		"",
+		http.StatusConflict)
+}
+
 // OrgIsNotKnown constructs an API error for when the desired org does not exist
 func OrgIsNotKnown(org string) APIError {
 	return NewAPIError(
@@ -131,6 +163,23 @@ func AppAlreadyKnown(app string) APIError {
 		http.StatusConflict)
 }
 
+// UploadTooLarge constructs an API error for uploads exceeding the maximal size
+func UploadTooLarge(maxSize int64) APIError {
+	return NewAPIError(
+		fmt.Sprintf("Upload exceeds the maximal size of %d bytes", maxSize),
+		"",
+		http.StatusRequestEntityTooLarge)
+}
+
+// RouteIsClaimed constructs an API error for when the host of a route is
+// already served by another application, or anything else in the cluster
+func RouteIsClaimed(host string) APIError {
+	return NewAPIError(
+		fmt.Sprintf("Route host '%s' is already in use by another application", host),
+		"",
+		http.StatusConflict)
+}
+
 // AppIsNotKnown constructs an API error for when the desired app does not exist
 func AppIsNotKnown(app string) APIError {
 	return NewAPIError(
@@ -163,6 +212,22 @@ func ServiceAlreadyKnown(service string) APIError {
 		http.StatusConflict)
 }
 
+// GitCredentialsIsNotKnown constructs an API error for when the desired git credentials do not exist
+func GitCredentialsIsNotKnown(credentials string) APIError {
+	return NewAPIError(
+		fmt.Sprintf("Git credentials '%s' do not exist", credentials),
+		"",
+		http.StatusNotFound)
+}
+
+// GitCredentialsAlreadyKnown constructs an API error for when we have a conflict with existing git credentials
+func GitCredentialsAlreadyKnown(credentials string) APIError {
+	return NewAPIError(
+		fmt.Sprintf("Git credentials '%s' already exist", credentials),
+		"",
+		http.StatusConflict)
+}
+
 // ServiceAlreadyBound constructs an API error for when the service to bind is already bound to the app
 func ServiceAlreadyBound(service string) APIError {
 	return NewAPIError(
@@ -0,0 +1,228 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// Exec handles the API endpoint GET /namespaces/:org/applications/:app/exec
+// It upgrades to a websocket, and runs the command of the `command` query
+// parameters, a shell by default, with a TTY in the container of an
+// instance of the named application. The `instance` query parameter
+// selects the instance by index, see application.SelectInstance. The
+// protocol of the session is described by models.ExecControl. The
+// session ends with a close message, whose text is the error of the
+// command, if any.
+func (hc ApplicationsController) Exec(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	index, apierr := instanceParam(r)
+	if apierr != nil {
+		return apierr
+	}
+
+	command := r.URL.Query()["command"]
+	if len(command) == 0 {
+		command = []string{"sh"}
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	instance, apierr := selectedInstance(ctx, cluster, org, appName, index)
+	if apierr != nil {
+		return apierr
+	}
+
+	log.Info("upgrade to web socket")
+
+	var upgrader = websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader responded with an error already
+		log.V(1).Error(err, "upgrade to web socket failed")
+		return nil
+	}
+	defer conn.Close()
+
+	log.Info("exec begin", "instance", instance.Name, "command", command)
+
+	stdin, stdinWriter := io.Pipe()
+	sizes := &execSizeQueue{sizes: make(chan remotecommand.TerminalSize, 1)}
+	go readExecInput(conn, stdinWriter, sizes)
+
+	err = cluster.ExecTTY(org, instance.Name, appName, command, stdin, &execOutput{conn: conn}, sizes)
+
+	// Unblocks readExecInput, should it be writing input nobody reads anymore
+	stdin.Close()
+
+	reason := ""
+	if err != nil {
+		log.V(1).Info("exec failed", "error", err.Error())
+		reason = err.Error()
+	}
+
+	// nolint:errcheck // the client may be gone already
+	conn.WriteMessage(websocket.CloseMessage, closeMessage(reason))
+
+	log.Info("exec completed")
+	return nil
+}
+
+// maxCloseReason is the maximal length of the reason of a close message.
+// Control frames carry at most 125 bytes, two of which are the close code.
+const maxCloseReason = 123
+
+// closeMessage returns the normal close message with the reason, truncated
+// to fit into the control frame
+func closeMessage(reason string) []byte {
+	if len(reason) > maxCloseReason {
+		reason = reason[:maxCloseReason]
+		// Do not split a multi-byte character
+		for !utf8.ValidString(reason) {
+			reason = reason[:len(reason)-1]
+		}
+	}
+	return websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason)
+}
+
+// instanceParam returns the index of the instance selected by the
+// `instance` query parameter of the request, or -1 if there is none
+func instanceParam(r *http.Request) (int, APIErrors) {
+	value := r.URL.Query().Get("instance")
+	if value == "" {
+		return -1, nil
+	}
+
+	index, err := strconv.Atoi(value)
+	if err != nil || index < 0 {
+		return 0, NewBadRequest(fmt.Sprintf("bad instance '%s', expected a number from 0", value))
+	}
+
+	return index, nil
+}
+
+// selectedInstance returns the instance of the named, deployed
+// application at the index, see application.SelectInstance
+func selectedInstance(ctx context.Context, cluster *kubernetes.Cluster, org, appName string, index int) (models.AppInstance, APIErrors) {
+	app, apierr := deployedApp(ctx, cluster, org, appName)
+	if apierr != nil {
+		return models.AppInstance{}, apierr
+	}
+
+	instances, err := application.NewWorkload(cluster, app.Meta).Instances(ctx)
+	if err != nil {
+		return models.AppInstance{}, InternalError(err)
+	}
+
+	instance, err := application.SelectInstance(instances, index)
+	if err != nil {
+		return models.AppInstance{}, NewBadRequest(err.Error())
+	}
+
+	return instance, nil
+}
+
+// readExecInput forwards the input of the client to stdin, and the
+// resize events to the size queue, until the client closes the
+// connection.
+func readExecInput(conn *websocket.Conn, stdin *io.PipeWriter, sizes *execSizeQueue) {
+	defer sizes.close()
+	defer stdin.Close()
+
+	for {
+		kind, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		if kind == websocket.BinaryMessage {
+			if _, err := stdin.Write(message); err != nil {
+				return
+			}
+			continue
+		}
+
+		var control models.ExecControl
+		if err := json.Unmarshal(message, &control); err != nil {
+			continue
+		}
+		if control.Width > 0 && control.Height > 0 {
+			sizes.push(remotecommand.TerminalSize{Width: control.Width, Height: control.Height})
+		}
+		if control.CloseStdin {
+			stdin.Close()
+		}
+	}
+}
+
+// execOutput writes the output of the TTY to the client, as binary
+// messages
+type execOutput struct {
+	conn *websocket.Conn
+}
+
+func (o *execOutput) Write(p []byte) (int, error) {
+	if err := o.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// execSizeQueue is the remotecommand.TerminalSizeQueue of the resize
+// events sent by the client. Only the latest pending size is kept.
+type execSizeQueue struct {
+	sizes  chan remotecommand.TerminalSize
+	mu     sync.Mutex
+	closed bool
+}
+
+func (q *execSizeQueue) Next() *remotecommand.TerminalSize {
+	size, ok := <-q.sizes
+	if !ok {
+		return nil
+	}
+	return &size
+}
+
+func (q *execSizeQueue) push(size remotecommand.TerminalSize) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+	select {
+	case <-q.sizes:
+	default:
+	}
+	q.sizes <- size
+}
+
+func (q *execSizeQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	close(q.sizes)
+}
@@ -0,0 +1,191 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/julienschmidt/httprouter"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// GitCredentialsController represents all functionality of the API related
+// to the credentials of private git repositories. The credentials are kept
+// in secrets of the gitCredentialsType, see gitAuth.
+type GitCredentialsController struct {
+}
+
+// Create handles the API endpoint POST /namespaces/:org/gitcredentials
+// It creates the git credentials of the request in the namespace. They are
+// validated as far as possible without contacting the git server.
+func (gc GitCredentialsController) Create(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+
+	username, err := GetUsername(r)
+	if err != nil {
+		return UserNotFound()
+	}
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var createRequest models.GitCredentialsCreateRequest
+	err = json.Unmarshal(bodyBytes, &createRequest)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	err = gitCredentialsValidate(createRequest)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	data := map[string][]byte{}
+	set := func(key, value string) {
+		if value != "" {
+			data[key] = []byte(value)
+		}
+	}
+	set(gitUsernameKey, createRequest.Username)
+	set(gitPasswordKey, createRequest.Password)
+	set(gitTokenKey, createRequest.Token)
+	set(gitSSHKey, createRequest.SSHKey)
+	set(gitKnownHostsKey, createRequest.KnownHosts)
+	if createRequest.InsecureSkipHostKey {
+		set(gitInsecureKey, "true")
+	}
+
+	err = cluster.CreateSecret(ctx, org, corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: createRequest.Name,
+			Labels: map[string]string{
+				"app.kubernetes.io/part-of":    org,
+				"app.kubernetes.io/managed-by": "epinio",
+				"app.kubernetes.io/created-by": username,
+				"app.kubernetes.io/component":  "git-credentials",
+			},
+		},
+		Type: gitCredentialsType,
+		Data: data,
+	})
+	if apierrors.IsAlreadyExists(err) {
+		return GitCredentialsAlreadyKnown(createRequest.Name)
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	err = jsonResponse(w, models.ResponseOK)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Delete handles the API endpoint DELETE /namespaces/:org/gitcredentials/:credentials
+// It removes the named git credentials. Other secrets are not touched.
+func (gc GitCredentialsController) Delete(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	name := params.ByName("credentials")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	secret, err := cluster.GetSecret(ctx, org, name)
+	if apierrors.IsNotFound(err) {
+		return GitCredentialsIsNotKnown(name)
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+	if secret.Type != gitCredentialsType {
+		return GitCredentialsIsNotKnown(name)
+	}
+
+	err = cluster.DeleteSecret(ctx, org, name)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, models.ResponseOK)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// gitCredentialsValidate checks the git credentials to create. They need
+// a valid name, and exactly one of password, token, and ssh key. An ssh
+// key has to parse, and requires the known hosts, unless the verification
+// of the host key is skipped.
+func gitCredentialsValidate(req models.GitCredentialsCreateRequest) error {
+	if errs := validation.IsDNS1123Subdomain(req.Name); len(errs) > 0 {
+		return fmt.Errorf("bad name '%s' for git credentials: %s", req.Name, strings.Join(errs, ", "))
+	}
+
+	given := 0
+	for _, value := range []string{req.Password, req.Token, req.SSHKey} {
+		if value != "" {
+			given++
+		}
+	}
+	if given != 1 {
+		return errors.New("git credentials require exactly one of password, token, or ssh key")
+	}
+
+	if req.SSHKey == "" {
+		if req.KnownHosts != "" || req.InsecureSkipHostKey {
+			return errors.New("known hosts, and skipping their check, apply to ssh keys only")
+		}
+		return nil
+	}
+
+	if _, err := gitssh.NewPublicKeys("git", []byte(req.SSHKey), ""); err != nil {
+		return fmt.Errorf("bad ssh key: %s", err.Error())
+	}
+	if req.KnownHosts == "" && !req.InsecureSkipHostKey {
+		return errors.New("an ssh key requires the known hosts to verify the host key with, or skipping the verification explicitly")
+	}
+	if req.KnownHosts != "" && req.InsecureSkipHostKey {
+		return errors.New("known hosts are pointless when skipping the verification of the host key")
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -10,19 +11,53 @@ import (
 	"github.com/epinio/epinio/helpers"
 	"github.com/epinio/epinio/helpers/kubernetes"
 	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/duration"
+	"github.com/epinio/epinio/internal/jobs"
 	"github.com/epinio/epinio/internal/s3manager"
 	"github.com/epinio/epinio/pkg/api/core/v1/models"
 	git "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// gitCredentialsType is the type of the secrets holding git credentials.
+// Only secrets of this type are used by imports, as they are created for
+// that purpose, see GitCredentialsController. Other secrets of the
+// namespace, e.g. service credentials, are never sent to a git server.
+const gitCredentialsType = "epinio.suse.org/git-credentials"
+
+// Keys of the secret holding the credentials for a private git repository.
+// Repositories accessed via HTTPS use the username (optional) and either the
+// password or the token. Repositories accessed via SSH use the private key,
+// and the known hosts for verification of the host key. Skipping the
+// verification has to be requested explicitly, by setting the insecure key
+// to "true".
+const (
+	gitUsernameKey   = "username"
+	gitPasswordKey   = "password"
+	gitTokenKey      = "token"
+	gitSSHKey        = "ssh-privatekey"
+	gitKnownHostsKey = "known_hosts"
+	gitInsecureKey   = "insecure-skip-host-key"
 )
 
 // ImportGit handles the API endpoint /namespaces/:org/applications/:app/import-git.
-// It receives a Git repo url and revision, clones that (shallow clone), creates a tarball
-// of the repo and puts it on S3.
+// It receives a Git repo url, revision and optional credentials, and queues a
+// background job which clones that, creates a tarball of the repo and puts it on S3.
+// The revision can be a branch, a tag, or a (short) commit SHA. The credentials
+// are the name of a secret in the namespace, see the git*Key constants above.
+// The response carries the ID of the job, to be queried for progress and the
+// blob UID of the imported sources. See jobs.go.
 func (hc ApplicationsController) ImportGit(w http.ResponseWriter, r *http.Request) APIErrors {
 	ctx := r.Context()
-	log := tracelog.Logger(ctx)
 
 	params := httprouter.ParamsFromContext(ctx)
 	org := params.ByName("org")
@@ -30,30 +65,75 @@ func (hc ApplicationsController) ImportGit(w http.ResponseWriter, r *http.Reques
 
 	url := r.FormValue("giturl")
 	revision := r.FormValue("gitrev")
+	credentials := r.FormValue("gitcredentials")
+
+	username, err := GetUsername(r)
+	if err != nil {
+		return UserNotFound()
+	}
+
+	app := models.NewAppRef(name, org)
+	job, err := jobs.Submit(ctx, app, duration.ToGitImport(), func(ctx context.Context, job *jobs.Job) error {
+		return importGit(ctx, job, app, models.GitRef{
+			URL:         url,
+			Revision:    revision,
+			Credentials: credentials,
+		}, username)
+	})
+	if err == jobs.ErrQueueFull {
+		return NewAPIError(err.Error(), "", http.StatusServiceUnavailable)
+	}
+	if err != nil {
+		return InternalError(err, "queueing the import of the git repository")
+	}
+
+	// Return response
+	resp := models.ImportGitResponse{JobID: job.State().ID}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	err = jsonResponse(w, resp)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// importGit is the background job importing the application sources from the
+// git repository. It clones the repository, creates a tarball from it, and
+// uploads that to S3. The blob UID of the upload, and the SHA of the imported
+// commit are recorded in the job.
+func importGit(ctx context.Context, job *jobs.Job, app models.AppRef, gitRef models.GitRef, username string) error {
+	log := tracelog.Logger(ctx)
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get access to a kube client")
+	}
+
+	auth, err := gitAuth(ctx, cluster, app.Org, gitRef)
+	if err != nil {
+		return err
+	}
 
 	gitRepo, err := ioutil.TempDir("", "epinio-app")
 	if err != nil {
-		return InternalError(err, "can't create temp directory")
+		return errors.Wrap(err, "can't create temp directory")
 	}
 	defer os.RemoveAll(gitRepo)
 
 	// Fetch the git repo
-	// TODO: This is pulling the git repository on user request (synchronously).
-	// This can be a slow process. A solution with background workers would be
-	// more appropriate. The "pull from git" feature may be redesigned and implemented
-	// through an "external" component that monitors git repos. In that case this code
-	// will be removed.
-	_, err = git.PlainCloneContext(ctx, gitRepo, false, &git.CloneOptions{
-		URL:           url,
-		ReferenceName: plumbing.NewBranchReferenceName(revision),
-		SingleBranch:  true,
-		Depth:         1,
-	})
+	job.Progress("cloning the git repository")
+	commit, err := gitClone(ctx, gitRepo, gitRef, auth)
 	if err != nil {
-		return InternalError(err, fmt.Sprintf("cloning the git repository: %s, revision: %s", url, revision))
+		return errors.Wrap(err, fmt.Sprintf("cloning the git repository: %s, revision: %s", gitRef.URL, gitRef.Revision))
 	}
+	job.SetCommit(commit)
 
 	// Create a tarball
+	job.Progress("creating a tarball from the git repository")
 	tmpDir, tarball, err := helpers.Tar(gitRepo)
 	defer func() {
 		if tmpDir != "" {
@@ -61,41 +141,195 @@ func (hc ApplicationsController) ImportGit(w http.ResponseWriter, r *http.Reques
 		}
 	}()
 	if err != nil {
-		return InternalError(err, "create a tarball from the git repository")
+		return errors.Wrap(err, "create a tarball from the git repository")
 	}
 
 	// Upload to S3
-	cluster, err := kubernetes.GetCluster(ctx)
-	if err != nil {
-		return InternalError(err, "failed to get access to a kube client")
-	}
+	job.Progress("uploading the application sources")
 	connectionDetails, err := s3manager.GetConnectionDetails(ctx, cluster, deployments.TektonStagingNamespace, deployments.S3ConnectionDetailsSecret)
 	if err != nil {
-		return InternalError(err, "fetching the S3 connection details from the Kubernetes secret")
+		return errors.Wrap(err, "fetching the S3 connection details from the Kubernetes secret")
 	}
 	manager, err := s3manager.New(connectionDetails)
 	if err != nil {
-		return InternalError(err, "creating an S3 manager")
+		return errors.Wrap(err, "creating an S3 manager")
 	}
 
-	username, err := GetUsername(r)
+	// The commit is recorded for the deployment, see stageGitRef
+	blobUID, err := manager.Upload(ctx, tarball, map[string]string{
+		"app": app.Name, "org": app.Org, "username": username,
+		application.BlobGitURLKey: gitRef.URL, application.BlobGitCommitKey: commit,
+	})
 	if err != nil {
-		return UserNotFound()
+		return errors.Wrap(err, "uploading the application sources blob")
 	}
-	blobUID, err := manager.Upload(ctx, tarball, map[string]string{
-		"app": name, "org": org, "username": username,
+	log.Info("uploaded app", "org", app.Org, "app", app.Name, "blobUID", blobUID, "commit", commit)
+
+	job.SetBlobUID(blobUID)
+	job.Progress("done")
+
+	return nil
+}
+
+// gitClone clones the referenced repository into the directory, and checks out
+// the revision. The revision can be a branch, a tag, or a full or short commit
+// SHA. Branches and tags are cloned shallow. Commits require a full clone, as
+// they cannot be fetched directly. An empty revision refers to the default
+// branch. The result is the SHA of the checked out commit.
+func gitClone(ctx context.Context, dir string, gitRef models.GitRef, auth transport.AuthMethod) (string, error) {
+	var reference plumbing.ReferenceName
+
+	if gitRef.Revision != "" {
+		remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
+			Name: git.DefaultRemoteName,
+			URLs: []string{gitRef.URL},
+		})
+
+		refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+		if err != nil {
+			return "", errors.Wrap(err, "listing the references of the git repository")
+		}
+
+		branch := plumbing.NewBranchReferenceName(gitRef.Revision)
+		tag := plumbing.NewTagReferenceName(gitRef.Revision)
+
+		for _, ref := range refs {
+			if ref.Name() == branch {
+				// Branches take precedence over tags of the same name
+				reference = branch
+				break
+			}
+			if ref.Name() == tag {
+				reference = tag
+			}
+		}
+	}
+
+	if gitRef.Revision == "" || reference != "" {
+		repo, err := git.PlainCloneContext(ctx, dir, false, &git.CloneOptions{
+			URL:           gitRef.URL,
+			Auth:          auth,
+			ReferenceName: reference,
+			SingleBranch:  true,
+			Depth:         1,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		hash, err := repo.ResolveRevision(plumbing.Revision(plumbing.HEAD))
+		if err != nil {
+			return "", errors.Wrap(err, "resolving the checked out commit")
+		}
+
+		return hash.String(), nil
+	}
+
+	// Neither branch, nor tag. Try as commit.
+	repo, err := git.PlainCloneContext(ctx, dir, false, &git.CloneOptions{
+		URL:  gitRef.URL,
+		Auth: auth,
 	})
 	if err != nil {
-		return InternalError(err, "uploading the application sources blob")
+		return "", err
 	}
-	log.Info("uploaded app", "org", org, "app", name, "blobUID", blobUID)
 
-	// Return response
-	resp := models.ImportGitResponse{BlobUID: blobUID}
-	err = jsonResponse(w, resp)
+	hash, err := repo.ResolveRevision(plumbing.Revision(gitRef.Revision))
 	if err != nil {
-		return InternalError(err)
+		return "", fmt.Errorf("revision '%s' is neither branch, tag, nor commit of the repository", gitRef.Revision)
 	}
 
-	return nil
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+
+	err = worktree.Checkout(&git.CheckoutOptions{Hash: *hash})
+	if err != nil {
+		return "", errors.Wrap(err, "checking out the commit")
+	}
+
+	return hash.String(), nil
+}
+
+// gitAuth returns the authentication method to use for the referenced repository,
+// as specified by its credentials secret. The secret has to be of the
+// gitCredentialsType. Without credentials the result is nil, i.e. anonymous
+// access.
+func gitAuth(ctx context.Context, cluster *kubernetes.Cluster, org string, gitRef models.GitRef) (transport.AuthMethod, error) {
+	if gitRef.Credentials == "" {
+		return nil, nil
+	}
+
+	secret, err := cluster.GetSecret(ctx, org, gitRef.Credentials)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading the git credentials secret '%s'", gitRef.Credentials)
+	}
+	if secret.Type != gitCredentialsType {
+		return nil, fmt.Errorf("secret '%s' holds no git credentials, create them with `epinio git-credentials create`",
+			gitRef.Credentials)
+	}
+
+	username := string(secret.Data[gitUsernameKey])
+
+	if key, ok := secret.Data[gitSSHKey]; ok {
+		if username == "" {
+			username = "git"
+		}
+
+		auth, err := gitssh.NewPublicKeys(username, key, "")
+		if err != nil {
+			return nil, errors.Wrap(err, "loading the ssh key of the git credentials")
+		}
+
+		if string(secret.Data[gitInsecureKey]) == "true" {
+			auth.HostKeyCallback = gossh.InsecureIgnoreHostKey() // nolint:gosec // Opt-in by the user, see gitInsecureKey
+			return auth, nil
+		}
+
+		knownHosts, ok := secret.Data[gitKnownHostsKey]
+		if !ok {
+			return nil, fmt.Errorf("git credentials secret '%s' has %s, but no %s to verify the host key with (set %s to \"true\" to skip the verification)",
+				gitRef.Credentials, gitSSHKey, gitKnownHostsKey, gitInsecureKey)
+		}
+
+		// The known hosts are read from file.
+		knownHostsFile, err := ioutil.TempFile("", "epinio-known-hosts")
+		if err != nil {
+			return nil, errors.Wrap(err, "can't create temp file")
+		}
+		defer os.Remove(knownHostsFile.Name())
+
+		_, err = knownHostsFile.Write(knownHosts)
+		knownHostsFile.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "writing the known hosts of the git credentials")
+		}
+
+		auth.HostKeyCallback, err = gitssh.NewKnownHostsCallback(knownHostsFile.Name())
+		if err != nil {
+			return nil, errors.Wrap(err, "loading the known hosts of the git credentials")
+		}
+
+		return auth, nil
+	}
+
+	password, ok := secret.Data[gitTokenKey]
+	if !ok {
+		password, ok = secret.Data[gitPasswordKey]
+	}
+	if !ok {
+		return nil, fmt.Errorf("git credentials secret '%s' has neither %s, %s, nor %s",
+			gitRef.Credentials, gitSSHKey, gitTokenKey, gitPasswordKey)
+	}
+
+	if username == "" {
+		// Token authentication. The user name is required, but not checked.
+		username = "epinio"
+	}
+
+	return &githttp.BasicAuth{
+		Username: username,
+		Password: string(password),
+	}, nil
 }
@@ -0,0 +1,41 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Instances handles the API endpoint GET /namespaces/:org/applications/:app/instances
+// It returns the instances of the named application, i.e. its pods, with
+// their status. The list is empty for applications which are not deployed.
+func (hc ApplicationsController) Instances(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	app, apierr := knownApp(ctx, cluster, org, appName)
+	if apierr != nil {
+		return apierr
+	}
+
+	instances, err := application.NewWorkload(cluster, app).Instances(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, instances)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
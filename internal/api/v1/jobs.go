@@ -0,0 +1,35 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/epinio/epinio/internal/jobs"
+	"github.com/julienschmidt/httprouter"
+)
+
+// JobsController represents all functionality of the API related to background jobs
+type JobsController struct {
+}
+
+// Show handles the API endpoint GET /namespaces/:org/jobs/:id
+// It returns the state of the specified background job, i.e. its
+// progress, and its result or failure.
+func (hc JobsController) Show(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	id := params.ByName("id")
+
+	job, ok := jobs.Get(org, id)
+	if !ok {
+		return NewNotFoundError(fmt.Sprintf("Job '%s' does not exist", id))
+	}
+
+	err := jsonResponse(w, job)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/epinio/epinio/internal/application"
 	"github.com/epinio/epinio/internal/organizations"
 	"github.com/epinio/epinio/internal/services"
+	"github.com/epinio/epinio/internal/users"
 	"github.com/epinio/epinio/pkg/api/core/v1/models"
 	"github.com/julienschmidt/httprouter"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -42,6 +43,7 @@ func (oc NamespacesController) Match(w http.ResponseWriter, r *http.Request) API
 	if err != nil {
 		return InternalError(err)
 	}
+	namespaces = allowedNamespaces(r, namespaces)
 
 	log.Info("get namespace prefix")
 	params := httprouter.ParamsFromContext(ctx)
@@ -66,7 +68,7 @@ func (oc NamespacesController) Match(w http.ResponseWriter, r *http.Request) API
 }
 
 // Index handles the API endpoint /namespaces (GET)
-// It returns a list of all Epinio-controlled namespaces
+// It returns a list of all Epinio-controlled namespaces the user is allowed to see.
 // An Epinio namespace is nothing but a kubernetes namespace which has a
 // special Label (Look at the code to see which).
 func (oc NamespacesController) Index(w http.ResponseWriter, r *http.Request) APIErrors {
@@ -80,6 +82,7 @@ func (oc NamespacesController) Index(w http.ResponseWriter, r *http.Request) API
 	if err != nil {
 		return InternalError(err)
 	}
+	orgList = allowedNamespaces(r, orgList)
 
 	namespaces := make(models.NamespaceList, 0, len(orgList))
 	for _, org := range orgList {
@@ -215,6 +218,12 @@ func (oc NamespacesController) Delete(w http.ResponseWriter, r *http.Request) AP
 		return InternalError(err)
 	}
 
+	// Drop the roles users had in the deleted namespace.
+	err = users.RevokeNamespace(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
@@ -0,0 +1,79 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/helpers/wsstream"
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PortForward handles the API endpoint GET /namespaces/:org/applications/:app/portforward
+// It upgrades to a websocket, and tunnels one TCP connection to the port
+// of the `port` query parameter of an instance of the named application.
+// The `instance` query parameter selects the instance, as for Exec. The
+// binary messages carry the data of the connection, see wsstream.Stream.
+// The text message wsstream.EndOfData ends the data of the client.
+// The tunnel ends with a close message, whose text is the error of the
+// connection, if any.
+func (hc ApplicationsController) PortForward(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	value := r.URL.Query().Get("port")
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return NewBadRequest(fmt.Sprintf("bad port '%s', expected a number from 1 to 65535", value))
+	}
+
+	index, apierr := instanceParam(r)
+	if apierr != nil {
+		return apierr
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	instance, apierr := selectedInstance(ctx, cluster, org, appName, index)
+	if apierr != nil {
+		return apierr
+	}
+
+	log.Info("upgrade to web socket")
+
+	var upgrader = websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader responded with an error already
+		log.V(1).Error(err, "upgrade to web socket failed")
+		return nil
+	}
+	defer conn.Close()
+
+	log.Info("port forward begin", "instance", instance.Name, "port", port)
+
+	err = cluster.PortForward(org, instance.Name, port, wsstream.New(conn))
+
+	reason := ""
+	if err != nil {
+		log.V(1).Info("port forward failed", "error", err.Error())
+		reason = err.Error()
+	}
+
+	// WriteControl, as the copy to the connection may still be running.
+	// nolint:errcheck // the client may be gone already
+	conn.WriteControl(websocket.CloseMessage, closeMessage(reason), time.Now().Add(time.Second))
+
+	log.Info("port forward completed")
+	return nil
+}
@@ -0,0 +1,197 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/epinio/epinio/internal/services"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Releases handles the API endpoint GET /namespaces/:org/applications/:app/releases
+// It returns the release history of the named application, oldest first.
+func (hc ApplicationsController) Releases(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	app := models.NewAppRef(appName, org)
+
+	exists, err = application.Exists(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if !exists {
+		return AppIsNotKnown(appName)
+	}
+
+	releases, err := application.Releases(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, releases)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Rollback handles the API endpoint POST /namespaces/:org/applications/:app/rollback
+// It redeploys the image of a previous release of the named application, without
+// staging. The environment and bound services of the application are restored to
+// the snapshot taken with that release. The rollback is recorded as a new release.
+func (hc ApplicationsController) Rollback(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+	username, err := GetUsername(r)
+	if err != nil {
+		return UserNotFound()
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	app := models.NewAppRef(appName, org)
+
+	exists, err = application.Exists(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if !exists {
+		return AppIsNotKnown(appName)
+	}
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var rollbackRequest models.RollbackRequest
+	if len(bodyBytes) > 0 {
+		err = json.Unmarshal(bodyBytes, &rollbackRequest)
+		if err != nil {
+			return BadRequest(err)
+		}
+	}
+
+	release, ok, err := application.ReleaseLookup(ctx, cluster, app, rollbackRequest.Release)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !ok {
+		if rollbackRequest.Release == 0 {
+			return NewBadRequest("Application has no previous release to roll back to")
+		}
+		return NewNotFoundError(fmt.Sprintf("Release '%d' does not exist", rollbackRequest.Release))
+	}
+
+	// Restore the configuration of the release. The services it was bound to have
+	// to still exist for that.
+	var theIssues []APIError
+	for _, serviceName := range release.Services {
+		_, err := services.Lookup(ctx, cluster, org, serviceName)
+		if err != nil {
+			if err.Error() == "service not found" {
+				theIssues = append(theIssues, ServiceIsNotKnown(serviceName))
+				continue
+			}
+			return InternalError(err)
+		}
+	}
+	if len(theIssues) > 0 {
+		return MultiError{theIssues}
+	}
+
+	err = application.EnvironmentSet(ctx, cluster, app, release.Environment, true)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	// Take old state
+	oldBound, err := application.BoundServiceNameSet(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = application.BoundServicesSet(ctx, cluster, app, release.Services, true)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	// Update the workload, if any. This removes the binding secrets of the
+	// services not bound anymore. The deployment below mounts the others.
+	current, err := application.Lookup(ctx, cluster, org, appName)
+	if err != nil {
+		return InternalError(err)
+	}
+	if current.Workload != nil {
+		newBound, err := application.BoundServices(ctx, cluster, app)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		err = application.NewWorkload(cluster, app).BoundServicesChange(ctx, username, oldBound, newBound)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	newRelease, route, apierr := deployApp(ctx, cluster, app, username, models.AppRelease{
+		ImageURL:   release.ImageURL,
+		StageID:    release.StageID,
+		Git:        release.Git,
+		RollbackOf: release.Number,
+	})
+	if apierr != nil {
+		return apierr
+	}
+
+	err = jsonResponse(w, models.RollbackResponse{
+		Release: newRelease,
+		Route:   route,
+	})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
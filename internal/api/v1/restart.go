@@ -0,0 +1,161 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Restart handles the API endpoint POST /namespaces/:org/applications/:app/restart
+// It replaces the pods of the named application, per its rollout strategy.
+// The application is not redeployed.
+func (hc ApplicationsController) Restart(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	app, apierr := deployedApp(ctx, cluster, org, appName)
+	if apierr != nil {
+		return apierr
+	}
+
+	stopped, err := application.Stopped(ctx, cluster, app.Meta)
+	if err != nil {
+		return InternalError(err)
+	}
+	if stopped {
+		return NewBadRequest("application is stopped, there is nothing to restart",
+			fmt.Sprintf("start it with `epinio app start %s`", appName))
+	}
+
+	err = application.NewWorkload(cluster, app.Meta).Restart(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, models.ResponseOK)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Stop handles the API endpoint POST /namespaces/:org/applications/:app/stop
+// It scales the named application to zero instances. The desired instances
+// are kept, for the start of the application. Stopping a stopped
+// application does nothing.
+func (hc ApplicationsController) Stop(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	app, apierr := deployedApp(ctx, cluster, org, appName)
+	if apierr != nil {
+		return apierr
+	}
+
+	err = application.StoppedSet(ctx, cluster, app.Meta, true)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = application.NewWorkload(cluster, app.Meta).Stop(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, models.ResponseOK)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Start handles the API endpoint POST /namespaces/:org/applications/:app/start
+// It scales the named application back to its desired instances, or
+// recreates its autoscaler. Starting a running application does nothing.
+func (hc ApplicationsController) Start(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	app, apierr := deployedApp(ctx, cluster, org, appName)
+	if apierr != nil {
+		return apierr
+	}
+
+	stopped, err := application.Stopped(ctx, cluster, app.Meta)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if stopped {
+		autoscale, err := application.Autoscale(ctx, cluster, app.Meta)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		err = application.NewWorkload(cluster, app.Meta).Start(ctx, *app.Configuration.Instances, autoscale)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		err = application.StoppedSet(ctx, cluster, app.Meta, false)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	err = jsonResponse(w, models.ResponseOK)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// deployedApp returns the named application, after checking that it
+// and its namespace exist, and that it is deployed.
+func deployedApp(ctx context.Context, cluster *kubernetes.Cluster, org, appName string) (*models.App, APIErrors) {
+	_, apierr := knownApp(ctx, cluster, org, appName)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	app, err := application.Lookup(ctx, cluster, org, appName)
+	if err != nil {
+		return nil, InternalError(err)
+	}
+
+	if app.Workload == nil {
+		return nil, NewBadRequest("application is not deployed",
+			fmt.Sprintf("deploy it with `epinio push %s`", appName))
+	}
+
+	return app, nil
+}
@@ -73,23 +73,57 @@ func patch(path string, h http.HandlerFunc) routes.Route {
 	return routes.NewRoute("PATCH", v+path, h)
 }
 
+func put(path string, h http.HandlerFunc) routes.Route {
+	return routes.NewRoute("PUT", v+path, h)
+}
+
 var Routes = routes.NamedRoutes{
 	"Info": get("/info", errorHandler(InfoController{}.Info)),
 
-	"AllApps":         get("/applications", errorHandler(ApplicationsController{}.FullIndex)),
-	"Apps":            get("/namespaces/:org/applications", errorHandler(ApplicationsController{}.Index)),
-	"AppCreate":       post("/namespaces/:org/applications", errorHandler(ApplicationsController{}.Create)),
-	"AppShow":         get("/namespaces/:org/applications/:app", errorHandler(ApplicationsController{}.Show)),
-	"AppLogs":         get("/namespaces/:org/applications/:app/logs", ApplicationsController{}.Logs),
-	"StagingLogs":     get("/namespaces/:org/staging/:stage_id/logs", ApplicationsController{}.Logs),
-	"StagingComplete": get("/namespaces/:org/staging/:stage_id/complete", errorHandler(ApplicationsController{}.Staged)), // See stage.go
-	"AppDelete":       delete("/namespaces/:org/applications/:app", errorHandler(ApplicationsController{}.Delete)),
-	"AppUpload":       post("/namespaces/:org/applications/:app/store", errorHandler(ApplicationsController{}.Upload)), // See upload.go
-	"AppImportGit":    post("/namespaces/:org/applications/:app/import-git", errorHandler(ApplicationsController{}.ImportGit)),
-	"AppStage":        post("/namespaces/:org/applications/:app/stage", errorHandler(ApplicationsController{}.Stage)), // See stage.go
-	"AppDeploy":       post("/namespaces/:org/applications/:app/deploy", errorHandler(ApplicationsController{}.Deploy)),
-	"AppUpdate":       patch("/namespaces/:org/applications/:app", errorHandler(ApplicationsController{}.Update)),
-	"AppRunning":      get("/namespaces/:org/applications/:app/running", errorHandler(ApplicationsController{}.Running)),
+	"AllApps":             get("/applications", errorHandler(ApplicationsController{}.FullIndex)),
+	"Apps":                get("/namespaces/:org/applications", errorHandler(ApplicationsController{}.Index)),
+	"AppCreate":           post("/namespaces/:org/applications", errorHandler(ApplicationsController{}.Create)),
+	"AppShow":             get("/namespaces/:org/applications/:app", errorHandler(ApplicationsController{}.Show)),
+	"AppLogs":             get("/namespaces/:org/applications/:app/logs", ApplicationsController{}.Logs),
+	"StagingLogs":         get("/namespaces/:org/staging/:stage_id/logs", ApplicationsController{}.Logs),
+	"StagingComplete":     get("/namespaces/:org/staging/:stage_id/complete", errorHandler(ApplicationsController{}.Staged)),  // See stage.go
+	"StagingStatus":       get("/namespaces/:org/staging/:stage_id", errorHandler(ApplicationsController{}.StagingStatus)),    // See stage.go
+	"StagingCancel":       delete("/namespaces/:org/staging/:stage_id", errorHandler(ApplicationsController{}.StagingCancel)), // See stage.go
+	"AppDelete":           delete("/namespaces/:org/applications/:app", errorHandler(ApplicationsController{}.Delete)),
+	"AppUpload":           post("/namespaces/:org/applications/:app/store", errorHandler(ApplicationsController{}.Upload)),       // See upload.go
+	"AppUploadInit":       post("/namespaces/:org/applications/:app/uploads", errorHandler(ApplicationsController{}.UploadInit)), // See upload.go
+	"AppUploadStatus":     get("/namespaces/:org/applications/:app/uploads/:upload", errorHandler(ApplicationsController{}.UploadStatus)),
+	"AppUploadPart":       put("/namespaces/:org/applications/:app/uploads/:upload/parts/:part", errorHandler(ApplicationsController{}.UploadPart)),
+	"AppUploadComplete":   post("/namespaces/:org/applications/:app/uploads/:upload/complete", errorHandler(ApplicationsController{}.UploadComplete)),
+	"AppUploadAbort":      delete("/namespaces/:org/applications/:app/uploads/:upload", errorHandler(ApplicationsController{}.UploadAbort)),
+	"AppImportGit":        post("/namespaces/:org/applications/:app/import-git", errorHandler(ApplicationsController{}.ImportGit)),
+	"AppStage":            post("/namespaces/:org/applications/:app/stage", errorHandler(ApplicationsController{}.Stage)), // See stage.go
+	"AppDeploy":           post("/namespaces/:org/applications/:app/deploy", errorHandler(ApplicationsController{}.Deploy)),
+	"AppUpdate":           patch("/namespaces/:org/applications/:app", errorHandler(ApplicationsController{}.Update)),
+	"AppRestart":          post("/namespaces/:org/applications/:app/restart", errorHandler(ApplicationsController{}.Restart)), // See restart.go
+	"AppStop":             post("/namespaces/:org/applications/:app/stop", errorHandler(ApplicationsController{}.Stop)),
+	"AppStart":            post("/namespaces/:org/applications/:app/start", errorHandler(ApplicationsController{}.Start)),
+	"AppInstances":        get("/namespaces/:org/applications/:app/instances", errorHandler(ApplicationsController{}.Instances)),     // See instances.go
+	"AppExec":             get("/namespaces/:org/applications/:app/exec", errorHandler(ApplicationsController{}.Exec)),               // See exec.go
+	"AppPortForward":      get("/namespaces/:org/applications/:app/portforward", errorHandler(ApplicationsController{}.PortForward)), // See portforward.go
+	"AppRunning":          get("/namespaces/:org/applications/:app/running", errorHandler(ApplicationsController{}.Running)),
+	"AppReleases":         get("/namespaces/:org/applications/:app/releases", errorHandler(ApplicationsController{}.Releases)), // See releases.go
+	"AppRollback":         post("/namespaces/:org/applications/:app/rollback", errorHandler(ApplicationsController{}.Rollback)),
+	"AppCache":            get("/namespaces/:org/applications/:app/cache", errorHandler(ApplicationsController{}.Cache)), // See cache.go
+	"AppCacheUpdate":      patch("/namespaces/:org/applications/:app/cache", errorHandler(ApplicationsController{}.CacheUpdate)),
+	"AppCacheClear":       delete("/namespaces/:org/applications/:app/cache", errorHandler(ApplicationsController{}.CacheClear)),
+	"AppAutoscale":        put("/namespaces/:org/applications/:app/autoscale", errorHandler(ApplicationsController{}.Autoscale)), // See autoscale.go
+	"AppAutoscaleDisable": delete("/namespaces/:org/applications/:app/autoscale", errorHandler(ApplicationsController{}.AutoscaleDisable)),
+
+	// Build caches of all applications, for admins. See cache.go
+	"Caches": get("/caches", errorHandler(ApplicationsController{}.Caches)),
+
+	// Credentials of private git repositories. See gitcredentials.go
+	"GitCredentialsCreate": post("/namespaces/:org/gitcredentials", errorHandler(GitCredentialsController{}.Create)),
+	"GitCredentialsDelete": delete("/namespaces/:org/gitcredentials/:credentials", errorHandler(GitCredentialsController{}.Delete)),
+
+	// See jobs.go
+	"JobShow": get("/namespaces/:org/jobs/:id", errorHandler(JobsController{}.Show)),
 
 	// See env.go
 	"EnvList": get("/namespaces/:org/applications/:app/environment", errorHandler(ApplicationsController{}.EnvIndex)),
@@ -117,6 +151,9 @@ var Routes = routes.NamedRoutes{
 	"NamespacesMatch":  get("/namespacematches/:pattern", errorHandler(NamespacesController{}.Match)),
 	"NamespacesMatch0": get("/namespacematches", errorHandler(NamespacesController{}.Match)),
 
+	// List the service classes and plans offered by the brokers
+	"ServiceCatalog": get("/catalog", errorHandler(ServicesController{}.Catalog)),
+
 	// List, show, create and delete services
 	"ServiceApps": get("/namespaces/:org/serviceapps", errorHandler(ApplicationsController{}.ServiceApps)),
 	//
@@ -124,14 +161,36 @@ var Routes = routes.NamedRoutes{
 	"ServiceShow":   get("/namespaces/:org/services/:service", errorHandler(ServicesController{}.Show)),
 	"ServiceCreate": post("/namespaces/:org/services", errorHandler(ServicesController{}.Create)),
 	"ServiceDelete": delete("/namespaces/:org/services/:service", errorHandler(ServicesController{}.Delete)),
+	"ServiceUpdate": patch("/namespaces/:org/services/:service", errorHandler(ServicesController{}.Update)),
+
+	// Issue, refresh and revoke API tokens. See auth.go
+	"AuthToken":        post("/auth/token", errorHandler(AuthController{}.Token)),
+	"AuthTokenRefresh": post("/auth/token/refresh", errorHandler(AuthController{}.Refresh)),
+	"AuthTokenRevoke":  delete("/auth/token", errorHandler(AuthController{}.Revoke)),
+
+	// List, create and delete users, grant and revoke their roles. See users.go
+	"Users":      get("/users", errorHandler(UsersController{}.Index)),
+	"UserCreate": post("/users", errorHandler(UsersController{}.Create)),
+	"UserDelete": delete("/users/:user", errorHandler(UsersController{}.Delete)),
+	"UserGrant":  post("/users/:user/grants", errorHandler(UsersController{}.Grant)),
+	"UserRevoke": delete("/users/:user/grants/:org", errorHandler(UsersController{}.Revoke)),
+
+	// List and prune the source blobs in the S3 storage. See blobs.go
+	"Blobs":      get("/blobs", errorHandler(BlobsController{}.Index)),
+	"BlobsPrune": post("/blobs/prune", errorHandler(BlobsController{}.Prune)),
 }
 
 // Router constructs and returns the router mapping methods and urls to the API handlers.
+// All handlers, except for the public routes, require authentication and authorization.
 func Router() *httprouter.Router {
 	router := httprouter.New()
 
-	for _, r := range Routes {
-		router.HandlerFunc(r.Method, r.Path, r.Handler)
+	for name, r := range Routes {
+		if _, ok := publicRoutes[name]; ok {
+			router.HandlerFunc(r.Method, r.Path, r.Handler)
+			continue
+		}
+		router.Handler(r.Method, r.Path, Authenticate(authorize(name, r)))
 	}
 
 	router.NotFound = http.NotFoundHandler()
@@ -209,7 +209,7 @@ func DeleteBinding(ctx context.Context, cluster *kubernetes.Cluster, org, appNam
 		return AppIsNotKnown(appName)
 	}
 
-	_, err = services.Lookup(ctx, cluster, org, serviceName)
+	service, err := services.Lookup(ctx, cluster, org, serviceName)
 	if err != nil && err.Error() == "service not found" {
 		return ServiceIsNotKnown(serviceName)
 	}
@@ -236,10 +236,17 @@ func DeleteBinding(ctx context.Context, cluster *kubernetes.Cluster, org, appNam
 			return InternalError(err)
 		}
 
+		// This removes the binding of the service as well.
 		err = application.NewWorkload(cluster, app.Meta).BoundServicesChange(ctx, username, oldBound, newBound)
 		if err != nil {
 			return InternalError(err)
 		}
+	} else {
+		// Without workload the application does not use the binding. Remove it, if any.
+		err = service.DeleteBinding(ctx, appName)
+		if err != nil {
+			return InternalError(err)
+		}
 	}
 
 	return nil
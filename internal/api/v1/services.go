@@ -9,6 +9,7 @@ import (
 
 	"github.com/epinio/epinio/helpers/kubernetes"
 	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/duration"
 	"github.com/epinio/epinio/internal/organizations"
 	"github.com/epinio/epinio/internal/services"
 	"github.com/epinio/epinio/pkg/api/core/v1/models"
@@ -63,6 +64,8 @@ func (sc ServicesController) Show(w http.ResponseWriter, r *http.Request) APIErr
 	err = jsonResponse(w, models.ServiceShowResponse{
 		Username: service.User(),
 		Details:  responseData,
+		Class:    service.Class,
+		Plan:     service.Plan,
 	})
 	if err != nil {
 		return InternalError(err)
@@ -112,6 +115,8 @@ func (sc ServicesController) Index(w http.ResponseWriter, r *http.Request) APIEr
 		responseData = append(responseData, models.ServiceResponse{
 			Name:      service.Name(),
 			BoundApps: appNames,
+			Class:     service.Class,
+			Plan:      service.Plan,
 		})
 	}
 
@@ -123,8 +128,32 @@ func (sc ServicesController) Index(w http.ResponseWriter, r *http.Request) APIEr
 	return nil
 }
 
+// Catalog handles the API end point /catalog
+// It returns the service classes and plans offered by the brokers
+func (sc ServicesController) Catalog(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	catalog, err := services.Catalog(ctx, cluster)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, catalog)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
 // Create handles the API end point /orgs/:org/services
-// It creates the named service from its parameters
+// It creates the named service from its parameters. Catalog services
+// are provisioned through their broker.
 func (sc ServicesController) Create(w http.ResponseWriter, r *http.Request) APIErrors {
 	ctx := r.Context()
 	params := httprouter.ParamsFromContext(ctx)
@@ -150,7 +179,14 @@ func (sc ServicesController) Create(w http.ResponseWriter, r *http.Request) APIE
 		return NewBadRequest("Cannot create service without a name")
 	}
 
-	if len(createRequest.Data) < 1 {
+	if createRequest.Class != "" {
+		if createRequest.Plan == "" {
+			return NewBadRequest("Cannot create catalog service without a plan")
+		}
+		if len(createRequest.Data) > 0 {
+			return NewBadRequest("Cannot create catalog service with data, use params")
+		}
+	} else if len(createRequest.Data) < 1 {
 		return NewBadRequest("Cannot create service without data")
 	}
 
@@ -180,7 +216,15 @@ func (sc ServicesController) Create(w http.ResponseWriter, r *http.Request) APIE
 	// any error here is `service not found`, and we can continue
 
 	// Create the new service. At last.
-	_, err = services.CreateService(ctx, cluster, createRequest.Name, org, username, createRequest.Data)
+	if createRequest.Class != "" {
+		_, err = services.CreateCatalogService(ctx, cluster, createRequest.Name, org, username,
+			createRequest.Class, createRequest.Plan, createRequest.Params)
+		if err == services.ErrUnknownClass || err == services.ErrUnknownPlan {
+			return BadRequest(err, createRequest.Class, createRequest.Plan)
+		}
+	} else {
+		_, err = services.CreateService(ctx, cluster, createRequest.Name, org, username, createRequest.Data)
+	}
 	if err != nil {
 		return InternalError(err)
 	}
@@ -282,6 +326,118 @@ func (sc ServicesController) Delete(w http.ResponseWriter, r *http.Request) APIE
 	return nil
 }
 
+// Update handles the API end point /orgs/:org/services/:service (PATCH)
+// It changes the parameters of the named service, and restarts the
+// applications bound to it, with their bindings synced to the changes.
+func (sc ServicesController) Update(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	serviceName := params.ByName("service")
+	username, err := GetUsername(r)
+	if err != nil {
+		return UserNotFound()
+	}
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var updateRequest models.ServiceUpdateRequest
+	err = json.Unmarshal(bodyBytes, &updateRequest)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	if len(updateRequest.Set) == 0 && len(updateRequest.Unset) == 0 {
+		return NewBadRequest("nothing to update")
+	}
+	for key := range updateRequest.Set {
+		if key == "" {
+			return NewBadRequest("cannot set an empty key")
+		}
+	}
+	for _, key := range updateRequest.Unset {
+		if _, ok := updateRequest.Set[key]; ok {
+			return NewBadRequest("cannot set and unset the same key", key)
+		}
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	service, err := services.Lookup(ctx, cluster, org, serviceName)
+	if err != nil && err.Error() == "service not found" {
+		return ServiceIsNotKnown(serviceName)
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = service.Update(ctx, updateRequest.Set, updateRequest.Unset)
+	if err != nil {
+		if err == services.ErrCatalogUpdate {
+			return BadRequest(err, serviceName)
+		}
+		return InternalError(err)
+	}
+
+	// Sync the bindings of the active bound applications and restart them.
+
+	appsOf, err := servicesToApps(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	restarted := []string{}
+	for _, app := range appsOf[service.Name()] {
+		if app.Workload == nil {
+			// Inactive applications pick up the changes when deployed.
+			continue
+		}
+
+		_, err := service.GetBinding(ctx, app.Meta.Name, username)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		workload := application.NewWorkload(cluster, app.Meta)
+
+		err = workload.Restart(ctx)
+		if err != nil {
+			return InternalError(err)
+		}
+
+		if updateRequest.Sequential {
+			err = workload.WaitForRollout(ctx, duration.ToDeployment())
+			if err != nil {
+				return InternalError(err, "waiting for the restart of "+app.Meta.Name)
+			}
+		}
+
+		restarted = append(restarted, app.Meta.Name)
+	}
+
+	err = jsonResponse(w, models.ServiceUpdateResponse{RestartedApps: restarted})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
 // servicesToApps is a helper to Index and Delete. It produces a map
 // from service instances names to application names, the apps bound
 // to each service.
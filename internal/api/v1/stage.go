@@ -1,10 +1,9 @@
 package v1
 
 import (
-	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"time"
@@ -12,10 +11,8 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/spf13/viper"
 	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
-	tekton "github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 
@@ -29,6 +26,7 @@ import (
 	"github.com/epinio/epinio/internal/duration"
 	"github.com/epinio/epinio/internal/organizations"
 	"github.com/epinio/epinio/internal/s3manager"
+	"github.com/epinio/epinio/internal/staging"
 	"github.com/epinio/epinio/pkg/api/core/v1/models"
 )
 
@@ -40,6 +38,8 @@ type stageParam struct {
 	models.AppRef
 	BlobUID             string
 	BuilderImage        string
+	Strategy            string
+	SourceImage         string
 	Environment         models.EnvVariableList
 	Owner               metav1.OwnerReference
 	RegistryURL         string
@@ -55,41 +55,6 @@ func (app *stageParam) ImageURL(registryURL string) string {
 	return fmt.Sprintf("%s/%s-%s", registryURL, app.Name, app.Stage.ID)
 }
 
-// ensurePVC creates a PVC for the application if one doesn't already exist.
-// This PVC is used to store the application source blobs (as they are uploaded
-// on the "upload" endpoint). It's also mounted in the staging task pod as the
-// "source" tekton workspace.
-// The same PVC stores the application's build cache (on a separate directory).
-func ensurePVC(ctx context.Context, cluster *kubernetes.Cluster, ar models.AppRef) error {
-	_, err := cluster.Kubectl.CoreV1().PersistentVolumeClaims(deployments.TektonStagingNamespace).
-		Get(ctx, ar.MakePVCName(), metav1.GetOptions{})
-	if err != nil && !apierrors.IsNotFound(err) { // Unknown error, irrelevant to non-existence
-		return err
-	}
-	if err == nil { // pvc already exists
-		return nil
-	}
-
-	// From here on, only if the PVC is missing
-	_, err = cluster.Kubectl.CoreV1().PersistentVolumeClaims(deployments.TektonStagingNamespace).
-		Create(ctx, &corev1.PersistentVolumeClaim{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      ar.MakePVCName(),
-				Namespace: deployments.TektonStagingNamespace,
-			},
-			Spec: corev1.PersistentVolumeClaimSpec{
-				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
-				Resources: corev1.ResourceRequirements{
-					Requests: map[corev1.ResourceName]resource.Quantity{
-						corev1.ResourceStorage: resource.MustParse("1Gi"),
-					},
-				},
-			},
-		}, metav1.CreateOptions{})
-
-	return err
-}
-
 // Stage handles the API endpoint /orgs/:org/applications/:app/stage
 // It creates a Tekton PipelineRun resource to stage the app
 func (hc ApplicationsController) Stage(w http.ResponseWriter, r *http.Request) APIErrors {
@@ -122,10 +87,6 @@ func (hc ApplicationsController) Stage(w http.ResponseWriter, r *http.Request) A
 		return NewBadRequest("org parameter from URL does not match org param in body")
 	}
 
-	if req.BuilderImage == "" {
-		return NewBadRequest("builder image cannot be empty")
-	}
-
 	cluster, err := kubernetes.GetCluster(ctx)
 	if err != nil {
 		return InternalError(err, "failed to get access to a kube client")
@@ -153,18 +114,13 @@ func (hc ApplicationsController) Stage(w http.ResponseWriter, r *http.Request) A
 		return InternalError(err, "failed to generate a uid")
 	}
 
-	l, err := client.List(ctx, metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("app.kubernetes.io/name=%s,app.kubernetes.io/part-of=%s", req.App.Name, req.App.Org),
-	})
+	inFlight, err := staging.InFlight(ctx, cluster, req.App)
 	if err != nil {
 		return InternalError(err)
 	}
-
-	// assume that completed pipelineruns are from the past and have a CompletionTime
-	for _, pr := range l.Items {
-		if pr.Status.CompletionTime == nil {
-			return NewBadRequest("pipelinerun for image ID still running")
-		}
+	if inFlight != "" {
+		return NewBadRequest("pipelinerun for image ID still running",
+			fmt.Sprintf("cancel staging %s with `epinio app stage cancel %s`", inFlight, req.App.Name))
 	}
 
 	environment, err := application.Environment(ctx, cluster, req.App)
@@ -189,9 +145,28 @@ func (hc ApplicationsController) Stage(w http.ResponseWriter, r *http.Request) A
 		return InternalError(err, "failed to fetch the S3 connection details")
 	}
 
+	strategy, builderImage, err := staging.Resolve(req, func() (io.ReadCloser, error) {
+		manager, err := s3manager.New(s3ConnectionDetails)
+		if err != nil {
+			return nil, err
+		}
+		return manager.Get(ctx, req.BlobUID)
+	})
+	if err != nil {
+		if err == staging.ErrUnknownStrategy || err == staging.ErrNoSourceImage ||
+			err == staging.ErrBadSourceImage || err == staging.ErrNoSources {
+			return NewBadRequest(err.Error(), req.Strategy)
+		}
+		return InternalError(err, "failed to determine the staging strategy")
+	}
+
+	log.Info("staging strategy", "app", req.App, "strategy", strategy)
+
 	params := stageParam{
 		AppRef:              req.App,
-		BuilderImage:        req.BuilderImage,
+		BuilderImage:        builderImage,
+		Strategy:            strategy,
+		SourceImage:         req.SourceImage,
 		BlobUID:             req.BlobUID,
 		Environment:         environment,
 		Owner:               owner,
@@ -201,9 +176,11 @@ func (hc ApplicationsController) Stage(w http.ResponseWriter, r *http.Request) A
 		Username:            username,
 	}
 
-	err = ensurePVC(ctx, cluster, req.App)
-	if err != nil {
-		return InternalError(err, "failed to ensure a PersistenVolumeClaim for the application source and cache")
+	if strategy != models.StagingImage {
+		err = application.CacheEnsure(ctx, cluster, req.App)
+		if err != nil {
+			return InternalError(err, "failed to ensure a PersistenVolumeClaim for the application source and cache")
+		}
 	}
 
 	pr := newPipelineRun(params)
@@ -236,6 +213,7 @@ func (hc ApplicationsController) Stage(w http.ResponseWriter, r *http.Request) A
 	resp := models.StageResponse{
 		Stage:    models.NewStage(uid),
 		ImageURL: params.ImageURL(params.RegistryURL),
+		Strategy: strategy,
 	}
 	err = jsonResponse(w, resp)
 	if err != nil {
@@ -246,7 +224,8 @@ func (hc ApplicationsController) Stage(w http.ResponseWriter, r *http.Request) A
 }
 
 // Staged handles the API endpoint /orgs/:org/staging/:stage_id/complete
-// It waits for the Tekton PipelineRun resource staging the app to complete
+// It waits for the Tekton PipelineRun resource staging the app to complete.
+// A failed staging is reported with the run's status, see stagingFailed.
 func (hc ApplicationsController) Staged(w http.ResponseWriter, r *http.Request) APIErrors {
 	ctx := r.Context()
 
@@ -265,45 +244,33 @@ func (hc ApplicationsController) Staged(w http.ResponseWriter, r *http.Request)
 	}
 
 	if !exists {
-		return InternalError(err)
+		return OrgIsNotKnown(org)
 	}
 
-	cs, err := tekton.NewForConfig(cluster.RestConfig)
-	if err != nil {
-		return InternalError(err)
-	}
-
-	client := cs.TektonV1beta1().PipelineRuns(deployments.TektonStagingNamespace)
-
+	var status *models.StageStatus
 	err = wait.PollImmediate(time.Second, duration.ToAppBuilt(),
 		func() (bool, error) {
-			l, err := client.List(ctx, metav1.ListOptions{LabelSelector: models.EpinioStageIDLabel + "=" + id})
-			if err != nil {
-				return false, err
-			}
-			if len(l.Items) == 0 {
+			status, err = staging.Status(ctx, cluster, org, id)
+			if err == staging.ErrStageNotFound {
+				// pr not created yet
 				return false, nil
 			}
-			for _, pr := range l.Items {
-				// any failed conditions, throw an error so we can exit early
-				for _, c := range pr.Status.Conditions {
-					if c.IsFalse() {
-						return false, errors.New(c.Message)
-					}
-				}
-				// it worked
-				if pr.Status.CompletionTime != nil {
-					return true, nil
-				}
+			if err != nil {
+				return false, err
 			}
-			// pr exists, but still running
-			return false, nil
+			return status.Phase == models.StageSucceeded ||
+				status.Phase == models.StageFailed ||
+				status.Phase == models.StageCancelled, nil
 		})
 
 	if err != nil {
 		return InternalError(err)
 	}
 
+	if status.Phase != models.StageSucceeded {
+		return stagingFailed(status)
+	}
+
 	err = jsonResponse(w, models.ResponseOK)
 	if err != nil {
 		return InternalError(err)
@@ -312,11 +279,161 @@ func (hc ApplicationsController) Staged(w http.ResponseWriter, r *http.Request)
 	return nil
 }
 
+// StagingStatus handles the API endpoint /orgs/:org/staging/:stage_id
+// It returns the status of the Tekton PipelineRun resource staging the
+// app, with the state of each task and step.
+func (hc ApplicationsController) StagingStatus(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	p := httprouter.ParamsFromContext(ctx)
+	org := p.ByName("org")
+	id := p.ByName("stage_id")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	status, err := staging.Status(ctx, cluster, org, id)
+	if err == staging.ErrStageNotFound {
+		return NewNotFoundError(err.Error(), id)
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, status)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// StagingCancel handles the API endpoint /orgs/:org/staging/:stage_id (DELETE)
+// It cancels the Tekton PipelineRun resource staging the app, and removes
+// the pods of its tasks.
+func (hc ApplicationsController) StagingCancel(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	p := httprouter.ParamsFromContext(ctx)
+	org := p.ByName("org")
+	id := p.ByName("stage_id")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	err = staging.Cancel(ctx, cluster, org, id)
+	if err == staging.ErrStageNotFound {
+		return NewNotFoundError(err.Error(), id)
+	}
+	if err == staging.ErrStageFinished {
+		return BadRequest(err, id)
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, models.ResponseOK)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// stagingFailed constructs an API error for a failed or cancelled staging
+// run. The details carry the run's status, as JSON, for the client to report.
+func stagingFailed(status *models.StageStatus) APIErrors {
+	details, err := json.Marshal(status)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return NewAPIError(staging.Summary(status), string(details), http.StatusUnprocessableEntity)
+}
+
 // newPipelineRun is a helper which creates a Tekton pipeline run
-// resource from the given staging params
+// resource from the given staging params. The pipeline, its parameters
+// and workspaces depend on the staging strategy.
 func newPipelineRun(app stageParam) *v1beta1.PipelineRun {
 	str := v1beta1.NewArrayOrString
 
+	var params []v1beta1.Param
+	var workspaces []v1beta1.WorkspaceBinding
+
+	switch app.Strategy {
+	case models.StagingImage:
+		params = []v1beta1.Param{
+			{Name: "APP_IMAGE", Value: *str(app.ImageURL(app.RegistryURL))},
+			{Name: "SOURCE_IMAGE", Value: *str(app.SourceImage)},
+		}
+	case models.StagingDockerfile:
+		params = append(sourceParams(app),
+			v1beta1.Param{Name: "APP_IMAGE", Value: *str(app.ImageURL(app.RegistryURL))})
+		workspaces = sourceWorkspaces(app)
+	default:
+		params = append(sourceParams(app),
+			v1beta1.Param{Name: "APP_IMAGE", Value: *str(app.ImageURL(app.RegistryURL))},
+			v1beta1.Param{Name: "BUILDER_IMAGE", Value: *str(app.BuilderImage)})
+		workspaces = append(sourceWorkspaces(app), v1beta1.WorkspaceBinding{
+			Name:    "cache",
+			SubPath: "cache",
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: app.MakePVCName(),
+				ReadOnly:  false,
+			},
+		})
+	}
+
+	return &v1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: app.Stage.ID,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":          app.Name,
+				"app.kubernetes.io/part-of":       app.Org,
+				"app.kubernetes.io/created-by":    app.Username,
+				models.EpinioStageIDLabel:         app.Stage.ID,
+				models.EpinioStageBlobUIDLabel:    app.BlobUID,
+				models.EpinioStagingStrategyLabel: app.Strategy,
+				"app.kubernetes.io/managed-by":    "epinio",
+				"app.kubernetes.io/component":     "staging",
+			},
+		},
+		Spec: v1beta1.PipelineRunSpec{
+			ServiceAccountName: "staging-triggers-admin",
+			PipelineRef:        &v1beta1.PipelineRef{Name: staging.Pipelines[app.Strategy]},
+			Params:             params,
+			Workspaces:         workspaces,
+		},
+	}
+}
+
+// sourceParams returns the pipeline parameters of the strategies staging
+// the uploaded sources, i.e. for fetching them, and the build environment.
+func sourceParams(app stageParam) []v1beta1.Param {
+	str := v1beta1.NewArrayOrString
+
 	protocol := "http"
 	if app.S3ConnectionDetails.UseSSL {
 		protocol = "https"
@@ -328,61 +445,38 @@ func newPipelineRun(app stageParam) *v1beta1.PipelineRun {
 		app.BlobUID,
 	}
 
-	return &v1beta1.PipelineRun{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: app.Stage.ID,
-			Labels: map[string]string{
-				"app.kubernetes.io/name":       app.Name,
-				"app.kubernetes.io/part-of":    app.Org,
-				"app.kubernetes.io/created-by": app.Username,
-				models.EpinioStageIDLabel:      app.Stage.ID,
-				models.EpinioStageBlobUIDLabel: app.BlobUID,
-				"app.kubernetes.io/managed-by": "epinio",
-				"app.kubernetes.io/component":  "staging",
-			},
+	return []v1beta1.Param{
+		{Name: "ENV_VARS", Value: v1beta1.ArrayOrString{
+			Type:     v1beta1.ParamTypeArray,
+			ArrayVal: app.Environment.StagingEnvArray()},
 		},
-		Spec: v1beta1.PipelineRunSpec{
-			ServiceAccountName: "staging-triggers-admin",
-			PipelineRef:        &v1beta1.PipelineRef{Name: "staging-pipeline"},
-			Params: []v1beta1.Param{
-				{Name: "APP_IMAGE", Value: *str(app.ImageURL(app.RegistryURL))},
-				{Name: "BUILDER_IMAGE", Value: *str(app.BuilderImage)},
-				{Name: "ENV_VARS", Value: v1beta1.ArrayOrString{
-					Type:     v1beta1.ParamTypeArray,
-					ArrayVal: app.Environment.StagingEnvArray()},
-				},
-				{Name: "AWS_SCRIPT", Value: *str(awsScript)},
-				{Name: "AWS_ARGS", Value: v1beta1.ArrayOrString{
-					Type:     v1beta1.ParamTypeArray,
-					ArrayVal: awsArgs},
-				},
+		{Name: "AWS_SCRIPT", Value: *str(awsScript)},
+		{Name: "AWS_ARGS", Value: v1beta1.ArrayOrString{
+			Type:     v1beta1.ParamTypeArray,
+			ArrayVal: awsArgs},
+		},
+	}
+}
+
+// sourceWorkspaces returns the pipeline workspaces of the strategies staging
+// the uploaded sources, i.e. for the sources and the S3 credentials.
+func sourceWorkspaces(app stageParam) []v1beta1.WorkspaceBinding {
+	return []v1beta1.WorkspaceBinding{
+		{
+			Name:    "source",
+			SubPath: "source",
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: app.MakePVCName(),
+				ReadOnly:  false,
 			},
-			Workspaces: []v1beta1.WorkspaceBinding{
-				{
-					Name:    "cache",
-					SubPath: "cache",
-					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-						ClaimName: app.MakePVCName(),
-						ReadOnly:  false,
-					},
-				},
-				{
-					Name:    "source",
-					SubPath: "source",
-					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-						ClaimName: app.MakePVCName(),
-						ReadOnly:  false,
-					},
-				},
-				{
-					Name: "s3secret",
-					Secret: &corev1.SecretVolumeSource{
-						SecretName: deployments.S3ConnectionDetailsSecret,
-						Items: []corev1.KeyToPath{
-							{Key: "config", Path: "config"},
-							{Key: "credentials", Path: "credentials"},
-						},
-					},
+		},
+		{
+			Name: "s3secret",
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: deployments.S3ConnectionDetailsSecret,
+				Items: []corev1.KeyToPath{
+					{Key: "config", Path: "config"},
+					{Key: "credentials", Path: "credentials"},
 				},
 			},
 		},
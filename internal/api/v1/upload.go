@@ -1,23 +1,33 @@
 package v1
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"path"
+	"strconv"
 
 	"github.com/epinio/epinio/deployments"
 	"github.com/epinio/epinio/helpers/kubernetes"
 	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/application"
 	"github.com/epinio/epinio/internal/s3manager"
+	"github.com/epinio/epinio/internal/uploads"
 	"github.com/epinio/epinio/pkg/api/core/v1/models"
 	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 )
 
 // Upload handles the API endpoint /orgs/:org/applications/:app/store.
 // It receives the application data as a tarball and stores it. Then
-// it creates the k8s resources needed for staging
+// it creates the k8s resources needed for staging.
+// Newer clients use the chunked upload, see UploadInit.
 func (hc ApplicationsController) Upload(w http.ResponseWriter, r *http.Request) APIErrors {
 	ctx := r.Context()
 	log := tracelog.Logger(ctx)
@@ -30,8 +40,16 @@ func (hc ApplicationsController) Upload(w http.ResponseWriter, r *http.Request)
 
 	log.V(2).Info("parsing multipart form")
 
+	maxSize := viper.GetInt64("max-upload-size")
+	if maxSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+	}
+
 	file, _, err := r.FormFile("file")
 	if err != nil {
+		if maxSize > 0 && r.ContentLength > maxSize {
+			return UploadTooLarge(maxSize)
+		}
 		return BadRequest(err, "can't read multipart file input")
 	}
 	defer file.Close()
@@ -49,7 +67,8 @@ func (hc ApplicationsController) Upload(w http.ResponseWriter, r *http.Request)
 	}
 	defer f.Close()
 
-	_, err = io.Copy(f, file)
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, hash), file)
 	if err != nil {
 		return InternalError(err, "failed to copy app sources to temp location")
 	}
@@ -59,33 +78,253 @@ func (hc ApplicationsController) Upload(w http.ResponseWriter, r *http.Request)
 		return InternalError(err, "failed to get access to a kube client")
 	}
 
-	connectionDetails, err := s3manager.GetConnectionDetails(ctx, cluster, deployments.TektonStagingNamespace, deployments.S3ConnectionDetailsSecret)
+	username, err := GetUsername(r)
 	if err != nil {
-		return InternalError(err, "fetching the S3 connection details from the Kubernetes secret")
+		return UserNotFound()
 	}
-	manager, err := s3manager.New(connectionDetails)
+
+	blobUID, apierr := storeBlob(ctx, cluster, models.NewAppRef(name, org), username,
+		blob, hex.EncodeToString(hash.Sum(nil)))
+	if apierr != nil {
+		return apierr
+	}
+
+	resp := models.UploadResponse{BlobUID: blobUID}
+	err = jsonResponse(w, resp)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// UploadInit handles the API endpoint POST /namespaces/:org/applications/:app/uploads
+// It initiates a chunked upload of the application sources, or resumes
+// the upload in progress for the same tarball. When the identical sources
+// were stored already the response carries their blob UID instead, and
+// nothing has to be uploaded. Tarballs larger than the configured maximum
+// are refused.
+func (hc ApplicationsController) UploadInit(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var req models.UploadInitRequest
+	err = json.Unmarshal(bodyBytes, &req)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// The parts are kept on the server's disk until the upload completes
+	if maxSize := viper.GetInt64("max-upload-size"); maxSize > 0 && req.Size > maxSize {
+		return UploadTooLarge(maxSize)
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	app, apierr := knownApp(ctx, cluster, org, appName)
+	if apierr != nil {
+		return apierr
+	}
+
+	blobUID, err := application.BlobFind(ctx, cluster, app, req.SHA256)
+	if err != nil {
+		return InternalError(err)
+	}
+	if blobUID != "" {
+		err = jsonResponse(w, models.UploadInitResponse{BlobUID: blobUID})
+		if err != nil {
+			return InternalError(err)
+		}
+		return nil
+	}
+
+	// Abandoned uploads are removed with the next one
+	if err := uploads.Sweep(); err != nil {
+		tracelog.Logger(ctx).Error(err, "removing abandoned uploads")
+	}
+
+	upload, err := uploads.Open(app, req.Size, req.SHA256)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	err = jsonResponse(w, models.UploadInitResponse{Upload: &upload.UploadState})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// UploadStatus handles the API endpoint GET /namespaces/:org/applications/:app/uploads/:upload
+// It returns the state of the chunked upload, i.e. the parts received so far.
+func (hc ApplicationsController) UploadStatus(w http.ResponseWriter, r *http.Request) APIErrors {
+	upload, apierr := loadUpload(r)
+	if apierr != nil {
+		return apierr
+	}
+
+	err := jsonResponse(w, upload.UploadState)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// UploadPart handles the API endpoint PUT /namespaces/:org/applications/:app/uploads/:upload/parts/:part
+// It receives the numbered part of the chunked upload, as the raw request body.
+func (hc ApplicationsController) UploadPart(w http.ResponseWriter, r *http.Request) APIErrors {
+	upload, apierr := loadUpload(r)
+	if apierr != nil {
+		return apierr
+	}
+
+	part, err := strconv.Atoi(httprouter.ParamsFromContext(r.Context()).ByName("part"))
 	if err != nil {
-		return InternalError(err, "creating an S3 manager")
+		return BadRequest(err, "the part is not a number")
+	}
+
+	defer r.Body.Close()
+	err = upload.WritePart(part, r.Body)
+	if errors.Is(err, uploads.ErrBadPart) {
+		return BadRequest(err)
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, models.ResponseOK)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// UploadComplete handles the API endpoint POST /namespaces/:org/applications/:app/uploads/:upload/complete
+// It assembles the parts of the chunked upload into the tarball, checks its
+// SHA-256, and stores it like Upload does.
+func (hc ApplicationsController) UploadComplete(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	upload, apierr := loadUpload(r)
+	if apierr != nil {
+		return apierr
 	}
 
 	username, err := GetUsername(r)
 	if err != nil {
 		return UserNotFound()
 	}
-	blobUID, err := manager.Upload(ctx, blob, map[string]string{
-		"app": name, "org": org, "username": username,
-	})
+
+	tarball, err := upload.Assemble()
+	if errors.Is(err, uploads.ErrBadPart) {
+		return BadRequest(err)
+	}
+	if err == uploads.ErrChecksumMismatch {
+		// The parts are unusable. Start over.
+		_ = upload.Remove()
+		return NewAPIError("checksum mismatch, upload the sources again",
+			upload.SHA256, http.StatusUnprocessableEntity)
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
 	if err != nil {
-		return InternalError(err, "uploading the application sources blob")
+		return InternalError(err)
 	}
 
-	log.Info("uploaded app", "org", org, "app", name, "blobUID", blobUID)
+	blobUID, apierr := storeBlob(ctx, cluster, upload.App, username, tarball, upload.SHA256)
+	if apierr != nil {
+		return apierr
+	}
 
-	resp := models.UploadResponse{BlobUID: blobUID}
-	err = jsonResponse(w, resp)
+	err = upload.Remove()
+	if err != nil {
+		tracelog.Logger(ctx).Error(err, "removing the completed upload", "upload", upload.ID)
+	}
+
+	err = jsonResponse(w, models.UploadResponse{BlobUID: blobUID})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// UploadAbort handles the API endpoint DELETE /namespaces/:org/applications/:app/uploads/:upload
+// It removes the chunked upload, with the parts received so far.
+func (hc ApplicationsController) UploadAbort(w http.ResponseWriter, r *http.Request) APIErrors {
+	upload, apierr := loadUpload(r)
+	if apierr != nil {
+		return apierr
+	}
+
+	err := upload.Remove()
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, models.ResponseOK)
 	if err != nil {
 		return InternalError(err)
 	}
 
 	return nil
 }
+
+// loadUpload returns the chunked upload addressed by the request.
+func loadUpload(r *http.Request) (*uploads.Upload, APIErrors) {
+	params := httprouter.ParamsFromContext(r.Context())
+	app := models.NewAppRef(params.ByName("app"), params.ByName("org"))
+	id := params.ByName("upload")
+
+	upload, err := uploads.Load(app, id)
+	if err == uploads.ErrUploadNotFound {
+		return nil, NewNotFoundError(err.Error(), id)
+	}
+	if err != nil {
+		return nil, InternalError(err)
+	}
+
+	return upload, nil
+}
+
+// storeBlob stores the tarball of application sources in the S3 storage,
+// and returns its blob UID. The UID is derived from the SHA-256 of the
+// tarball, see application.BlobID, to recognize identical sources.
+func storeBlob(ctx context.Context, cluster *kubernetes.Cluster, app models.AppRef, username, tarball, checksum string) (string, APIErrors) {
+	connectionDetails, err := s3manager.GetConnectionDetails(ctx, cluster, deployments.TektonStagingNamespace, deployments.S3ConnectionDetailsSecret)
+	if err != nil {
+		return "", InternalError(err, "fetching the S3 connection details from the Kubernetes secret")
+	}
+	manager, err := s3manager.New(connectionDetails)
+	if err != nil {
+		return "", InternalError(err, "creating an S3 manager")
+	}
+
+	blobUID, err := manager.UploadAs(ctx, application.BlobID(app, checksum), tarball, map[string]string{
+		"app": app.Name, "org": app.Org, "username": username, "sha256": checksum,
+	})
+	if err != nil {
+		return "", InternalError(err, "uploading the application sources blob")
+	}
+
+	tracelog.Logger(ctx).Info("uploaded app", "org", app.Org, "app", app.Name, "blobUID", blobUID)
+
+	return blobUID, nil
+}
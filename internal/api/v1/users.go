@@ -0,0 +1,209 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/epinio/epinio/internal/users"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/julienschmidt/httprouter"
+)
+
+// UsersController represents all functionality of the API related to users.
+// All of its endpoints are restricted to admins, see adminRoutes.
+type UsersController struct {
+}
+
+// Index handles the API endpoint /users (GET)
+// It returns a list of all users, with their roles.
+func (uc UsersController) Index(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	userList, err := users.List(ctx, cluster)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, userList)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Create handles the API endpoint /users (POST).
+// It creates a user with the specified name and password.
+func (uc UsersController) Create(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var createRequest models.UserCreateRequest
+	err = json.Unmarshal(bodyBytes, &createRequest)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	err = users.ValidateUsername(createRequest.Username)
+	if err != nil {
+		return BadRequest(err)
+	}
+	if createRequest.Password == "" {
+		return BadRequest(errors.New("password of user to create not found"))
+	}
+
+	err = users.Create(ctx, cluster, createRequest.Username, createRequest.Password, createRequest.Admin)
+	if err == users.ErrUserExists {
+		return UserAlreadyKnown(createRequest.Username)
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	err = jsonResponse(w, models.ResponseOK)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Delete handles the API endpoint /users/:user (DELETE).
+// It removes the specified user. Users cannot remove themselves.
+func (uc UsersController) Delete(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	username := params.ByName("user")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	current, err := GetUsername(r)
+	if err != nil {
+		return UserNotFound()
+	}
+	if current == username {
+		return NewBadRequest("Cannot remove the current user")
+	}
+
+	err = users.Delete(ctx, cluster, username)
+	if err == users.ErrUserNotFound {
+		return UserIsNotKnown(username)
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, models.ResponseOK)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Grant handles the API endpoint /users/:user/grants (POST).
+// It gives the user the specified role in the specified namespace.
+func (uc UsersController) Grant(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	username := params.ByName("user")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var grantRequest models.UserGrantRequest
+	err = json.Unmarshal(bodyBytes, &grantRequest)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	err = users.ValidateRole(grantRequest.Role)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, grantRequest.Namespace)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(grantRequest.Namespace)
+	}
+
+	err = users.Grant(ctx, cluster, username, grantRequest.Namespace, grantRequest.Role)
+	if err == users.ErrUserNotFound {
+		return UserIsNotKnown(username)
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, models.ResponseOK)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Revoke handles the API endpoint /users/:user/grants/:org (DELETE).
+// It removes the role of the user in the namespace.
+func (uc UsersController) Revoke(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	username := params.ByName("user")
+	org := params.ByName("org")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = users.Revoke(ctx, cluster, username, org)
+	if err == users.ErrUserNotFound {
+		return UserIsNotKnown(username)
+	}
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, models.ResponseOK)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"regexp"
 	"sync"
+	"time"
 
 	"github.com/epinio/epinio/deployments"
 	"github.com/epinio/epinio/helpers/kubernetes"
@@ -18,6 +19,7 @@ import (
 	"github.com/epinio/epinio/pkg/api/core/v1/models"
 	"github.com/pkg/errors"
 
+	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -191,6 +193,14 @@ func Delete(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppR
 		return err
 	}
 
+	// delete the source blobs not used by the pipelineruns, e.g. uploads never staged.
+	// Blobs left behind are orphaned, and removed by the collection of blobs, see CollectBlobs.
+	err = blobsDelete(ctx, cluster, appRef)
+	if err != nil {
+		tracelog.Logger(ctx).Error(err, "removing the source blobs, left to the blob collection",
+			"namespace", appRef.Org, "application", appRef.Name)
+	}
+
 	// delete staging PVC (the one that stores "source" and "cache" tekton workspaces)
 	err = deleteStagePVC(ctx, cluster, appRef)
 	if err != nil && !apierrors.IsNotFound(err) {
@@ -243,6 +253,14 @@ func Unstage(ctx context.Context, cluster *kubernetes.Cluster, appRef models.App
 		return err
 	}
 
+	// Identical sources are not uploaded again, i.e. runs may share their blob.
+	blobCurrent := ""
+	for _, pr := range l.Items {
+		if stageIDCurrent != "" && stageIDCurrent == pr.Labels[models.EpinioStageIDLabel] {
+			blobCurrent = pr.Labels[models.EpinioStageBlobUIDLabel]
+		}
+	}
+
 	for _, pr := range l.Items {
 		id := pr.Labels[models.EpinioStageIDLabel]
 		// stageIDCurrent is either empty or the id to keep
@@ -255,7 +273,11 @@ func Unstage(ctx context.Context, cluster *kubernetes.Cluster, appRef models.App
 			return err
 		}
 
-		if err = s3m.DeleteObject(ctx, pr.ObjectMeta.Labels[models.EpinioStageBlobUIDLabel]); err != nil {
+		blobUID := pr.ObjectMeta.Labels[models.EpinioStageBlobUIDLabel]
+		if blobUID == "" || blobUID == blobCurrent {
+			continue
+		}
+		if err = s3m.DeleteObject(ctx, blobUID); err != nil {
 			return err
 		}
 	}
@@ -263,12 +285,25 @@ func Unstage(ctx context.Context, cluster *kubernetes.Cluster, appRef models.App
 	return nil
 }
 
+// LogParameters are the optional settings for Logs. A zero Since selects
+// duration.LogHistory. A nil Tail returns all lines. The Include and
+// Exclude patterns filter the lines, see tailer.Config. An empty Instance
+// returns the logs of all pods, else only the logs of the named pod.
+type LogParameters struct {
+	Since      time.Duration
+	Tail       *int64
+	Include    []*regexp.Regexp
+	Exclude    []*regexp.Regexp
+	Timestamps bool
+	Instance   string
+}
+
 // Logs method writes log lines to the specified logChan. The caller can stop
 // the logging with the ctx cancelFunc. It's also the callers responsibility
 // to close the logChan when done.
 // When stageID is an empty string, no staging logs are returned. If it is set,
 // then only logs from that staging process are returned.
-func Logs(ctx context.Context, logChan chan tailer.ContainerLogLine, wg *sync.WaitGroup, cluster *kubernetes.Cluster, follow bool, app, stageID, org string) error {
+func Logs(ctx context.Context, logChan chan tailer.ContainerLogLine, wg *sync.WaitGroup, cluster *kubernetes.Cluster, follow bool, app, stageID, org string, params LogParameters) error {
 	logger := tracelog.NewLogger().WithName("logs-backend").V(2)
 	selector := labels.NewSelector()
 
@@ -297,19 +332,29 @@ func Logs(ctx context.Context, logChan chan tailer.ContainerLogLine, wg *sync.Wa
 		selector = selector.Add(*req)
 	}
 
+	since := params.Since
+	if since == 0 {
+		since = duration.LogHistory()
+	}
+
+	podQuery := regexp.MustCompile(".*")
+	if params.Instance != "" {
+		podQuery = regexp.MustCompile("^" + regexp.QuoteMeta(params.Instance) + "$")
+	}
+
 	config := &tailer.Config{
 		ContainerQuery:        regexp.MustCompile(".*"),
 		ExcludeContainerQuery: regexp.MustCompile("linkerd-(proxy|init)"),
 		ContainerState:        "running",
-		Exclude:               nil,
-		Include:               nil,
-		Timestamps:            false,
-		Since:                 duration.LogHistory(),
+		Exclude:               params.Exclude,
+		Include:               params.Include,
+		Timestamps:            params.Timestamps,
+		Since:                 since,
 		AllNamespaces:         true,
 		LabelSelector:         selector,
-		TailLines:             nil,
+		TailLines:             params.Tail,
 		Namespace:             "",
-		PodQuery:              regexp.MustCompile(".*"),
+		PodQuery:              podQuery,
 	}
 
 	if follow {
@@ -345,9 +390,33 @@ func fetch(ctx context.Context, cluster *kubernetes.Cluster, app *models.App) er
 		return err
 	}
 
+	routes, err := Routes(ctx, cluster, app.Meta)
+	if err != nil {
+		return err
+	}
+
+	healthCheck, err := HealthCheck(ctx, cluster, app.Meta)
+	if err != nil {
+		return err
+	}
+
+	rollout, err := Rollout(ctx, cluster, app.Meta)
+	if err != nil {
+		return err
+	}
+
+	resources, err := Resources(ctx, cluster, app.Meta)
+	if err != nil {
+		return err
+	}
+
 	app.Configuration.Instances = &instances
 	app.Configuration.Services = services
 	app.Configuration.Environment = environment
+	app.Configuration.Routes = routes
+	app.Configuration.HealthCheck = &healthCheck
+	app.Configuration.Rollout = &rollout
+	app.Configuration.Resources = &resources
 
 	// Check if app is active, and if yes, fill the associated parts.
 	// May have to straighten the workload structure a bit further.
@@ -367,3 +436,58 @@ func fetch(ctx context.Context, cluster *kubernetes.Cluster, app *models.App) er
 
 	return nil
 }
+
+// appSettingsSecret locates and returns the named kube secret storing
+// settings of the referenced application. If necessary it creates that
+// secret, owned by the application, so that it is removed with it. A
+// concurrent creation of the secret is not an error, the secret created
+// by it is returned instead.
+func appSettingsSecret(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, secretName string) (*v1.Secret, error) {
+	secrets := cluster.Kubectl.CoreV1().Secrets(appRef.Org)
+
+	secret, err := secrets.Get(ctx, secretName, metav1.GetOptions{})
+	if err == nil {
+		return secret, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	// Error is `Not Found`. Create the secret.
+
+	app, err := Get(ctx, cluster, appRef)
+	if err != nil {
+		// The callers validated the existence of the application. It
+		// may have been deleted since.
+		return nil, err
+	}
+
+	secret, err = secrets.Create(ctx, &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: appRef.Org,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: app.GetAPIVersion(),
+					Kind:       app.GetKind(),
+					Name:       app.GetName(),
+					UID:        app.GetUID(),
+				},
+			},
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       appRef.Name,
+				"app.kubernetes.io/part-of":    appRef.Org,
+				"app.kubernetes.io/managed-by": "epinio",
+				"app.kubernetes.io/component":  "application",
+			},
+		},
+	}, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return secrets.Get(ctx, secretName, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create secret %s", secretName)
+	}
+
+	return secret, nil
+}
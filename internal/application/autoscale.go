@@ -0,0 +1,130 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	autoscaleMinKey        = "autoscale-min"
+	autoscaleMaxKey        = "autoscale-max"
+	autoscaleCPUPercentKey = "autoscale-cpu-percent"
+)
+
+// ErrAutoscaled is returned for manual changes of the instances of an
+// autoscaled application
+var ErrAutoscaled = errors.New("application is autoscaled, its instances cannot be changed")
+
+// ErrNoCPURequest is returned for the autoscaling of an application whose
+// instances have no CPU request, as the utilization is relative to it
+var ErrNoCPURequest = errors.New("autoscaling requires a CPU request for the instances")
+
+// Autoscale returns the autoscaling settings of the application. The
+// result is nil for applications which are not autoscaled.
+func Autoscale(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (*models.AppAutoscale, error) {
+	scaleSecret, err := scaleLoad(ctx, cluster, appRef)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := scaleSecret.Data[autoscaleMaxKey]; !ok {
+		return nil, nil
+	}
+
+	result := &models.AppAutoscale{}
+	for key, value := range map[string]*int32{
+		autoscaleMinKey:        &result.Min,
+		autoscaleMaxKey:        &result.Max,
+		autoscaleCPUPercentKey: &result.CPUPercent,
+	} {
+		n, err := strconv.Atoi(string(scaleSecret.Data[key]))
+		if err != nil {
+			return nil, err
+		}
+		*value = int32(n)
+	}
+
+	return result, nil
+}
+
+// AutoscaleSet replaces the autoscaling settings of the named
+// application. Nil settings disable autoscaling. When the function
+// returns the settings are saved.
+func AutoscaleSet(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, autoscale *models.AppAutoscale) error {
+	return scaleUpdate(ctx, cluster, appRef, func(scaleSecret *v1.Secret) {
+		if autoscale == nil {
+			delete(scaleSecret.Data, autoscaleMinKey)
+			delete(scaleSecret.Data, autoscaleMaxKey)
+			delete(scaleSecret.Data, autoscaleCPUPercentKey)
+			return
+		}
+
+		a := autoscale.WithDefaults()
+		scaleSecret.Data[autoscaleMinKey] = []byte(strconv.Itoa(int(a.Min)))
+		scaleSecret.Data[autoscaleMaxKey] = []byte(strconv.Itoa(int(a.Max)))
+		scaleSecret.Data[autoscaleCPUPercentKey] = []byte(strconv.Itoa(int(a.CPUPercent)))
+	})
+}
+
+// AutoscaleValidate checks the autoscaling settings, and returns an error
+// describing the first issue found, if any.
+func AutoscaleValidate(autoscale models.AppAutoscale) error {
+	if autoscale.Min < 1 {
+		return fmt.Errorf("minimum instances %d is less than 1", autoscale.Min)
+	}
+	if autoscale.Max < autoscale.Min {
+		return fmt.Errorf("maximum instances %d is less than the minimum %d", autoscale.Max, autoscale.Min)
+	}
+	if autoscale.CPUPercent < 0 {
+		return fmt.Errorf("target CPU utilization %d%% is negative", autoscale.CPUPercent)
+	}
+
+	return nil
+}
+
+// NewAutoscaler returns the horizontal pod autoscaler for the
+// application's deployment. It is owned by the deployment, i.e. deleted
+// with it.
+func NewAutoscaler(deployment *appsv1.Deployment, autoscale models.AppAutoscale) *autoscalingv1.HorizontalPodAutoscaler {
+	autoscale = autoscale.WithDefaults()
+
+	return &autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deployment.Name,
+			Namespace: deployment.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "apps/v1",
+					Kind:       "Deployment",
+					Name:       deployment.Name,
+					UID:        deployment.UID,
+				},
+			},
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       deployment.Name,
+				"app.kubernetes.io/part-of":    deployment.Namespace,
+				"app.kubernetes.io/managed-by": "epinio",
+				"app.kubernetes.io/component":  "application",
+			},
+		},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       deployment.Name,
+			},
+			MinReplicas:                    &autoscale.Min,
+			MaxReplicas:                    autoscale.Max,
+			TargetCPUUtilizationPercentage: &autoscale.CPUPercent,
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package application_test
+
+import (
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AutoscaleValidate", func() {
+	It("accepts bounds, with and without a target", func() {
+		Expect(application.AutoscaleValidate(models.AppAutoscale{Min: 1, Max: 1})).To(Succeed())
+		Expect(application.AutoscaleValidate(models.AppAutoscale{Min: 2, Max: 10, CPUPercent: 70})).To(Succeed())
+	})
+
+	It("rejects a minimum below one", func() {
+		err := application.AutoscaleValidate(models.AppAutoscale{Min: 0, Max: 3})
+		Expect(err).To(MatchError("minimum instances 0 is less than 1"))
+	})
+
+	It("rejects a maximum below the minimum", func() {
+		err := application.AutoscaleValidate(models.AppAutoscale{Min: 3, Max: 2})
+		Expect(err).To(MatchError("maximum instances 2 is less than the minimum 3"))
+	})
+
+	It("rejects a negative target", func() {
+		err := application.AutoscaleValidate(models.AppAutoscale{Min: 1, Max: 2, CPUPercent: -5})
+		Expect(err).To(MatchError(ContainSubstring("is negative")))
+	})
+})
+
+var _ = Describe("NewAutoscaler", func() {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app",
+			Namespace: "workspace",
+			UID:       types.UID("1234"),
+		},
+	}
+
+	It("targets and is owned by the deployment", func() {
+		autoscaler := application.NewAutoscaler(deployment, models.AppAutoscale{Min: 2, Max: 10, CPUPercent: 70})
+		Expect(autoscaler.Name).To(Equal("app"))
+		Expect(autoscaler.Namespace).To(Equal("workspace"))
+		Expect(autoscaler.OwnerReferences).To(HaveLen(1))
+		Expect(autoscaler.OwnerReferences[0].UID).To(Equal(types.UID("1234")))
+		Expect(autoscaler.Spec.ScaleTargetRef.Kind).To(Equal("Deployment"))
+		Expect(autoscaler.Spec.ScaleTargetRef.Name).To(Equal("app"))
+		Expect(*autoscaler.Spec.MinReplicas).To(Equal(int32(2)))
+		Expect(autoscaler.Spec.MaxReplicas).To(Equal(int32(10)))
+		Expect(*autoscaler.Spec.TargetCPUUtilizationPercentage).To(Equal(int32(70)))
+	})
+
+	It("uses the default target", func() {
+		autoscaler := application.NewAutoscaler(deployment, models.AppAutoscale{Min: 1, Max: 3})
+		Expect(*autoscaler.Spec.TargetCPUUtilizationPercentage).To(Equal(int32(models.DefaultAutoscaleCPUPercent)))
+	})
+})
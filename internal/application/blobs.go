@@ -0,0 +1,305 @@
+package application
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/epinio/epinio/internal/s3manager"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Blobs returns the source blobs in the S3 storage, with their state per
+// the retention policy keeping the newest `keep` blobs of each application.
+func Blobs(ctx context.Context, cluster *kubernetes.Cluster, keep int) (models.BlobList, error) {
+	s3m, err := blobStore(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return blobs(ctx, cluster, s3m, keep)
+}
+
+// BlobsPrune removes the expired and orphaned source blobs from the S3
+// storage, per the retention policy keeping the newest `keep` blobs of
+// each application.
+func BlobsPrune(ctx context.Context, cluster *kubernetes.Cluster, keep int) (models.BlobPruneResponse, error) {
+	response := models.BlobPruneResponse{Removed: models.BlobList{}}
+
+	s3m, err := blobStore(ctx, cluster)
+	if err != nil {
+		return response, err
+	}
+
+	list, err := blobs(ctx, cluster, s3m, keep)
+	if err != nil {
+		return response, err
+	}
+
+	for _, blob := range list {
+		if blob.State != models.BlobExpired && blob.State != models.BlobOrphaned {
+			continue
+		}
+
+		if err := s3m.DeleteObject(ctx, blob.ID); err != nil {
+			return response, errors.Wrapf(err, "removing blob %s", blob.ID)
+		}
+
+		response.Removed = append(response.Removed, blob)
+		response.Freed += blob.Size
+	}
+
+	return response, nil
+}
+
+// CollectBlobs prunes the source blobs every interval, until the context
+// is done. It is run by the server, in the background.
+func CollectBlobs(ctx context.Context, interval time.Duration, keep int) {
+	log := tracelog.NewLogger().WithName("blob-gc").WithValues("keep", keep)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cluster, err := kubernetes.GetCluster(ctx)
+		if err != nil {
+			log.Error(err, "accessing the cluster")
+			continue
+		}
+
+		response, err := BlobsPrune(ctx, cluster, keep)
+		if err != nil {
+			log.Error(err, "pruning blobs")
+			continue
+		}
+
+		log.Info("pruned blobs", "removed", len(response.Removed), "freed", response.Freed)
+	}
+}
+
+// BlobStates sets the state of the blobs per the retention policy. The
+// newest `keep` blobs of each application are retained, older blobs
+// expire. Blobs used by staging runs (`staged`) are never expired. Blobs
+// of applications not in `existing` are orphaned. The blobs are sorted
+// by namespace and application, newest first.
+func BlobStates(blobs models.BlobList, staged map[string]bool, existing map[models.AppRef]bool, keep int) {
+	sort.SliceStable(blobs, func(i, j int) bool {
+		a, b := blobs[i], blobs[j]
+		if a.App.Org != b.App.Org {
+			return a.App.Org < b.App.Org
+		}
+		if a.App.Name != b.App.Name {
+			return a.App.Name < b.App.Name
+		}
+		return a.Created.After(b.Created)
+	})
+
+	seen := map[models.AppRef]int{}
+	for i := range blobs {
+		blob := &blobs[i]
+
+		if blob.App.Name == "" || blob.App.Org == "" {
+			blob.State = models.BlobUnknown
+			continue
+		}
+		if !existing[blob.App] {
+			blob.State = models.BlobOrphaned
+			continue
+		}
+
+		seen[blob.App]++
+		switch {
+		case staged[blob.ID]:
+			blob.State = models.BlobStaged
+		case seen[blob.App] <= keep:
+			blob.State = models.BlobRetained
+		default:
+			blob.State = models.BlobExpired
+		}
+	}
+}
+
+// Keys of the metadata of source blobs imported from git, naming the
+// repository and the commit the import resolved the revision to
+const (
+	BlobGitURLKey    = "giturl"
+	BlobGitCommitKey = "gitcommit"
+)
+
+// BlobGit returns the repository and commit the source blob was imported
+// from. The result is nil for blobs which were not imported from git.
+func BlobGit(ctx context.Context, cluster *kubernetes.Cluster, blobUID string) (*models.GitRef, error) {
+	s3m, err := blobStore(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := s3m.Metadata(ctx, blobUID)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, ok := metadata[BlobGitCommitKey]
+	if !ok {
+		return nil, nil
+	}
+
+	return &models.GitRef{URL: metadata[BlobGitURLKey], Revision: commit}, nil
+}
+
+// blobNamespace is the namespace of the name-based UUIDs of the source
+// blobs, see BlobID
+var blobNamespace = uuid.MustParse("2bd59b6c-0a6e-4c49-8a3a-3c0b7e5cbd4e")
+
+// BlobID returns the id under which the application's sources with the
+// given SHA-256 are stored. It is derived from the application and the
+// checksum, so that identical sources are found without listing the
+// storage, see BlobFind.
+func BlobID(appRef models.AppRef, checksum string) string {
+	return uuid.NewSHA1(blobNamespace, []byte(appRef.Org+"/"+appRef.Name+"/"+checksum)).String()
+}
+
+// BlobFind returns the id of the application's source blob with the given
+// SHA-256, if any, i.e. of identical sources uploaded before.
+func BlobFind(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, checksum string) (string, error) {
+	s3m, err := blobStore(ctx, cluster)
+	if err != nil {
+		return "", err
+	}
+
+	id := BlobID(appRef, checksum)
+	exists, err := s3m.Exists(ctx, id)
+	if err != nil || !exists {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// blobsDelete removes all source blobs of the application from the S3
+// storage. The blobs are selected by the metadata of the listing. Blobs
+// which cannot be removed are skipped, the first error is reported after
+// trying all.
+func blobsDelete(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) error {
+	s3m, err := blobStore(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	objects, err := s3m.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	var result error
+	for _, object := range objects {
+		if object.Metadata["app"] != appRef.Name || object.Metadata["org"] != appRef.Org {
+			continue
+		}
+		if err := s3m.DeleteObject(ctx, object.ID); err != nil && result == nil {
+			result = errors.Wrapf(err, "removing blob %s", object.ID)
+		}
+	}
+
+	return result
+}
+
+// blobs returns the source blobs in the S3 storage, with their state
+func blobs(ctx context.Context, cluster *kubernetes.Cluster, s3m *s3manager.Manager, keep int) (models.BlobList, error) {
+	objects, err := s3m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	staged, err := stagedBlobs(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	result := models.BlobList{}
+	existing := map[models.AppRef]bool{}
+	checked := map[models.AppRef]bool{}
+
+	for _, object := range objects {
+		blob := models.Blob{
+			ID:       object.ID,
+			App:      models.NewAppRef(object.Metadata["app"], object.Metadata["org"]),
+			Username: object.Metadata["username"],
+			Size:     object.Size,
+			Created:  object.Modified,
+		}
+		result = append(result, blob)
+
+		if blob.App.Name == "" || blob.App.Org == "" || checked[blob.App] {
+			continue
+		}
+		checked[blob.App] = true
+
+		exists, err := organizations.Exists(ctx, cluster, blob.App.Org)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			exists, err = Exists(ctx, cluster, blob.App)
+			if err != nil {
+				return nil, err
+			}
+		}
+		existing[blob.App] = exists
+	}
+
+	BlobStates(result, staged, existing, keep)
+
+	return result, nil
+}
+
+// stagedBlobs returns the ids of the blobs used by the staging runs in the cluster
+func stagedBlobs(ctx context.Context, cluster *kubernetes.Cluster) (map[string]bool, error) {
+	tc, err := cluster.ClientTekton()
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := tc.PipelineRuns(deployments.TektonStagingNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]bool{}
+	for _, pr := range l.Items {
+		if id := pr.Labels[models.EpinioStageBlobUIDLabel]; id != "" {
+			result[id] = true
+		}
+	}
+
+	return result, nil
+}
+
+// blobStore returns a manager for the S3 storage holding the source blobs
+func blobStore(ctx context.Context, cluster *kubernetes.Cluster) (*s3manager.Manager, error) {
+	s3ConnectionDetails, err := s3manager.GetConnectionDetails(ctx, cluster,
+		deployments.TektonStagingNamespace, deployments.S3ConnectionDetailsSecret)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching the S3 connection details from the Kubernetes secret")
+	}
+
+	s3m, err := s3manager.New(s3ConnectionDetails)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating an S3 manager")
+	}
+
+	return s3m, nil
+}
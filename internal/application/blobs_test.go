@@ -0,0 +1,95 @@
+package application_test
+
+import (
+	"time"
+
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// blob returns a blob of the app, created at the offset.
+func blob(id string, app models.AppRef, offset time.Duration) models.Blob {
+	return models.Blob{ID: id, App: app, Created: time.Unix(1000, 0).Add(offset)}
+}
+
+// states returns the ids and states of the blobs, in order.
+func states(blobs models.BlobList) []string {
+	result := []string{}
+	for _, b := range blobs {
+		result = append(result, b.ID+"="+b.State)
+	}
+	return result
+}
+
+var _ = Describe("BlobStates", func() {
+	app := models.NewAppRef("app", "org")
+	other := models.NewAppRef("other", "org")
+	existing := map[models.AppRef]bool{app: true, other: true}
+
+	It("retains the newest blobs of each application", func() {
+		blobs := models.BlobList{
+			blob("a1", app, 1*time.Second),
+			blob("o1", other, 1*time.Second),
+			blob("a3", app, 3*time.Second),
+			blob("a2", app, 2*time.Second),
+		}
+		application.BlobStates(blobs, map[string]bool{}, existing, 2)
+		Expect(states(blobs)).To(Equal([]string{
+			"a3=retained", "a2=retained", "a1=expired", "o1=retained",
+		}))
+	})
+
+	It("never expires staged blobs", func() {
+		blobs := models.BlobList{
+			blob("a1", app, 1*time.Second),
+			blob("a2", app, 2*time.Second),
+			blob("a3", app, 3*time.Second),
+		}
+		application.BlobStates(blobs, map[string]bool{"a1": true}, existing, 1)
+		Expect(states(blobs)).To(Equal([]string{
+			"a3=retained", "a2=expired", "a1=staged",
+		}))
+	})
+
+	It("orphans the blobs of deleted applications", func() {
+		gone := models.NewAppRef("gone", "org")
+		blobs := models.BlobList{
+			blob("g1", gone, 1*time.Second),
+			blob("a1", app, 1*time.Second),
+		}
+		application.BlobStates(blobs, map[string]bool{"g1": true}, existing, 1)
+		Expect(states(blobs)).To(Equal([]string{
+			"a1=retained", "g1=orphaned",
+		}))
+	})
+
+	It("leaves blobs without application alone", func() {
+		blobs := models.BlobList{
+			blob("x1", models.AppRef{}, 1*time.Second),
+		}
+		application.BlobStates(blobs, map[string]bool{}, existing, 0)
+		Expect(states(blobs)).To(Equal([]string{"x1=unknown"}))
+	})
+})
+
+var _ = Describe("BlobID", func() {
+	app := models.NewAppRef("app", "org")
+
+	It("is the same for the same sources of the same application", func() {
+		Expect(application.BlobID(app, "abc")).To(Equal(application.BlobID(app, "abc")))
+	})
+
+	It("differs for other sources, or other applications", func() {
+		id := application.BlobID(app, "abc")
+		Expect(application.BlobID(app, "abd")).ToNot(Equal(id))
+		Expect(application.BlobID(models.NewAppRef("app", "other"), "abc")).ToNot(Equal(id))
+		Expect(application.BlobID(models.NewAppRef("other", "org"), "abc")).ToNot(Equal(id))
+	})
+
+	It("is usable as label value", func() {
+		Expect(application.BlobID(app, "abc")).To(MatchRegexp(`^[0-9a-f-]{36}$`))
+	})
+})
@@ -0,0 +1,441 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/duration"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/pkg/errors"
+	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	cacheSizeKey         = "size"
+	cacheStorageClassKey = "storageclass"
+
+	// cacheComponent is the component label of the claims holding build caches
+	cacheComponent = "staging-cache"
+)
+
+// Cache returns the settings of the application's build cache, and its
+// claim, if the application was staged already.
+func Cache(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (models.AppCache, error) {
+	result := models.AppCache{}
+
+	config, err := cacheConfig(ctx, cluster, appRef)
+	if err != nil {
+		return result, err
+	}
+	result.Config = config
+
+	pvc, err := cluster.Kubectl.CoreV1().PersistentVolumeClaims(deployments.TektonStagingNamespace).
+		Get(ctx, appRef.MakePVCName(), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return result, nil
+		}
+		return result, err
+	}
+
+	lastUsed, err := cacheLastUsed(ctx, cluster)
+	if err != nil {
+		return result, err
+	}
+
+	claim := cacheClaim(pvc, lastUsed)
+	claim.App = appRef
+	result.Claim = &claim
+
+	return result, nil
+}
+
+// CacheSet replaces the settings of the application's build cache. An
+// existing claim not matching the new settings is deleted, to be created
+// anew by the next staging. The result reports whether this happened.
+// The caller has to ensure that the application is not staging.
+func CacheSet(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, config models.AppCacheConfig) (bool, error) {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cacheSecret, err := cacheLoad(ctx, cluster, appRef)
+		if err != nil {
+			return err
+		}
+
+		cacheSecret.Data = map[string][]byte{
+			cacheSizeKey:         []byte(config.Size),
+			cacheStorageClassKey: []byte(config.StorageClass),
+		}
+
+		_, err = cluster.Kubectl.CoreV1().Secrets(appRef.Org).Update(
+			ctx, cacheSecret, metav1.UpdateOptions{})
+
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	claims := cluster.Kubectl.CoreV1().PersistentVolumeClaims(deployments.TektonStagingNamespace)
+	pvc, err := claims.Get(ctx, appRef.MakePVCName(), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if cacheMatches(pvc, config) {
+		return false, nil
+	}
+
+	err = claims.Delete(ctx, pvc.Name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// CacheEnsure creates the claim for the application's build cache, per
+// its settings, if it does not exist yet. This PVC is also used to store
+// the application source blobs during staging. It's mounted in the
+// staging task pod as the "source" tekton workspace, with the build cache
+// on a separate directory.
+func CacheEnsure(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) error {
+	claims := cluster.Kubectl.CoreV1().PersistentVolumeClaims(deployments.TektonStagingNamespace)
+
+	pvc, err := claims.Get(ctx, appRef.MakePVCName(), metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) { // Unknown error, irrelevant to non-existence
+		return err
+	}
+	if err == nil { // pvc already exists
+		if pvc.Labels[cacheAppLabel] != "" {
+			return nil
+		}
+
+		// Claims of older versions lack the labels identifying the application.
+		pvc.Labels = cacheLabels(appRef)
+		_, err = claims.Update(ctx, pvc, metav1.UpdateOptions{})
+		return err
+	}
+
+	// From here on, only if the PVC is missing
+	config, err := cacheConfig(ctx, cluster, appRef)
+	if err != nil {
+		return err
+	}
+
+	size, err := resource.ParseQuantity(cacheSize(config))
+	if err != nil {
+		return err
+	}
+
+	pvc = &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      appRef.MakePVCName(),
+			Namespace: deployments.TektonStagingNamespace,
+			Labels:    cacheLabels(appRef),
+		},
+		Spec: v1.PersistentVolumeClaimSpec{
+			AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+			Resources: v1.ResourceRequirements{
+				Requests: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceStorage: size,
+				},
+			},
+		},
+	}
+	if config.StorageClass != "" {
+		pvc.Spec.StorageClassName = &config.StorageClass
+	}
+
+	_, err = claims.Create(ctx, pvc, metav1.CreateOptions{})
+
+	return err
+}
+
+// CacheClear removes the contents of the application's build cache,
+// keeping the claim. It runs the cleanup task of the staging pipelines
+// on the cache directory, and waits for it to complete. The result
+// reports whether there was a cache to clear. The caller has to ensure
+// that the application is not staging.
+func CacheClear(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (bool, error) {
+	_, err := cluster.Kubectl.CoreV1().PersistentVolumeClaims(deployments.TektonStagingNamespace).
+		Get(ctx, appRef.MakePVCName(), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	tc, err := cluster.ClientTekton()
+	if err != nil {
+		return false, err
+	}
+	client := tc.TaskRuns(deployments.TektonStagingNamespace)
+
+	labels := cacheLabels(appRef)
+	labels["app.kubernetes.io/component"] = "cache-clear"
+
+	tr, err := client.Create(ctx, &v1beta1.TaskRun{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "cache-clear-",
+			Labels:       labels,
+		},
+		Spec: v1beta1.TaskRunSpec{
+			TaskRef: &v1beta1.TaskRef{Name: "cleanup"},
+			Workspaces: []v1beta1.WorkspaceBinding{
+				{
+					Name:    "source",
+					SubPath: "cache",
+					PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+						ClaimName: appRef.MakePVCName(),
+					},
+				},
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	defer func() {
+		_ = client.Delete(ctx, tr.Name, metav1.DeleteOptions{})
+	}()
+
+	err = wait.PollImmediate(time.Second, duration.ToAppBuilt(), func() (bool, error) {
+		tr, err := client.Get(ctx, tr.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, c := range tr.Status.Conditions {
+			if c.Type != "Succeeded" {
+				continue
+			}
+			if c.IsFalse() {
+				return false, errors.Errorf("clearing the cache failed: %s", c.Message)
+			}
+			return c.IsTrue(), nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Caches returns the claims holding the build caches of all
+// applications, across namespaces. Only the claims managed by epinio are
+// considered, not the other claims of the staging namespace. The claims of
+// older versions, not labeled yet, are recognized by their names, see
+// legacyCacheApp. Claims of applications or namespaces which do not exist
+// anymore are marked as orphaned.
+func Caches(ctx context.Context, cluster *kubernetes.Cluster) (models.CacheClaimList, error) {
+	pvcs, err := cluster.Kubectl.CoreV1().PersistentVolumeClaims(deployments.TektonStagingNamespace).
+		List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	lastUsed, err := cacheLastUsed(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	result := models.CacheClaimList{}
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		if pvc.Labels["app.kubernetes.io/managed-by"] != "epinio" {
+			appRef, ok := legacyCacheApp(pvc)
+			if !ok {
+				continue
+			}
+			pvc.Labels = cacheLabels(appRef)
+		}
+		claim := cacheClaim(pvc, lastUsed)
+
+		if claim.App.Name != "" {
+			exists, err := organizations.Exists(ctx, cluster, claim.App.Org)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				exists, err = Exists(ctx, cluster, claim.App)
+				if err != nil {
+					return nil, err
+				}
+			}
+			claim.Orphaned = !exists
+		}
+
+		result = append(result, claim)
+	}
+
+	return result, nil
+}
+
+// legacyCacheApp returns the application of a claim created by a version
+// before the claims were labeled. These claims are named per MakePVCName,
+// i.e. namespace and application joined by a dot. Names truncated for
+// their length cannot be traced back, these claims stay unknown until
+// the next staging of their application labels them, see CacheEnsure.
+func legacyCacheApp(pvc *v1.PersistentVolumeClaim) (models.AppRef, bool) {
+	parts := strings.SplitN(pvc.Name, ".", 2)
+	if len(parts) != 2 {
+		return models.AppRef{}, false
+	}
+
+	appRef := models.NewAppRef(parts[1], parts[0])
+	if appRef.MakePVCName() != pvc.Name {
+		return models.AppRef{}, false
+	}
+
+	return appRef, true
+}
+
+// cacheAppLabel is the label of cache claims naming their application
+const cacheAppLabel = "app.kubernetes.io/name"
+
+// cacheLabels returns the labels identifying the claim holding the build cache of the application
+func cacheLabels(appRef models.AppRef) map[string]string {
+	return map[string]string{
+		cacheAppLabel:                  appRef.Name,
+		"app.kubernetes.io/part-of":    appRef.Org,
+		"app.kubernetes.io/managed-by": "epinio",
+		"app.kubernetes.io/component":  cacheComponent,
+	}
+}
+
+// cacheClaim returns the description of the claim holding a build cache.
+// lastUsed maps applications to the end of their last staging.
+func cacheClaim(pvc *v1.PersistentVolumeClaim, lastUsed map[models.AppRef]time.Time) models.CacheClaim {
+	claim := models.CacheClaim{
+		Name:    pvc.Name,
+		App:     models.NewAppRef(pvc.Labels[cacheAppLabel], pvc.Labels["app.kubernetes.io/part-of"]),
+		Created: pvc.CreationTimestamp.Time,
+	}
+
+	if pvc.Spec.StorageClassName != nil {
+		claim.StorageClass = *pvc.Spec.StorageClassName
+	}
+	if capacity, ok := pvc.Status.Capacity[v1.ResourceStorage]; ok {
+		claim.Capacity = capacity.String()
+	} else if request, ok := pvc.Spec.Resources.Requests[v1.ResourceStorage]; ok {
+		claim.Capacity = request.String()
+	}
+	if t, ok := lastUsed[claim.App]; ok && claim.App.Name != "" {
+		claim.LastUsed = &t
+	}
+
+	return claim
+}
+
+// cacheLastUsed returns the time of the last completed staging of each
+// application, as known from the pipeline runs kept.
+func cacheLastUsed(ctx context.Context, cluster *kubernetes.Cluster) (map[models.AppRef]time.Time, error) {
+	tc, err := cluster.ClientTekton()
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := tc.PipelineRuns(deployments.TektonStagingNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[models.AppRef]time.Time{}
+	for _, pr := range l.Items {
+		if pr.Status.CompletionTime == nil {
+			continue
+		}
+		app := models.NewAppRef(pr.Labels["app.kubernetes.io/name"], pr.Labels["app.kubernetes.io/part-of"])
+		if last, ok := result[app]; !ok || pr.Status.CompletionTime.Time.After(last) {
+			result[app] = pr.Status.CompletionTime.Time
+		}
+	}
+
+	return result, nil
+}
+
+// cacheMatches returns true if the claim satisfies the settings.
+func cacheMatches(pvc *v1.PersistentVolumeClaim, config models.AppCacheConfig) bool {
+	if config.StorageClass != "" &&
+		(pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName != config.StorageClass) {
+		return false
+	}
+
+	size, err := resource.ParseQuantity(cacheSize(config))
+	if err != nil {
+		return false
+	}
+	request := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+
+	return request.Cmp(size) == 0
+}
+
+// cacheSize returns the size of the build cache per the settings.
+func cacheSize(config models.AppCacheConfig) string {
+	if config.Size == "" {
+		return models.DefaultCacheSize
+	}
+	return config.Size
+}
+
+// cacheConfig returns the settings of the application's build cache.
+func cacheConfig(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (models.AppCacheConfig, error) {
+	cacheSecret, err := cacheLoad(ctx, cluster, appRef)
+	if err != nil {
+		return models.AppCacheConfig{}, err
+	}
+
+	return models.AppCacheConfig{
+		Size:         string(cacheSecret.Data[cacheSizeKey]),
+		StorageClass: string(cacheSecret.Data[cacheStorageClassKey]),
+	}, nil
+}
+
+// cacheLoad locates and returns the kube secret storing the settings of the referenced application's
+// build cache. If necessary it creates that secret.
+func cacheLoad(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (*v1.Secret, error) {
+	return appSettingsSecret(ctx, cluster, appRef, appRef.MakeCacheSecretName())
+}
+
+// CacheConfigCheck validates the settings of a build cache, i.e. that the
+// size is a positive quantity, and that the storage class exists.
+func CacheConfigCheck(ctx context.Context, cluster *kubernetes.Cluster, config models.AppCacheConfig) error {
+	if config.Size != "" {
+		size, err := resource.ParseQuantity(config.Size)
+		if err != nil {
+			return fmt.Errorf("bad cache size '%s': %s", config.Size, err.Error())
+		}
+		if size.Sign() <= 0 {
+			return fmt.Errorf("bad cache size '%s': not positive", config.Size)
+		}
+	}
+
+	if config.StorageClass != "" {
+		_, err := cluster.Kubectl.StorageV1().StorageClasses().Get(ctx, config.StorageClass, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return fmt.Errorf("storage class '%s' does not exist", config.StorageClass)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	healthCheckKey = "healthcheck"
+	rolloutKey     = "rollout"
+
+	// probeFailureThreshold is the number of failed checks after which
+	// a pod is considered unready, respectively restarted
+	probeFailureThreshold = 3
+)
+
+// HealthCheck returns the health check settings of the application, with
+// the defaults filled in.
+func HealthCheck(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (models.HealthCheck, error) {
+	var result models.HealthCheck
+
+	err := healthGet(ctx, cluster, appRef, healthCheckKey, &result)
+	if err != nil {
+		return result, err
+	}
+
+	return result.WithDefaults(), nil
+}
+
+// HealthCheckSet replaces the health check settings of the named
+// application. When the function returns the settings are saved.
+func HealthCheckSet(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, healthCheck models.HealthCheck) error {
+	return healthSet(ctx, cluster, appRef, healthCheckKey, healthCheck.WithDefaults())
+}
+
+// Rollout returns the rollout settings of the application, with the
+// defaults filled in.
+func Rollout(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (models.Rollout, error) {
+	var result models.Rollout
+
+	err := healthGet(ctx, cluster, appRef, rolloutKey, &result)
+	if err != nil {
+		return result, err
+	}
+
+	return result.WithDefaults(), nil
+}
+
+// RolloutSet replaces the rollout settings of the named application.
+// When the function returns the settings are saved.
+func RolloutSet(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, rollout models.Rollout) error {
+	return healthSet(ctx, cluster, appRef, rolloutKey, rollout.WithDefaults())
+}
+
+// HealthCheckValidate checks the health check settings, and returns an
+// error describing the first issue found, if any.
+func HealthCheckValidate(healthCheck models.HealthCheck) error {
+	switch healthCheck.Type {
+	case "", models.HealthCheckHTTP, models.HealthCheckTCP, models.HealthCheckNone:
+	default:
+		return fmt.Errorf("unknown health check type '%s', expected http, tcp, or none", healthCheck.Type)
+	}
+
+	if healthCheck.Path != "" {
+		if healthCheck.Type != models.HealthCheckHTTP {
+			return fmt.Errorf("a health check path requires the http type")
+		}
+		if !strings.HasPrefix(healthCheck.Path, "/") {
+			return fmt.Errorf("health check path '%s' does not start with /", healthCheck.Path)
+		}
+	}
+	if healthCheck.Port < 0 || healthCheck.Port > 65535 {
+		return fmt.Errorf("health check port %d is out of range", healthCheck.Port)
+	}
+	if healthCheck.InitialDelay < 0 || healthCheck.Timeout < 0 || healthCheck.Period < 0 {
+		return fmt.Errorf("health check times must not be negative")
+	}
+
+	return nil
+}
+
+// RolloutValidate checks the rollout settings, and returns an error
+// describing the first issue found, if any.
+func RolloutValidate(rollout models.Rollout) error {
+	rollout = rollout.WithDefaults()
+
+	surge, err := rolloutValue("max surge", rollout.MaxSurge)
+	if err != nil {
+		return err
+	}
+	unavailable, err := rolloutValue("max unavailable", rollout.MaxUnavailable)
+	if err != nil {
+		return err
+	}
+
+	if surge == 0 && unavailable == 0 {
+		return fmt.Errorf("max surge and max unavailable must not both be zero")
+	}
+
+	return nil
+}
+
+// HealthProbes returns the readiness and liveness probes for the health
+// check. Both are nil for the check of type none. The default check, i.e.
+// the empty one, gets a readiness probe only: a liveness probe would
+// restart applications which start slowly, or listen on another port, in
+// a loop. Liveness is checked only when the user configured the check.
+func HealthProbes(healthCheck models.HealthCheck) (*v1.Probe, *v1.Probe) {
+	configured := healthCheck != models.HealthCheck{}
+	healthCheck = healthCheck.WithDefaults()
+
+	var handler v1.Handler
+	port := intstr.FromInt(int(healthCheck.Port))
+
+	switch healthCheck.Type {
+	case models.HealthCheckHTTP:
+		handler.HTTPGet = &v1.HTTPGetAction{Path: healthCheck.Path, Port: port}
+	case models.HealthCheckTCP:
+		handler.TCPSocket = &v1.TCPSocketAction{Port: port}
+	default:
+		return nil, nil
+	}
+
+	readiness := &v1.Probe{
+		Handler:          handler,
+		TimeoutSeconds:   healthCheck.Timeout,
+		PeriodSeconds:    healthCheck.Period,
+		FailureThreshold: probeFailureThreshold,
+	}
+
+	if !configured {
+		return readiness, nil
+	}
+
+	liveness := readiness.DeepCopy()
+	liveness.InitialDelaySeconds = healthCheck.InitialDelay
+
+	return readiness, liveness
+}
+
+// RolloutStrategy returns the deployment strategy for the rollout settings.
+func RolloutStrategy(rollout models.Rollout) appsv1.DeploymentStrategy {
+	rollout = rollout.WithDefaults()
+
+	surge := intstr.Parse(rollout.MaxSurge)
+	unavailable := intstr.Parse(rollout.MaxUnavailable)
+
+	return appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxSurge:       &surge,
+			MaxUnavailable: &unavailable,
+		},
+	}
+}
+
+// rolloutValue checks a rollout setting, i.e. a number or a percentage,
+// and returns its numeric part.
+func rolloutValue(name, value string) (int, error) {
+	number := strings.TrimSuffix(value, "%")
+
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s '%s' is neither a number of pods, nor a percentage", name, value)
+	}
+	if number != value && n > 100 {
+		return 0, fmt.Errorf("%s '%s' is more than 100%%", name, value)
+	}
+
+	return n, nil
+}
+
+// healthGet reads the setting stored under the key into the result. A
+// missing setting leaves the result untouched.
+func healthGet(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, key string, result interface{}) error {
+	healthSecret, err := healthLoad(ctx, cluster, appRef)
+	if err != nil {
+		return err
+	}
+
+	if data, ok := healthSecret.Data[key]; ok {
+		return json.Unmarshal(data, result)
+	}
+
+	return nil
+}
+
+// healthSet replaces the setting stored under the key.
+func healthSet(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		healthSecret, err := healthLoad(ctx, cluster, appRef)
+		if err != nil {
+			return err
+		}
+
+		if healthSecret.Data == nil {
+			healthSecret.Data = map[string][]byte{}
+		}
+		healthSecret.Data[key] = data
+
+		_, err = cluster.Kubectl.CoreV1().Secrets(appRef.Org).Update(
+			ctx, healthSecret, metav1.UpdateOptions{})
+
+		return err
+	})
+}
+
+// healthLoad locates and returns the kube secret storing the referenced application's health
+// check and rollout settings. If necessary it creates that secret.
+func healthLoad(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (*v1.Secret, error) {
+	return appSettingsSecret(ctx, cluster, appRef, appRef.MakeHealthSecretName())
+}
@@ -0,0 +1,156 @@
+package application_test
+
+import (
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HealthCheckValidate", func() {
+	It("accepts the empty health check", func() {
+		Expect(application.HealthCheckValidate(models.HealthCheck{})).To(Succeed())
+	})
+
+	It("rejects an unknown type", func() {
+		err := application.HealthCheckValidate(models.HealthCheck{Type: "exec"})
+		Expect(err).To(MatchError(ContainSubstring("unknown health check type 'exec'")))
+	})
+
+	It("rejects a path without the http type", func() {
+		err := application.HealthCheckValidate(models.HealthCheck{Type: models.HealthCheckTCP, Path: "/health"})
+		Expect(err).To(MatchError(ContainSubstring("requires the http type")))
+	})
+
+	It("rejects a relative path", func() {
+		err := application.HealthCheckValidate(models.HealthCheck{Type: models.HealthCheckHTTP, Path: "health"})
+		Expect(err).To(MatchError(ContainSubstring("does not start with /")))
+	})
+
+	It("rejects a bad port", func() {
+		err := application.HealthCheckValidate(models.HealthCheck{Port: 70000})
+		Expect(err).To(MatchError(ContainSubstring("out of range")))
+	})
+})
+
+var _ = Describe("RolloutValidate", func() {
+	It("accepts counts and percentages", func() {
+		Expect(application.RolloutValidate(models.Rollout{})).To(Succeed())
+		Expect(application.RolloutValidate(models.Rollout{MaxSurge: "1", MaxUnavailable: "50%"})).To(Succeed())
+	})
+
+	It("rejects garbage", func() {
+		err := application.RolloutValidate(models.Rollout{MaxSurge: "many"})
+		Expect(err).To(MatchError(ContainSubstring("max surge 'many'")))
+	})
+
+	It("rejects percentages above 100", func() {
+		err := application.RolloutValidate(models.Rollout{MaxUnavailable: "150%"})
+		Expect(err).To(MatchError(ContainSubstring("more than 100%")))
+	})
+
+	It("rejects a rollout which cannot progress", func() {
+		err := application.RolloutValidate(models.Rollout{MaxSurge: "0%", MaxUnavailable: "0"})
+		Expect(err).To(MatchError(ContainSubstring("must not both be zero")))
+	})
+})
+
+var _ = Describe("HealthProbes", func() {
+	It("renders the default tcp check", func() {
+		readiness, liveness := application.HealthProbes(models.HealthCheck{})
+		Expect(readiness.TCPSocket).ToNot(BeNil())
+		Expect(readiness.TCPSocket.Port).To(Equal(intstr.FromInt(models.DefaultHealthCheckPort)))
+		Expect(readiness.TimeoutSeconds).To(Equal(int32(models.DefaultHealthCheckTimeout)))
+		Expect(liveness).To(BeNil())
+	})
+
+	It("renders liveness for a configured tcp check", func() {
+		readiness, liveness := application.HealthProbes(models.HealthCheck{Port: 3000})
+		Expect(readiness.TCPSocket.Port).To(Equal(intstr.FromInt(3000)))
+		Expect(liveness.TCPSocket.Port).To(Equal(intstr.FromInt(3000)))
+	})
+
+	It("renders an http check, with the initial delay for liveness only", func() {
+		readiness, liveness := application.HealthProbes(models.HealthCheck{
+			Type:         models.HealthCheckHTTP,
+			Path:         "/health",
+			Port:         9000,
+			InitialDelay: 30,
+		})
+		Expect(readiness.HTTPGet.Path).To(Equal("/health"))
+		Expect(readiness.HTTPGet.Port).To(Equal(intstr.FromInt(9000)))
+		Expect(readiness.InitialDelaySeconds).To(BeZero())
+		Expect(liveness.HTTPGet.Path).To(Equal("/health"))
+		Expect(liveness.InitialDelaySeconds).To(Equal(int32(30)))
+	})
+
+	It("renders no probes for the none check", func() {
+		readiness, liveness := application.HealthProbes(models.HealthCheck{Type: models.HealthCheckNone})
+		Expect(readiness).To(BeNil())
+		Expect(liveness).To(BeNil())
+	})
+})
+
+var _ = Describe("RolloutStrategy", func() {
+	It("renders a rolling update, with the defaults", func() {
+		strategy := application.RolloutStrategy(models.Rollout{MaxSurge: "2"})
+		Expect(strategy.Type).To(Equal(appsv1.RollingUpdateDeploymentStrategyType))
+		Expect(*strategy.RollingUpdate.MaxSurge).To(Equal(intstr.FromInt(2)))
+		Expect(*strategy.RollingUpdate.MaxUnavailable).To(Equal(intstr.FromInt(0)))
+	})
+})
+
+var _ = Describe("RolloutFailure", func() {
+	pod := func(name string, status corev1.ContainerStatus) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{status},
+			},
+		}
+	}
+
+	It("ignores starting and running pods", func() {
+		pods := []corev1.Pod{
+			pod("starting", corev1.ContainerStatus{State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"},
+			}}),
+			pod("running", corev1.ContainerStatus{State: corev1.ContainerState{
+				Running: &corev1.ContainerStateRunning{},
+			}}),
+		}
+		Expect(application.RolloutFailure(pods)).To(BeNil())
+	})
+
+	It("reports a crashing pod, with its last termination", func() {
+		pods := []corev1.Pod{
+			pod("crashing", corev1.ContainerStatus{
+				Name: "app",
+				State: corev1.ContainerState{
+					Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+				},
+				LastTerminationState: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 3},
+				},
+			}),
+		}
+		failure := application.RolloutFailure(pods)
+		Expect(failure).ToNot(BeNil())
+		Expect(failure.Pod).To(Equal("crashing"))
+		Expect(failure.Error()).To(Equal("pod crashing: CrashLoopBackOff, container app terminated: Error, exit code 3"))
+	})
+
+	It("reports an image which cannot be pulled", func() {
+		pods := []corev1.Pod{
+			pod("pulling", corev1.ContainerStatus{State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "not found"},
+			}}),
+		}
+		Expect(application.RolloutFailure(pods).Error()).To(Equal("pod pulling: ImagePullBackOff, not found"))
+	})
+})
@@ -0,0 +1,112 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxInstanceEvents is the number of warning events reported for an
+// instance which is not ready
+const maxInstanceEvents = 5
+
+// Instances returns the instances of the application, i.e. the pods of
+// all its replica sets, sorted by name. Instances which are not ready
+// come with their recent warning events.
+func (a *Workload) Instances(ctx context.Context) (models.AppInstanceList, error) {
+	pods, err := a.cluster.Kubectl.CoreV1().Pods(a.app.Org).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app.kubernetes.io/component=application,app.kubernetes.io/part-of=%s,app.kubernetes.io/name=%s",
+			a.app.Org, a.app.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	instances := models.AppInstanceList{}
+	for _, pod := range pods.Items {
+		instance := NewAppInstance(pod, a.app.Name)
+
+		if !instance.Ready {
+			instance.Events, err = a.cluster.GetPodWarningEvents(ctx, a.app.Org, pod.Name, maxInstanceEvents)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		instances = append(instances, instance)
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Name < instances[j].Name
+	})
+
+	return instances, nil
+}
+
+// NewAppInstance returns the status of the pod, as an instance of the
+// application whose container has the given name.
+func NewAppInstance(pod corev1.Pod, containerName string) models.AppInstance {
+	instance := models.AppInstance{
+		Name:    pod.Name,
+		Phase:   string(pod.Status.Phase),
+		Node:    pod.Spec.NodeName,
+		Created: pod.CreationTimestamp.Time,
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			instance.Ready = condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != containerName {
+			continue
+		}
+
+		instance.Restarts = status.RestartCount
+		if waiting := status.State.Waiting; waiting != nil {
+			instance.Reason = waiting.Reason
+		}
+		if last := status.LastTerminationState.Terminated; last != nil {
+			instance.LastTermination = &models.InstanceTermination{
+				Reason:   last.Reason,
+				ExitCode: last.ExitCode,
+				Finished: last.FinishedAt.Time,
+			}
+		}
+	}
+
+	return instance
+}
+
+// SelectInstance returns the instance to run commands in, or to forward
+// ports to. That is the instance at the index, counting from 0 in the
+// order of Instances, or, for a negative index, the first ready
+// instance. The instance must be running.
+func SelectInstance(instances models.AppInstanceList, index int) (models.AppInstance, error) {
+	if index < 0 {
+		for _, instance := range instances {
+			if instance.Ready {
+				return instance, nil
+			}
+		}
+		return models.AppInstance{}, errors.New("application has no ready instance")
+	}
+
+	if index >= len(instances) {
+		return models.AppInstance{}, fmt.Errorf("application has no instance %d, it has %d instances", index, len(instances))
+	}
+
+	instance := instances[index]
+	if instance.Phase != string(corev1.PodRunning) {
+		return models.AppInstance{}, fmt.Errorf("instance %d of the application is not running, it is %s", index, instance.Phase)
+	}
+
+	return instance, nil
+}
@@ -0,0 +1,116 @@
+package application_test
+
+import (
+	"time"
+
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewAppInstance", func() {
+	created := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	finished := created.Add(time.Minute)
+
+	pod := func(ready corev1.ConditionStatus, statuses ...corev1.ContainerStatus) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "app-1234",
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Spec: corev1.PodSpec{NodeName: "node-1"},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: ready},
+				},
+				ContainerStatuses: statuses,
+			},
+		}
+	}
+
+	It("reports a ready instance", func() {
+		instance := application.NewAppInstance(pod(corev1.ConditionTrue, corev1.ContainerStatus{
+			Name:  "app",
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		}), "app")
+
+		Expect(instance).To(Equal(models.AppInstance{
+			Name:    "app-1234",
+			Phase:   "Running",
+			Ready:   true,
+			Node:    "node-1",
+			Created: created,
+		}))
+	})
+
+	It("reports the restarts and last termination of a crashing instance", func() {
+		instance := application.NewAppInstance(pod(corev1.ConditionFalse,
+			corev1.ContainerStatus{
+				Name:         "sidecar",
+				RestartCount: 7,
+			},
+			corev1.ContainerStatus{
+				Name:         "app",
+				RestartCount: 3,
+				State: corev1.ContainerState{
+					Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+				},
+				LastTerminationState: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{
+						Reason:     "Error",
+						ExitCode:   2,
+						FinishedAt: metav1.NewTime(finished),
+					},
+				},
+			}), "app")
+
+		Expect(instance.Ready).To(BeFalse())
+		Expect(instance.Reason).To(Equal("CrashLoopBackOff"))
+		Expect(instance.Restarts).To(Equal(int32(3)))
+		Expect(instance.LastTermination).To(Equal(&models.InstanceTermination{
+			Reason:   "Error",
+			ExitCode: 2,
+			Finished: finished,
+		}))
+	})
+})
+
+var _ = Describe("SelectInstance", func() {
+	instances := models.AppInstanceList{
+		{Name: "app-1", Phase: "Pending"},
+		{Name: "app-2", Phase: "Running", Ready: false},
+		{Name: "app-3", Phase: "Running", Ready: true},
+	}
+
+	It("selects the first ready instance by default", func() {
+		instance, err := application.SelectInstance(instances, -1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instance.Name).To(Equal("app-3"))
+	})
+
+	It("selects a running instance by index", func() {
+		instance, err := application.SelectInstance(instances, 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instance.Name).To(Equal("app-2"))
+	})
+
+	It("rejects instances which are not running", func() {
+		_, err := application.SelectInstance(instances, 0)
+		Expect(err).To(MatchError("instance 0 of the application is not running, it is Pending"))
+	})
+
+	It("rejects unknown instances", func() {
+		_, err := application.SelectInstance(instances, 3)
+		Expect(err).To(MatchError("application has no instance 3, it has 3 instances"))
+	})
+
+	It("fails without ready instances", func() {
+		_, err := application.SelectInstance(instances[:2], -1)
+		Expect(err).To(MatchError("application has no ready instance"))
+	})
+})
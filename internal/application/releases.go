@@ -0,0 +1,129 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// releaseHistory is the number of releases kept per application.
+	// Older releases are dropped when new ones are added.
+	releaseHistory = 10
+)
+
+// Releases returns the release history of the application, ordered by
+// release number, oldest first. Each release is stored under its
+// number as key in the application's releases secret.
+func Releases(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (models.AppReleaseList, error) {
+	releasesSecret, err := releasesLoad(ctx, cluster, appRef)
+	if err != nil {
+		return nil, err
+	}
+
+	return releasesDecode(releasesSecret)
+}
+
+// ReleaseLookup returns the release with the given number. The zero
+// number refers to the release before the current, i.e. latest one.
+// The boolean result is false if there is no such release.
+func ReleaseLookup(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, number int) (models.AppRelease, bool, error) {
+	releases, err := Releases(ctx, cluster, appRef)
+	if err != nil {
+		return models.AppRelease{}, false, err
+	}
+
+	if number == 0 {
+		if len(releases) < 2 {
+			return models.AppRelease{}, false, nil
+		}
+		return releases[len(releases)-2], true, nil
+	}
+
+	for _, release := range releases {
+		if release.Number == number {
+			return release, true, nil
+		}
+	}
+
+	return models.AppRelease{}, false, nil
+}
+
+// ReleaseAdd records a new release for the application. The release
+// is numbered after the latest one, and returned. Releases falling out
+// of the history are dropped.
+func ReleaseAdd(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, release models.AppRelease) (models.AppRelease, error) {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		releasesSecret, err := releasesLoad(ctx, cluster, appRef)
+		if err != nil {
+			return err
+		}
+
+		releases, err := releasesDecode(releasesSecret)
+		if err != nil {
+			return err
+		}
+
+		release.Number = 1
+		if len(releases) > 0 {
+			release.Number = releases[len(releases)-1].Number + 1
+		}
+
+		value, err := json.Marshal(release)
+		if err != nil {
+			return err
+		}
+
+		if releasesSecret.Data == nil {
+			releasesSecret.Data = map[string][]byte{}
+		}
+		releasesSecret.Data[strconv.Itoa(release.Number)] = value
+
+		for len(releases) >= releaseHistory {
+			delete(releasesSecret.Data, strconv.Itoa(releases[0].Number))
+			releases = releases[1:]
+		}
+
+		_, err = cluster.Kubectl.CoreV1().Secrets(appRef.Org).Update(
+			ctx, releasesSecret, metav1.UpdateOptions{})
+
+		return err
+	})
+
+	return release, err
+}
+
+// releasesDecode is a helper for the public functions. It decodes the
+// releases stored in the secret and sorts them by number.
+func releasesDecode(releasesSecret *v1.Secret) (models.AppReleaseList, error) {
+	releases := models.AppReleaseList{}
+
+	for key, value := range releasesSecret.Data {
+		var release models.AppRelease
+		err := json.Unmarshal(value, &release)
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad release %s", key)
+		}
+		releases = append(releases, release)
+	}
+
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].Number < releases[j].Number
+	})
+
+	return releases, nil
+}
+
+// releasesLoad locates and returns the kube secret storing the referenced application's release
+// history. If necessary it creates that secret.
+func releasesLoad(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (*v1.Secret, error) {
+	return appSettingsSecret(ctx, cluster, appRef, appRef.MakeReleasesSecretName())
+}
@@ -0,0 +1,78 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/domain"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	routesKey = "routes"
+)
+
+// Routes returns the routes set by a user for the application, in the
+// form HOST[/PATH]. Without such, the result is the default route of
+// the application.
+func Routes(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) ([]string, error) {
+	routesSecret, err := routesLoad(ctx, cluster, appRef)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	if data, ok := routesSecret.Data[routesKey]; ok {
+		err = json.Unmarshal(data, &result)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(result) == 0 {
+		route, err := domain.AppDefaultRoute(ctx, appRef.Name)
+		if err != nil {
+			return nil, err
+		}
+		result = []string{route}
+	}
+
+	return result, nil
+}
+
+// RoutesSet replaces the routes of the named application. An empty list
+// restores the default route. When the function returns the routes are
+// saved.
+func RoutesSet(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, routes []string) error {
+	data, err := json.Marshal(routes)
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		routesSecret, err := routesLoad(ctx, cluster, appRef)
+		if err != nil {
+			return err
+		}
+
+		if routesSecret.Data == nil {
+			routesSecret.Data = map[string][]byte{}
+		}
+		routesSecret.Data[routesKey] = data
+
+		_, err = cluster.Kubectl.CoreV1().Secrets(appRef.Org).Update(
+			ctx, routesSecret, metav1.UpdateOptions{})
+
+		return err
+	})
+}
+
+// routesLoad locates and returns the kube secret storing the referenced application's routes.
+// If necessary it creates that secret.
+func routesLoad(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (*v1.Secret, error) {
+	return appSettingsSecret(ctx, cluster, appRef, appRef.MakeRoutesSecretName())
+}
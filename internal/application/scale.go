@@ -2,19 +2,25 @@ package application
 
 import (
 	"context"
+	"fmt"
 	"math"
 	"strconv"
 
 	"github.com/epinio/epinio/helpers/kubernetes"
 	"github.com/epinio/epinio/pkg/api/core/v1/models"
 	v1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/retry"
 )
 
 const (
-	instanceKey = "desired"
+	instanceKey      = "desired"
+	memoryRequestKey = "memory-request"
+	memoryLimitKey   = "memory-limit"
+	cpuRequestKey    = "cpu-request"
+	cpuLimitKey      = "cpu-limit"
+	stoppedKey       = "stopped"
 )
 
 // Scaling returns the number of desired instances set by a user for the application
@@ -45,6 +51,131 @@ func ScalingSet(ctx context.Context, cluster *kubernetes.Cluster, appRef models.
 	})
 }
 
+// Stopped returns true if the application was stopped by a user
+func Stopped(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (bool, error) {
+	scaleSecret, err := scaleLoad(ctx, cluster, appRef)
+	if err != nil {
+		return false, err
+	}
+
+	_, ok := scaleSecret.Data[stoppedKey]
+	return ok, nil
+}
+
+// StoppedSet records whether the named application is stopped. The
+// desired number of instances is kept, for the start of the application.
+// When the function returns the state is saved.
+func StoppedSet(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, stopped bool) error {
+	return scaleUpdate(ctx, cluster, appRef, func(scaleSecret *v1.Secret) {
+		if stopped {
+			scaleSecret.Data[stoppedKey] = []byte("true")
+		} else {
+			delete(scaleSecret.Data, stoppedKey)
+		}
+	})
+}
+
+// Resources returns the compute resources set by a user for the application
+func Resources(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (models.AppResources, error) {
+	scaleSecret, err := scaleLoad(ctx, cluster, appRef)
+	if err != nil {
+		return models.AppResources{}, err
+	}
+
+	return models.AppResources{
+		MemoryRequest: string(scaleSecret.Data[memoryRequestKey]),
+		MemoryLimit:   string(scaleSecret.Data[memoryLimitKey]),
+		CPURequest:    string(scaleSecret.Data[cpuRequestKey]),
+		CPULimit:      string(scaleSecret.Data[cpuLimitKey]),
+	}, nil
+}
+
+// ResourcesSet replaces the compute resources of the named application.
+// When the function returns the resources are saved.
+func ResourcesSet(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, resources models.AppResources) error {
+	return scaleUpdate(ctx, cluster, appRef, func(scaleSecret *v1.Secret) {
+		for key, value := range map[string]string{
+			memoryRequestKey: resources.MemoryRequest,
+			memoryLimitKey:   resources.MemoryLimit,
+			cpuRequestKey:    resources.CPURequest,
+			cpuLimitKey:      resources.CPULimit,
+		} {
+			if value == "" {
+				delete(scaleSecret.Data, key)
+				continue
+			}
+			scaleSecret.Data[key] = []byte(value)
+		}
+	})
+}
+
+// ResourcesValidate checks the compute resources, and returns an error
+// describing the first issue found, if any. The values have to be valid
+// quantities, and requests must not exceed their limits.
+func ResourcesValidate(resources models.AppResources) error {
+	_, err := ResourceRequirements(resources)
+	return err
+}
+
+// ResourceRequirements returns the resource requirements of the pods
+// for the compute resources.
+func ResourceRequirements(resources models.AppResources) (v1.ResourceRequirements, error) {
+	result := v1.ResourceRequirements{}
+
+	for _, r := range []struct {
+		name           v1.ResourceName
+		request, limit string
+	}{
+		{v1.ResourceMemory, resources.MemoryRequest, resources.MemoryLimit},
+		{v1.ResourceCPU, resources.CPURequest, resources.CPULimit},
+	} {
+		request, err := resourceQuantity(r.name, "request", r.request)
+		if err != nil {
+			return result, err
+		}
+		limit, err := resourceQuantity(r.name, "limit", r.limit)
+		if err != nil {
+			return result, err
+		}
+
+		if request != nil && limit != nil && request.Cmp(*limit) > 0 {
+			return result, fmt.Errorf("%s request %s exceeds the limit %s", r.name, r.request, r.limit)
+		}
+
+		if request != nil {
+			if result.Requests == nil {
+				result.Requests = v1.ResourceList{}
+			}
+			result.Requests[r.name] = *request
+		}
+		if limit != nil {
+			if result.Limits == nil {
+				result.Limits = v1.ResourceList{}
+			}
+			result.Limits[r.name] = *limit
+		}
+	}
+
+	return result, nil
+}
+
+// resourceQuantity parses a request or limit. The result is nil for the empty value.
+func resourceQuantity(name v1.ResourceName, kind, value string) (*resource.Quantity, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return nil, fmt.Errorf("bad %s %s '%s': %s", name, kind, value, err)
+	}
+	if quantity.Sign() <= 0 {
+		return nil, fmt.Errorf("bad %s %s '%s': must be positive", name, kind, value)
+	}
+
+	return &quantity, nil
+}
+
 // scaleUpdate is a helper for the public functions. It encapsulates the read/modify/write cycle
 // necessary to update the application's kube resource holding the application's number of desired
 // instances
@@ -73,53 +204,7 @@ func scaleUpdate(ctx context.Context, cluster *kubernetes.Cluster,
 }
 
 // scaleLoad locates and returns the kube secret storing the referenced application's desired number of
-// instances. If necessary it creates that secret.
+// instances, compute resources, autoscaling settings, and stopped state. If necessary it creates that secret.
 func scaleLoad(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (*v1.Secret, error) {
-	secretName := appRef.MakeScaleSecretName()
-
-	scaleSecret, err := cluster.GetSecret(ctx, appRef.Org, secretName)
-	if err != nil {
-		if !apierrors.IsNotFound(err) {
-			return nil, err
-		}
-
-		// Error is `Not Found`. Create the secret.
-
-		app, err := Get(ctx, cluster, appRef)
-		if err != nil {
-			// Should not happen. The application was validated to exist already somewhere
-			// by this function's callers.
-			return nil, err
-		}
-
-		owner := metav1.OwnerReference{
-			APIVersion: app.GetAPIVersion(),
-			Kind:       app.GetKind(),
-			Name:       app.GetName(),
-			UID:        app.GetUID(),
-		}
-
-		scaleSecret = &v1.Secret{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      secretName,
-				Namespace: appRef.Org,
-				OwnerReferences: []metav1.OwnerReference{
-					owner,
-				},
-				Labels: map[string]string{
-					"app.kubernetes.io/name":       appRef.Name,
-					"app.kubernetes.io/part-of":    appRef.Org,
-					"app.kubernetes.io/managed-by": "epinio",
-					"app.kubernetes.io/component":  "application",
-				},
-			},
-		}
-		err = cluster.CreateSecret(ctx, appRef.Org, *scaleSecret)
-
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return scaleSecret, nil
+	return appSettingsSecret(ctx, cluster, appRef, appRef.MakeScaleSecretName())
 }
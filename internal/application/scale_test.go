@@ -0,0 +1,84 @@
+package application_test
+
+import (
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ResourcesValidate", func() {
+	It("accepts no resources", func() {
+		Expect(application.ResourcesValidate(models.AppResources{})).To(Succeed())
+	})
+
+	It("accepts quantities", func() {
+		Expect(application.ResourcesValidate(models.AppResources{
+			MemoryRequest: "256Mi",
+			MemoryLimit:   "1Gi",
+			CPURequest:    "250m",
+			CPULimit:      "1",
+		})).To(Succeed())
+	})
+
+	It("rejects garbage", func() {
+		err := application.ResourcesValidate(models.AppResources{MemoryRequest: "lots"})
+		Expect(err).To(MatchError(ContainSubstring("bad memory request 'lots'")))
+	})
+
+	It("rejects negative quantities", func() {
+		err := application.ResourcesValidate(models.AppResources{CPULimit: "-1"})
+		Expect(err).To(MatchError(ContainSubstring("must be positive")))
+	})
+
+	It("rejects a request above the limit", func() {
+		err := application.ResourcesValidate(models.AppResources{MemoryRequest: "1Gi", MemoryLimit: "512Mi"})
+		Expect(err).To(MatchError("memory request 1Gi exceeds the limit 512Mi"))
+	})
+})
+
+var _ = Describe("ResourceRequirements", func() {
+	It("renders the set resources only", func() {
+		requirements, err := application.ResourceRequirements(models.AppResources{
+			MemoryRequest: "512Mi",
+			MemoryLimit:   "512Mi",
+			CPURequest:    "250m",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requirements.Requests).To(Equal(corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("512Mi"),
+			corev1.ResourceCPU:    resource.MustParse("250m"),
+		}))
+		Expect(requirements.Limits).To(Equal(corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("512Mi"),
+		}))
+	})
+
+	It("renders nothing for no resources", func() {
+		requirements, err := application.ResourceRequirements(models.AppResources{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requirements).To(Equal(corev1.ResourceRequirements{}))
+	})
+})
+
+var _ = Describe("AppResources", func() {
+	It("merges an update, keeping, replacing, and removing values", func() {
+		current := models.AppResources{
+			MemoryRequest: "256Mi",
+			MemoryLimit:   "512Mi",
+			CPURequest:    "250m",
+		}
+		Expect(current.Merge(models.AppResources{
+			MemoryRequest: "384Mi",
+			CPURequest:    "0",
+			CPULimit:      "1",
+		})).To(Equal(models.AppResources{
+			MemoryRequest: "384Mi",
+			MemoryLimit:   "512Mi",
+			CPULimit:      "1",
+		}))
+	})
+})
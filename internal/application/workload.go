@@ -3,6 +3,7 @@ package application
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/epinio/epinio/helpers/kubernetes"
 	"github.com/epinio/epinio/internal/names"
@@ -12,8 +13,11 @@ import (
 	pkgerrors "github.com/pkg/errors"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/util/retry"
 )
 
@@ -87,7 +91,7 @@ func (b AppServiceBindList) ToMountsArray() []corev1.VolumeMount {
 // BoundServicesChange imports the currently bound services into the deployment. It takes a ServiceList, not just
 // names, as it has to create/retrieve the associated service binding secrets. It further takes a set of the old
 // services. This enables incremental modification of the deployment (add, remove affected, instead of wholsesale
-// replacement).
+// replacement). The binding secrets of the removed services are deleted after the deployment is updated.
 func (a *Workload) BoundServicesChange(ctx context.Context, userName string, oldServices NameSet, newServices services.ServiceList) error {
 	_, err := Get(ctx, a.cluster, a.app)
 	if err != nil {
@@ -110,7 +114,7 @@ func (a *Workload) BoundServicesChange(ctx context.Context, userName string, old
 	}
 
 	// Read, modify and write the deployment
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		// Retrieve the latest version of Deployment before attempting update
 		// RetryOnConflict uses exponential backoff to avoid exhausting the apiserver
 		deployment, err := a.Deployment(ctx)
@@ -187,6 +191,32 @@ func (a *Workload) BoundServicesChange(ctx context.Context, userName string, old
 
 		return err
 	})
+	if err != nil {
+		return err
+	}
+
+	// The deployment no longer mounts the bindings of the removed services. Delete them.
+	for serviceName := range oldServices {
+		if _, hasnew := new[serviceName]; hasnew {
+			continue
+		}
+
+		service, err := services.Lookup(ctx, a.cluster, a.app.Org, serviceName)
+		if err != nil {
+			if err.Error() == "service not found" {
+				// Nothing to unbind. Any binding secret left behind is removed with the app.
+				continue
+			}
+			return err
+		}
+
+		err = service.DeleteBinding(ctx, a.app.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // EnvironmentChange imports the current environment into the
@@ -254,8 +284,17 @@ func (a *Workload) EnvironmentChange(ctx context.Context, varNames []string) err
 }
 
 // Scale changes the number of instances (replicas) for the
-// application's Deployment.
+// application's Deployment. It returns ErrAutoscaled while the
+// application's autoscaler is active.
 func (a *Workload) Scale(ctx context.Context, instances int32) error {
+	autoscaler, err := a.Autoscaler(ctx)
+	if err != nil {
+		return err
+	}
+	if autoscaler != nil {
+		return ErrAutoscaled
+	}
+
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		// Retrieve the latest version of Deployment before attempting update
 		// RetryOnConflict uses exponential backoff to avoid exhausting the apiserver
@@ -273,6 +312,325 @@ func (a *Workload) Scale(ctx context.Context, instances int32) error {
 	})
 }
 
+// AutoscaleChange creates, updates, or, for nil settings, deletes the
+// autoscaler of the application's deployment.
+func (a *Workload) AutoscaleChange(ctx context.Context, autoscale *models.AppAutoscale) error {
+	client := a.cluster.Kubectl.AutoscalingV1().HorizontalPodAutoscalers(a.app.Org)
+
+	if autoscale == nil {
+		err := client.Delete(ctx, a.app.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	deployment, err := a.Deployment(ctx)
+	if err != nil {
+		return err
+	}
+	desired := NewAutoscaler(deployment, *autoscale)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		autoscaler, err := a.Autoscaler(ctx)
+		if err != nil {
+			return err
+		}
+
+		if autoscaler == nil {
+			_, err = client.Create(ctx, desired, metav1.CreateOptions{})
+			return err
+		}
+
+		autoscaler.Spec = desired.Spec
+		_, err = client.Update(ctx, autoscaler, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// Autoscaler returns the autoscaler of the application's deployment,
+// or nil, if the application is not autoscaled.
+func (a *Workload) Autoscaler(ctx context.Context) (*autoscalingv1.HorizontalPodAutoscaler, error) {
+	autoscaler, err := a.cluster.Kubectl.AutoscalingV1().HorizontalPodAutoscalers(a.app.Org).Get(
+		ctx, a.app.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return autoscaler, nil
+}
+
+// Restart restarts the application's pods, by changing an annotation of the
+// deployment's pod template. The pods are replaced per the deployment's
+// rollout strategy, and pick up changed service bindings.
+func (a *Workload) Restart(ctx context.Context) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// Retrieve the latest version of Deployment before attempting update
+		// RetryOnConflict uses exponential backoff to avoid exhausting the apiserver
+		deployment, err := a.Deployment(ctx)
+		if err != nil {
+			return err
+		}
+
+		if deployment.Spec.Template.Annotations == nil {
+			deployment.Spec.Template.Annotations = map[string]string{}
+		}
+		deployment.Spec.Template.Annotations[models.EpinioRestartedAtAnnotation] = time.Now().Format(time.RFC3339)
+
+		_, err = a.cluster.Kubectl.AppsV1().Deployments(a.app.Org).Update(
+			ctx, deployment, metav1.UpdateOptions{})
+
+		return err
+	})
+}
+
+// Stop scales the application's deployment to zero. An autoscaler is
+// deleted first, as it would scale the deployment up again.
+func (a *Workload) Stop(ctx context.Context) error {
+	err := a.AutoscaleChange(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	return a.Scale(ctx, 0)
+}
+
+// Start scales the application's deployment back to the given number
+// of instances. For autoscaled applications the autoscaler is recreated,
+// and the deployment starts with the minimum instances.
+func (a *Workload) Start(ctx context.Context, instances int32, autoscale *models.AppAutoscale) error {
+	if autoscale != nil {
+		instances = autoscale.Min
+	}
+
+	err := a.Scale(ctx, instances)
+	if err != nil {
+		return err
+	}
+
+	return a.AutoscaleChange(ctx, autoscale)
+}
+
+// HealthChange replaces the probes and the rollout strategy of the
+// application's deployment. Changed probes roll out new pods.
+func (a *Workload) HealthChange(ctx context.Context, healthCheck models.HealthCheck, rollout models.Rollout) error {
+	readiness, liveness := HealthProbes(healthCheck)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// Retrieve the latest version of Deployment before attempting update
+		// RetryOnConflict uses exponential backoff to avoid exhausting the apiserver
+		deployment, err := a.Deployment(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := range deployment.Spec.Template.Spec.Containers {
+			container := &deployment.Spec.Template.Spec.Containers[i]
+			if container.Name != a.app.Name {
+				continue
+			}
+			container.ReadinessProbe = readiness
+			container.LivenessProbe = liveness
+		}
+		deployment.Spec.Strategy = RolloutStrategy(rollout)
+
+		_, err = a.cluster.Kubectl.AppsV1().Deployments(a.app.Org).Update(
+			ctx, deployment, metav1.UpdateOptions{})
+
+		return err
+	})
+}
+
+// ResourcesChange replaces the compute resources of the application's
+// container. Changed resources roll out new pods.
+func (a *Workload) ResourcesChange(ctx context.Context, resources models.AppResources) error {
+	requirements, err := ResourceRequirements(resources)
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// Retrieve the latest version of Deployment before attempting update
+		// RetryOnConflict uses exponential backoff to avoid exhausting the apiserver
+		deployment, err := a.Deployment(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := range deployment.Spec.Template.Spec.Containers {
+			container := &deployment.Spec.Template.Spec.Containers[i]
+			if container.Name != a.app.Name {
+				continue
+			}
+			container.Resources = requirements
+		}
+
+		_, err = a.cluster.Kubectl.AppsV1().Deployments(a.app.Org).Update(
+			ctx, deployment, metav1.UpdateOptions{})
+
+		return err
+	})
+}
+
+// WaitForRollout waits until all pods of the application run the current
+// pod template of the deployment, and are available. It returns a
+// RolloutError as soon as the rollout is known to fail, i.e. when a pod
+// of the current template is crashing, or the deployment exceeded its
+// progress deadline.
+func (a *Workload) WaitForRollout(ctx context.Context, timeout time.Duration) error {
+	return wait.PollImmediate(time.Second, timeout, func() (bool, error) {
+		deployment, err := a.Deployment(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		if deployment.Status.ObservedGeneration < deployment.Generation {
+			// The status is about an older pod template
+			return false, nil
+		}
+
+		replicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
+		}
+
+		if deployment.Status.UpdatedReplicas == replicas &&
+			deployment.Status.Replicas == replicas &&
+			deployment.Status.AvailableReplicas == replicas {
+			return true, nil
+		}
+
+		for _, condition := range deployment.Status.Conditions {
+			if condition.Type == appsv1.DeploymentProgressing &&
+				condition.Status == corev1.ConditionFalse &&
+				condition.Reason == progressDeadlineExceeded {
+				return false, &RolloutError{Reason: condition.Reason, Message: condition.Message}
+			}
+		}
+
+		pods, err := a.currentPods(ctx, deployment)
+		if err != nil {
+			return false, err
+		}
+		if failure := RolloutFailure(pods); failure != nil {
+			return false, failure
+		}
+
+		return false, nil
+	})
+}
+
+// RolloutError reports the failed rollout of an application, with the
+// reason given by a failing pod, or by the deployment itself. In the
+// latter case the Pod is empty.
+type RolloutError struct {
+	Pod     string
+	Reason  string
+	Message string
+}
+
+func (e *RolloutError) Error() string {
+	msg := e.Reason
+	if e.Pod != "" {
+		msg = fmt.Sprintf("pod %s: %s", e.Pod, e.Reason)
+	}
+	if e.Message != "" {
+		msg = fmt.Sprintf("%s, %s", msg, e.Message)
+	}
+	return msg
+}
+
+const (
+	// progressDeadlineExceeded is the reason of the failed progressing
+	// condition of a deployment not rolled out in time
+	progressDeadlineExceeded = "ProgressDeadlineExceeded"
+
+	// revisionAnnotation holds the revision of deployments and their replica sets
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+)
+
+// failedWaitingReasons are the reasons of waiting containers which do
+// not resolve without intervention
+var failedWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImageNeverPull":          true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+}
+
+// RolloutFailure returns the failure of the first pod with a container
+// which does not start, or keeps crashing, if any. For crashing
+// containers the reason and exit code of their last termination is
+// reported.
+func RolloutFailure(pods []corev1.Pod) *RolloutError {
+	for _, pod := range pods {
+		statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.ContainerStatuses...)
+
+		for _, status := range statuses {
+			waiting := status.State.Waiting
+			if waiting == nil || !failedWaitingReasons[waiting.Reason] {
+				continue
+			}
+
+			failure := &RolloutError{Pod: pod.Name, Reason: waiting.Reason, Message: waiting.Message}
+			if last := status.LastTerminationState.Terminated; last != nil {
+				failure.Message = fmt.Sprintf("container %s terminated: %s, exit code %d",
+					status.Name, last.Reason, last.ExitCode)
+				if last.Message != "" {
+					failure.Message = fmt.Sprintf("%s, %s", failure.Message, last.Message)
+				}
+			}
+
+			return failure
+		}
+	}
+
+	return nil
+}
+
+// currentPods returns the pods of the deployment's current pod template,
+// i.e. of its replica set with the deployment's revision.
+func (a *Workload) currentPods(ctx context.Context, deployment *appsv1.Deployment) ([]corev1.Pod, error) {
+	selector := fmt.Sprintf("app.kubernetes.io/name=%s", a.app.Name)
+
+	sets, err := a.cluster.Kubectl.AppsV1().ReplicaSets(a.app.Org).List(ctx, metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hash := ""
+	for i := range sets.Items {
+		set := &sets.Items[i]
+		if metav1.IsControlledBy(set, deployment) &&
+			set.Annotations[revisionAnnotation] == deployment.Annotations[revisionAnnotation] {
+			hash = set.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+			break
+		}
+	}
+	if hash == "" {
+		// The replica set is not created yet
+		return nil, nil
+	}
+
+	pods, err := a.cluster.Kubectl.CoreV1().Pods(a.app.Org).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s,%s=%s", selector, appsv1.DefaultDeploymentUniqueLabelKey, hash),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pods.Items, nil
+}
+
 // deployment is a helper, it returns the kube deployment resource of the workload.
 func (a *Workload) Deployment(ctx context.Context) (*appsv1.Deployment, error) {
 	return a.cluster.Kubectl.AppsV1().Deployments(a.app.Org).Get(
@@ -281,12 +639,17 @@ func (a *Workload) Deployment(ctx context.Context) (*appsv1.Deployment, error) {
 }
 
 // Get returns the state of the app deployment encoded in the workload.
+// The instances are left out, as they are expensive to query, and only
+// shown for single applications, see Instances.
 func (a *Workload) Get(ctx context.Context, deployment *appsv1.Deployment) *models.AppDeployment {
 	active := false
 	route := ""
 	stageID := ""
 	status := ""
 	username := ""
+	var gitRef *models.GitRef
+	var currentReplicas, desiredReplicas int32
+	var autoscale *models.AppAutoscale
 
 	// Query application deployment for stageID and status (ready vs desired replicas)
 
@@ -311,13 +674,47 @@ func (a *Workload) Get(ctx context.Context, deployment *appsv1.Deployment) *mode
 			Spec.Template.ObjectMeta.Labels["epinio.suse.org/stage-id"]
 		username = deployments.Items[0].Spec.Template.ObjectMeta.Labels["app.kubernetes.io/created-by"]
 
+		if commit, ok := deployments.Items[0].Annotations[models.EpinioGitCommitAnnotation]; ok {
+			gitRef = &models.GitRef{
+				URL:      deployments.Items[0].Annotations[models.EpinioGitURLAnnotation],
+				Revision: commit,
+			}
+		}
+
+		currentReplicas = deployments.Items[0].Status.Replicas
+		if deployments.Items[0].Spec.Replicas != nil {
+			desiredReplicas = *deployments.Items[0].Spec.Replicas
+		}
+
+		// An active autoscaler decides the replicas. Errors leave the
+		// deployment's view in place.
+		autoscaler, err := a.Autoscaler(ctx)
+		if err == nil && autoscaler != nil {
+			desiredReplicas = autoscaler.Status.DesiredReplicas
+			autoscale = &models.AppAutoscale{
+				Max: autoscaler.Spec.MaxReplicas,
+			}
+			if autoscaler.Spec.MinReplicas != nil {
+				autoscale.Min = *autoscaler.Spec.MinReplicas
+			}
+			if autoscaler.Spec.TargetCPUUtilizationPercentage != nil {
+				autoscale.CPUPercent = *autoscaler.Spec.TargetCPUUtilizationPercentage
+			}
+		}
+
 		active = true
 	}
 
+	// Errors leave the application running, as far as reported
+	stopped, err := Stopped(ctx, a.cluster, a.app)
+	if err == nil && stopped {
+		status = "stopped"
+	}
+
 	routes, err := a.cluster.ListIngressRoutes(ctx, a.app.Org, names.IngressName(a.app.Name))
 	if err != nil {
 		route = err.Error()
-	} else {
+	} else if len(routes) > 0 {
 		route = routes[0]
 	}
 
@@ -327,5 +724,12 @@ func (a *Workload) Get(ctx context.Context, deployment *appsv1.Deployment) *mode
 		StageID:  stageID,
 		Status:   status,
 		Route:    route,
+		Routes:   routes,
+		Git:      gitRef,
+		Stopped:  stopped,
+
+		CurrentReplicas: currentReplicas,
+		DesiredReplicas: desiredReplicas,
+		Autoscale:       autoscale,
 	}
 }
@@ -50,10 +50,13 @@ func ExtendLocalTrust(certs string) {
 
 // CertParam describes the cert-manager certificate CRD. It's passed to
 // CreateCertificate to create the cert-manager certificate CR.
+// The certificate is for the host `Name.Domain`, unless Host is
+// specified.
 type CertParam struct {
 	Name      string
 	Namespace string
 	Domain    string
+	Host      string
 	Issuer    string
 }
 
@@ -88,6 +91,27 @@ func CreateCertificate(
 	return nil
 }
 
+// DeleteCertificate removes the named certificate resource, and the
+// secret holding the certificate. Missing resources are not an error.
+func DeleteCertificate(ctx context.Context, cluster *kubernetes.Cluster, namespace, name string) error {
+	client, err := cluster.ClientCertificate()
+	if err != nil {
+		return err
+	}
+
+	err = client.Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	err = cluster.Kubectl.CoreV1().Secrets(namespace).Delete(ctx, name+"-tls", metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
 // newCertificate creates a proper certificate resource from the
 // specified parameters. The result is suitable for upload to the
 // cluster.
@@ -107,7 +131,12 @@ func newCertificate(cert CertParam) (*unstructured.Unstructured, error) {
 	//   full string as means of keeping the text unique across
 	//   apps.
 
-	cn := names.TruncateMD5(fmt.Sprintf("%s.%s", cert.Name, cert.Domain), 64)
+	host := cert.Host
+	if host == "" {
+		host = fmt.Sprintf("%s.%s", cert.Name, cert.Domain)
+	}
+
+	cn := names.TruncateMD5(host, 64)
 	data := fmt.Sprintf(`{
 		"apiVersion": "cert-manager.io/v1alpha2",
 		"kind": "Certificate",
@@ -118,14 +147,14 @@ func newCertificate(cert CertParam) (*unstructured.Unstructured, error) {
 			"commonName" : "%[2]s",
 			"secretName" : "%[1]s-tls",
 			"dnsNames": [
-				"%[1]s.%[3]s"
+				"%[3]s"
 			],
 			"issuerRef" : {
 				"name" : "%[4]s",
 				"kind" : "ClusterIssuer"
 			}
 		}
-        }`, cert.Name, cn, cert.Domain, cert.Issuer)
+        }`, cert.Name, cn, host, cert.Issuer)
 
 	decoderUnstructured := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
 	obj := &unstructured.Unstructured{}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrBadToken is returned for tokens which are malformed, or not signed with the key.
+	ErrBadToken = errors.New("bad token")
+	// ErrTokenExpired is returned for tokens past their expiry.
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// TokenClaims is the information carried by an API token. The ID
+// identifies the token for revocation. Expires is in unix seconds.
+type TokenClaims struct {
+	ID       string `json:"id"`
+	Username string `json:"user"`
+	Expires  int64  `json:"exp"`
+}
+
+// ExpiresAt returns the time the token expires.
+func (tc TokenClaims) ExpiresAt() time.Time {
+	return time.Unix(tc.Expires, 0)
+}
+
+// SignToken returns the token carrying the claims, signed with the
+// key. The token is made of the base64 encoded JSON claims and the
+// base64 encoded HMAC-SHA256 signature of them, separated by a dot.
+func SignToken(key []byte, claims TokenClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	encodedSignature := base64.RawURLEncoding.EncodeToString(sign(key, encodedPayload))
+
+	return encodedPayload + "." + encodedSignature, nil
+}
+
+// VerifyToken checks the signature and expiry of the token, and
+// returns its claims.
+func VerifyToken(key []byte, token string, now time.Time) (TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return TokenClaims{}, ErrBadToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return TokenClaims{}, ErrBadToken
+	}
+	if !hmac.Equal(signature, sign(key, parts[0])) {
+		return TokenClaims{}, ErrBadToken
+	}
+
+	claims, err := ParseToken(token)
+	if err != nil {
+		return TokenClaims{}, err
+	}
+
+	if !now.Before(claims.ExpiresAt()) {
+		return TokenClaims{}, ErrTokenExpired
+	}
+
+	return claims, nil
+}
+
+// ParseToken returns the claims of the token, without verifying it.
+// Clients use it to see when their token expires.
+func ParseToken(token string) (TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return TokenClaims{}, ErrBadToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return TokenClaims{}, ErrBadToken
+	}
+
+	var claims TokenClaims
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return TokenClaims{}, ErrBadToken
+	}
+
+	return claims, nil
+}
+
+// sign returns the HMAC-SHA256 of the encoded payload
+func sign(key []byte, encodedPayload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encodedPayload)) // nolint:errcheck // Never fails
+	return mac.Sum(nil)
+}
@@ -0,0 +1,77 @@
+package auth_test
+
+import (
+	"strings"
+	"time"
+
+	"github.com/epinio/epinio/internal/auth"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tokens", func() {
+	key := []byte("signing-key")
+	now := time.Unix(1600000000, 0)
+	claims := auth.TokenClaims{
+		ID:       "id",
+		Username: "user",
+		Expires:  now.Add(time.Hour).Unix(),
+	}
+
+	It("verifies a signed token", func() {
+		token, err := auth.SignToken(key, claims)
+		Expect(err).ToNot(HaveOccurred())
+
+		verified, err := auth.VerifyToken(key, token, now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(verified).To(Equal(claims))
+	})
+
+	It("parses a token without the key", func() {
+		token, err := auth.SignToken(key, claims)
+		Expect(err).ToNot(HaveOccurred())
+
+		parsed, err := auth.ParseToken(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed.ExpiresAt()).To(Equal(now.Add(time.Hour)))
+	})
+
+	It("rejects a token signed with another key", func() {
+		token, err := auth.SignToken([]byte("other-key"), claims)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = auth.VerifyToken(key, token, now)
+		Expect(err).To(Equal(auth.ErrBadToken))
+	})
+
+	It("rejects a token with modified claims", func() {
+		token, err := auth.SignToken(key, claims)
+		Expect(err).ToNot(HaveOccurred())
+
+		forged := claims
+		forged.Username = "admin"
+		forgedToken, err := auth.SignToken([]byte("other-key"), forged)
+		Expect(err).ToNot(HaveOccurred())
+
+		parts := strings.Split(token, ".")
+		forgedParts := strings.Split(forgedToken, ".")
+
+		_, err = auth.VerifyToken(key, forgedParts[0]+"."+parts[1], now)
+		Expect(err).To(Equal(auth.ErrBadToken))
+	})
+
+	It("rejects an expired token", func() {
+		token, err := auth.SignToken(key, claims)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = auth.VerifyToken(key, token, now.Add(2*time.Hour))
+		Expect(err).To(Equal(auth.ErrTokenExpired))
+	})
+
+	It("rejects malformed tokens", func() {
+		_, err := auth.VerifyToken(key, "garbage", now)
+		Expect(err).To(Equal(auth.ErrBadToken))
+		_, err = auth.ParseToken("not.base64!")
+		Expect(err).To(Equal(auth.ErrBadToken))
+	})
+})
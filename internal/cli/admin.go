@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/epinio/epinio/internal/cli/usercmd"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdAdmin implements the command: epinio admin
+var CmdAdmin = &cobra.Command{
+	Use:           "admin",
+	Short:         "Epinio administration",
+	Long:          `Inspect and maintain the resources epinio manages across namespaces. Admins only.`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	Args:          cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cmd.Usage(); err != nil {
+			return err
+		}
+		return fmt.Errorf(`Unknown method "%s"`, args[0])
+	},
+}
+
+// CmdAdminBlobs implements the command: epinio admin blobs
+var CmdAdminBlobs = &cobra.Command{
+	Use:           "blobs",
+	Short:         "Source blobs",
+	Long:          `Inspect and prune the application sources kept in the S3 storage`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	Args:          cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cmd.Usage(); err != nil {
+			return err
+		}
+		return fmt.Errorf(`Unknown method "%s"`, args[0])
+	},
+}
+
+func init() {
+	pruneFlags := CmdBlobsPrune.Flags()
+	pruneFlags.Int("keep", 0, "number of blobs to keep per application, overriding the retention of the server")
+
+	CmdAdminBlobs.AddCommand(CmdBlobsList)
+	CmdAdminBlobs.AddCommand(CmdBlobsPrune)
+
+	CmdAdmin.AddCommand(CmdAdminBlobs)
+}
+
+// CmdBlobsList implements the command: epinio admin blobs list
+var CmdBlobsList = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the source blobs",
+	Long:  "Lists the source blobs of all applications, with their state per the retention policy of the server",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.Blobs()
+		if err != nil {
+			return errors.Wrap(err, "error listing blobs")
+		}
+
+		return nil
+	},
+}
+
+// CmdBlobsPrune implements the command: epinio admin blobs prune
+var CmdBlobsPrune = &cobra.Command{
+	Use:   "prune [--keep N]",
+	Short: "Removes expired and orphaned source blobs",
+	Long:  "Removes the source blobs older than the retained blobs of their application, and those of deleted applications",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		keep, err := cmd.Flags().GetInt("keep")
+		if err != nil {
+			return errors.Wrap(err, "could not read keep parameter")
+		}
+		if keep < 0 {
+			return errors.New("the number of blobs to keep must not be negative")
+		}
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.BlobsPrune(keep)
+		if err != nil {
+			return errors.Wrap(err, "error pruning blobs")
+		}
+
+		return nil
+	},
+}
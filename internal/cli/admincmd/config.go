@@ -89,6 +89,8 @@ func (a *Admin) ConfigUpdate(ctx context.Context) error {
 
 	a.Config.User = user
 	a.Config.Password = password
+	// The stored credentials replace any token of a previous login.
+	a.Config.Token = ""
 	a.Config.API = api
 	a.Config.WSS = wss
 	a.Config.Certs = certs
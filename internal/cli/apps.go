@@ -3,8 +3,10 @@ package cli
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/epinio/epinio/internal/cli/usercmd"
+	"github.com/epinio/epinio/internal/manifest"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -32,6 +34,13 @@ func init() {
 	flags := CmdAppLogs.Flags()
 	flags.Bool("follow", false, "follow the logs of the application")
 	flags.Bool("staging", false, "show the staging logs of the application")
+	flags.Duration("since", 0, "show only the logs younger than the duration, e.g. 1h (default 48h)")
+	flags.Int64("tail", -1, "number of most recent lines to show of each container. A negative number shows all lines")
+	flags.StringArray("include", []string{}, "show only the lines matching one of the regular expressions. Repeatable")
+	flags.StringArray("exclude", []string{}, "hide the lines matching one of the regular expressions. Repeatable")
+	flags.Bool("timestamps", false, "prefix the lines with their time")
+	flags.Int("instance", -1, "index of the instance to show the logs of, counting from 0 as listed by 'epinio app show'. A negative index shows all instances")
+	flags.StringP("output", "o", "text", "output format, one of: text, json. The json output prints one log line object per line")
 
 	bindOption(CmdAppCreate)
 	bindOption(CmdAppUpdate)
@@ -39,15 +48,31 @@ func init() {
 	envOption(CmdAppUpdate)
 	instancesOption(CmdAppCreate)
 	instancesOption(CmdAppUpdate)
+	healthOption(CmdAppCreate)
+	healthOption(CmdAppUpdate)
+	resourcesOption(CmdAppCreate)
+	resourcesOption(CmdAppUpdate)
 
 	flags = CmdAppList.Flags()
 	flags.Bool("all", false, "list all applications")
 
+	CmdApp.AddCommand(CmdAppAutoscale) // See autoscale.go for implementation
+	CmdApp.AddCommand(CmdAppCache)     // See cache.go for implementation
 	CmdApp.AddCommand(CmdAppCreate)
-	CmdApp.AddCommand(CmdAppEnv) // See env.go for implementation
+	CmdApp.AddCommand(CmdAppEnv)  // See env.go for implementation
+	CmdApp.AddCommand(CmdAppExec) // See exec.go for implementation
 	CmdApp.AddCommand(CmdAppList)
 	CmdApp.AddCommand(CmdAppLogs)
+	CmdApp.AddCommand(CmdAppManifest)
+	CmdApp.AddCommand(CmdAppPortForward) // See portforward.go for implementation
+	CmdApp.AddCommand(CmdAppReleases)
+	CmdApp.AddCommand(CmdAppRestart) // See restart.go for implementation
+	CmdApp.AddCommand(CmdAppRollback)
+	CmdApp.AddCommand(CmdAppRoute) // See routes.go for implementation
 	CmdApp.AddCommand(CmdAppShow)
+	CmdApp.AddCommand(CmdAppStage) // See stage.go for implementation
+	CmdApp.AddCommand(CmdAppStart)
+	CmdApp.AddCommand(CmdAppStop)
 	CmdApp.AddCommand(CmdAppUpdate)
 	CmdApp.AddCommand(CmdDeleteApp)
 	CmdApp.AddCommand(CmdPush) // See push.go for implementation
@@ -193,7 +218,12 @@ var CmdAppLogs = &cobra.Command{
 			stageID = ""
 		}
 
-		err = client.AppLogs(args[0], stageID, follow, nil)
+		options, err := logOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		err = client.AppLogs(args[0], stageID, follow, options, nil)
 		if err != nil {
 			return errors.Wrap(err, "error streaming application logs")
 		}
@@ -245,3 +275,134 @@ var CmdAppUpdate = &cobra.Command{
 		return matches, cobra.ShellCompDirectiveNoFileComp
 	},
 }
+
+// CmdAppManifest implements the command: epinio apps manifest
+var CmdAppManifest = &cobra.Command{
+	Use:   "manifest NAME [MANIFESTPATH]",
+	Short: "Save the named application's configuration as a manifest",
+	Long:  "Save the configuration of the named application, as known to the server, in the manifest file (default: " + manifest.DefaultName + ")",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		manifestPath := manifest.DefaultName
+		if len(args) == 2 {
+			manifestPath = args[1]
+		}
+
+		err = client.AppManifest(args[0], manifestPath)
+		if err != nil {
+			return errors.Wrap(err, "error saving the app manifest")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 1 {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := usercmd.New()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(context.Background(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// CmdAppReleases implements the command: epinio apps releases
+var CmdAppReleases = &cobra.Command{
+	Use:   "releases NAME",
+	Short: "List the releases of the named application",
+	Long:  "List the release history of the named application, oldest first",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppReleases(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error listing app releases")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := usercmd.New()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(context.Background(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// CmdAppRollback implements the command: epinio apps rollback
+var CmdAppRollback = &cobra.Command{
+	Use:   "rollback NAME [RELEASE]",
+	Short: "Roll back the named application to a previous release",
+	Long:  "Redeploy the image, environment and bound services of a previous release of the named application, without staging. The default is the release before the current one.",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		release := 0
+		if len(args) == 2 {
+			var err error
+			release, err = strconv.Atoi(args[1])
+			if err != nil || release < 1 {
+				return fmt.Errorf("bad release '%s', expected a positive number", args[1])
+			}
+		}
+
+		client, err := usercmd.New()
+
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppRollback(args[0], release)
+		if err != nil {
+			return errors.Wrap(err, "error rolling back the app")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		app, err := usercmd.New()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := app.AppsMatching(context.Background(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
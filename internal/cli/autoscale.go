@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/epinio/epinio/internal/cli/usercmd"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	flags := CmdAppAutoscale.Flags()
+	flags.Int32("min", 1, "minimum number of instances")
+	flags.Int32("max", 0, "maximum number of instances")
+	flags.Int32("cpu-percent", 0,
+		fmt.Sprintf("target average CPU utilization of the instances, relative to their CPU request (default: %d)", models.DefaultAutoscaleCPUPercent))
+	flags.Bool("disable", false, "disable autoscaling, returning to the configured instances")
+}
+
+// CmdAppAutoscale implements the command: epinio app autoscale
+var CmdAppAutoscale = &cobra.Command{
+	Use:   "autoscale NAME",
+	Short: "Autoscale the named application",
+	Long: `Keep the number of instances of the named application between --min and --max,
+adding instances when their average CPU utilization is above --cpu-percent, and removing instances when it is below.
+The utilization is relative to the CPU request of the instances, set with 'epinio app update --cpu'.
+Manual changes of the instances are refused while autoscaling is enabled.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		disable, err := cmd.Flags().GetBool("disable")
+		if err != nil {
+			return errors.Wrap(err, "could not read disable parameter")
+		}
+
+		autoscale := models.AppAutoscale{}
+		if autoscale.Min, err = cmd.Flags().GetInt32("min"); err != nil {
+			return errors.Wrap(err, "could not read min parameter")
+		}
+		if autoscale.Max, err = cmd.Flags().GetInt32("max"); err != nil {
+			return errors.Wrap(err, "could not read max parameter")
+		}
+		if autoscale.CPUPercent, err = cmd.Flags().GetInt32("cpu-percent"); err != nil {
+			return errors.Wrap(err, "could not read cpu-percent parameter")
+		}
+
+		if disable && (cmd.Flags().Changed("min") || cmd.Flags().Changed("max") || cmd.Flags().Changed("cpu-percent")) {
+			cmd.SilenceUsage = false
+			return errors.New("--disable conflicts with --min, --max, and --cpu-percent")
+		}
+		if !disable && !cmd.Flags().Changed("max") {
+			cmd.SilenceUsage = false
+			return errors.New("--max is required")
+		}
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		if disable {
+			err = client.AppAutoscaleDisable(args[0])
+			if err != nil {
+				return errors.Wrap(err, "error disabling autoscaling")
+			}
+			return nil
+		}
+
+		err = client.AppAutoscale(args[0], autoscale)
+		if err != nil {
+			return errors.Wrap(err, "error autoscaling the app")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: matchingAppsFinder,
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/epinio/epinio/internal/cli/usercmd"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdAppCache implements the command: epinio app cache
+var CmdAppCache = &cobra.Command{
+	Use:           "cache",
+	Short:         "Epinio application build caches",
+	Long:          `Manage the build caches used when staging epinio applications`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	Args:          cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cmd.Usage(); err != nil {
+			return err
+		}
+		return fmt.Errorf(`Unknown method "%s"`, args[0])
+	},
+}
+
+func init() {
+	configFlags := CmdCacheConfig.Flags()
+	configFlags.String("size", "",
+		fmt.Sprintf("size of the cache, e.g. 2Gi. Empty selects the default, %s", models.DefaultCacheSize))
+	configFlags.String("storage-class", "", "storage class of the cache. Empty selects the cluster's default")
+
+	CmdAppCache.AddCommand(CmdCacheShow)
+	CmdAppCache.AddCommand(CmdCacheConfig)
+	CmdAppCache.AddCommand(CmdCacheClear)
+	CmdAppCache.AddCommand(CmdCacheList)
+}
+
+// CmdCacheShow implements the command: epinio app cache show
+var CmdCacheShow = &cobra.Command{
+	Use:   "show NAME",
+	Short: "Show the build cache of an application",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppCache(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error showing the build cache")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: matchingAppsFinder,
+}
+
+// CmdCacheConfig implements the command: epinio app cache config
+var CmdCacheConfig = &cobra.Command{
+	Use:   "config NAME",
+	Short: "Configure the build cache of an application",
+	Long: `Change the size and storage class of the build cache of the named application.
+An existing cache not matching the new settings is removed. The next staging recreates it, empty.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		var size, storageClass *string
+
+		if cmd.Flags().Changed("size") {
+			s, err := cmd.Flags().GetString("size")
+			if err != nil {
+				return errors.Wrap(err, "could not read size parameter")
+			}
+			size = &s
+		}
+		if cmd.Flags().Changed("storage-class") {
+			s, err := cmd.Flags().GetString("storage-class")
+			if err != nil {
+				return errors.Wrap(err, "could not read storage-class parameter")
+			}
+			storageClass = &s
+		}
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppCacheConfig(args[0], size, storageClass)
+		if err != nil {
+			return errors.Wrap(err, "error configuring the build cache")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: matchingAppsFinder,
+}
+
+// CmdCacheClear implements the command: epinio app cache clear
+var CmdCacheClear = &cobra.Command{
+	Use:   "clear NAME",
+	Short: "Clear the build cache of an application",
+	Long:  "Remove the contents of the build cache of the named application. The next staging starts from scratch",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppCacheClear(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error clearing the build cache")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: matchingAppsFinder,
+}
+
+// CmdCacheList implements the command: epinio app cache list
+var CmdCacheList = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the build caches of all applications",
+	Long:  "Lists the build caches of all applications, across namespaces, including the orphaned caches of deleted applications. The capacity reported is the size of the volume, not the space used by the cache. Admins only.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.Caches()
+		if err != nil {
+			return errors.Wrap(err, "error listing build caches")
+		}
+
+		return nil
+	},
+}
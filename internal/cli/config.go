@@ -101,12 +101,18 @@ var CmdConfigShow = &cobra.Command{
 			certInfo = color.BlueString("Present")
 		}
 
+		tokenInfo := color.CyanString("None")
+		if theConfig.Token != "" {
+			tokenInfo = color.BlueString("Present")
+		}
+
 		ui.Success().
 			WithTable("Key", "Value").
 			WithTableRow("Colorized Output", color.MagentaString("%t", theConfig.Colors)).
 			WithTableRow("Current Namespace", color.CyanString(theConfig.Org)).
 			WithTableRow("API User Name", color.BlueString(theConfig.User)).
 			WithTableRow("API Password", color.BlueString(theConfig.Password)).
+			WithTableRow("API Token", tokenInfo).
 			WithTableRow("API Url", color.BlueString(theConfig.API)).
 			WithTableRow("WSS Url", color.BlueString(theConfig.WSS)).
 			WithTableRow("Certificates", certInfo).
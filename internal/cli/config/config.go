@@ -27,6 +27,7 @@ type Config struct {
 	Org      string `mapstructure:"namespace"`
 	User     string `mapstructure:"user"`
 	Password string `mapstructure:"pass"`
+	Token    string `mapstructure:"token"`
 	API      string `mapstructure:"api"`
 	WSS      string `mapstructure:"wss"`
 	Certs    string `mapstructure:"certs"`
@@ -67,6 +68,7 @@ func LoadFrom(file string) (*Config, error) {
 	// Use empty defaults in viper to allow NeededOptions defaults to apply
 	v.SetDefault("user", "")
 	v.SetDefault("pass", "")
+	v.SetDefault("token", "")
 	v.SetDefault("api", "")
 	v.SetDefault("wss", "")
 	v.SetDefault("certs", "")
@@ -129,8 +131,8 @@ func LoadFrom(file string) (*Config, error) {
 // Generates a string representation of the configuration (for debugging)
 func (c *Config) String() string {
 	return fmt.Sprintf(
-		"namespace=(%s), user=(%s), pass=(%s), api=(%s), wss=(%s), color=(%v), @(%s)",
-		c.Org, c.User, c.Password, c.API, c.WSS, c.Colors, c.Location)
+		"namespace=(%s), user=(%s), pass=(%s), token=(%v), api=(%s), wss=(%s), color=(%v), @(%s)",
+		c.Org, c.User, c.Password, c.Token != "", c.API, c.WSS, c.Colors, c.Location)
 }
 
 // Save saves the Epinio config
@@ -138,6 +140,7 @@ func (c *Config) Save() error {
 	c.v.Set("namespace", c.Org)
 	c.v.Set("user", c.User)
 	c.v.Set("pass", c.Password)
+	c.v.Set("token", c.Token)
 	c.v.Set("api", c.API)
 	c.v.Set("wss", c.WSS)
 	c.v.Set("certs", c.Certs)
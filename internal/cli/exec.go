@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"github.com/epinio/epinio/internal/cli/usercmd"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	CmdAppExec.Flags().Int("instance", -1, "index of the instance to run in, counting from 0 as listed by 'epinio app show'. A negative index selects the first ready instance")
+}
+
+// CmdAppExec implements the command: epinio app exec
+var CmdAppExec = &cobra.Command{
+	Use:   "exec NAME [-- COMMAND [ARG...]]",
+	Short: "Run a command in an instance of the named application",
+	Long:  "Run the command, or a shell, in an instance of the named application, attached to the terminal",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		if dash := cmd.ArgsLenAtDash(); dash > 1 || (dash < 0 && len(args) > 1) {
+			return errors.New("the command has to follow '--'")
+		}
+
+		instance, err := cmd.Flags().GetInt("instance")
+		if err != nil {
+			return errors.Wrap(err, "could not read the instance")
+		}
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppExec(args[0], instance, args[1:])
+		if err != nil {
+			return errors.Wrap(err, "error running in the app")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: matchingAppsFinder,
+}
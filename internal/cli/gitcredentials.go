@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/epinio/epinio/internal/cli/usercmd"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdGitCredentials implements the command: epinio git-credentials
+var CmdGitCredentials = &cobra.Command{
+	Use:           "git-credentials",
+	Short:         "Epinio git credentials",
+	Long:          `Manage the credentials of private git repositories, for use by epinio push --git-credentials`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	Args:          cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cmd.Usage(); err != nil {
+			return err
+		}
+		return fmt.Errorf(`Unknown method "%s"`, args[0])
+	},
+}
+
+func init() {
+	createFlags := CmdGitCredentialsCreate.Flags()
+	createFlags.String("username", "", "user name for https access, optional")
+	createFlags.String("password-file", "", "file holding the password for https access, - for stdin")
+	createFlags.String("token-file", "", "file holding the token for https access, - for stdin")
+	createFlags.String("ssh-key-file", "", "file holding the private key for ssh access")
+	createFlags.String("known-hosts-file", "", "file holding the known hosts to verify the ssh host key with")
+	createFlags.Bool("insecure-skip-host-key", false, "do not verify the ssh host key")
+
+	CmdGitCredentials.AddCommand(CmdGitCredentialsCreate)
+	CmdGitCredentials.AddCommand(CmdGitCredentialsDelete)
+}
+
+// CmdGitCredentialsCreate implements the command: epinio git-credentials create
+var CmdGitCredentialsCreate = &cobra.Command{
+	Use:   "create NAME",
+	Short: "Creates git credentials in the targeted namespace",
+	Long: `Creates git credentials in the targeted namespace, from exactly one of password, token, or ssh key.
+The secrets are read from files, or stdin, never from the command line.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		req := models.GitCredentialsCreateRequest{Name: args[0]}
+
+		var err error
+		if req.Username, err = cmd.Flags().GetString("username"); err != nil {
+			return errors.Wrap(err, "could not read option --username")
+		}
+		if req.Password, err = flagFile(cmd, "password-file"); err != nil {
+			return err
+		}
+		if req.Token, err = flagFile(cmd, "token-file"); err != nil {
+			return err
+		}
+		if req.SSHKey, err = flagFile(cmd, "ssh-key-file"); err != nil {
+			return err
+		}
+		if req.KnownHosts, err = flagFile(cmd, "known-hosts-file"); err != nil {
+			return err
+		}
+		if req.InsecureSkipHostKey, err = cmd.Flags().GetBool("insecure-skip-host-key"); err != nil {
+			return errors.Wrap(err, "could not read option --insecure-skip-host-key")
+		}
+
+		// Passwords and tokens end with the line, not with its newline
+		req.Password = strings.TrimRight(req.Password, "\r\n")
+		req.Token = strings.TrimRight(req.Token, "\r\n")
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.GitCredentialsCreate(req)
+		if err != nil {
+			return errors.Wrap(err, "error creating git credentials")
+		}
+
+		return nil
+	},
+}
+
+// CmdGitCredentialsDelete implements the command: epinio git-credentials delete
+var CmdGitCredentialsDelete = &cobra.Command{
+	Use:   "delete NAME",
+	Short: "Deletes git credentials of the targeted namespace",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.GitCredentialsDelete(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error deleting git credentials")
+		}
+
+		return nil
+	},
+}
+
+// flagFile returns the contents of the file named by the option, or of
+// stdin for `-`. The result is empty if the option is not set.
+func flagFile(cmd *cobra.Command, name string) (string, error) {
+	file, err := cmd.Flags().GetString(name)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read option --%s", name)
+	}
+
+	var contents []byte
+	switch file {
+	case "":
+		return "", nil
+	case "-":
+		contents, err = ioutil.ReadAll(os.Stdin)
+	default:
+		contents, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read the file of option --%s", name)
+	}
+
+	return string(contents), nil
+}
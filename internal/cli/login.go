@@ -1 +1,105 @@
 package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/epinio/epinio/internal/cli/usercmd"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+func init() {
+	flags := CmdLogin.Flags()
+	flags.StringP("user", "u", "", "name of the user, asked for if not specified")
+	flags.Bool("password-stdin", false, "read the password of the user from stdin, instead of asking for it")
+}
+
+// CmdLogin implements the command: epinio login
+var CmdLogin = &cobra.Command{
+	Use:   "login",
+	Short: "Logs into the Epinio API",
+	Long:  "Requests an API token for the user and stores it in the configuration, in place of the password. The token is refreshed automatically while in use, and expires otherwise.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		username, err := cmd.Flags().GetString("user")
+		if err != nil {
+			return errors.Wrap(err, "could not read user parameter")
+		}
+		passwordStdin, err := cmd.Flags().GetBool("password-stdin")
+		if err != nil {
+			return errors.Wrap(err, "could not read password-stdin parameter")
+		}
+
+		password := ""
+		if passwordStdin {
+			if username == "" {
+				return errors.New("the user has to be specified when reading the password from stdin")
+			}
+			secret, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				return errors.Wrap(err, "could not read password")
+			}
+			password = strings.TrimRight(string(secret), "\r\n")
+		}
+
+		if username == "" {
+			cmd.Print("User: ")
+			username, err = bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil {
+				return errors.Wrap(err, "could not read user")
+			}
+			username = strings.TrimSpace(username)
+		}
+		if !passwordStdin {
+			cmd.Print("Password: ")
+			secret, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+			cmd.Println()
+			if err != nil {
+				return errors.Wrap(err, "could not read password")
+			}
+			password = string(secret)
+		}
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.Login(username, password)
+		if err != nil {
+			return errors.Wrap(err, "error logging in")
+		}
+
+		return nil
+	},
+}
+
+// CmdLogout implements the command: epinio logout
+var CmdLogout = &cobra.Command{
+	Use:   "logout",
+	Short: "Logs out of the Epinio API",
+	Long:  "Revokes the API token stored in the configuration, and removes it.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.Logout()
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("error logging out of %s", client.Config.API))
+		}
+
+		return nil
+	},
+}
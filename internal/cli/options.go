@@ -6,6 +6,7 @@ import (
 
 	v1 "github.com/epinio/epinio/internal/api/v1"
 	"github.com/epinio/epinio/internal/cli/usercmd"
+	"github.com/epinio/epinio/internal/manifest"
 	"github.com/epinio/epinio/pkg/api/core/v1/models"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -68,8 +69,115 @@ func envOption(cmd *cobra.Command) {
 	// nolint:errcheck // Unable to handle error in init block this will be called from
 }
 
-// appConfiguration processes the `--bind` and `--instances` options of
-// the command into a proper application configuration.
+// healthOption initializes the health check and rollout options for the provided command
+func healthOption(cmd *cobra.Command) {
+	cmd.Flags().String("health-check", "", "type of the health check: http, tcp, or none (default: tcp, or http with a path)")
+	cmd.Flags().String("health-check-path", "", "path requested by the http health check (default: "+models.DefaultHealthCheckPath+")")
+	cmd.Flags().Int32("health-check-port", 0, "port checked by the health check (default: 8080)")
+	cmd.Flags().Int32("health-check-timeout", 0, "seconds after which a health check fails (default: 1)")
+	cmd.Flags().Int32("health-check-initial-delay", 0, "seconds before the first liveness check of a new instance")
+	cmd.Flags().String("max-surge", "", "instances created above the desired number during a rollout, count or percentage (default: "+models.DefaultRolloutMaxSurge+")")
+	cmd.Flags().String("max-unavailable", "", "instances unavailable during a rollout, count or percentage (default: "+models.DefaultRolloutMaxUnavailable+")")
+}
+
+// resourcesOption initializes the compute resource options for the provided command
+func resourcesOption(cmd *cobra.Command) {
+	cmd.Flags().String("memory", "", "memory of each instance, like 512Mi, used as request and limit (0 removes)")
+	cmd.Flags().String("memory-limit", "", "memory limit of each instance, overrides the limit set by --memory (0 removes)")
+	cmd.Flags().String("cpu", "", "CPU requested by each instance, like 250m (0 removes)")
+	cmd.Flags().String("cpu-limit", "", "CPU limit of each instance (0 removes)")
+}
+
+// resourcesConfiguration processes the compute resource options of the
+// command. The result is nil when no such options were given. Options
+// not given keep the current setting.
+func resourcesConfiguration(cmd *cobra.Command) (*models.AppResources, error) {
+	flags := cmd.Flags()
+
+	if !flags.Changed("memory") && !flags.Changed("memory-limit") &&
+		!flags.Changed("cpu") && !flags.Changed("cpu-limit") {
+		return nil, nil
+	}
+
+	resources := &models.AppResources{}
+	var err error
+
+	if resources.MemoryRequest, err = flags.GetString("memory"); err != nil {
+		return nil, errors.Wrap(err, "failed to read option --memory")
+	}
+	if resources.MemoryLimit, err = flags.GetString("memory-limit"); err != nil {
+		return nil, errors.Wrap(err, "failed to read option --memory-limit")
+	}
+	if resources.CPURequest, err = flags.GetString("cpu"); err != nil {
+		return nil, errors.Wrap(err, "failed to read option --cpu")
+	}
+	if resources.CPULimit, err = flags.GetString("cpu-limit"); err != nil {
+		return nil, errors.Wrap(err, "failed to read option --cpu-limit")
+	}
+
+	if !flags.Changed("memory-limit") {
+		resources.MemoryLimit = resources.MemoryRequest
+	}
+
+	return resources, nil
+}
+
+// healthConfiguration processes the health check and rollout options of
+// the command. The results are nil when no such options were given.
+// Given health check options replace all health check settings, i.e.
+// the options not given select the defaults.
+func healthConfiguration(cmd *cobra.Command) (*models.HealthCheck, *models.Rollout, error) {
+	var healthCheck *models.HealthCheck
+	var rollout *models.Rollout
+
+	flags := cmd.Flags()
+
+	if flags.Changed("health-check") || flags.Changed("health-check-path") ||
+		flags.Changed("health-check-port") || flags.Changed("health-check-timeout") ||
+		flags.Changed("health-check-initial-delay") {
+
+		healthCheck = &models.HealthCheck{}
+		var err error
+
+		if healthCheck.Type, err = flags.GetString("health-check"); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read option --health-check")
+		}
+		if healthCheck.Path, err = flags.GetString("health-check-path"); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read option --health-check-path")
+		}
+		if healthCheck.Port, err = flags.GetInt32("health-check-port"); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read option --health-check-port")
+		}
+		if healthCheck.Timeout, err = flags.GetInt32("health-check-timeout"); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read option --health-check-timeout")
+		}
+		if healthCheck.InitialDelay, err = flags.GetInt32("health-check-initial-delay"); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read option --health-check-initial-delay")
+		}
+
+		if healthCheck.Type == "" && healthCheck.Path != "" {
+			healthCheck.Type = models.HealthCheckHTTP
+		}
+	}
+
+	if flags.Changed("max-surge") || flags.Changed("max-unavailable") {
+		rollout = &models.Rollout{}
+		var err error
+
+		if rollout.MaxSurge, err = flags.GetString("max-surge"); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read option --max-surge")
+		}
+		if rollout.MaxUnavailable, err = flags.GetString("max-unavailable"); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read option --max-unavailable")
+		}
+	}
+
+	return healthCheck, rollout, nil
+}
+
+// appConfiguration processes the `--bind`, `--instances`, `--env`,
+// health check, and compute resource options of the command into a
+// proper application configuration.
 func appConfiguration(cmd *cobra.Command) (models.ApplicationUpdateRequest, error) {
 	result := models.ApplicationUpdateRequest{}
 
@@ -86,6 +194,16 @@ func appConfiguration(cmd *cobra.Command) (models.ApplicationUpdateRequest, erro
 		return result, errors.Wrap(err, "failed to read option --bind")
 	}
 
+	healthCheck, rollout, err := healthConfiguration(cmd)
+	if err != nil {
+		return result, err
+	}
+
+	resources, err := resourcesConfiguration(cmd)
+	if err != nil {
+		return result, err
+	}
+
 	// From here on out errors cannot happen anymore. Just filling
 	// the structure with the extracted information.
 	if instances != nil {
@@ -95,6 +213,10 @@ func appConfiguration(cmd *cobra.Command) (models.ApplicationUpdateRequest, erro
 	result.Services = uniqueStrings(services)
 	sort.Strings(result.Services)
 
+	result.HealthCheck = healthCheck
+	result.Rollout = rollout
+	result.Resources = resources
+
 	assignments, err := cmd.Flags().GetStringSlice("env")
 	if err != nil {
 		return result, errors.Wrap(err, "failed to read option --env")
@@ -114,6 +236,65 @@ func appConfiguration(cmd *cobra.Command) (models.ApplicationUpdateRequest, erro
 	return result, nil
 }
 
+// manifestConfiguration processes the options of the command, like
+// appConfiguration, and combines the result with the configuration
+// declared by the manifest. Options override the manifest. The
+// environment variables are merged, with options overriding
+// manifest variables of the same name.
+func manifestConfiguration(cmd *cobra.Command, m manifest.ApplicationManifest) (models.ApplicationUpdateRequest, error) {
+	options, err := appConfiguration(cmd)
+	if err != nil {
+		return options, err
+	}
+
+	result := m.Configuration()
+
+	if options.Instances != nil {
+		result.Instances = options.Instances
+	}
+
+	if options.HealthCheck != nil {
+		result.HealthCheck = options.HealthCheck
+	}
+
+	if options.Rollout != nil {
+		result.Rollout = options.Rollout
+	}
+
+	if options.Resources != nil {
+		resources := options.Resources
+		if result.Resources != nil {
+			merged := result.Resources.Merge(*options.Resources)
+			resources = &merged
+		}
+		result.Resources = resources
+	}
+
+	if cmd.Flags().Changed("bind") {
+		result.Services = options.Services
+	} else {
+		result.Services = uniqueStrings(result.Services)
+		sort.Strings(result.Services)
+	}
+
+	for _, ev := range options.Environment {
+		replaced := false
+		for i := range result.Environment {
+			if result.Environment[i].Name == ev.Name {
+				result.Environment[i].Value = ev.Value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result.Environment = append(result.Environment, ev)
+		}
+	}
+	sort.Sort(result.Environment)
+
+	return result, nil
+}
+
 // instances checks if the user provided an instance count. If they didn't, then we'll
 // pass nil and either use the default or whatever is deployed in the cluster.
 func instances(cmd *cobra.Command) (*int32, error) {
@@ -149,3 +330,58 @@ func uniqueStrings(stringSlice []string) []string {
 	}
 	return list
 }
+
+// logOptions processes the log selection, filter, and output options of
+// the logs command
+func logOptions(cmd *cobra.Command) (usercmd.LogOptions, error) {
+	flags := cmd.Flags()
+	options := usercmd.LogOptions{}
+	var err error
+
+	if options.Since, err = flags.GetDuration("since"); err != nil {
+		return options, errors.Wrap(err, "failed to read option --since")
+	}
+	if options.Since < 0 {
+		return options, errors.New("option --since has to be a positive duration")
+	}
+
+	tail, err := flags.GetInt64("tail")
+	if err != nil {
+		return options, errors.Wrap(err, "failed to read option --tail")
+	}
+	if tail >= 0 {
+		options.Tail = &tail
+	}
+
+	if options.Include, err = flags.GetStringArray("include"); err != nil {
+		return options, errors.Wrap(err, "failed to read option --include")
+	}
+	if options.Exclude, err = flags.GetStringArray("exclude"); err != nil {
+		return options, errors.Wrap(err, "failed to read option --exclude")
+	}
+	if options.Timestamps, err = flags.GetBool("timestamps"); err != nil {
+		return options, errors.Wrap(err, "failed to read option --timestamps")
+	}
+
+	instance, err := flags.GetInt("instance")
+	if err != nil {
+		return options, errors.Wrap(err, "failed to read option --instance")
+	}
+	if instance >= 0 {
+		options.Instance = &instance
+	}
+
+	output, err := flags.GetString("output")
+	if err != nil {
+		return options, errors.Wrap(err, "failed to read option --output")
+	}
+	switch output {
+	case "text":
+	case "json":
+		options.JSON = true
+	default:
+		return options, errors.Errorf("bad option --output '%s', expected one of: text, json", output)
+	}
+
+	return options, nil
+}
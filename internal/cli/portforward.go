@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/epinio/epinio/internal/cli/usercmd"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	flags := CmdAppPortForward.Flags()
+	flags.Int("instance", -1, "index of the instance to forward to, counting from 0 as listed by 'epinio app show'. A negative index selects the first ready instance")
+	flags.String("address", "localhost", "local address to listen on")
+}
+
+// CmdAppPortForward implements the command: epinio app port-forward
+var CmdAppPortForward = &cobra.Command{
+	Use:   "port-forward NAME [LOCAL_PORT:]REMOTE_PORT",
+	Short: "Forward a local port to the named application",
+	Long: `Forward the connections to the local port to the remote port of an instance of the named application, through the API server.
+A local port 0 selects a free port. Without a local port the remote port is used.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		localPort, remotePort, err := parsePorts(args[1])
+		if err != nil {
+			return err
+		}
+
+		instance, err := cmd.Flags().GetInt("instance")
+		if err != nil {
+			return errors.Wrap(err, "could not read the instance")
+		}
+		address, err := cmd.Flags().GetString("address")
+		if err != nil {
+			return errors.Wrap(err, "could not read the address")
+		}
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppPortForward(args[0], instance, address, localPort, remotePort)
+		if err != nil {
+			return errors.Wrap(err, "error forwarding to the app")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: matchingAppsFinder,
+}
+
+// parsePorts returns the local and remote ports of the
+// [LOCAL_PORT:]REMOTE_PORT argument
+func parsePorts(spec string) (int, int, error) {
+	local, remote := "", spec
+	if i := strings.Index(spec, ":"); i >= 0 {
+		local, remote = spec[:i], spec[i+1:]
+	}
+
+	remotePort, err := strconv.Atoi(remote)
+	if err != nil || remotePort < 1 || remotePort > 65535 {
+		return 0, 0, fmt.Errorf("bad remote port '%s', expected a number from 1 to 65535", remote)
+	}
+
+	if local == "" {
+		return remotePort, remotePort, nil
+	}
+
+	localPort, err := strconv.Atoi(local)
+	if err != nil || localPort < 0 || localPort > 65535 {
+		return 0, 0, fmt.Errorf("bad local port '%s', expected a number from 0 to 65535", local)
+	}
+
+	return localPort, remotePort, nil
+}
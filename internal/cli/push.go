@@ -2,8 +2,11 @@ package cli
 
 import (
 	"os"
+	"path/filepath"
 
 	"github.com/epinio/epinio/internal/cli/usercmd"
+	"github.com/epinio/epinio/internal/manifest"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -11,20 +14,35 @@ import (
 var ()
 
 func init() {
-	CmdPush.Flags().String("builder-image", "paketobuildpacks/builder:full", "paketo builder image to use for staging")
-	CmdPush.Flags().String("git", "", "git revision of sources. PATH becomes repository location")
+	CmdPush.Flags().String("builder-image", "", "paketo builder image to use for staging with buildpacks (default "+models.DefaultBuilderImage+")")
+	CmdPush.Flags().String("staging-strategy", "", "staging strategy: buildpacks, dockerfile, or image (default: chosen by the server, per the sources)")
+	CmdPush.Flags().String("git", "", "git revision of sources, i.e. branch, tag, or commit. PATH becomes repository location")
+	CmdPush.Flags().String("git-credentials", "", "name of the credentials for the git repository, see `epinio git-credentials create`")
 	CmdPush.Flags().String("docker-image-url", "", "docker image url for the app workload image")
+	CmdPush.Flags().StringP("manifest", "m", "", "manifest file to use (default: "+manifest.DefaultName+" in the application sources)")
+	CmdPush.Flags().Bool("dry-run", false, "list the application sources to upload, and their total size, without pushing")
 
 	bindOption(CmdPush)
 	envOption(CmdPush)
 	instancesOption(CmdPush)
+	healthOption(CmdPush)
+	resourcesOption(CmdPush)
 }
 
 // CmdPush implements the command: epinio app push
 var CmdPush = &cobra.Command{
-	Use:   "push NAME [URL|PATH_TO_APPLICATION_SOURCES]",
+	Use:   "push [NAME] [URL|PATH_TO_APPLICATION_SOURCES]",
 	Short: "Push an application from the specified directory, or the current working directory",
-	Args:  cobra.RangeArgs(1, 2),
+	Long: `Push an application from the specified directory, or the current working directory.
+
+The application's configuration is read from the manifest, if present.
+Options override the settings of the manifest. The NAME can be left out
+when the manifest declares it.
+
+Files matching the patterns of a .epinioignore file (gitignore syntax)
+are not uploaded. A directory without .epinioignore uses the patterns of
+its .gitignore instead. Use --dry-run to see the sources to upload.`,
+	Args: cobra.RangeArgs(0, 2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
 
@@ -38,11 +56,20 @@ var CmdPush = &cobra.Command{
 			return errors.Wrap(err, "could not read option --git")
 		}
 
+		gitCredentials, err := cmd.Flags().GetString("git-credentials")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --git-credentials")
+		}
+
 		dockerImageURL, err := cmd.Flags().GetString("docker-image-url")
 		if err != nil {
 			return errors.Wrap(err, "could not read option --docker-image-url")
 		}
 
+		if gitCredentials != "" && gitRevision == "" {
+			return errors.New("git credentials require a git revision")
+		}
+
 		if gitRevision != "" && dockerImageURL != "" {
 			return errors.Wrap(err, "cannot use both, git and docker image url")
 		}
@@ -52,14 +79,32 @@ var CmdPush = &cobra.Command{
 			return errors.Wrap(err, "could not read option --builder-image")
 		}
 
+		strategy, err := cmd.Flags().GetString("staging-strategy")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --staging-strategy")
+		}
+
+		switch strategy {
+		case "", models.StagingBuildpacks, models.StagingDockerfile:
+			if dockerImageURL != "" && strategy != "" {
+				return errors.New("a docker image url can only be staged with the image strategy")
+			}
+		case models.StagingImage:
+			if dockerImageURL == "" {
+				return errors.New("the image strategy requires a docker image url")
+			}
+		default:
+			return errors.Errorf("unknown staging strategy '%s'", strategy)
+		}
+
 		// Syntax:
-		// 1. push NAME
+		// 1. push [NAME]
 		// 2. push NAME PATH
 		// 3. push NAME URL --git REV
-		// 4. push NAME --docker-image-url URL
+		// 4. push NAME --docker-image-url URL [--staging-strategy image]
 
 		var path string
-		if len(args) == 1 {
+		if len(args) < 2 {
 			if gitRevision != "" {
 				// Missing argument is user error. Show usage
 				cmd.SilenceUsage = false
@@ -74,6 +119,62 @@ var CmdPush = &cobra.Command{
 			path = args[1]
 		}
 
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --dry-run")
+		}
+		if dryRun {
+			if gitRevision != "" || dockerImageURL != "" {
+				return errors.New("a dry run requires local sources")
+			}
+			if _, err := os.Stat(path); err != nil {
+				// Path issue is user error. Show usage
+				cmd.SilenceUsage = false
+				return errors.Wrap(err, "path not accessible")
+			}
+			return client.PushSources(path)
+		}
+
+		// The default manifest is searched for in the local
+		// application sources, or the working directory.
+		manifestPath, err := cmd.Flags().GetString("manifest")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --manifest")
+		}
+		if manifestPath == "" {
+			manifestDir := path
+			if gitRevision != "" || dockerImageURL != "" {
+				manifestDir, err = os.Getwd()
+				if err != nil {
+					return errors.Wrap(err, "working directory not accessible")
+				}
+			}
+			manifestPath = filepath.Join(manifestDir, manifest.DefaultName)
+		} else if _, err := os.Stat(manifestPath); err != nil {
+			// Path issue is user error. Show usage
+			cmd.SilenceUsage = false
+			return errors.Wrap(err, "manifest not accessible")
+		}
+
+		m, err := manifest.Get(manifestPath)
+		if err != nil {
+			return errors.Wrap(err, "unable to read the manifest")
+		}
+
+		name := m.Name
+		if len(args) > 0 {
+			name = args[0]
+		}
+		if name == "" {
+			// Missing argument is user error. Show usage
+			cmd.SilenceUsage = false
+			return errors.New("app name missing, neither given as argument, nor in the manifest")
+		}
+
+		if m.BuilderImage != "" && !cmd.Flags().Changed("builder-image") {
+			builderImage = m.BuilderImage
+		}
+
 		if dockerImageURL != "" {
 			path = ""
 		}
@@ -86,18 +187,20 @@ var CmdPush = &cobra.Command{
 			}
 		}
 
-		ac, err := appConfiguration(cmd)
+		ac, err := manifestConfiguration(cmd, m)
 		if err != nil {
 			return errors.Wrap(err, "unable to get app configuration")
 		}
 
 		params := usercmd.PushParams{
-			Name:          args[0],
-			GitRev:        gitRevision,
-			Docker:        dockerImageURL,
-			Path:          path,
-			BuilderImage:  builderImage,
-			Configuration: ac,
+			Name:           name,
+			GitRev:         gitRevision,
+			GitCredentials: gitCredentials,
+			Docker:         dockerImageURL,
+			Path:           path,
+			BuilderImage:   builderImage,
+			Strategy:       strategy,
+			Configuration:  ac,
 		}
 
 		err = client.Push(cmd.Context(), params)
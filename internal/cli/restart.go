@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"github.com/epinio/epinio/internal/cli/usercmd"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdAppRestart implements the command: epinio app restart
+var CmdAppRestart = &cobra.Command{
+	Use:   "restart NAME",
+	Short: "Restart the named application",
+	Long:  "Replace the instances of the named application, per its rollout strategy, without redeploying it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppRestart(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error restarting the app")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: matchingAppsFinder,
+}
+
+// CmdAppStop implements the command: epinio app stop
+var CmdAppStop = &cobra.Command{
+	Use:   "stop NAME",
+	Short: "Stop the named application",
+	Long:  "Scale the named application to zero instances. Its desired instances are kept, for 'epinio app start'",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppStop(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error stopping the app")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: matchingAppsFinder,
+}
+
+// CmdAppStart implements the command: epinio app start
+var CmdAppStart = &cobra.Command{
+	Use:   "start NAME",
+	Short: "Start the named application",
+	Long:  "Scale the named, stopped application back to its desired instances",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppStart(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error starting the app")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: matchingAppsFinder,
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/epinio/epinio/helpers/kubernetes/config"
 	"github.com/epinio/epinio/helpers/tracelog"
 	pconfig "github.com/epinio/epinio/internal/cli/config"
+	"github.com/epinio/epinio/internal/cli/usercmd"
 	"github.com/epinio/epinio/internal/duration"
 	"github.com/epinio/epinio/internal/version"
 	"github.com/kyokomi/emoji"
@@ -34,6 +35,12 @@ var rootCmd = &cobra.Command{
 	Long:          `epinio cli is the official command line interface for Epinio PaaS `,
 	Version:       version.Version,
 	SilenceErrors: true,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Shell completion has to be quick, see usercmd.DisableTokenRefresh
+		if cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd {
+			usercmd.DisableTokenRefresh()
+		}
+	},
 }
 
 // Execute executes the root command.
@@ -72,6 +79,7 @@ func init() {
 
 	config.AddEnvToUsage(rootCmd, argToEnv)
 
+	rootCmd.AddCommand(CmdAdmin)
 	rootCmd.AddCommand(CmdCompletion)
 	rootCmd.AddCommand(CmdConfig)
 	rootCmd.AddCommand(CmdInstall)
@@ -79,12 +87,16 @@ func init() {
 	rootCmd.AddCommand(CmdInstallCertManager)
 	rootCmd.AddCommand(CmdUninstall)
 	rootCmd.AddCommand(CmdInfo)
+	rootCmd.AddCommand(CmdGitCredentials)
+	rootCmd.AddCommand(CmdLogin)
+	rootCmd.AddCommand(CmdLogout)
 	rootCmd.AddCommand(CmdNamespace)
 	rootCmd.AddCommand(CmdPush)
 	rootCmd.AddCommand(CmdApp)
 	rootCmd.AddCommand(CmdTarget)
 	rootCmd.AddCommand(CmdService)
 	rootCmd.AddCommand(CmdServer)
+	rootCmd.AddCommand(CmdUser)
 	rootCmd.AddCommand(cmdVersion)
 	// Hidden command providing developer tools
 	rootCmd.AddCommand(CmdDebug)
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/epinio/epinio/internal/cli/usercmd"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdAppRoute implements the command: epinio app route
+var CmdAppRoute = &cobra.Command{
+	Use:           "route",
+	Short:         "Epinio application routes",
+	Long:          `Manage the routes of epinio applications`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	Args:          cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cmd.Usage(); err != nil {
+			return err
+		}
+		return fmt.Errorf(`Unknown method "%s"`, args[0])
+	},
+}
+
+func init() {
+	CmdAppRoute.AddCommand(CmdRouteAdd)
+	CmdAppRoute.AddCommand(CmdRouteList)
+	CmdAppRoute.AddCommand(CmdRouteRemove)
+}
+
+// CmdRouteList implements the command: epinio app route list
+var CmdRouteList = &cobra.Command{
+	Use:   "list APPNAME",
+	Short: "Lists application routes",
+	Long:  "Lists the routes of the named application",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.RouteList(cmd.Context(), args[0])
+		if err != nil {
+			return errors.Wrap(err, "error listing app routes")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: matchingAppsFinder,
+}
+
+// CmdRouteAdd implements the command: epinio app route add
+var CmdRouteAdd = &cobra.Command{
+	Use:   "add APPNAME HOST[/PATH]",
+	Short: "Add application route",
+	Long:  "Add a route to the named application. A certificate is created for a new host.",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.RouteAdd(cmd.Context(), args[0], args[1])
+		if err != nil {
+			return errors.Wrap(err, "error adding app route")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: matchingAppsFinder,
+}
+
+// CmdRouteRemove implements the command: epinio app route remove
+var CmdRouteRemove = &cobra.Command{
+	Use:   "remove APPNAME HOST[/PATH]",
+	Short: "Remove application route",
+	Long:  "Remove a route from the named application. Removing the last route restores the default route.",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.RouteRemove(cmd.Context(), args[0], args[1])
+		if err != nil {
+			return errors.Wrap(err, "error removing app route")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: matchingAppsFinder,
+}
+
+// matchingAppsFinder completes the first argument of a command with the
+// names of the applications in the targeted org.
+func matchingAppsFinder(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	app, err := usercmd.New()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	matches := app.AppsMatching(context.Background(), toComplete)
+
+	return matches, cobra.ShellCompDirectiveNoFileComp
+}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -13,13 +14,17 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/epinio/epinio/deployments"
 	"github.com/epinio/epinio/helpers/termui"
 	"github.com/epinio/epinio/helpers/tracelog"
 	apiv1 "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/application"
 	"github.com/epinio/epinio/internal/filesystem"
+	"github.com/epinio/epinio/internal/jobs"
 	"github.com/epinio/epinio/internal/web"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
 	"github.com/go-logr/logr"
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -40,6 +45,22 @@ func init() {
 	flags.Bool("use-internal-registry-node-port", true, "(USE_INTERNAL_REGISTRY_NODE_PORT) Use the internal registry via a node port")
 	viper.BindPFlag("use-internal-registry-node-port", flags.Lookup("use-internal-registry-node-port"))
 	viper.BindEnv("use-internal-registry-node-port", "USE_INTERNAL_REGISTRY_NODE_PORT")
+
+	flags.Int("job-workers", 4, "(JOB_WORKERS) The number of workers running background jobs, like the import of sources from git")
+	viper.BindPFlag("job-workers", flags.Lookup("job-workers"))
+	viper.BindEnv("job-workers", "JOB_WORKERS")
+
+	flags.Int("blob-retention", models.DefaultBlobRetention, "(BLOB_RETENTION) The number of source blobs kept per application")
+	viper.BindPFlag("blob-retention", flags.Lookup("blob-retention"))
+	viper.BindEnv("blob-retention", "BLOB_RETENTION")
+
+	flags.Int64("max-upload-size", models.DefaultMaxUploadSize, "(MAX_UPLOAD_SIZE) The maximal size of uploaded application sources, in bytes. Uploads in progress are kept on the server's disk")
+	viper.BindPFlag("max-upload-size", flags.Lookup("max-upload-size"))
+	viper.BindEnv("max-upload-size", "MAX_UPLOAD_SIZE")
+
+	flags.Duration("blob-gc-interval", time.Hour, "(BLOB_GC_INTERVAL) The interval between removals of expired and orphaned source blobs. Zero disables them")
+	viper.BindPFlag("blob-gc-interval", flags.Lookup("blob-gc-interval"))
+	viper.BindEnv("blob-gc-interval", "BLOB_GC_INTERVAL")
 }
 
 // CmdServer implements the command: epinio server
@@ -49,6 +70,11 @@ var CmdServer = &cobra.Command{
 	Long:  "This command starts the Epinio server. `epinio install` ensures the server is running inside your cluster. Normally you don't need to run this command manually.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
+
+		if workers := viper.GetInt("job-workers"); workers < 1 {
+			return fmt.Errorf("bad value %d for --job-workers, at least one worker is required", workers)
+		}
+
 		httpServerWg := &sync.WaitGroup{}
 		httpServerWg.Add(1)
 		port := viper.GetInt("port")
@@ -75,9 +101,16 @@ func startEpinioServer(wg *sync.WaitGroup, port int, _ *termui.UI, logger logr.L
 	elements := strings.Split(listener.Addr().String(), ":")
 	listeningPort := elements[len(elements)-1]
 
+	jobs.Start(context.Background(), viper.GetInt("job-workers"))
+
+	if interval := viper.GetDuration("blob-gc-interval"); interval > 0 {
+		go application.CollectBlobs(context.Background(), interval, viper.GetInt("blob-retention"))
+	}
+
 	http.Handle("/api/v1/", loggingHandler(apiv1.Router(), logger))
 	http.Handle("/ready", ReadyRouter())
-	http.Handle("/", loggingHandler(web.Router(), logger))
+	// The dashboard shows all namespaces, thus is for admins only.
+	http.Handle("/", loggingHandler(apiv1.Authenticate(apiv1.AdminOnly(web.Router())), logger))
 	// Static files
 	var assetsDir http.FileSystem
 	if os.Getenv("LOCAL_FILESYSTEM") == "true" {
@@ -117,7 +150,7 @@ func loggingHandler(h http.Handler, logger logr.Logger) http.Handler {
 		log := logger.WithName(id).WithValues(
 			"method", r.Method,
 			"uri", r.URL.String(),
-			"user", r.Header.Get("X-Webauth-User"),
+			"user", requestUser(r),
 		)
 
 		// add our logger
@@ -138,6 +171,15 @@ func loggingHandler(h http.Handler, logger logr.Logger) http.Handler {
 	})
 }
 
+// requestUser returns the name of the user claimed by the basic auth
+// credentials of the request, for logging. The credentials are checked
+// later, by the authentication middleware. Requests authenticated by
+// token are logged without user.
+func requestUser(r *http.Request) string {
+	username, _, _ := r.BasicAuth()
+	return username
+}
+
 // logRequest is the logging backend for requests
 func logRequest(r *http.Request, log logr.Logger) {
 	if log.V(15).Enabled() {
@@ -11,8 +11,15 @@ import (
 
 func init() {
 	CmdServiceDelete.Flags().Bool("unbind", false, "Unbind from applications before deleting")
+	CmdServiceCreateCatalog.Flags().String("params", "", "Parameters for the broker, as JSON object")
+	CmdServiceUpdate.Flags().StringArray("set", []string{}, "Parameter to add or change, as KEY=VALUE")
+	CmdServiceUpdate.Flags().StringArray("unset", []string{}, "Parameter to remove")
+	CmdServiceUpdate.Flags().Bool("one-at-a-time", false, "Restart the bound applications one after the other")
 	CmdService.AddCommand(CmdServiceShow)
 	CmdService.AddCommand(CmdServiceCreate)
+	CmdService.AddCommand(CmdServiceCreateCatalog)
+	CmdService.AddCommand(CmdServiceCatalog)
+	CmdService.AddCommand(CmdServiceUpdate)
 	CmdService.AddCommand(CmdServiceDelete)
 	CmdService.AddCommand(CmdServiceBind)
 	CmdService.AddCommand(CmdServiceUnbind)
@@ -76,6 +83,47 @@ var CmdServiceCreate = &cobra.Command{
 	RunE: ServiceCreate,
 }
 
+// CmdServiceCreateCatalog implements the command: epinio service create-catalog
+var CmdServiceCreateCatalog = &cobra.Command{
+	Use:   "create-catalog NAME CLASS PLAN",
+	Short: "Create a service from the catalog",
+	Long:  `Create service by name, provisioned by the broker of the class, with the plan.`,
+	Args:  cobra.ExactArgs(3),
+	RunE:  ServiceCreateCatalog,
+}
+
+// CmdServiceCatalog implements the command: epinio service catalog
+var CmdServiceCatalog = &cobra.Command{
+	Use:   "catalog",
+	Short: "Lists the service catalog",
+	Long:  `List the service classes and plans offered by the brokers.`,
+	Args:  cobra.ExactArgs(0),
+	RunE:  ServiceCatalog,
+}
+
+// CmdServiceUpdate implements the command: epinio service update
+var CmdServiceUpdate = &cobra.Command{
+	Use:   "update NAME",
+	Short: "Update a service",
+	Long:  `Change the parameters of the named service, and restart the applications bound to it.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  ServiceUpdate,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		epinioClient, err := usercmd.New()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		matches := epinioClient.ServiceMatching(context.Background(), toComplete)
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
 // CmdServiceDelete implements the command: epinio service delete
 var CmdServiceDelete = &cobra.Command{
 	Use:   "delete NAME",
@@ -218,6 +266,81 @@ func ServiceCreate(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// ServiceCreateCatalog is the backend of command: epinio service create-catalog
+func ServiceCreateCatalog(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	params, err := cmd.Flags().GetString("params")
+	if err != nil {
+		return errors.Wrap(err, "error reading option --params")
+	}
+
+	client, err := usercmd.New()
+	if err != nil {
+		return errors.Wrap(err, "error initializing cli")
+	}
+
+	err = client.CreateCatalogService(args[0], args[1], args[2], params)
+	if err != nil {
+		return errors.Wrap(err, "error creating service")
+	}
+
+	return nil
+}
+
+// ServiceCatalog is the backend of command: epinio service catalog
+func ServiceCatalog(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	client, err := usercmd.New()
+	if err != nil {
+		return errors.Wrap(err, "error initializing cli")
+	}
+
+	err = client.ServiceCatalog()
+	if err != nil {
+		return errors.Wrap(err, "error listing the service catalog")
+	}
+
+	return nil
+}
+
+// ServiceUpdate is the backend of command: epinio service update
+func ServiceUpdate(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	set, err := cmd.Flags().GetStringArray("set")
+	if err != nil {
+		return errors.Wrap(err, "error reading option --set")
+	}
+
+	unset, err := cmd.Flags().GetStringArray("unset")
+	if err != nil {
+		return errors.Wrap(err, "error reading option --unset")
+	}
+
+	sequential, err := cmd.Flags().GetBool("one-at-a-time")
+	if err != nil {
+		return errors.Wrap(err, "error reading option --one-at-a-time")
+	}
+
+	if len(set) == 0 && len(unset) == 0 {
+		return errors.New("nothing to update, use --set or --unset")
+	}
+
+	client, err := usercmd.New()
+	if err != nil {
+		return errors.Wrap(err, "error initializing cli")
+	}
+
+	err = client.UpdateService(args[0], set, unset, sequential)
+	if err != nil {
+		return errors.Wrap(err, "error updating service")
+	}
+
+	return nil
+}
+
 // ServiceDelete is the backend of command: epinio service delete
 func ServiceDelete(cmd *cobra.Command, args []string) error {
 	cmd.SilenceUsage = true
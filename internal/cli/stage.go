@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/epinio/epinio/internal/cli/usercmd"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdAppStage implements the command: epinio app stage
+var CmdAppStage = &cobra.Command{
+	Use:           "stage",
+	Short:         "Epinio application staging",
+	Long:          `Manage the staging of epinio applications`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	Args:          cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cmd.Usage(); err != nil {
+			return err
+		}
+		return fmt.Errorf(`Unknown method "%s"`, args[0])
+	},
+}
+
+func init() {
+	CmdAppStage.AddCommand(CmdStageCancel)
+}
+
+// CmdStageCancel implements the command: epinio app stage cancel
+var CmdStageCancel = &cobra.Command{
+	Use:   "cancel NAME",
+	Short: "Cancel the staging of an application",
+	Long:  "Cancel the staging run in flight for the named application, and remove its pods",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.StageCancel(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error cancelling the staging")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: matchingAppsFinder,
+}
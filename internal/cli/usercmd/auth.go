@@ -0,0 +1,131 @@
+package usercmd
+
+import (
+	"time"
+
+	"github.com/epinio/epinio/internal/auth"
+	"github.com/epinio/epinio/internal/duration"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+)
+
+// Login requests a token for the credentials, and stores it in the
+// configuration, in place of any stored password.
+func (c *EpinioClient) Login(username, password string) error {
+	log := c.Log.WithName("Login").WithValues("User", username)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("User", username).
+		WithStringValue("API", c.Config.API).
+		Msg("Logging in...")
+
+	resp, err := c.API.AuthToken(models.AuthTokenRequest{
+		Username: username,
+		Password: password,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Config.User = username
+	c.Config.Password = ""
+	c.Config.Token = resp.Token
+	err = c.Config.Save()
+	if err != nil {
+		return err
+	}
+
+	c.API.SetToken(resp.Token)
+
+	c.ui.Success().
+		WithStringValue("Expires", resp.Expires.Local().Format(time.RFC1123)).
+		Msg("Logged in.")
+
+	return nil
+}
+
+// Logout revokes the stored token, and removes it from the configuration
+func (c *EpinioClient) Logout() error {
+	log := c.Log.WithName("Logout")
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().Msg("Logging out...")
+
+	if c.Config.Token == "" {
+		c.ui.Exclamation().Msg("Not logged in.")
+		return nil
+	}
+
+	// Expired tokens cannot be used anymore, and need no revocation.
+	claims, err := auth.ParseToken(c.Config.Token)
+	if err == nil && time.Now().Before(claims.ExpiresAt()) {
+		_, err := c.API.AuthTokenRevoke()
+		if err != nil {
+			return err
+		}
+	}
+
+	c.Config.Token = ""
+	err = c.Config.Save()
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Logged out.")
+
+	return nil
+}
+
+// tokenRefresh enables the refresh of the stored token by the clients of
+// New, see refreshToken.
+var tokenRefresh = true
+
+// DisableTokenRefresh keeps the clients of New from refreshing the stored
+// token, e.g. for shell completion, which has to be quick, and free of
+// side effects.
+func DisableTokenRefresh() {
+	tokenRefresh = false
+}
+
+// refreshToken replaces the stored token with a fresh one when it is
+// about to expire. It is run before the first request of the client to
+// the API server. Failures are not fatal, the old token is kept. An
+// expired token cannot be refreshed anymore, and requires a new login.
+func (c *EpinioClient) refreshToken() {
+	log := c.Log.WithName("RefreshToken")
+
+	if c.Config.Token == "" {
+		return
+	}
+
+	claims, err := auth.ParseToken(c.Config.Token)
+	if err != nil {
+		log.Info("bad token", "error", err.Error())
+		return
+	}
+
+	remaining := time.Until(claims.ExpiresAt())
+	if remaining <= 0 || remaining > duration.TokenRefresh() {
+		return
+	}
+
+	log.Info("refresh", "remaining", remaining.String())
+
+	resp, err := c.API.AuthTokenRefresh()
+	if err != nil {
+		log.Info("refresh failed", "error", err.Error())
+		return
+	}
+
+	// The server revoked the old token, it is unusable for the remainder
+	// of the command as well.
+	c.API.SetToken(resp.Token)
+
+	c.Config.Token = resp.Token
+	err = c.Config.Save()
+	if err != nil {
+		log.Info("saving the refreshed token failed", "error", err.Error())
+	}
+}
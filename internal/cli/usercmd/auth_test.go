@@ -0,0 +1,91 @@
+package usercmd_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"time"
+
+	"github.com/epinio/epinio/internal/auth"
+	"github.com/epinio/epinio/internal/cli/config"
+	"github.com/epinio/epinio/internal/cli/usercmd"
+	"github.com/spf13/viper"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("token refresh", func() {
+	var (
+		server     *httptest.Server
+		dir        string
+		configFile string
+		oldToken   string
+		refreshes  []string
+		requests   []string
+	)
+
+	BeforeEach(func() {
+		var err error
+		oldToken, err = auth.SignToken([]byte("key"), auth.TokenClaims{
+			ID:       "old",
+			Username: "dev",
+			Expires:  time.Now().Add(time.Hour).Unix(),
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		refreshes = []string{}
+		requests = []string{}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" || r.URL.Path != "/api/v1/auth/token/refresh" {
+				requests = append(requests, r.Header.Get("Authorization"))
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			refreshes = append(refreshes, r.Header.Get("Authorization"))
+			fmt.Fprint(w, `{"token":"fresh"}`)
+		}))
+
+		dir, err = ioutil.TempDir("", "epinio-config")
+		Expect(err).ToNot(HaveOccurred())
+		configFile = path.Join(dir, "config.yaml")
+		Expect(ioutil.WriteFile(configFile, []byte(fmt.Sprintf("namespace: workspace\napi: %s\nwss: %s\ntoken: %s\n",
+			server.URL, "ws"+server.URL[len("http"):], oldToken)), 0600)).To(Succeed())
+
+		viper.Set("config-file", configFile)
+		usercmd.ClearMemoization()
+	})
+
+	AfterEach(func() {
+		viper.Set("config-file", "")
+		usercmd.ClearMemoization()
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	It("refreshes nothing without a request", func() {
+		_, err := usercmd.New()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(refreshes).To(BeEmpty())
+	})
+
+	It("switches the client to the fresh token before the first request, and saves it", func() {
+		client, err := usercmd.New()
+		Expect(err).ToNot(HaveOccurred())
+
+		// The server knows the refresh only
+		_, _ = client.API.Info()
+
+		Expect(refreshes).To(Equal([]string{"Bearer " + oldToken}))
+		Expect(requests).To(Equal([]string{"Bearer fresh"}))
+		Expect(client.API.AuthorizationHeader()).To(Equal("Bearer fresh"))
+
+		cfg, err := config.LoadFrom(configFile)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cfg.Token).To(Equal("fresh"))
+	})
+})
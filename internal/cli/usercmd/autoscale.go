@@ -0,0 +1,62 @@
+package usercmd
+
+import (
+	"fmt"
+
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+)
+
+// AppAutoscale enables, or changes, the autoscaling of the named application
+func (c *EpinioClient) AppAutoscale(appName string, autoscale models.AppAutoscale) error {
+	log := c.Log.WithName("AppAutoscale").WithValues("Namespace", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	autoscale = autoscale.WithDefaults()
+
+	c.ui.Note().
+		WithStringValue("Namespace", c.Config.Org).
+		WithStringValue("Application", appName).
+		WithIntValue("Minimum Instances", int(autoscale.Min)).
+		WithIntValue("Maximum Instances", int(autoscale.Max)).
+		WithStringValue("Target CPU", fmt.Sprintf("%d%%", autoscale.CPUPercent)).
+		Msg("Autoscale application")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	_, err := c.API.AppAutoscale(autoscale, c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Application autoscaled")
+
+	return nil
+}
+
+// AppAutoscaleDisable disables the autoscaling of the named application
+func (c *EpinioClient) AppAutoscaleDisable(appName string) error {
+	log := c.Log.WithName("AppAutoscaleDisable").WithValues("Namespace", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Namespace", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Disable application autoscaling")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	_, err := c.API.AppAutoscaleDisable(c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Autoscaling disabled, the application returns to its configured instances")
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package usercmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+)
+
+// Blobs lists the source blobs in the S3 storage, with their state per
+// the retention policy of the server
+func (c *EpinioClient) Blobs() error {
+	log := c.Log.WithName("Blobs")
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().Msg("Listing source blobs")
+
+	blobs, err := c.API.Blobs()
+	if err != nil {
+		return err
+	}
+
+	c.showBlobs(blobs, "Source Blobs:")
+
+	return nil
+}
+
+// BlobsPrune removes the expired and orphaned source blobs from the S3
+// storage. A positive keep overrides the retention of the server.
+func (c *EpinioClient) BlobsPrune(keep int) error {
+	log := c.Log.WithName("BlobsPrune").WithValues("Keep", keep)
+	log.Info("start")
+	defer log.Info("return")
+
+	msg := c.ui.Note()
+	if keep > 0 {
+		msg = msg.WithIntValue("Keep", keep)
+	}
+	msg.Msg("Pruning source blobs")
+
+	response, err := c.API.BlobsPrune(models.BlobPruneRequest{Keep: keep})
+	if err != nil {
+		return err
+	}
+
+	if len(response.Removed) == 0 {
+		c.ui.Exclamation().Msg("No blobs to prune")
+		return nil
+	}
+
+	c.showBlobs(response.Removed, "Removed Blobs:")
+
+	c.ui.Success().
+		WithIntValue("Removed", len(response.Removed)).
+		WithStringValue("Freed", fmt.Sprintf("%d bytes", response.Freed)).
+		Msg("Source blobs pruned")
+
+	return nil
+}
+
+// showBlobs reports the blobs as a table
+func (c *EpinioClient) showBlobs(blobs models.BlobList, title string) {
+	msg := c.ui.Success().WithTable("Namespace", "Application", "Blob", "Size", "Created", "User", "State")
+
+	for _, blob := range blobs {
+		msg = msg.WithTableRow(blob.App.Org, blob.App.Name, blob.ID,
+			fmt.Sprintf("%d", blob.Size), blob.Created.Format(time.RFC3339),
+			blob.Username, blob.State)
+	}
+
+	msg.Msg(title)
+}
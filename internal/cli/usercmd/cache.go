@@ -0,0 +1,178 @@
+package usercmd
+
+import (
+	"sort"
+	"time"
+
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+)
+
+// AppCache shows the settings of the named application's build cache, and
+// the claim holding it
+func (c *EpinioClient) AppCache(appName string) error {
+	log := c.Log.WithName("AppCache").WithValues("Namespace", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Namespace", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Show application build cache")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	cache, err := c.API.AppCache(c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	c.showCache(cache)
+
+	return nil
+}
+
+// AppCacheConfig changes the settings of the named application's build
+// cache. Nil arguments keep the current setting. An empty string selects
+// the default.
+func (c *EpinioClient) AppCacheConfig(appName string, size, storageClass *string) error {
+	log := c.Log.WithName("AppCacheConfig").WithValues("Namespace", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+	details := log.V(1) // NOTE: Increment of level, not absolute.
+
+	c.ui.Note().
+		WithStringValue("Namespace", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Configure application build cache")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	cache, err := c.API.AppCache(c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	config := cache.Config
+	if size != nil {
+		config.Size = *size
+	}
+	if storageClass != nil {
+		config.StorageClass = *storageClass
+	}
+
+	details.Info("update cache", "Size", config.Size, "StorageClass", config.StorageClass)
+
+	updated, err := c.API.AppCacheUpdate(config, c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	if cache.Claim != nil && updated.Claim == nil {
+		c.ui.Exclamation().Msg("The existing cache does not match, and was removed. The next staging starts without cache")
+	}
+
+	c.showCache(updated)
+
+	return nil
+}
+
+// AppCacheClear removes the contents of the named application's build cache
+func (c *EpinioClient) AppCacheClear(appName string) error {
+	log := c.Log.WithName("AppCacheClear").WithValues("Namespace", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Namespace", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Clearing application build cache")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	_, err := c.API.AppCacheClear(c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Build cache cleared")
+
+	return nil
+}
+
+// Caches lists the claims holding the build caches of all applications,
+// across namespaces
+func (c *EpinioClient) Caches() error {
+	log := c.Log.WithName("Caches")
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().Msg("Listing build caches")
+
+	caches, err := c.API.Caches()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(caches, func(i, j int) bool {
+		if caches[i].App.Org != caches[j].App.Org {
+			return caches[i].App.Org < caches[j].App.Org
+		}
+		return caches[i].App.Name < caches[j].App.Name
+	})
+
+	msg := c.ui.Success().WithTable("Namespace", "Application", "Claim", "Capacity (Not Usage)", "Storage Class", "Last Used", "Orphaned")
+
+	for _, cache := range caches {
+		lastUsed := ""
+		if cache.LastUsed != nil {
+			lastUsed = cache.LastUsed.Format(time.RFC3339)
+		}
+		orphaned := ""
+		if cache.Orphaned {
+			orphaned = "yes"
+		}
+
+		msg = msg.WithTableRow(cache.App.Org, cache.App.Name, cache.Name,
+			cache.Capacity, cache.StorageClass, lastUsed, orphaned)
+	}
+
+	msg.Msg("Build Caches:")
+
+	return nil
+}
+
+// showCache reports the settings of a build cache, and its claim
+func (c *EpinioClient) showCache(cache models.AppCache) {
+	size := cache.Config.Size
+	if size == "" {
+		size = models.DefaultCacheSize + " (default)"
+	}
+	storageClass := cache.Config.StorageClass
+	if storageClass == "" {
+		storageClass = "(default)"
+	}
+
+	msg := c.ui.Success().WithTable("Key", "Value").
+		WithTableRow("Size", size).
+		WithTableRow("Storage Class", storageClass)
+
+	if claim := cache.Claim; claim != nil {
+		msg = msg.
+			WithTableRow("Claim", claim.Name).
+			WithTableRow("Capacity (Not Usage)", claim.Capacity).
+			WithTableRow("Created", claim.Created.Format(time.RFC3339))
+		if claim.LastUsed != nil {
+			msg = msg.WithTableRow("Last Used", claim.LastUsed.Format(time.RFC3339))
+		}
+	} else {
+		msg = msg.WithTableRow("Claim", "not created yet, the application is not staged")
+	}
+
+	msg.Msg("Build Cache:")
+}
@@ -3,7 +3,6 @@ package usercmd
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -18,6 +17,8 @@ import (
 	api "github.com/epinio/epinio/internal/api/v1"
 	"github.com/epinio/epinio/internal/cli/config"
 	"github.com/epinio/epinio/internal/cli/logprinter"
+	"github.com/epinio/epinio/internal/manifest"
+	"github.com/epinio/epinio/internal/routes"
 	epinioapi "github.com/epinio/epinio/pkg/api/core/v1/client"
 	"github.com/epinio/epinio/pkg/api/core/v1/models"
 
@@ -61,6 +62,11 @@ func New() (*EpinioClient, error) {
 		Config: configConfig,
 		Log:    logger,
 	}
+
+	if tokenRefresh {
+		apiClient.SetTokenRefresh(epinioClient.refreshToken)
+	}
+
 	return epinioClient, nil
 }
 
@@ -202,6 +208,125 @@ func (c *EpinioClient) EnvMatching(ctx context.Context, appName, prefix string)
 	return resp.Names
 }
 
+// RouteList displays the routes of the named application.
+func (c *EpinioClient) RouteList(ctx context.Context, appName string) error {
+	log := c.Log.WithName("RouteList")
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Namespace", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Show application routes")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	app, err := c.API.AppShow(c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	msg := c.ui.Success().WithTable("Route")
+
+	for _, route := range app.Configuration.Routes {
+		msg = msg.WithTableRow(route)
+	}
+
+	msg.Msg("Ok")
+	return nil
+}
+
+// RouteAdd adds the specified route to the named application. The
+// ingress of a workload is updated.
+func (c *EpinioClient) RouteAdd(ctx context.Context, appName, spec string) error {
+	log := c.Log.WithName("Route")
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Namespace", c.Config.Org).
+		WithStringValue("Application", appName).
+		WithStringValue("Route", spec).
+		Msg("Add application route")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	route, err := routes.FromString(spec)
+	if err != nil {
+		return err
+	}
+
+	app, err := c.API.AppShow(c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	newRoutes := append(app.Configuration.Routes, route.String())
+
+	_, err = c.API.AppUpdate(models.ApplicationUpdateRequest{Routes: newRoutes}, c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("OK")
+	return nil
+}
+
+// RouteRemove removes the specified route from the named application.
+// Removing the last route restores the default route. The ingress of
+// a workload is updated.
+func (c *EpinioClient) RouteRemove(ctx context.Context, appName, spec string) error {
+	log := c.Log.WithName("Route")
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Namespace", c.Config.Org).
+		WithStringValue("Application", appName).
+		WithStringValue("Route", spec).
+		Msg("Remove application route")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	route, err := routes.FromString(spec)
+	if err != nil {
+		return err
+	}
+
+	app, err := c.API.AppShow(c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	newRoutes := []string{}
+	for _, existing := range app.Configuration.Routes {
+		if r, err := routes.FromString(existing); err == nil && r == route {
+			found = true
+			continue
+		}
+		newRoutes = append(newRoutes, existing)
+	}
+
+	if !found {
+		return fmt.Errorf("application %s has no route %s", appName, route.String())
+	}
+
+	_, err = c.API.AppUpdate(models.ApplicationUpdateRequest{Routes: newRoutes}, c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("OK")
+	return nil
+}
+
 // Services gets all Epinio services in the targeted org
 func (c *EpinioClient) Services() error {
 	log := c.Log.WithName("Services").WithValues("Namespace", c.Config.Org)
@@ -227,11 +352,15 @@ func (c *EpinioClient) Services() error {
 	details.Info("list services")
 
 	sort.Sort(response)
-	msg := c.ui.Success().WithTable("Name", "Applications")
+	msg := c.ui.Success().WithTable("Name", "Class", "Plan", "Applications")
 
 	details.Info("list services")
 	for _, service := range response {
-		msg = msg.WithTableRow(service.Name, strings.Join(service.BoundApps, ", "))
+		class := service.Class
+		if class == "" {
+			class = "custom"
+		}
+		msg = msg.WithTableRow(service.Name, class, service.Plan, strings.Join(service.BoundApps, ", "))
 	}
 	msg.Msg("Epinio Services:")
 
@@ -458,6 +587,141 @@ func (c *EpinioClient) CreateService(name string, dict []string) error {
 	return nil
 }
 
+// UpdateService changes the parameters of a service specified by name. The
+// assignments are KEY=VALUE. The applications bound to the service are
+// restarted, one after the other if sequential is set.
+func (c *EpinioClient) UpdateService(name string, assignments []string, unset []string, sequential bool) error {
+	log := c.Log.WithName("Update Service").
+		WithValues("Name", name, "Namespace", c.Config.Org)
+	log.Info("start")
+	defer log.Info("return")
+
+	set := make(map[string]string)
+	msg := c.ui.Note().
+		WithStringValue("Name", name).
+		WithStringValue("Namespace", c.Config.Org).
+		WithTable("Parameter", "Change")
+	for _, assignment := range assignments {
+		pieces := strings.SplitN(assignment, "=", 2)
+		if len(pieces) != 2 || pieces[0] == "" {
+			return fmt.Errorf("bad assignment '%s', expected KEY=VALUE", assignment)
+		}
+		set[pieces[0]] = pieces[1]
+		msg = msg.WithTableRow(pieces[0], "set")
+	}
+	for _, key := range unset {
+		msg = msg.WithTableRow(key, "unset")
+	}
+	msg.Msg("Update Service")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	request := models.ServiceUpdateRequest{
+		Set:        set,
+		Unset:      unset,
+		Sequential: sequential,
+	}
+
+	resp, err := c.API.ServiceUpdate(request, c.Config.Org, name)
+	if err != nil {
+		return err
+	}
+
+	if len(resp.RestartedApps) > 0 {
+		sort.Strings(resp.RestartedApps)
+		msg := c.ui.Note().WithTable("Restarted Applications")
+		for _, app := range resp.RestartedApps {
+			msg = msg.WithTableRow(app)
+		}
+		msg.Msg("")
+	}
+
+	c.ui.Success().
+		WithStringValue("Name", name).
+		WithStringValue("Namespace", c.Config.Org).
+		Msg("Service Updated.")
+	return nil
+}
+
+// CreateCatalogService provisions a service specified by name, class, and plan, through its broker.
+// The optional parameters are a JSON object.
+func (c *EpinioClient) CreateCatalogService(name, class, plan, params string) error {
+	log := c.Log.WithName("Create Catalog Service").
+		WithValues("Name", name, "Class", class, "Plan", plan, "Namespace", c.Config.Org)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Name", name).
+		WithStringValue("Class", class).
+		WithStringValue("Plan", plan).
+		WithStringValue("Namespace", c.Config.Org).
+		Msg("Create Catalog Service")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	var parameters map[string]interface{}
+	if params != "" {
+		err := json.Unmarshal([]byte(params), &parameters)
+		if err != nil {
+			return errors.Wrap(err, "bad parameters, expected a JSON object")
+		}
+	}
+
+	request := models.ServiceCreateRequest{
+		Name:   name,
+		Class:  class,
+		Plan:   plan,
+		Params: parameters,
+	}
+
+	_, err := c.API.ServiceCreate(request, c.Config.Org)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().
+		WithStringValue("Name", name).
+		WithStringValue("Namespace", c.Config.Org).
+		Msg("Service Provisioned.")
+	return nil
+}
+
+// ServiceCatalog lists the service classes and plans offered by the brokers
+func (c *EpinioClient) ServiceCatalog() error {
+	log := c.Log.WithName("ServiceCatalog")
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().Msg("Listing the service catalog")
+
+	catalog, err := c.API.ServiceCatalog()
+	if err != nil {
+		return err
+	}
+
+	msg := c.ui.Success().WithTable("Class", "Plan", "Free", "Description", "Broker")
+
+	for _, service := range catalog {
+		msg = msg.WithTableRow(service.Name, "", "", service.Description, service.Broker)
+		for _, plan := range service.Plans {
+			free := ""
+			if plan.Free {
+				free = "yes"
+			}
+			msg = msg.WithTableRow("", plan.Name, free, plan.Description, "")
+		}
+	}
+
+	msg.Msg("Epinio Service Catalog:")
+
+	return nil
+}
+
 // ServiceDetails shows the information of a service specified by name
 func (c *EpinioClient) ServiceDetails(name string) error {
 	log := c.Log.WithName("Service Details").
@@ -480,9 +744,14 @@ func (c *EpinioClient) ServiceDetails(name string) error {
 	}
 	serviceDetails := resp.Details
 
-	c.ui.Note().
-		WithStringValue("User", resp.Username).
-		Msg("")
+	note := c.ui.Note().
+		WithStringValue("User", resp.Username)
+	if resp.Class != "" {
+		note = note.
+			WithStringValue("Class", resp.Class).
+			WithStringValue("Plan", resp.Plan)
+	}
+	note.Msg("")
 
 	msg := c.ui.Success()
 
@@ -613,7 +882,7 @@ func (c *EpinioClient) Apps(all bool) error {
 					app.Meta.Org,
 					app.Meta.Name,
 					app.Workload.Status,
-					app.Workload.Route,
+					strings.Join(app.Workload.Routes, ", "),
 					strings.Join(app.Configuration.Services, ", "))
 			}
 		}
@@ -631,7 +900,7 @@ func (c *EpinioClient) Apps(all bool) error {
 				msg = msg.WithTableRow(
 					app.Meta.Name,
 					app.Workload.Status,
-					app.Workload.Route,
+					strings.Join(app.Workload.Routes, ", "),
 					strings.Join(app.Configuration.Services, ", "))
 			}
 		}
@@ -671,16 +940,106 @@ func (c *EpinioClient) AppShow(appName string) error {
 		msg = msg.WithTableRow("Status", app.Workload.Status).
 			WithTableRow("Username", app.Workload.Username).
 			WithTableRow("StageId", app.Workload.StageID).
-			WithTableRow("Routes", app.Workload.Route)
+			WithTableRow("Routes", strings.Join(app.Workload.Routes, ", "))
+		if app.Workload.Git != nil {
+			msg = msg.WithTableRow("Git", fmt.Sprintf("%s @ %s", app.Workload.Git.URL, app.Workload.Git.Revision))
+		}
+		msg = msg.WithTableRow("Replicas", fmt.Sprintf("%d current, %d desired",
+			app.Workload.CurrentReplicas, app.Workload.DesiredReplicas))
+		if a := app.Workload.Autoscale; a != nil {
+			msg = msg.WithTableRow("Autoscale", fmt.Sprintf("min %d, max %d, target CPU %d%%",
+				a.Min, a.Max, a.CPUPercent))
+		}
 	} else {
 		msg = msg.WithTableRow("Status", "not deployed")
 	}
+	if app.StagingID != "" {
+		msg = msg.WithTableRow("Staging", app.StagingID)
+	}
 
-	msg.
+	msg = msg.
 		WithTableRow("Desired Instances", fmt.Sprintf("%d", *app.Configuration.Instances)).
 		WithTableRow("Bound Services", strings.Join(app.Configuration.Services, ", ")).
-		WithTableRow("Environment", `See it by running the command "epinio app env list `+appName+`"`).
-		Msg("Details:")
+		WithTableRow("Environment", `See it by running the command "epinio app env list `+appName+`"`)
+
+	if app.Configuration.HealthCheck != nil {
+		msg = msg.WithTableRow("Health Check", healthCheckString(*app.Configuration.HealthCheck))
+	}
+	if app.Configuration.Rollout != nil {
+		msg = msg.WithTableRow("Rollout", fmt.Sprintf("max surge %s, max unavailable %s",
+			app.Configuration.Rollout.MaxSurge, app.Configuration.Rollout.MaxUnavailable))
+	}
+	if app.Configuration.Resources != nil {
+		r := app.Configuration.Resources
+		msg = msg.
+			WithTableRow("Memory", resourceString(r.MemoryRequest, r.MemoryLimit)).
+			WithTableRow("CPU", resourceString(r.CPURequest, r.CPULimit))
+	}
+
+	msg.Msg("Details:")
+
+	if app.Workload != nil && len(app.Workload.Instances) > 0 {
+		c.showInstances(app.Workload.Instances)
+	}
+
+	return nil
+}
+
+// healthCheckString returns a short description of the health check
+func healthCheckString(h models.HealthCheck) string {
+	switch h.Type {
+	case models.HealthCheckNone:
+		return "none"
+	case models.HealthCheckHTTP:
+		return fmt.Sprintf("http %d%s, timeout %ds, initial delay %ds", h.Port, h.Path, h.Timeout, h.InitialDelay)
+	default:
+		return fmt.Sprintf("%s %d, timeout %ds, initial delay %ds", h.Type, h.Port, h.Timeout, h.InitialDelay)
+	}
+}
+
+// resourceString returns a short description of a compute resource
+func resourceString(request, limit string) string {
+	if request == "" {
+		request = "none"
+	}
+	if limit == "" {
+		limit = "none"
+	}
+	return fmt.Sprintf("request %s, limit %s", request, limit)
+}
+
+// AppManifest saves the configuration of the named app, in the targeted org, as a manifest
+func (c *EpinioClient) AppManifest(appName, manifestPath string) error {
+	log := c.Log.WithName("AppManifest").WithValues("Namespace", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+	details := log.V(1) // NOTE: Increment of level, not absolute.
+
+	c.ui.Note().
+		WithStringValue("Namespace", c.Config.Org).
+		WithStringValue("Application", appName).
+		WithStringValue("Manifest", manifestPath).
+		Msg("Save application manifest")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	details.Info("show application")
+
+	app, err := c.API.AppShow(c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	err = manifest.Save(manifest.FromApp(app), manifestPath)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().
+		WithStringValue("Manifest", manifestPath).
+		Msg("Saved application manifest")
 
 	return nil
 }
@@ -731,6 +1090,96 @@ func (c *EpinioClient) AppUpdate(appName string, appConfig models.ApplicationUpd
 	return nil
 }
 
+// AppReleases displays the release history of the named app, in the targeted org
+func (c *EpinioClient) AppReleases(appName string) error {
+	log := c.Log.WithName("AppReleases").WithValues("Namespace", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+	details := log.V(1) // NOTE: Increment of level, not absolute.
+
+	c.ui.Note().
+		WithStringValue("Namespace", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Listing application releases")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	details.Info("list releases")
+
+	releases, err := c.API.AppReleases(c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	msg := c.ui.Success().WithTable("Release", "Image", "StageId", "Services", "Username", "Created")
+
+	for _, release := range releases {
+		number := fmt.Sprintf("%d", release.Number)
+		if release.RollbackOf > 0 {
+			number = fmt.Sprintf("%d (rollback to %d)", release.Number, release.RollbackOf)
+		}
+		msg = msg.WithTableRow(
+			number,
+			release.ImageURL,
+			release.StageID,
+			strings.Join(release.Services, ", "),
+			release.Username,
+			release.Created.Format(time.RFC3339))
+	}
+
+	msg.Msg("Releases:")
+
+	return nil
+}
+
+// AppRollback redeploys a previous release of the named app, in the targeted org.
+// The zero release refers to the release before the current one.
+func (c *EpinioClient) AppRollback(appName string, release int) error {
+	log := c.Log.WithName("AppRollback").WithValues("Namespace", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+	details := log.V(1) // NOTE: Increment of level, not absolute.
+
+	msg := c.ui.Note().
+		WithStringValue("Namespace", c.Config.Org).
+		WithStringValue("Application", appName)
+	if release > 0 {
+		msg = msg.WithStringValue("Release", fmt.Sprintf("%d", release))
+	} else {
+		msg = msg.WithStringValue("Release", "previous")
+	}
+	msg.Msg("Rolling back application")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	details.Info("rollback")
+
+	resp, err := c.API.AppRollback(models.RollbackRequest{Release: release}, c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	details.Info("wait for application resources")
+	c.ui.ProgressNote().KeeplineUnder(1).Msg("Creating application resources")
+
+	_, err = c.API.AppRunning(models.NewAppRef(appName, c.Config.Org))
+	if err != nil {
+		return errors.Wrap(err, "waiting for app failed")
+	}
+
+	c.ui.Success().
+		WithStringValue("Release", fmt.Sprintf("%d", resp.Release.Number)).
+		WithStringValue("Image", resp.Release.ImageURL).
+		WithStringValue("Route", fmt.Sprintf("https://%s", resp.Route)).
+		Msg("Rolled back application")
+
+	return nil
+}
+
 // AppLogs streams the logs of all the application instances, in the targeted org
 // If stageID is an empty string, runtime application logs are streamed. If stageID
 // is set, then the matching staging logs are streamed.
@@ -756,17 +1205,20 @@ func (c *EpinioClient) AppUpdate(appName string, appConfig models.ApplicationUpd
 // 5. The main thread returns
 // When the connection is closed (e.g. from the server side), the process is the
 // same but starts from #2 above.
-// TODO move into transport package
-func (c *EpinioClient) AppLogs(appName, stageID string, follow bool, interrupt chan bool) error {
+// The options select and filter the logs, and their output, see LogOptions.
+func (c *EpinioClient) AppLogs(appName, stageID string, follow bool, options LogOptions, interrupt chan bool) error {
 	log := c.Log.WithName("Apps").WithValues("Namespace", c.Config.Org, "Application", appName)
 	log.Info("start")
 	defer log.Info("return")
 	details := log.V(1) // NOTE: Increment of level, not absolute.
 
-	c.ui.Note().
-		WithStringValue("Namespace", c.Config.Org).
-		WithStringValue("Application", appName).
-		Msg("Streaming application logs")
+	// The JSON output is for other tools, without decoration
+	if !options.JSON {
+		c.ui.Note().
+			WithStringValue("Namespace", c.Config.Org).
+			WithStringValue("Application", appName).
+			Msg("Streaming application logs")
+	}
 
 	if err := c.TargetOk(); err != nil {
 		return err
@@ -774,24 +1226,9 @@ func (c *EpinioClient) AppLogs(appName, stageID string, follow bool, interrupt c
 
 	details.Info("application logs")
 
-	var urlArgs = []string{}
-	urlArgs = append(urlArgs, fmt.Sprintf("follow=%t", follow))
-	urlArgs = append(urlArgs, fmt.Sprintf("stage_id=%s", stageID))
-
-	headers := http.Header{
-		"Authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", c.Config.User, c.Config.Password)))},
-	}
-
-	var endpoint string
-	if stageID == "" {
-		endpoint = api.Routes.Path("AppLogs", c.Config.Org, appName)
-	} else {
-		endpoint = api.Routes.Path("StagingLogs", c.Config.Org, stageID)
-	}
-	webSocketConn, resp, err := websocket.DefaultDialer.Dial(
-		fmt.Sprintf("%s/%s?%s", c.API.WsURL, endpoint, strings.Join(urlArgs, "&")), headers)
+	webSocketConn, err := c.API.AppLogs(c.Config.Org, appName, stageID, options.query(follow, stageID))
 	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("Failed to connect to websockets endpoint. Response was = %+v\nThe error is", resp))
+		return err
 	}
 
 	done := make(chan bool)
@@ -838,6 +1275,11 @@ func (c *EpinioClient) AppLogs(appName, stageID string, follow bool, interrupt c
 			}
 			return err
 		}
+		if options.JSON {
+			fmt.Println(string(message))
+			continue
+		}
+
 		err = json.Unmarshal(message, &logLine)
 		if err != nil {
 			return err
@@ -38,6 +38,9 @@ func getEpinioAPIClient() (*epinioapi.Client, error) {
 		log.Info("cached in config")
 
 		epinioClient := epinioapi.New(log, cfg.API, cfg.WSS, cfg.User, cfg.Password)
+		if cfg.Token != "" {
+			epinioClient.SetToken(cfg.Token)
+		}
 		epinioClientMemo = epinioClient
 
 		return epinioClient, nil
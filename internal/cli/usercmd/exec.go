@@ -0,0 +1,117 @@
+package usercmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// AppExec runs the command, or a shell, in an instance of the named
+// application, with the local terminal attached. A negative instance
+// selects the first ready instance.
+func (c *EpinioClient) AppExec(appName string, instance int, command []string) error {
+	log := c.Log.WithName("AppExec").WithValues("Namespace", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	conn, err := c.API.AppExec(c.Config.Org, appName, instance, command)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	session := &execSession{conn: conn}
+
+	fd := int(os.Stdin.Fd())
+	if terminal.IsTerminal(fd) {
+		state, err := terminal.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		// nolint:errcheck // nothing to do about a failure
+		defer terminal.Restore(fd, state)
+
+		stop := watchTerminalSize(fd, session.resize)
+		defer stop()
+	}
+
+	go session.forwardInput(os.Stdin)
+
+	for {
+		kind, message, err := conn.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) && closeErr.Code == websocket.CloseNormalClosure {
+				if closeErr.Text != "" {
+					return errors.New(closeErr.Text)
+				}
+				return nil
+			}
+			return err
+		}
+
+		if kind == websocket.BinaryMessage {
+			if _, err := os.Stdout.Write(message); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// execSession sends the input and the terminal size changes of an exec
+// session to the server. The websocket connection supports only one
+// writer at a time.
+type execSession struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+func (s *execSession) send(kind int, message []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.conn.WriteMessage(kind, message)
+}
+
+func (s *execSession) control(control models.ExecControl) error {
+	message, err := json.Marshal(control)
+	if err != nil {
+		return err
+	}
+	return s.send(websocket.TextMessage, message)
+}
+
+// resize sends the terminal size to the server
+func (s *execSession) resize(width, height int) {
+	// nolint:errcheck // a failed connection ends the session anyway
+	s.control(models.ExecControl{Width: uint16(width), Height: uint16(height)})
+}
+
+// forwardInput sends the input to the server, until its end, or the end
+// of the session
+func (s *execSession) forwardInput(input io.Reader) {
+	buf := make([]byte, 1024)
+	for {
+		n, err := input.Read(buf)
+		if n > 0 {
+			if err := s.send(websocket.BinaryMessage, buf[:n]); err != nil {
+				return
+			}
+		}
+		if err != nil {
+			// nolint:errcheck // a failed connection ends the session anyway
+			s.control(models.ExecControl{CloseStdin: true})
+			return
+		}
+	}
+}
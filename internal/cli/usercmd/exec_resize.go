@@ -0,0 +1,41 @@
+// +build !windows
+
+package usercmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// watchTerminalSize calls resize with the size of the terminal, now and
+// whenever it changes, until stopped
+func watchTerminalSize(fd int, resize func(width, height int)) func() {
+	changes := make(chan os.Signal, 1)
+	signal.Notify(changes, syscall.SIGWINCH)
+	select {
+	case changes <- syscall.SIGWINCH:
+	default:
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-changes:
+				if width, height, err := terminal.GetSize(fd); err == nil {
+					resize(width, height)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(changes)
+		close(done)
+	}
+}
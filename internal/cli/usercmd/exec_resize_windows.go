@@ -0,0 +1,15 @@
+package usercmd
+
+import (
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// watchTerminalSize calls resize with the size of the terminal. Windows
+// has no signal for the changes of the size.
+func watchTerminalSize(fd int, resize func(width, height int)) func() {
+	if width, height, err := terminal.GetSize(fd); err == nil {
+		resize(width, height)
+	}
+
+	return func() {}
+}
@@ -0,0 +1,68 @@
+package usercmd
+
+import (
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+)
+
+// GitCredentialsCreate creates git credentials in the targeted namespace
+func (c *EpinioClient) GitCredentialsCreate(req models.GitCredentialsCreateRequest) error {
+	log := c.Log.WithName("GitCredentialsCreate").
+		WithValues("Name", req.Name, "Namespace", c.Config.Org)
+	log.Info("start")
+	defer log.Info("return")
+
+	kind := "password"
+	switch {
+	case req.Token != "":
+		kind = "token"
+	case req.SSHKey != "":
+		kind = "ssh key"
+	}
+
+	c.ui.Note().
+		WithStringValue("Name", req.Name).
+		WithStringValue("Namespace", c.Config.Org).
+		WithStringValue("Kind", kind).
+		Msg("Creating git credentials...")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	_, err := c.API.GitCredentialsCreate(req, c.Config.Org)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().
+		WithStringValue("Name", req.Name).
+		Msg("Git credentials created. Use them with `epinio push --git-credentials`.")
+
+	return nil
+}
+
+// GitCredentialsDelete deletes git credentials of the targeted namespace
+func (c *EpinioClient) GitCredentialsDelete(name string) error {
+	log := c.Log.WithName("GitCredentialsDelete").
+		WithValues("Name", name, "Namespace", c.Config.Org)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Name", name).
+		WithStringValue("Namespace", c.Config.Org).
+		Msg("Deleting git credentials...")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	_, err := c.API.GitCredentialsDelete(c.Config.Org, name)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Git credentials deleted.")
+
+	return nil
+}
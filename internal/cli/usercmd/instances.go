@@ -0,0 +1,53 @@
+package usercmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// showInstances prints the instances of an application, with their
+// status, followed by the warning events of the instances which are
+// not ready, if any
+func (c *EpinioClient) showInstances(instances models.AppInstanceList) {
+	msg := c.ui.Normal().WithTable("Name", "Phase", "Ready", "Restarts", "Last Termination", "Node", "Age")
+
+	withEvents := false
+	for _, instance := range instances {
+		phase := instance.Phase
+		if instance.Reason != "" {
+			phase = fmt.Sprintf("%s (%s)", phase, instance.Reason)
+		}
+
+		lastTermination := ""
+		if last := instance.LastTermination; last != nil {
+			lastTermination = fmt.Sprintf("%s, exit code %d, %s ago",
+				last.Reason, last.ExitCode, duration.HumanDuration(time.Since(last.Finished)))
+		}
+
+		msg = msg.WithTableRow(instance.Name, phase,
+			fmt.Sprintf("%t", instance.Ready),
+			fmt.Sprintf("%d", instance.Restarts),
+			lastTermination,
+			instance.Node,
+			duration.HumanDuration(time.Since(instance.Created)))
+
+		if len(instance.Events) > 0 {
+			withEvents = true
+		}
+	}
+
+	if withEvents {
+		msg = msg.WithTable("Instance", "Recent Warnings")
+		for _, instance := range instances {
+			if len(instance.Events) > 0 {
+				msg = msg.WithTableRow(instance.Name, strings.Join(instance.Events, "\n"))
+			}
+		}
+	}
+
+	msg.Msg("Instances:")
+}
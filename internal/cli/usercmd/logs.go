@@ -0,0 +1,49 @@
+package usercmd
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// LogOptions are the optional settings of AppLogs. The zero value shows
+// the logs of the last 48 hours of all instances, as plain text. See the
+// API's logParameters for the meaning of the fields.
+type LogOptions struct {
+	Since      time.Duration
+	Tail       *int64
+	Include    []string
+	Exclude    []string
+	Timestamps bool
+	Instance   *int
+	JSON       bool // Print the log lines as JSON objects, one per line
+}
+
+// query returns the query parameters of the logs endpoint for the options
+func (o LogOptions) query(follow bool, stageID string) url.Values {
+	query := url.Values{}
+	query.Set("follow", strconv.FormatBool(follow))
+	query.Set("stage_id", stageID)
+
+	if o.Since != 0 {
+		query.Set("since", o.Since.String())
+	}
+	if o.Tail != nil {
+		query.Set("tail", fmt.Sprintf("%d", *o.Tail))
+	}
+	for _, pattern := range o.Include {
+		query.Add("include", pattern)
+	}
+	for _, pattern := range o.Exclude {
+		query.Add("exclude", pattern)
+	}
+	if o.Timestamps {
+		query.Set("timestamps", "true")
+	}
+	if o.Instance != nil {
+		query.Set("instance", strconv.Itoa(*o.Instance))
+	}
+
+	return query
+}
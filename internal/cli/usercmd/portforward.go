@@ -0,0 +1,90 @@
+package usercmd
+
+import (
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/epinio/epinio/helpers/wsstream"
+	"github.com/epinio/epinio/internal/application"
+)
+
+// AppPortForward forwards the connections to the local port to the
+// remote port of an instance of the named application, through the API
+// server, until the listener fails. A negative instance selects the
+// first ready instance. A local port 0 selects a free port.
+func (c *EpinioClient) AppPortForward(appName string, instance int, address string, localPort, remotePort int) error {
+	log := c.Log.WithName("AppPortForward").WithValues("Namespace", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	// Check the application and the instance, before listening
+	instances, err := c.API.AppInstances(c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+	if _, err := application.SelectInstance(instances, instance); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(address, fmt.Sprintf("%d", localPort)))
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	c.ui.Note().
+		WithStringValue("Namespace", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg(fmt.Sprintf("Forwarding from %s to port %d", listener.Addr(), remotePort))
+
+	for {
+		local, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			log.Info("handling connection", "from", local.RemoteAddr())
+
+			if err := c.forwardConnection(local, appName, instance, remotePort); err != nil {
+				c.ui.Problem().Msg(fmt.Sprintf("Connection from %s failed: %s", local.RemoteAddr(), err))
+			}
+		}()
+	}
+}
+
+// forwardConnection tunnels the local connection to the remote port,
+// until the remote side ends. The end of the local data is signalled to
+// the remote side, which may still respond.
+func (c *EpinioClient) forwardConnection(local net.Conn, appName string, instance, remotePort int) error {
+	defer local.Close()
+
+	conn, err := c.API.AppPortForward(c.Config.Org, appName, instance, remotePort)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stream := wsstream.New(conn)
+
+	remoteDone := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(local, stream)
+		remoteDone <- err
+		// Stops the copy below
+		local.Close()
+	}()
+
+	// nolint:errcheck // the end of the copy is all that matters
+	io.Copy(stream, local)
+
+	// nolint:errcheck // the remote side may have ended already
+	stream.CloseWrite()
+
+	return <-remoteDone
+}
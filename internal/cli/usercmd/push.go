@@ -1,10 +1,12 @@
 package usercmd
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
 	"sync"
 
@@ -14,16 +16,58 @@ import (
 
 	"github.com/epinio/epinio/helpers"
 	"github.com/epinio/epinio/internal/duration"
+	epinioapi "github.com/epinio/epinio/pkg/api/core/v1/client"
 	"github.com/epinio/epinio/pkg/api/core/v1/models"
 )
 
 type PushParams struct {
-	Configuration models.ApplicationUpdateRequest // instances, services, EVs
-	Docker        string
-	GitRev        string
-	BuilderImage  string
-	Name          string
-	Path          string
+	Configuration  models.ApplicationUpdateRequest // instances, services, EVs
+	Docker         string
+	GitRev         string
+	GitCredentials string
+	BuilderImage   string
+	Strategy       string
+	Name           string
+	Path           string
+}
+
+// PushSources lists the application sources in the directory which a push
+// would upload, with their total size. Nothing is pushed.
+func (c *EpinioClient) PushSources(source string) error {
+	log := c.Log.WithName("PushSources").WithValues("Sources", source)
+	log.Info("start")
+	defer log.Info("return")
+
+	sources, err := helpers.Sources(source)
+	if err != nil {
+		return err
+	}
+
+	files := 0
+	var size int64
+	msg := c.ui.Success().WithTable("Path", "Size")
+	for _, file := range sources {
+		if file.Info.IsDir() {
+			continue
+		}
+		files++
+		size += file.Info.Size()
+		msg = msg.WithTableRow(file.Path, fmt.Sprintf("%d", file.Info.Size()))
+	}
+
+	if files == 0 {
+		c.ui.Exclamation().Msg("No sources to upload")
+		return nil
+	}
+
+	msg.Msg("Sources to upload:")
+
+	c.ui.Success().
+		WithIntValue("Files", files).
+		WithStringValue("Size", fmt.Sprintf("%d bytes", size)).
+		Msg("Dry run, nothing pushed")
+
+	return nil
 }
 
 // Push pushes an app
@@ -133,32 +177,45 @@ func (c *EpinioClient) Push(ctx context.Context, params PushParams) error {
 		}
 		log.V(3).Info("upload response", "response", upload)
 
+		if upload.Skipped {
+			c.ui.Note().Msg("Sources unchanged, skipping upload")
+		}
+
 		blobUID = upload.BlobUID
 
 	} else if params.GitRev != "" {
 		c.ui.Normal().Msg("Importing the application sources from Git ...")
 
 		gitRef := models.GitRef{
-			URL:      source,
-			Revision: params.GitRev,
+			URL:         source,
+			Revision:    params.GitRev,
+			Credentials: params.GitCredentials,
 		}
 		response, err := c.API.AppImportGit(appRef, gitRef)
 		if err != nil {
 			return errors.Wrap(err, "importing git remote")
 		}
 		blobUID = response.BlobUID
+
+		c.ui.Normal().Msg(fmt.Sprintf("Imported commit %s", response.Commit))
 	}
 
 	// AppStage
+	// A docker image is staged only when asked for. By default it is deployed as is.
+	stageImage := params.Docker != "" && params.Strategy == models.StagingImage
 	stageID := ""
 	var stageResponse *models.StageResponse
-	if params.Docker == "" {
+	if params.Docker == "" || stageImage {
 		c.ui.Normal().Msg("Staging application via docker image ...")
 
 		req := models.StageRequest{
 			App:          appRef,
 			BlobUID:      blobUID,
 			BuilderImage: params.BuilderImage,
+			Strategy:     params.Strategy,
+		}
+		if stageImage {
+			req.SourceImage = params.Docker
 		}
 		details.Info("staging code", "Blob", blobUID)
 		stageResponse, err = c.API.AppStage(req)
@@ -168,6 +225,8 @@ func (c *EpinioClient) Push(ctx context.Context, params PushParams) error {
 		stageID = stageResponse.Stage.ID
 		log.V(3).Info("stage response", "response", stageResponse)
 
+		c.ui.Normal().Msg(fmt.Sprintf("Staging with strategy %s", stageResponse.Strategy))
+
 		details.Info("start tailing logs", "StageID", stageResponse.Stage.ID)
 		err = c.stageLogs(details, appRef, stageResponse.Stage.ID)
 		if err != nil {
@@ -182,7 +241,7 @@ func (c *EpinioClient) Push(ctx context.Context, params PushParams) error {
 	}
 	// If docker param is specified, then we just take it into ImageURL
 	// If not, we take the one from the staging response
-	if params.Docker != "" {
+	if params.Docker != "" && !stageImage {
 		deployRequest.ImageURL = params.Docker
 	} else {
 		deployRequest.ImageURL = stageResponse.ImageURL
@@ -202,12 +261,21 @@ func (c *EpinioClient) Push(ctx context.Context, params PushParams) error {
 		return errors.Wrap(err, "waiting for app failed")
 	}
 
-	c.ui.Success().
+	msg = c.ui.Success().
 		WithStringValue("Name", appRef.Name).
 		WithStringValue("Namespace", appRef.Org).
-		WithStringValue("Route", fmt.Sprintf("https://%s", deployResponse.Route)).
-		WithStringValue("Builder Image", params.BuilderImage).
-		Msg("App is online.")
+		WithStringValue("Route", fmt.Sprintf("https://%s", deployResponse.Route))
+	if stageResponse != nil {
+		msg = msg.WithStringValue("Staging Strategy", stageResponse.Strategy)
+		if stageResponse.Strategy == models.StagingBuildpacks {
+			builderImage := params.BuilderImage
+			if builderImage == "" {
+				builderImage = models.DefaultBuilderImage
+			}
+			msg = msg.WithStringValue("Builder Image", builderImage)
+		}
+	}
+	msg.Msg("App is online.")
 
 	return nil
 }
@@ -215,14 +283,18 @@ func (c *EpinioClient) Push(ctx context.Context, params PushParams) error {
 func (c *EpinioClient) stageLogs(details logr.Logger, appRef models.AppRef, stageID string) error {
 	// Buffered because the go routine may no longer be listening when we try
 	// to stop it. Stopping it should be a fire and forget. We have wg to wait
-	// for the routine to be gone.
+	// for the routine to be gone. The once guards against stopping it twice.
 	stopChan := make(chan bool, 1)
+	var stopOnce sync.Once
+	stopLogs := func() {
+		stopOnce.Do(func() { stopChan <- true })
+	}
 	var wg sync.WaitGroup
 	wg.Add(1)
 	defer wg.Wait()
 	go func() {
 		defer wg.Done()
-		err := c.AppLogs(appRef.Name, stageID, true, stopChan)
+		err := c.AppLogs(appRef.Name, stageID, true, LogOptions{}, stopChan)
 		if err != nil {
 			c.ui.Problem().Msg(fmt.Sprintf("failed to tail logs: %s", err.Error()))
 		}
@@ -231,12 +303,136 @@ func (c *EpinioClient) stageLogs(details logr.Logger, appRef models.AppRef, stag
 	details.Info("wait for pipelinerun", "StageID", stageID)
 	c.ui.ProgressNote().KeeplineUnder(1).Msg("Running staging")
 
-	_, err := c.API.StagingComplete(appRef.Org, stageID)
+	// The first Ctrl+C offers to cancel the staging. Any further one
+	// interrupts the client as usual.
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.API.StagingComplete(appRef.Org, stageID)
+		done <- err
+	}()
+
+	var err error
+wait:
+	for {
+		select {
+		case err = <-done:
+			break wait
+		case <-interrupts:
+			signal.Stop(interrupts)
+			stopLogs()
+
+			if !c.askCancelStaging() {
+				c.ui.Note().Msg("Waiting for the staging to complete, without logs")
+				continue
+			}
+
+			details.Info("cancel pipelinerun", "StageID", stageID)
+			if _, cerr := c.API.StagingCancel(appRef.Org, stageID); cerr != nil {
+				c.ui.Problem().Msg(fmt.Sprintf("failed to cancel staging: %s", cerr.Error()))
+			}
+		}
+	}
+	stopLogs() // Stop the printing go routine
+
 	if err != nil {
-		stopChan <- true // Stop the printing go routine
+		var stagingErr *epinioapi.StagingError
+		if errors.As(err, &stagingErr) {
+			if stagingErr.Status.Phase == models.StageCancelled {
+				c.ui.Exclamation().Msg("Staging cancelled")
+				return errors.New("staging cancelled")
+			}
+			c.stagingFailure(stagingErr.Status)
+			return errors.New("staging failed")
+		}
 		return errors.Wrap(err, "waiting for staging failed")
 	}
-	stopChan <- true // Stop the printing go routine
 
-	return err
+	return nil
+}
+
+// askCancelStaging asks the user whether to cancel the staging run.
+func (c *EpinioClient) askCancelStaging() bool {
+	fmt.Print("\nCancel the staging? (y/n): ")
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		s, err := reader.ReadString('\n')
+		if err != nil {
+			return false
+		}
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+		fmt.Print("Please enter y or n: ")
+	}
+}
+
+// StageCancel cancels the staging run in flight for the named application, if any
+func (c *EpinioClient) StageCancel(appName string) error {
+	log := c.Log.WithName("StageCancel").WithValues("Namespace", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+	details := log.V(1) // NOTE: Increment of level, not absolute.
+
+	c.ui.Note().
+		WithStringValue("Namespace", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Cancelling the staging of application")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	app, err := c.API.AppShow(c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	if app.StagingID == "" {
+		c.ui.Exclamation().Msg("The application is not staging")
+		return nil
+	}
+
+	details.Info("cancel pipelinerun", "StageID", app.StagingID)
+
+	_, err = c.API.StagingCancel(c.Config.Org, app.StagingID)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().
+		WithStringValue("Stage", app.StagingID).
+		Msg("Staging cancelled")
+
+	return nil
+}
+
+// stagingFailure reports the step at fault for a failed staging, and what
+// to do about it.
+func (c *EpinioClient) stagingFailure(status models.StageStatus) {
+	msg := c.ui.Problem()
+	if step := status.FailedStep; step != nil {
+		msg = msg.
+			WithStringValue("Task", step.Task).
+			WithStringValue("Step", step.Name)
+		if step.ExitCode != nil {
+			msg = msg.WithIntValue("Exit Code", int(*step.ExitCode))
+		}
+		if step.Reason != "" {
+			msg = msg.WithStringValue("Reason", step.Reason)
+		}
+	} else if status.Message != "" {
+		msg = msg.WithStringValue("Reason", status.Message)
+	}
+	msg.Msg("Staging failed")
+
+	if status.Hint != "" {
+		c.ui.Exclamation().Compact().Msg(status.Hint)
+	}
 }
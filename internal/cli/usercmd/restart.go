@@ -0,0 +1,105 @@
+package usercmd
+
+import (
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/pkg/errors"
+)
+
+// AppRestart restarts the pods of the named application, and waits for
+// the new pods to run
+func (c *EpinioClient) AppRestart(appName string) error {
+	log := c.Log.WithName("AppRestart").WithValues("Namespace", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Namespace", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Restarting application")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	_, err := c.API.AppRestart(c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	err = c.waitForApp(appName)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Application restarted")
+
+	return nil
+}
+
+// AppStop scales the named application to zero instances
+func (c *EpinioClient) AppStop(appName string) error {
+	log := c.Log.WithName("AppStop").WithValues("Namespace", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Namespace", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Stopping application")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	_, err := c.API.AppStop(c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Application stopped")
+
+	return nil
+}
+
+// AppStart scales the named, stopped application back to its desired
+// instances, and waits for them to run
+func (c *EpinioClient) AppStart(appName string) error {
+	log := c.Log.WithName("AppStart").WithValues("Namespace", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Namespace", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Starting application")
+
+	if err := c.TargetOk(); err != nil {
+		return err
+	}
+
+	_, err := c.API.AppStart(c.Config.Org, appName)
+	if err != nil {
+		return err
+	}
+
+	err = c.waitForApp(appName)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Application started")
+
+	return nil
+}
+
+// waitForApp waits for the rollout of the named application's pods
+func (c *EpinioClient) waitForApp(appName string) error {
+	c.ui.ProgressNote().KeeplineUnder(1).Msg("Waiting for the application's instances")
+
+	_, err := c.API.AppRunning(models.NewAppRef(appName, c.Config.Org))
+	if err != nil {
+		return errors.Wrap(err, "waiting for app failed")
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package usercmd_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestUsercmd(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Usercmd Suite")
+}
@@ -0,0 +1,150 @@
+package usercmd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/epinio/epinio/helpers/randstr"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+)
+
+// Users lists the API users, with their roles
+func (c *EpinioClient) Users() error {
+	log := c.Log.WithName("Users")
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().Msg("Listing users")
+
+	users, err := c.API.Users()
+	if err != nil {
+		return err
+	}
+
+	msg := c.ui.Success().WithTable("Name", "Admin", "Roles")
+
+	for _, user := range users {
+		roles := []string{}
+		for namespace, role := range user.Namespaces {
+			roles = append(roles, namespace+": "+role)
+		}
+		sort.Strings(roles)
+
+		admin := ""
+		if user.Admin {
+			admin = "yes"
+		}
+
+		msg = msg.WithTableRow(user.Username, admin, strings.Join(roles, ", "))
+	}
+
+	msg.Msg("Epinio Users:")
+
+	return nil
+}
+
+// UserAdd creates an API user. A random password is generated and shown
+// when none is specified.
+func (c *EpinioClient) UserAdd(username, password string, admin bool) error {
+	log := c.Log.WithName("UserAdd").WithValues("User", username)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Name", username).
+		WithBoolValue("Admin", admin).
+		Msg("Creating user...")
+
+	generated := false
+	if password == "" {
+		var err error
+		password, err = randstr.Hex16()
+		if err != nil {
+			return err
+		}
+		generated = true
+	}
+
+	_, err := c.API.UserCreate(models.UserCreateRequest{
+		Username: username,
+		Password: password,
+		Admin:    admin,
+	})
+	if err != nil {
+		return err
+	}
+
+	msg := c.ui.Success()
+	if generated {
+		msg = msg.WithStringValue("Password", password)
+	}
+	msg.Msg("User created.")
+
+	return nil
+}
+
+// UserRemove deletes an API user
+func (c *EpinioClient) UserRemove(username string) error {
+	log := c.Log.WithName("UserRemove").WithValues("User", username)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Name", username).
+		Msg("Removing user...")
+
+	_, err := c.API.UserDelete(username)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("User removed.")
+
+	return nil
+}
+
+// UserGrant gives the user a role in the namespace
+func (c *EpinioClient) UserGrant(username, org, role string) error {
+	log := c.Log.WithName("UserGrant").WithValues("User", username, "Namespace", org, "Role", role)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Name", username).
+		WithStringValue("Namespace", org).
+		WithStringValue("Role", role).
+		Msg("Granting role...")
+
+	_, err := c.API.UserGrant(username, models.UserGrantRequest{
+		Namespace: org,
+		Role:      role,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Role granted.")
+
+	return nil
+}
+
+// UserRevoke removes the role of the user in the namespace
+func (c *EpinioClient) UserRevoke(username, org string) error {
+	log := c.Log.WithName("UserRevoke").WithValues("User", username, "Namespace", org)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Name", username).
+		WithStringValue("Namespace", org).
+		Msg("Revoking role...")
+
+	_, err := c.API.UserRevoke(username, org)
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Role revoked.")
+
+	return nil
+}
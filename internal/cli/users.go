@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/epinio/epinio/internal/cli/usercmd"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdUser implements the command: epinio user
+var CmdUser = &cobra.Command{
+	Use:           "user",
+	Aliases:       []string{"users"},
+	Short:         "Epinio API users",
+	Long:          `Manage the users of the epinio API, and their roles in namespaces. Admins only.`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	Args:          cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cmd.Usage(); err != nil {
+			return err
+		}
+		return fmt.Errorf(`Unknown method "%s"`, args[0])
+	},
+}
+
+func init() {
+	addFlags := CmdUserAdd.Flags()
+	addFlags.String("password", "", "password of the new user, generated if not specified")
+	addFlags.Bool("admin", false, "make the new user an admin")
+
+	grantFlags := CmdUserGrant.Flags()
+	grantFlags.String("role", models.RoleDeveloper,
+		fmt.Sprintf("role to grant, one of %s, %s", models.RoleDeveloper, models.RoleViewer))
+
+	CmdUser.AddCommand(CmdUserList)
+	CmdUser.AddCommand(CmdUserAdd)
+	CmdUser.AddCommand(CmdUserRemove)
+	CmdUser.AddCommand(CmdUserGrant)
+	CmdUser.AddCommand(CmdUserRevoke)
+}
+
+// CmdUserList implements the command: epinio user list
+var CmdUserList = &cobra.Command{
+	Use:   "list",
+	Short: "Lists all users",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.Users()
+		if err != nil {
+			return errors.Wrap(err, "error listing users")
+		}
+
+		return nil
+	},
+}
+
+// CmdUserAdd implements the command: epinio user add
+var CmdUserAdd = &cobra.Command{
+	Use:   "add NAME",
+	Short: "Creates a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		password, err := cmd.Flags().GetString("password")
+		if err != nil {
+			return errors.Wrap(err, "could not read password parameter")
+		}
+		admin, err := cmd.Flags().GetBool("admin")
+		if err != nil {
+			return errors.Wrap(err, "could not read admin parameter")
+		}
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.UserAdd(args[0], password, admin)
+		if err != nil {
+			return errors.Wrap(err, "error creating user")
+		}
+
+		return nil
+	},
+}
+
+// CmdUserRemove implements the command: epinio user remove
+var CmdUserRemove = &cobra.Command{
+	Use:   "remove NAME",
+	Short: "Removes a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.UserRemove(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error removing user")
+		}
+
+		return nil
+	},
+}
+
+// CmdUserGrant implements the command: epinio user grant
+var CmdUserGrant = &cobra.Command{
+	Use:   "grant NAME NAMESPACE",
+	Short: "Grants a role in a namespace to a user",
+	Long:  "Grants a role in a namespace to a user. Developers can read and modify the namespace, viewers can only read it. Replaces any role the user had in the namespace before.",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		role, err := cmd.Flags().GetString("role")
+		if err != nil {
+			return errors.Wrap(err, "could not read role parameter")
+		}
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.UserGrant(args[0], args[1], role)
+		if err != nil {
+			return errors.Wrap(err, "error granting role")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: userNamespaceCompletion,
+}
+
+// CmdUserRevoke implements the command: epinio user revoke
+var CmdUserRevoke = &cobra.Command{
+	Use:   "revoke NAME NAMESPACE",
+	Short: "Revokes the role of a user in a namespace",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := usercmd.New()
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.UserRevoke(args[0], args[1])
+		if err != nil {
+			return errors.Wrap(err, "error revoking role")
+		}
+
+		return nil
+	},
+	ValidArgsFunction: userNamespaceCompletion,
+}
+
+// userNamespaceCompletion completes the namespace argument of the
+// grant and revoke commands
+func userNamespaceCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 1 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	app, err := usercmd.New()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	matches := app.OrgsMatching(toComplete)
+
+	return matches, cobra.ShellCompDirectiveNoFileComp
+}
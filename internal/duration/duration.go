@@ -23,11 +23,15 @@ const (
 	certManagerReady    = 5 * time.Minute
 	kubedReady          = 5 * time.Minute
 	secretCopied        = 5 * time.Minute
+	gitImport           = 10 * time.Minute
 
 	// Fixed. __Not__ affected by the multiplier.
 	pollInterval = 3 * time.Second
 	userAbort    = 5 * time.Second
 	logHistory   = 48 * time.Hour
+	jobRetention = 1 * time.Hour
+	tokenLife    = 12 * time.Hour
+	tokenRefresh = 6 * time.Hour
 
 	// Fixed. Standard number of attempts to retry various operations.
 	RetryMax = 10
@@ -69,6 +73,12 @@ func ToAppBuilt() time.Duration {
 	return Multiplier() * appBuilt
 }
 
+// ToGitImport returns the duration to wait until giving up on the
+// import of application sources from a git repository
+func ToGitImport() time.Duration {
+	return Multiplier() * gitImport
+}
+
 // ToPodReady returns the duration to wait until giving up on getting
 // a system domain
 func ToPodReady() time.Duration {
@@ -142,3 +152,20 @@ func UserAbort() time.Duration {
 func LogHistory() time.Duration {
 	return logHistory
 }
+
+// JobRetention returns the duration to keep the state of finished
+// background jobs around for queries.
+func JobRetention() time.Duration {
+	return jobRetention
+}
+
+// TokenLifetime returns the duration API tokens are valid for.
+func TokenLifetime() time.Duration {
+	return tokenLife
+}
+
+// TokenRefresh returns the remaining lifetime of an API token below
+// which the CLI replaces it with a fresh token.
+func TokenRefresh() time.Duration {
+	return tokenRefresh
+}
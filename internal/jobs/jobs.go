@@ -0,0 +1,236 @@
+// Package jobs implements a simple in-memory queue of background jobs,
+// executed by a pool of workers. The API server uses it for long-running
+// operations, like the import of application sources from a git
+// repository, which must not block the HTTP request triggering them.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/duration"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+)
+
+// queueSize is the number of jobs which can wait for a worker before
+// the queue refuses new jobs.
+const queueSize = 100
+
+var (
+	// ErrNotRunning is returned by Submit when the queue was not started.
+	ErrNotRunning = errors.New("the job queue is not running")
+	// ErrQueueFull is returned by Submit when too many jobs are waiting.
+	ErrQueueFull = errors.New("too many jobs waiting, try again later")
+)
+
+// Func is the work performed by a job. It reports progress and
+// results through the job.
+type Func func(ctx context.Context, job *Job) error
+
+// Job is a unit of background work, and its state.
+type Job struct {
+	mu       sync.Mutex
+	state    models.JobResponse
+	work     Func
+	timeout  time.Duration
+	log      logr.Logger
+	finished time.Time
+}
+
+// Progress records a message about the progress of the job.
+func (j *Job) Progress(message string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state.Progress = message
+	j.log.V(1).Info("progress", "message", message)
+}
+
+// SetBlobUID records the blob UID resulting from the job.
+func (j *Job) SetBlobUID(blobUID string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state.BlobUID = blobUID
+}
+
+// SetCommit records the SHA of the git commit resulting from the job.
+func (j *Job) SetCommit(commit string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state.Commit = commit
+}
+
+// State returns a snapshot of the job's state.
+func (j *Job) State() models.JobResponse {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state
+}
+
+func (j *Job) run() {
+	j.setStatus(models.JobRunning)
+
+	ctx, cancel := context.WithTimeout(tracelog.WithLogger(context.Background(), j.log), j.timeout)
+	defer cancel()
+
+	err := j.work(ctx, j)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err != nil {
+		j.log.Error(err, "job failed")
+		j.state.Status = models.JobFailed
+		j.state.Error = err.Error()
+	} else {
+		j.log.Info("job done")
+		j.state.Status = models.JobSucceeded
+	}
+	j.finished = time.Now()
+}
+
+func (j *Job) setStatus(status string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state.Status = status
+}
+
+// expired returns true if the job is finished, and was so for longer
+// than the retention period.
+func (j *Job) expired(now time.Time) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return !j.finished.IsZero() && now.Sub(j.finished) > duration.JobRetention()
+}
+
+// queue holds the jobs known to the server, and feeds the waiting
+// ones to the workers.
+type queue struct {
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	pending chan *Job
+}
+
+var (
+	// defaultQueueMu guards defaultQueue, which is replaced by Start
+	defaultQueueMu sync.RWMutex
+	defaultQueue   *queue
+)
+
+// current returns the queue started last, or nil
+func current() *queue {
+	defaultQueueMu.RLock()
+	defer defaultQueueMu.RUnlock()
+	return defaultQueue
+}
+
+// Start creates the job queue and starts the specified number of
+// workers for it, at least one. The workers stop when the context is
+// done. The queue replaces the queue of a previous start.
+func Start(ctx context.Context, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	q := &queue{
+		jobs:    map[string]*Job{},
+		pending: make(chan *Job, queueSize),
+	}
+
+	for i := 0; i < workers; i++ {
+		go q.worker(ctx)
+	}
+
+	defaultQueueMu.Lock()
+	defer defaultQueueMu.Unlock()
+	defaultQueue = q
+}
+
+func (q *queue) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-q.pending:
+			job.run()
+		}
+	}
+}
+
+// Submit queues the work for execution in the background, on behalf
+// of the referenced application. The work is cancelled when it runs
+// longer than the timeout. The logger of the context is used for
+// the job's log.
+func Submit(ctx context.Context, app models.AppRef, timeout time.Duration, work Func) (*Job, error) {
+	q := current()
+	if q == nil {
+		return nil, ErrNotRunning
+	}
+
+	id := uuid.New().String()
+	job := &Job{
+		state: models.JobResponse{
+			ID:        id,
+			Namespace: app.Org,
+			App:       app.Name,
+			Status:    models.JobPending,
+		},
+		work:    work,
+		timeout: timeout,
+		log:     tracelog.Logger(ctx).WithName("Job").WithValues("id", id),
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.expire()
+
+	select {
+	case q.pending <- job:
+	default:
+		return nil, ErrQueueFull
+	}
+	q.jobs[id] = job
+
+	return job, nil
+}
+
+// Get returns the state of the job with the given ID in the given
+// namespace. The boolean result is false if there is no such job.
+func Get(org, id string) (models.JobResponse, bool) {
+	q := current()
+	if q == nil {
+		return models.JobResponse{}, false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.expire()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return models.JobResponse{}, false
+	}
+
+	state := job.State()
+	if state.Namespace != org {
+		return models.JobResponse{}, false
+	}
+
+	return state, true
+}
+
+// expire drops finished jobs past their retention period. The caller
+// has to hold the lock of the queue.
+func (q *queue) expire() {
+	now := time.Now()
+	for id, job := range q.jobs {
+		if job.expired(now) {
+			delete(q.jobs, id)
+		}
+	}
+}
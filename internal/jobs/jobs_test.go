@@ -0,0 +1,75 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/epinio/epinio/internal/jobs"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Jobs", func() {
+	var cancel context.CancelFunc
+	app := models.NewAppRef("app", "workspace")
+
+	BeforeEach(func() {
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		jobs.Start(ctx, 2)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	state := func(org, id string) func() string {
+		return func() string {
+			job, ok := jobs.Get(org, id)
+			Expect(ok).To(BeTrue())
+			return job.Status
+		}
+	}
+
+	It("runs the job and records its result", func() {
+		job, err := jobs.Submit(context.Background(), app, time.Minute, func(ctx context.Context, job *jobs.Job) error {
+			job.Progress("working")
+			job.SetBlobUID("blob")
+			return nil
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		id := job.State().ID
+		Eventually(state("workspace", id)).Should(Equal(models.JobSucceeded))
+
+		result, _ := jobs.Get("workspace", id)
+		Expect(result.BlobUID).To(Equal("blob"))
+		Expect(result.App).To(Equal("app"))
+		Expect(result.Progress).To(Equal("working"))
+	})
+
+	It("records the failure of the job", func() {
+		job, err := jobs.Submit(context.Background(), app, time.Minute, func(ctx context.Context, job *jobs.Job) error {
+			return errors.New("broken")
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		id := job.State().ID
+		Eventually(state("workspace", id)).Should(Equal(models.JobFailed))
+
+		result, _ := jobs.Get("workspace", id)
+		Expect(result.Error).To(Equal("broken"))
+	})
+
+	It("does not report jobs of other namespaces", func() {
+		job, err := jobs.Submit(context.Background(), app, time.Minute, func(ctx context.Context, job *jobs.Job) error {
+			return nil
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, ok := jobs.Get("other", job.State().ID)
+		Expect(ok).To(BeFalse())
+	})
+})
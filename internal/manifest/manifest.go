@@ -0,0 +1,124 @@
+// Package manifest implements the reading and writing of application
+// manifests, i.e. the `epinio.yml` files declaring the configuration
+// of an application, as used by `epinio push`.
+package manifest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultName is the name of the manifest file `epinio push` looks for
+// in the application sources when no manifest was specified explicitly.
+const DefaultName = "epinio.yml"
+
+// ApplicationManifest represents the contents of an application manifest.
+// All fields are optional. Missing fields mean `default`/`no change`,
+// exactly like the missing options of `epinio push`.
+type ApplicationManifest struct {
+	Name         string               `json:"name,omitempty"`
+	Instances    *int32               `json:"instances,omitempty"`
+	Services     []string             `json:"services,omitempty"`
+	Environment  map[string]string    `json:"environment,omitempty"`
+	BuilderImage string               `json:"builder_image,omitempty"`
+	Routes       []string             `json:"routes,omitempty"`
+	HealthCheck  *models.HealthCheck  `json:"healthcheck,omitempty"`
+	Rollout      *models.Rollout      `json:"rollout,omitempty"`
+	Resources    *models.AppResources `json:"resources,omitempty"`
+}
+
+// Get reads the manifest at the specified path. A missing file is not
+// an error. The result is the empty manifest in that case.
+func Get(manifestPath string) (ApplicationManifest, error) {
+	manifest := ApplicationManifest{}
+
+	content, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return manifest, nil
+		}
+		return manifest, errors.Wrapf(err, "failed to read manifest %s", manifestPath)
+	}
+
+	err = yaml.Unmarshal(content, &manifest)
+	if err != nil {
+		return manifest, errors.Wrapf(err, "failed to parse manifest %s", manifestPath)
+	}
+
+	if manifest.Instances != nil && *manifest.Instances < 0 {
+		return manifest, fmt.Errorf("bad manifest %s: instances must not be negative", manifestPath)
+	}
+
+	return manifest, nil
+}
+
+// Save writes the manifest to the specified path, replacing any
+// existing file.
+func Save(manifest ApplicationManifest, manifestPath string) error {
+	content, err := yaml.Marshal(manifest)
+	if err != nil {
+		return errors.Wrap(err, "failed to serialize manifest")
+	}
+
+	err = ioutil.WriteFile(manifestPath, content, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write manifest %s", manifestPath)
+	}
+
+	return nil
+}
+
+// FromApp returns the manifest describing the configuration of the
+// given application, as known to the server.
+func FromApp(app models.App) ApplicationManifest {
+	manifest := ApplicationManifest{
+		Name:        app.Meta.Name,
+		Instances:   app.Configuration.Instances,
+		Services:    app.Configuration.Services,
+		Routes:      app.Configuration.Routes,
+		HealthCheck: app.Configuration.HealthCheck,
+		Rollout:     app.Configuration.Rollout,
+	}
+
+	if app.Configuration.Resources != nil && *app.Configuration.Resources != (models.AppResources{}) {
+		manifest.Resources = app.Configuration.Resources
+	}
+
+	if len(app.Configuration.Environment) > 0 {
+		manifest.Environment = map[string]string{}
+		for _, ev := range app.Configuration.Environment {
+			manifest.Environment[ev.Name] = ev.Value
+		}
+	}
+
+	return manifest
+}
+
+// Configuration returns the application configuration declared by
+// the manifest, in the form used by the API.
+func (m ApplicationManifest) Configuration() models.ApplicationUpdateRequest {
+	result := models.ApplicationUpdateRequest{
+		Instances:   m.Instances,
+		Services:    m.Services,
+		Routes:      m.Routes,
+		HealthCheck: m.HealthCheck,
+		Rollout:     m.Rollout,
+		Resources:   m.Resources,
+	}
+
+	for name, value := range m.Environment {
+		result.Environment = append(result.Environment, models.EnvVariable{
+			Name:  name,
+			Value: value,
+		})
+	}
+	sort.Sort(result.Environment)
+
+	return result
+}
@@ -0,0 +1,131 @@
+package manifest_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/epinio/epinio/internal/manifest"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Manifest", func() {
+	var manifestDir, manifestPath string
+	var err error
+
+	BeforeEach(func() {
+		manifestDir, err = ioutil.TempDir("", "epinio-test")
+		Expect(err).ToNot(HaveOccurred())
+		manifestPath = path.Join(manifestDir, manifest.DefaultName)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(manifestDir)
+	})
+
+	It("returns an empty manifest for a missing file", func() {
+		m, err := manifest.Get(manifestPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m).To(Equal(manifest.ApplicationManifest{}))
+	})
+
+	It("reads the application configuration", func() {
+		err := ioutil.WriteFile(manifestPath, []byte(`
+name: sample
+instances: 2
+services:
+- mydb
+environment:
+  B: two
+  A: one
+builder_image: paketobuildpacks/builder:tiny
+`), 0600)
+		Expect(err).ToNot(HaveOccurred())
+
+		m, err := manifest.Get(manifestPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Name).To(Equal("sample"))
+		Expect(m.BuilderImage).To(Equal("paketobuildpacks/builder:tiny"))
+
+		config := m.Configuration()
+		Expect(*config.Instances).To(Equal(int32(2)))
+		Expect(config.Services).To(Equal([]string{"mydb"}))
+		Expect(config.Environment).To(Equal(models.EnvVariableList{
+			{Name: "A", Value: "one"},
+			{Name: "B", Value: "two"},
+		}))
+	})
+
+	It("reads the health check and rollout settings", func() {
+		err := ioutil.WriteFile(manifestPath, []byte(`
+healthcheck:
+  type: http
+  path: /health
+  initial_delay: 20
+rollout:
+  max_surge: "1"
+`), 0600)
+		Expect(err).ToNot(HaveOccurred())
+
+		m, err := manifest.Get(manifestPath)
+		Expect(err).ToNot(HaveOccurred())
+
+		config := m.Configuration()
+		Expect(*config.HealthCheck).To(Equal(models.HealthCheck{
+			Type:         models.HealthCheckHTTP,
+			Path:         "/health",
+			InitialDelay: 20,
+		}))
+		Expect(*config.Rollout).To(Equal(models.Rollout{MaxSurge: "1"}))
+	})
+
+	It("reads the compute resources", func() {
+		err := ioutil.WriteFile(manifestPath, []byte(`
+resources:
+  memory_request: 256Mi
+  memory_limit: 512Mi
+  cpu_request: 250m
+`), 0600)
+		Expect(err).ToNot(HaveOccurred())
+
+		m, err := manifest.Get(manifestPath)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(*m.Configuration().Resources).To(Equal(models.AppResources{
+			MemoryRequest: "256Mi",
+			MemoryLimit:   "512Mi",
+			CPURequest:    "250m",
+		}))
+	})
+
+	It("rejects a negative number of instances", func() {
+		err := ioutil.WriteFile(manifestPath, []byte("instances: -1\n"), 0600)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = manifest.Get(manifestPath)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("round-trips the configuration of an application", func() {
+		instances := int32(3)
+		app := models.App{
+			Meta: models.AppRef{Name: "sample", Org: "workspace"},
+			Configuration: models.ApplicationUpdateRequest{
+				Instances:   &instances,
+				Services:    []string{"mydb"},
+				Environment: models.EnvVariableList{{Name: "A", Value: "one"}},
+				Routes:      []string{"sample.example.com", "example.com/sample"},
+			},
+		}
+
+		err := manifest.Save(manifest.FromApp(app), manifestPath)
+		Expect(err).ToNot(HaveOccurred())
+
+		m, err := manifest.Get(manifestPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Name).To(Equal("sample"))
+		Expect(m.Configuration()).To(Equal(app.Configuration))
+	})
+})
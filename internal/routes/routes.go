@@ -0,0 +1,99 @@
+// Package routes implements the parsing and handling of application
+// routes, i.e. the HOST[/PATH] specifications under which an
+// application is reachable through its ingress.
+package routes
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Route is a parsed application route.
+type Route struct {
+	Host string
+	Path string
+}
+
+// badPathChars are the characters not allowed in route paths. Besides
+// whitespace these are the characters the ingress controllers may
+// interpret as regular expression.
+const badPathChars = `^$*+?()[]{}|\`
+
+// FromString parses a route specification of the form HOST[/PATH].
+// The host is lower-cased, and has to be a valid DNS name. The path
+// defaults to `/`. It must not contain whitespace, nor regular
+// expression characters.
+func FromString(spec string) (Route, error) {
+	host := spec
+	path := "/"
+
+	if idx := strings.Index(spec, "/"); idx >= 0 {
+		host = spec[:idx]
+		path = spec[idx:]
+	}
+
+	host = strings.ToLower(host)
+
+	if errs := validation.IsDNS1123Subdomain(host); len(errs) > 0 {
+		return Route{}, fmt.Errorf("bad route '%s': host %s", spec, strings.Join(errs, ", "))
+	}
+
+	if strings.IndexFunc(path, unicode.IsSpace) >= 0 {
+		return Route{}, fmt.Errorf("bad route '%s': path contains whitespace", spec)
+	}
+	if strings.ContainsAny(path, badPathChars) {
+		return Route{}, fmt.Errorf("bad route '%s': path contains one of the characters %s", spec, badPathChars)
+	}
+
+	return Route{Host: host, Path: path}, nil
+}
+
+// String returns the specification of the route, in the form accepted
+// by FromString. The default path is left out.
+func (r Route) String() string {
+	if r.Path == "/" {
+		return r.Host
+	}
+	return r.Host + r.Path
+}
+
+// FromStrings parses a list of route specifications. Duplicates are
+// dropped, keeping the order of the first occurrences.
+func FromStrings(specs []string) ([]Route, error) {
+	result := []Route{}
+	seen := map[string]struct{}{}
+
+	for _, spec := range specs {
+		route, err := FromString(spec)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[route.String()]; ok {
+			continue
+		}
+		seen[route.String()] = struct{}{}
+		result = append(result, route)
+	}
+
+	return result, nil
+}
+
+// Hosts returns the distinct hosts of the routes, in order of first
+// occurrence.
+func Hosts(routes []Route) []string {
+	result := []string{}
+	seen := map[string]struct{}{}
+
+	for _, route := range routes {
+		if _, ok := seen[route.Host]; ok {
+			continue
+		}
+		seen[route.Host] = struct{}{}
+		result = append(result, route.Host)
+	}
+
+	return result
+}
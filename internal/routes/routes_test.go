@@ -0,0 +1,56 @@
+package routes_test
+
+import (
+	"github.com/epinio/epinio/internal/routes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Routes", func() {
+	Describe("FromString", func() {
+		It("defaults the path", func() {
+			route, err := routes.FromString("app.example.com")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(route).To(Equal(routes.Route{Host: "app.example.com", Path: "/"}))
+			Expect(route.String()).To(Equal("app.example.com"))
+		})
+
+		It("splits host and path", func() {
+			route, err := routes.FromString("App.Example.com/api/v1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(route).To(Equal(routes.Route{Host: "app.example.com", Path: "/api/v1"}))
+			Expect(route.String()).To(Equal("app.example.com/api/v1"))
+		})
+
+		It("rejects bad hosts", func() {
+			_, err := routes.FromString("under_score.example.com")
+			Expect(err).To(HaveOccurred())
+
+			_, err = routes.FromString("/path")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("rejects bad paths", func() {
+			_, err := routes.FromString("app.example.com/api v1")
+			Expect(err).To(MatchError("bad route 'app.example.com/api v1': path contains whitespace"))
+
+			_, err = routes.FromString("app.example.com/api/.*")
+			Expect(err).To(HaveOccurred())
+
+			_, err = routes.FromString("app.example.com/(api|admin)")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("FromStrings", func() {
+		It("drops duplicates", func() {
+			result, err := routes.FromStrings([]string{"a.com", "b.com/x", "A.com/", "b.com/x"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal([]routes.Route{
+				{Host: "a.com", Path: "/"},
+				{Host: "b.com", Path: "/x"},
+			}))
+			Expect(routes.Hosts(result)).To(Equal([]string{"a.com", "b.com"}))
+		})
+	})
+})
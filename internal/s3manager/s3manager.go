@@ -5,7 +5,10 @@ package s3manager
 import (
 	"context"
 	"fmt"
+	"io"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/epinio/epinio/helpers/kubernetes"
 	"github.com/google/uuid"
@@ -161,11 +164,17 @@ region = %s
 // Upload uploads the given file to the S3 endpoint and returns a blobUID which
 // can later be used to fetch the same file.
 func (m *Manager) Upload(ctx context.Context, filepath string, metadata map[string]string) (string, error) {
+	return m.UploadAs(ctx, uuid.New().String(), filepath, metadata)
+}
+
+// UploadAs uploads the given file to the S3 endpoint, as the named object.
+// An existing object of the name is replaced. It returns the name, i.e.
+// the blobUID.
+func (m *Manager) UploadAs(ctx context.Context, objectName, filepath string, metadata map[string]string) (string, error) {
 	if err := m.EnsureBucket(ctx); err != nil {
 		return "", errors.Wrap(err, "ensuring bucket")
 	}
 
-	objectName := uuid.New().String()
 	contentType := "application/tar"
 
 	_, err := m.minioClient.FPutObject(ctx, m.connectionDetails.Bucket,
@@ -177,6 +186,45 @@ func (m *Manager) Upload(ctx context.Context, filepath string, metadata map[stri
 	return objectName, nil
 }
 
+// Get returns a reader for the object with the given blobUID, as
+// returned by Upload. The caller has to close it.
+func (m *Manager) Get(ctx context.Context, objectID string) (io.ReadCloser, error) {
+	object, err := m.minioClient.GetObject(ctx, m.connectionDetails.Bucket, objectID,
+		minio.GetObjectOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "reading the object")
+	}
+
+	return object, nil
+}
+
+// Metadata returns the metadata of the object with the given blobUID. The
+// keys are lower case.
+func (m *Manager) Metadata(ctx context.Context, objectID string) (map[string]string, error) {
+	stat, err := m.minioClient.StatObject(ctx, m.connectionDetails.Bucket, objectID,
+		minio.StatObjectOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "reading the object metadata")
+	}
+
+	return userMetadata(stat.UserMetadata), nil
+}
+
+// Exists returns whether the object with the given blobUID is in the storage
+func (m *Manager) Exists(ctx context.Context, objectID string) (bool, error) {
+	_, err := m.minioClient.StatObject(ctx, m.connectionDetails.Bucket, objectID,
+		minio.StatObjectOptions{})
+	if err == nil {
+		return true, nil
+	}
+
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NoSuchBucket":
+		return false, nil
+	}
+	return false, errors.Wrap(err, "reading the object metadata")
+}
+
 // EnsureBucket creates our bucket if it's missing
 func (m *Manager) EnsureBucket(ctx context.Context) error {
 	exists, err := m.minioClient.BucketExists(ctx, m.connectionDetails.Bucket)
@@ -196,3 +244,63 @@ func (m *Manager) DeleteObject(ctx context.Context, objectID string) error {
 	return m.minioClient.RemoveObject(ctx, m.connectionDetails.Bucket, objectID,
 		minio.RemoveObjectOptions{})
 }
+
+// Object describes an object in the storage. The keys of the metadata
+// are lower case.
+type Object struct {
+	ID       string
+	Size     int64
+	Modified time.Time
+	Metadata map[string]string
+}
+
+// List returns all objects in the storage, with their metadata. The
+// metadata is taken from the listing, if the store supports minio's
+// extension for that, else from the objects themselves.
+func (m *Manager) List(ctx context.Context) ([]Object, error) {
+	exists, err := m.minioClient.BucketExists(ctx, m.connectionDetails.Bucket)
+	if err != nil {
+		return nil, errors.Wrap(err, "checking the bucket")
+	}
+	if !exists {
+		return []Object{}, nil
+	}
+
+	objects := []Object{}
+	for info := range m.minioClient.ListObjects(ctx, m.connectionDetails.Bucket,
+		minio.ListObjectsOptions{Recursive: true, WithMetadata: true}) {
+		if info.Err != nil {
+			return nil, errors.Wrap(info.Err, "listing the objects")
+		}
+
+		metadata := userMetadata(info.UserMetadata)
+		if info.UserMetadata == nil {
+			// Listings do not carry the user metadata, except for minio's own extension.
+			stat, err := m.minioClient.StatObject(ctx, m.connectionDetails.Bucket, info.Key,
+				minio.StatObjectOptions{})
+			if err != nil {
+				return nil, errors.Wrap(err, "reading the object metadata")
+			}
+			metadata = userMetadata(stat.UserMetadata)
+		}
+
+		objects = append(objects, Object{
+			ID:       info.Key,
+			Size:     info.Size,
+			Modified: info.LastModified,
+			Metadata: metadata,
+		})
+	}
+
+	return objects, nil
+}
+
+// userMetadata returns the user metadata of an object with lower case
+// keys. The `x-amz-meta-` prefix of the keys in listings is removed.
+func userMetadata(userMetadata map[string]string) map[string]string {
+	metadata := map[string]string{}
+	for key, value := range userMetadata {
+		metadata[strings.TrimPrefix(strings.ToLower(key), "x-amz-meta-")] = value
+	}
+	return metadata
+}
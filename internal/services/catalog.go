@@ -0,0 +1,439 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/duration"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Catalog services are provisioned by an Open Service Broker, through the
+// kubernetes service catalog. Epinio creates a ServiceInstance for each of
+// them, and a ServiceBinding per application bound to it. The service
+// catalog places the credentials of a binding into a secret, which is
+// mounted into the application, like the secret of a custom service.
+
+// ErrUnknownClass is returned when provisioning a service of a class the
+// brokers do not offer.
+var ErrUnknownClass = errors.New("unknown service class")
+
+// ErrUnknownPlan is returned when provisioning a service with a plan its
+// class does not offer.
+var ErrUnknownPlan = errors.New("unknown service plan")
+
+// Catalog returns the service classes offered by the brokers registered
+// with the service catalog, with their plans, sorted by name.
+func Catalog(ctx context.Context, kubeClient *kubernetes.Cluster) (models.CatalogServiceList, error) {
+	classClient, err := kubeClient.ClientServiceCatalog("clusterserviceclasses")
+	if err != nil {
+		return nil, err
+	}
+	planClient, err := kubeClient.ClientServiceCatalog("clusterserviceplans")
+	if err != nil {
+		return nil, err
+	}
+
+	classes, err := classClient.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	plans, err := planClient.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return CatalogFromResources(classes.Items, plans.Items), nil
+}
+
+// CatalogFromResources assembles the catalog from the ClusterServiceClass
+// and ClusterServicePlan resources of the service catalog. Classes removed
+// from the catalog of their broker are skipped.
+func CatalogFromResources(classes, plans []unstructured.Unstructured) models.CatalogServiceList {
+	plansOf := map[string][]models.CatalogServicePlan{}
+	for _, plan := range plans {
+		if removed, _, _ := unstructured.NestedBool(plan.Object, "status", "removedFromBrokerCatalog"); removed {
+			continue
+		}
+
+		classRef, _, _ := unstructured.NestedString(plan.Object, "spec", "clusterServiceClassRef", "name")
+		name, _, _ := unstructured.NestedString(plan.Object, "spec", "externalName")
+		description, _, _ := unstructured.NestedString(plan.Object, "spec", "description")
+		free, _, _ := unstructured.NestedBool(plan.Object, "spec", "free")
+
+		plansOf[classRef] = append(plansOf[classRef], models.CatalogServicePlan{
+			Name:        name,
+			Description: description,
+			Free:        free,
+		})
+	}
+
+	result := models.CatalogServiceList{}
+	for _, class := range classes {
+		if removed, _, _ := unstructured.NestedBool(class.Object, "status", "removedFromBrokerCatalog"); removed {
+			continue
+		}
+
+		name, _, _ := unstructured.NestedString(class.Object, "spec", "externalName")
+		description, _, _ := unstructured.NestedString(class.Object, "spec", "description")
+		broker, _, _ := unstructured.NestedString(class.Object, "spec", "clusterServiceBrokerName")
+
+		classPlans := plansOf[class.GetName()]
+		if classPlans == nil {
+			classPlans = []models.CatalogServicePlan{}
+		}
+		sort.Slice(classPlans, func(i, j int) bool {
+			return classPlans[i].Name < classPlans[j].Name
+		})
+
+		result = append(result, models.CatalogService{
+			Name:        name,
+			Description: description,
+			Broker:      broker,
+			Plans:       classPlans,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// CreateCatalogService provisions a new service instance of the class and
+// plan, with the parameters, through the service catalog. It waits for the
+// broker to complete the provisioning. A failed or timed out instance is
+// removed again.
+func CreateCatalogService(ctx context.Context, kubeClient *kubernetes.Cluster, name, org, username, class, plan string,
+	params map[string]interface{}) (*Service, error) {
+
+	catalog, err := Catalog(ctx, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+	err = catalogCheck(catalog, class, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	instanceClient, err := kubeClient.ClientServiceCatalog("serviceinstances")
+	if err != nil {
+		return nil, err
+	}
+
+	resourceName := serviceResourceName(org, name)
+
+	spec := map[string]interface{}{
+		"clusterServiceClassExternalName": class,
+		"clusterServicePlanExternalName":  plan,
+	}
+	if len(params) > 0 {
+		// Round-trip through JSON to get the plain types the unstructured
+		// content requires, i.e. no typed numbers, slices or maps.
+		var plainParams map[string]interface{}
+		b, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(b, &plainParams)
+		if err != nil {
+			return nil, err
+		}
+		spec["parameters"] = plainParams
+	}
+
+	instance := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "servicecatalog.k8s.io/v1beta1",
+			"kind":       "ServiceInstance",
+			"metadata": map[string]interface{}{
+				"name":      resourceName,
+				"namespace": org,
+				"labels":    serviceLabels(name, org, username),
+			},
+			"spec": spec,
+		},
+	}
+
+	_, err = instanceClient.Namespace(org).Create(ctx, instance, metav1.CreateOptions{})
+	if err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return nil, errors.New("Service of this name already exists.")
+		}
+		return nil, err
+	}
+
+	err = waitForCatalogResource(ctx, instanceClient.Namespace(org), resourceName, duration.ToServiceProvision())
+	if err != nil {
+		// Do not leave the failed instance behind, it would block the name.
+		// The request's context may be done already, hence the background.
+		derr := instanceClient.Namespace(org).Delete(context.Background(), resourceName, metav1.DeleteOptions{})
+		if derr != nil && !apierrors.IsNotFound(derr) {
+			return nil, fmt.Errorf("provisioning service '%s' failed: %s, and removing it failed: %s",
+				name, err.Error(), derr.Error())
+		}
+		return nil, fmt.Errorf("provisioning service '%s' failed: %s", name, err.Error())
+	}
+
+	return &Service{
+		SecretName: resourceName,
+		OrgName:    org,
+		Service:    name,
+		Username:   username,
+		Class:      class,
+		Plan:       plan,
+		kubeClient: kubeClient,
+	}, nil
+}
+
+// CatalogReady checks the conditions of a ServiceInstance or ServiceBinding
+// resource. The result is true if the resource is ready. An error is
+// returned if the service catalog reports that the resource failed.
+func CatalogReady(obj *unstructured.Unstructured) (bool, error) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+
+	ready := false
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if condition["status"] != "True" {
+			continue
+		}
+
+		switch condition["type"] {
+		case "Ready":
+			ready = true
+		case "Failed":
+			return false, fmt.Errorf("%v: %v", condition["reason"], condition["message"])
+		}
+	}
+
+	return ready, nil
+}
+
+// catalogCheck verifies that the catalog offers the class and plan
+func catalogCheck(catalog models.CatalogServiceList, class, plan string) error {
+	for _, service := range catalog {
+		if service.Name != class {
+			continue
+		}
+		for _, p := range service.Plans {
+			if p.Name == plan {
+				return nil
+			}
+		}
+		return ErrUnknownPlan
+	}
+	return ErrUnknownClass
+}
+
+// waitForCatalogResource waits until the named ServiceInstance or
+// ServiceBinding is ready, or failed.
+func waitForCatalogResource(ctx context.Context, client catalogGetter, name string, timeout time.Duration) error {
+	return wait.PollImmediate(duration.PollInterval(), timeout, func() (bool, error) {
+		obj, err := client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return CatalogReady(obj)
+	})
+}
+
+// catalogGetter is the part of the dynamic client used by waitForCatalogResource
+type catalogGetter interface {
+	Get(ctx context.Context, name string, options metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error)
+}
+
+// lookupCatalogService locates the catalog service by org and name.
+func lookupCatalogService(ctx context.Context, kubeClient *kubernetes.Cluster, org, service string) (*Service, error) {
+	instanceClient, err := kubeClient.ClientServiceCatalog("serviceinstances")
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := instanceClient.Namespace(org).Get(ctx, serviceResourceName(org, service), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return catalogServiceFrom(kubeClient, instance), nil
+}
+
+// listCatalogServices returns the catalog services in the org.
+func listCatalogServices(ctx context.Context, kubeClient *kubernetes.Cluster, org string) (ServiceList, error) {
+	instanceClient, err := kubeClient.ClientServiceCatalog("serviceinstances")
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := instanceClient.Namespace(org).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app.kubernetes.io/name=epinio, epinio.suse.org/namespace=%s", org),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := ServiceList{}
+	for i := range instances.Items {
+		result = append(result, catalogServiceFrom(kubeClient, &instances.Items[i]))
+	}
+
+	return result, nil
+}
+
+// catalogServiceFrom returns the service for the ServiceInstance resource
+func catalogServiceFrom(kubeClient *kubernetes.Cluster, instance *unstructured.Unstructured) *Service {
+	class, _, _ := unstructured.NestedString(instance.Object, "spec", "clusterServiceClassExternalName")
+	plan, _, _ := unstructured.NestedString(instance.Object, "spec", "clusterServicePlanExternalName")
+	labels := instance.GetLabels()
+
+	return &Service{
+		SecretName: instance.GetName(),
+		OrgName:    labels["epinio.suse.org/namespace"],
+		Service:    labels["epinio.suse.org/service"],
+		Username:   labels["app.kubernetes.io/created-by"],
+		Class:      class,
+		Plan:       plan,
+		kubeClient: kubeClient,
+	}
+}
+
+// catalogBinding returns the secret holding the credentials of the
+// binding of the catalog service to the application. It creates the
+// ServiceBinding if necessary, and waits for the secret. The binding is
+// owned by the application, and removed with it.
+func (s *Service) catalogBinding(ctx context.Context, appName, username string) (*corev1.Secret, error) {
+	bindingClient, err := s.kubeClient.ClientServiceCatalog("servicebindings")
+	if err != nil {
+		return nil, err
+	}
+
+	bindingName := bindingResourceName(s.OrgName, s.Service, appName)
+
+	_, err = bindingClient.Namespace(s.OrgName).Get(ctx, bindingName, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		owner, err := appOwnerReference(ctx, s.kubeClient, s.OrgName, appName)
+		if err != nil {
+			return nil, err
+		}
+
+		labels := bindingLabels(s.Service, s.OrgName, appName, username)
+
+		binding := &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "servicecatalog.k8s.io/v1beta1",
+				"kind":       "ServiceBinding",
+				"metadata": map[string]interface{}{
+					"name":      bindingName,
+					"namespace": s.OrgName,
+					"labels":    labels,
+				},
+				"spec": map[string]interface{}{
+					"instanceRef": map[string]interface{}{
+						"name": s.SecretName,
+					},
+					"secretName": bindingName,
+				},
+			},
+		}
+		binding.SetOwnerReferences([]metav1.OwnerReference{owner})
+
+		_, err = bindingClient.Namespace(s.OrgName).Create(ctx, binding, metav1.CreateOptions{})
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			return nil, err
+		}
+	}
+
+	err = waitForCatalogResource(ctx, bindingClient.Namespace(s.OrgName), bindingName, duration.ToServiceSecret())
+	if err != nil {
+		return nil, fmt.Errorf("binding service '%s' failed: %s", s.Service, err.Error())
+	}
+
+	return s.kubeClient.WaitForSecret(ctx, s.OrgName, bindingName, duration.ToServiceSecret())
+}
+
+// deleteCatalogBinding removes the ServiceBinding of the catalog service to
+// the application, if any. The service catalog removes its secret.
+func (s *Service) deleteCatalogBinding(ctx context.Context, appName string) error {
+	bindingClient, err := s.kubeClient.ClientServiceCatalog("servicebindings")
+	if err != nil {
+		return err
+	}
+
+	err = bindingClient.Namespace(s.OrgName).Delete(ctx,
+		bindingResourceName(s.OrgName, s.Service, appName), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// deleteCatalogService removes the ServiceInstance. The service catalog
+// has the broker deprovision the service.
+func (s *Service) deleteCatalogService(ctx context.Context) error {
+	instanceClient, err := s.kubeClient.ClientServiceCatalog("serviceinstances")
+	if err != nil {
+		return err
+	}
+
+	return instanceClient.Namespace(s.OrgName).Delete(ctx, s.SecretName, metav1.DeleteOptions{})
+}
+
+// catalogDetails returns the parameters the catalog service was provisioned
+// with. Values which are not strings are shown as JSON.
+func (s *Service) catalogDetails(ctx context.Context) (map[string]string, error) {
+	instanceClient, err := s.kubeClient.ClientServiceCatalog("serviceinstances")
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := instanceClient.Namespace(s.OrgName).Get(ctx, s.SecretName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, errors.New("service does not exist")
+		}
+		return nil, err
+	}
+
+	details := map[string]string{}
+
+	params, _, _ := unstructured.NestedMap(instance.Object, "spec", "parameters")
+	for k, v := range params {
+		if value, ok := v.(string); ok {
+			details[k] = value
+			continue
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		details[k] = string(value)
+	}
+
+	return details, nil
+}
+
+// bindingResourceName returns a name for the kube resources representing
+// the binding of the service to the application
+func bindingResourceName(org, service, app string) string {
+	return fmt.Sprintf("svc.org-%s.svc-%s.app-%s", org, service, app)
+}
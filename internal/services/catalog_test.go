@@ -0,0 +1,113 @@
+package services_test
+
+import (
+	"github.com/epinio/epinio/internal/services"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func catalogResource(name string, spec, status map[string]interface{}) unstructured.Unstructured {
+	return unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name},
+			"spec":     spec,
+			"status":   status,
+		},
+	}
+}
+
+var _ = Describe("Catalog", func() {
+	Describe("CatalogFromResources", func() {
+		It("assembles classes and their plans, sorted by name", func() {
+			classes := []unstructured.Unstructured{
+				catalogResource("uid-redis", map[string]interface{}{
+					"externalName":             "redis",
+					"description":              "Redis",
+					"clusterServiceBrokerName": "fake",
+				}, nil),
+				catalogResource("uid-mysql", map[string]interface{}{
+					"externalName":             "mysql",
+					"clusterServiceBrokerName": "fake",
+				}, nil),
+				catalogResource("uid-gone", map[string]interface{}{
+					"externalName": "gone",
+				}, map[string]interface{}{"removedFromBrokerCatalog": true}),
+			}
+			plans := []unstructured.Unstructured{
+				catalogResource("uid-large", map[string]interface{}{
+					"externalName":           "large",
+					"clusterServiceClassRef": map[string]interface{}{"name": "uid-redis"},
+				}, nil),
+				catalogResource("uid-small", map[string]interface{}{
+					"externalName":           "small",
+					"description":            "Small",
+					"free":                   true,
+					"clusterServiceClassRef": map[string]interface{}{"name": "uid-redis"},
+				}, nil),
+			}
+
+			Expect(services.CatalogFromResources(classes, plans)).To(Equal(models.CatalogServiceList{
+				{
+					Name:   "mysql",
+					Broker: "fake",
+					Plans:  []models.CatalogServicePlan{},
+				},
+				{
+					Name:        "redis",
+					Description: "Redis",
+					Broker:      "fake",
+					Plans: []models.CatalogServicePlan{
+						{Name: "large"},
+						{Name: "small", Description: "Small", Free: true},
+					},
+				},
+			}))
+		})
+	})
+
+	Describe("CatalogReady", func() {
+		condition := func(kind, status string) map[string]interface{} {
+			return map[string]interface{}{
+				"type":    kind,
+				"status":  status,
+				"reason":  "Reason",
+				"message": "message",
+			}
+		}
+
+		It("is not ready without conditions", func() {
+			obj := catalogResource("x", nil, nil)
+			ready, err := services.CatalogReady(&obj)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ready).To(BeFalse())
+		})
+
+		It("is not ready while the ready condition is false", func() {
+			obj := catalogResource("x", nil, map[string]interface{}{
+				"conditions": []interface{}{condition("Ready", "False")},
+			})
+			ready, err := services.CatalogReady(&obj)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ready).To(BeFalse())
+		})
+
+		It("is ready when the ready condition is true", func() {
+			obj := catalogResource("x", nil, map[string]interface{}{
+				"conditions": []interface{}{condition("Ready", "True")},
+			})
+			ready, err := services.CatalogReady(&obj)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ready).To(BeTrue())
+		})
+
+		It("fails when the failed condition is true", func() {
+			obj := catalogResource("x", nil, map[string]interface{}{
+				"conditions": []interface{}{condition("Ready", "False"), condition("Failed", "True")},
+			})
+			_, err := services.CatalogReady(&obj)
+			Expect(err).To(MatchError("Reason: message"))
+		})
+	})
+})
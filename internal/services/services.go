@@ -5,21 +5,32 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 
 	"github.com/epinio/epinio/helpers/kubernetes"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
 )
 
+// ErrCatalogUpdate is returned by Update for catalog services, whose
+// parameters are managed by their broker.
+var ErrCatalogUpdate = errors.New("catalog services cannot be updated")
+
 type ServiceList []*Service
 
 // Service contains the information needed for Epinio to address a specific service.
+// Custom services are backed by a secret holding their parameters. Catalog services
+// are provisioned by a broker, see catalog.go. Only the latter have a Class and Plan.
 type Service struct {
 	SecretName string
 	OrgName    string
 	Service    string
 	Username   string
+	Class      string
+	Plan       string
 	kubeClient *kubernetes.Cluster
 }
 
@@ -32,10 +43,19 @@ func Lookup(ctx context.Context, kubeClient *kubernetes.Cluster, org, service st
 
 	s, err := kubeClient.GetSecret(ctx, org, secretName)
 	if err != nil {
-		if apierrors.IsNotFound(err) {
-			return nil, errors.New("service not found")
+		if !apierrors.IsNotFound(err) {
+			return nil, err
 		}
-		return nil, err
+
+		// Not a custom service. Try the catalog.
+		service, err := lookupCatalogService(ctx, kubeClient, org, service)
+		if err != nil {
+			if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				return nil, errors.New("service not found")
+			}
+			return nil, err
+		}
+		return service, nil
 	}
 	username := s.ObjectMeta.Labels["app.kubernetes.io/created-by"]
 
@@ -80,7 +100,16 @@ func List(ctx context.Context, kubeClient *kubernetes.Cluster, org string) (Serv
 		})
 	}
 
-	return result, nil
+	catalogServices, err := listCatalogServices(ctx, kubeClient, org)
+	if err != nil {
+		// Without service catalog there are no catalog services.
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return result, nil
+		}
+		return nil, err
+	}
+
+	return append(result, catalogServices...), nil
 }
 
 // CreateService creates a new  service instance from org,
@@ -103,17 +132,7 @@ func CreateService(ctx context.Context, kubeClient *kubernetes.Cluster, name, or
 	}
 
 	err = kubeClient.CreateLabeledSecret(ctx, org, secretName, sdata,
-		map[string]string{
-			// "epinio.suse.org/service-type": "custom",
-			"epinio.suse.org/service":      name,
-			"epinio.suse.org/namespace":    org,
-			"app.kubernetes.io/name":       "epinio",
-			"app.kubernetes.io/created-by": username,
-			// "app.kubernetes.io/version":     cmd.Version
-			// FIXME: Importing cmd causes cycle
-			// FIXME: Move version info to separate package!
-		},
-	)
+		serviceLabels(name, org, username))
 	if err != nil {
 		return nil, err
 	}
@@ -140,31 +159,90 @@ func (s *Service) Org() string {
 	return s.OrgName
 }
 
+// IsCatalog returns true for services provisioned by a broker
+func (s *Service) IsCatalog() bool {
+	return s.Class != ""
+}
+
 // GetBinding returns the secret representing the instance's binding
-// to the application. This is actually the instance's secret itself,
-// independent of the application.
-func (s *Service) GetBinding(ctx context.Context, appName string, _ string) (*corev1.Secret, error) {
-	kubeClient := s.kubeClient
-	serviceSecret, err := kubeClient.GetSecret(ctx, s.OrgName, s.SecretName)
-	if err != nil {
-		if apierrors.IsNotFound(err) {
-			return nil, errors.New("service does not exist")
-		}
-		return nil, err
+// to the application, created as needed. For custom services this is a
+// copy of the instance's parameters, owned by the application, and kept
+// up to date with the instance's secret. For catalog services it is the
+// secret holding the credentials of the binding to the application.
+func (s *Service) GetBinding(ctx context.Context, appName string, username string) (*corev1.Secret, error) {
+	if s.IsCatalog() {
+		return s.catalogBinding(ctx, appName, username)
 	}
+	return s.customBinding(ctx, appName, username)
+}
 
-	return serviceSecret, nil
+// DeleteBinding removes the binding of the service to the application,
+// i.e. the binding secret, or, for catalog services, the ServiceBinding.
+func (s *Service) DeleteBinding(ctx context.Context, appName string) error {
+	if s.IsCatalog() {
+		return s.deleteCatalogBinding(ctx, appName)
+	}
+
+	err := s.kubeClient.DeleteSecret(ctx, s.OrgName, bindingResourceName(s.OrgName, s.Service, appName))
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
 }
 
 // Delete destroys the service instance, i.e. its underlying secret
-// holding the instance's parameters
+// holding the instance's parameters, or, for catalog services, the
+// provisioned instance.
 func (s *Service) Delete(ctx context.Context) error {
+	if s.IsCatalog() {
+		return s.deleteCatalogService(ctx)
+	}
 	return s.kubeClient.DeleteSecret(ctx, s.OrgName, s.SecretName)
 }
 
+// Update changes the parameters of the custom service. The keys of set
+// are added or replaced, the keys in unset removed. Bound applications
+// see the changes after their bindings are synced, see GetBinding, and
+// they are restarted. Catalog services cannot be updated.
+func (s *Service) Update(ctx context.Context, set map[string]string, unset []string) error {
+	if s.IsCatalog() {
+		return ErrCatalogUpdate
+	}
+
+	secrets := s.kubeClient.Kubectl.CoreV1().Secrets(s.OrgName)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		serviceSecret, err := secrets.Get(ctx, s.SecretName, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return errors.New("service does not exist")
+			}
+			return err
+		}
+
+		if serviceSecret.Data == nil {
+			serviceSecret.Data = make(map[string][]byte)
+		}
+		for _, key := range unset {
+			delete(serviceSecret.Data, key)
+		}
+		for key, value := range set {
+			serviceSecret.Data[key] = []byte(value)
+		}
+
+		_, err = secrets.Update(ctx, serviceSecret, metav1.UpdateOptions{})
+		return err
+	})
+}
+
 // Details returns the service instance's configuration.
 // I.e. the parameter data.
 func (s *Service) Details(ctx context.Context) (map[string]string, error) {
+	if s.IsCatalog() {
+		return s.catalogDetails(ctx)
+	}
+
 	serviceSecret, err := s.kubeClient.GetSecret(ctx, s.OrgName, s.SecretName)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
@@ -182,6 +260,117 @@ func (s *Service) Details(ctx context.Context) (map[string]string, error) {
 	return details, nil
 }
 
+// serviceLabels returns the labels identifying the kube resources of a service
+func serviceLabels(name, org, username string) map[string]string {
+	return map[string]string{
+		// "epinio.suse.org/service-type": "custom",
+		"epinio.suse.org/service":      name,
+		"epinio.suse.org/namespace":    org,
+		"app.kubernetes.io/name":       "epinio",
+		"app.kubernetes.io/created-by": username,
+		// "app.kubernetes.io/version":     cmd.Version
+		// FIXME: Importing cmd causes cycle
+		// FIXME: Move version info to separate package!
+	}
+}
+
+// customBinding returns the secret binding the custom service to the
+// application, creating it if necessary. Its data is synced from the
+// service's secret, so that changes to the service reach the application
+// on its next restart.
+func (s *Service) customBinding(ctx context.Context, appName, username string) (*corev1.Secret, error) {
+	serviceSecret, err := s.kubeClient.GetSecret(ctx, s.OrgName, s.SecretName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, errors.New("service does not exist")
+		}
+		return nil, err
+	}
+
+	bindingName := bindingResourceName(s.OrgName, s.Service, appName)
+	secrets := s.kubeClient.Kubectl.CoreV1().Secrets(s.OrgName)
+
+	var binding *corev1.Secret
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := secrets.Get(ctx, bindingName, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+
+			owner, err := appOwnerReference(ctx, s.kubeClient, s.OrgName, appName)
+			if err != nil {
+				return err
+			}
+
+			binding, err = secrets.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            bindingName,
+					Namespace:       s.OrgName,
+					OwnerReferences: []metav1.OwnerReference{owner},
+					Labels:          bindingLabels(s.Service, s.OrgName, appName, username),
+				},
+				Data: serviceSecret.Data,
+			}, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// Created concurrently. Retry to load it.
+				return apierrors.NewConflict(corev1.Resource("secrets"), bindingName, err)
+			}
+			return err
+		}
+
+		if reflect.DeepEqual(current.Data, serviceSecret.Data) {
+			binding = current
+			return nil
+		}
+
+		current.Data = serviceSecret.Data
+		binding, err = secrets.Update(ctx, current, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return binding, nil
+}
+
+// appOwnerReference returns the reference to the named application, for
+// making it the owner of the resources binding services to it. These
+// are then removed together with the application.
+func appOwnerReference(ctx context.Context, kubeClient *kubernetes.Cluster, org, appName string) (metav1.OwnerReference, error) {
+	appClient, err := kubeClient.ClientApp()
+	if err != nil {
+		return metav1.OwnerReference{}, err
+	}
+
+	app, err := appClient.Namespace(org).Get(ctx, appName, metav1.GetOptions{})
+	if err != nil {
+		return metav1.OwnerReference{}, err
+	}
+
+	return metav1.OwnerReference{
+		APIVersion: app.GetAPIVersion(),
+		Kind:       app.GetKind(),
+		Name:       app.GetName(),
+		UID:        app.GetUID(),
+	}, nil
+}
+
+// bindingLabels returns the labels identifying the kube resources binding
+// a service to an application. Note that these are not matched by the
+// selector of List, as they lack the namespace label of the services.
+func bindingLabels(service, org, appName, username string) map[string]string {
+	return map[string]string{
+		"epinio.suse.org/service":      service,
+		"app.kubernetes.io/name":       appName,
+		"app.kubernetes.io/part-of":    org,
+		"app.kubernetes.io/component":  "service-binding",
+		"app.kubernetes.io/managed-by": "epinio",
+		"app.kubernetes.io/created-by": username,
+	}
+}
+
 // serviceResourceName returns a name for a kube service resource
 // representing the org and service
 func serviceResourceName(org, service string) string {
@@ -0,0 +1,75 @@
+package staging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+)
+
+// ErrStageFinished is returned by Cancel for staging runs which are done already.
+var ErrStageFinished = errors.New("staging run already finished")
+
+// InFlight returns the id of the application's staging run which has not
+// completed yet, if any. Completed runs have a CompletionTime.
+func InFlight(ctx context.Context, cluster *kubernetes.Cluster, app models.AppRef) (string, error) {
+	client, err := cluster.ClientTekton()
+	if err != nil {
+		return "", err
+	}
+
+	l, err := client.PipelineRuns(deployments.TektonStagingNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app.kubernetes.io/name=%s,app.kubernetes.io/part-of=%s", app.Name, app.Org),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, pr := range l.Items {
+		if pr.Status.CompletionTime == nil {
+			return pr.Labels[models.EpinioStageIDLabel], nil
+		}
+	}
+
+	return "", nil
+}
+
+// Cancel cancels the org's staging run with the given id. Tekton stops
+// the run's tasks, and the pods running them are deleted right away.
+func Cancel(ctx context.Context, cluster *kubernetes.Cluster, org, id string) error {
+	client, err := cluster.ClientTekton()
+	if err != nil {
+		return err
+	}
+	runs := client.PipelineRuns(deployments.TektonStagingNamespace)
+
+	var name string
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pr, err := pipelineRun(ctx, cluster, org, id)
+		if err != nil {
+			return err
+		}
+		if pr.Status.CompletionTime != nil {
+			return ErrStageFinished
+		}
+
+		name = pr.Name
+		pr.Spec.Status = v1beta1.PipelineRunSpecStatusCancelled
+		_, err = runs.Update(ctx, pr, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return cluster.Kubectl.CoreV1().Pods(deployments.TektonStagingNamespace).DeleteCollection(ctx,
+		metav1.DeleteOptions{},
+		metav1.ListOptions{LabelSelector: "tekton.dev/pipelineRun=" + name})
+}
@@ -0,0 +1,244 @@
+package staging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	duckv1beta1 "knative.dev/pkg/apis/duck/v1beta1"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+)
+
+// LogTailLines is the number of lines of the failed step's log reported by Status.
+const LogTailLines = 20
+
+// reasonCancelled is the reason tekton gives to cancelled pipeline runs.
+const reasonCancelled = "PipelineRunCancelled"
+
+// ErrStageNotFound is returned by Status when there is no pipeline run for the stage id.
+var ErrStageNotFound = errors.New("staging run not found")
+
+// Status returns the status of the org's staging run with the given id. For
+// a failed run it includes the tail of the log of the failed step.
+func Status(ctx context.Context, cluster *kubernetes.Cluster, org, id string) (*models.StageStatus, error) {
+	pr, err := pipelineRun(ctx, cluster, org, id)
+	if err != nil {
+		return nil, err
+	}
+
+	status := PipelineRunStatus(pr)
+
+	if status.FailedStep != nil {
+		pod, container := stepContainer(pr, status.FailedStep)
+		if pod != "" {
+			lines := int64(LogTailLines)
+			raw, err := cluster.Kubectl.CoreV1().Pods(deployments.TektonStagingNamespace).
+				GetLogs(pod, &corev1.PodLogOptions{
+					Container: container,
+					TailLines: &lines,
+				}).DoRaw(ctx)
+			// The pod may be gone already. The status is useful without the logs.
+			if err == nil {
+				status.FailedStep.Logs = strings.Split(strings.TrimRight(string(raw), "\n"), "\n")
+			}
+		}
+	}
+
+	return status, nil
+}
+
+// pipelineRun returns the pipeline run of the org's staging run with the given id.
+func pipelineRun(ctx context.Context, cluster *kubernetes.Cluster, org, id string) (*v1beta1.PipelineRun, error) {
+	client, err := cluster.ClientTekton()
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := client.PipelineRuns(deployments.TektonStagingNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,app.kubernetes.io/part-of=%s", models.EpinioStageIDLabel, id, org),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(l.Items) == 0 {
+		return nil, ErrStageNotFound
+	}
+
+	return &l.Items[0], nil
+}
+
+// PipelineRunStatus returns the status of the staging run represented by
+// the pipeline run. The tasks are ordered by their start, and the failed
+// step is the first step exiting with a non-zero code.
+func PipelineRunStatus(pr *v1beta1.PipelineRun) *models.StageStatus {
+	status := &models.StageStatus{
+		ID:       pr.Labels[models.EpinioStageIDLabel],
+		Strategy: pr.Labels[models.EpinioStagingStrategyLabel],
+		Started:  timeOf(pr.Status.StartTime),
+		Finished: timeOf(pr.Status.CompletionTime),
+	}
+	status.Phase, status.Reason, status.Message = phaseOf(pr.Status.Conditions, pr.Status.StartTime)
+
+	for _, tr := range pr.Status.TaskRuns {
+		task := models.StageTask{Name: tr.PipelineTaskName, Phase: models.StagePending}
+		if tr.Status != nil {
+			task.Phase, _, _ = phaseOf(tr.Status.Conditions, tr.Status.StartTime)
+			task.Started = timeOf(tr.Status.StartTime)
+			task.Finished = timeOf(tr.Status.CompletionTime)
+			for _, s := range tr.Status.Steps {
+				task.Steps = append(task.Steps, stepOf(tr.PipelineTaskName, s))
+			}
+		}
+		status.Tasks = append(status.Tasks, task)
+	}
+
+	sort.SliceStable(status.Tasks, func(i, j int) bool {
+		a, b := status.Tasks[i].Started, status.Tasks[j].Started
+		switch {
+		case a == nil && b == nil:
+			return status.Tasks[i].Name < status.Tasks[j].Name
+		case a == nil || b == nil:
+			return b == nil
+		case a.Equal(*b):
+			return status.Tasks[i].Name < status.Tasks[j].Name
+		}
+		return a.Before(*b)
+	})
+
+	if status.Phase == models.StageFailed {
+	found:
+		for _, task := range status.Tasks {
+			for i := range task.Steps {
+				if task.Steps[i].Phase == models.StageFailed {
+					step := task.Steps[i]
+					status.FailedStep = &step
+					break found
+				}
+			}
+		}
+		status.Hint = hintFor(status)
+	}
+
+	return status
+}
+
+// Summary returns a one line description of the failure of the staging run.
+func Summary(status *models.StageStatus) string {
+	if status.Phase == models.StageCancelled {
+		return "staging cancelled"
+	}
+
+	step := status.FailedStep
+	if step == nil {
+		if status.Message != "" {
+			return fmt.Sprintf("staging failed: %s", status.Message)
+		}
+		return "staging failed"
+	}
+
+	msg := fmt.Sprintf("staging failed in step '%s' of task '%s'", step.Name, step.Task)
+	if step.ExitCode != nil {
+		msg += fmt.Sprintf(", exit code %d", *step.ExitCode)
+	}
+	if step.Reason != "" && step.Reason != "Error" {
+		msg += fmt.Sprintf(" (%s)", step.Reason)
+	}
+	return msg
+}
+
+// phaseOf returns the phase, reason and message for the conditions of a
+// pipeline or task run. A run without a verdict is running when started.
+func phaseOf(conditions duckv1beta1.Conditions, started *metav1.Time) (string, string, string) {
+	for _, c := range conditions {
+		if c.Type != "Succeeded" {
+			continue
+		}
+		switch {
+		case c.IsTrue():
+			return models.StageSucceeded, c.Reason, c.Message
+		case c.IsFalse() && (c.Reason == reasonCancelled || c.Reason == string(v1beta1.PipelineRunReasonCancelled)):
+			return models.StageCancelled, c.Reason, c.Message
+		case c.IsFalse():
+			return models.StageFailed, c.Reason, c.Message
+		}
+	}
+
+	if started != nil {
+		return models.StageRunning, "", ""
+	}
+	return models.StagePending, "", ""
+}
+
+// stepOf converts the state of a step of the named task.
+func stepOf(task string, s v1beta1.StepState) models.StageStep {
+	step := models.StageStep{Task: task, Name: s.Name, Phase: models.StagePending}
+
+	switch {
+	case s.Terminated != nil:
+		code := s.Terminated.ExitCode
+		step.ExitCode = &code
+		step.Reason = s.Terminated.Reason
+		step.Phase = models.StageSucceeded
+		if code != 0 {
+			step.Phase = models.StageFailed
+		}
+	case s.Running != nil:
+		step.Phase = models.StageRunning
+	case s.Waiting != nil:
+		step.Reason = s.Waiting.Reason
+	}
+
+	return step
+}
+
+// stepContainer returns the pod and container which ran the step.
+func stepContainer(pr *v1beta1.PipelineRun, step *models.StageStep) (string, string) {
+	for _, tr := range pr.Status.TaskRuns {
+		if tr.PipelineTaskName != step.Task || tr.Status == nil {
+			continue
+		}
+		for _, s := range tr.Status.Steps {
+			if s.Name == step.Name {
+				return tr.Status.PodName, s.ContainerName
+			}
+		}
+	}
+	return "", ""
+}
+
+// hintFor returns a suggestion for dealing with the failure of the staging run.
+func hintFor(status *models.StageStatus) string {
+	step := status.FailedStep
+	switch {
+	case step == nil:
+		return "Check the staging logs, with `epinio app logs --staging`"
+	case step.Reason == "OOMKilled":
+		return "The step ran out of memory"
+	case step.Task == "fetch-sources" || step.Task == "extract":
+		return "The uploaded sources could not be read. Push the application again"
+	case status.Strategy == models.StagingDockerfile:
+		return "Check that the Dockerfile builds, e.g. with `docker build`"
+	case status.Strategy == models.StagingImage:
+		return "Check that the image exists, and that it is accessible without credentials"
+	case status.Strategy == models.StagingBuildpacks:
+		return "Check that the builder image supports the sources, or add a Dockerfile to build them with"
+	}
+	return "Check the staging logs, with `epinio app logs --staging`"
+}
+
+// timeOf returns the time of a kube timestamp, if any.
+func timeOf(t *metav1.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	return &t.Time
+}
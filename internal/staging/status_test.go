@@ -0,0 +1,155 @@
+package staging_test
+
+import (
+	"time"
+
+	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+
+	"github.com/epinio/epinio/internal/staging"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// succeeded returns the condition of a finished run.
+func succeeded(status corev1.ConditionStatus, reason, message string) apis.Condition {
+	return apis.Condition{
+		Type:    apis.ConditionSucceeded,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+}
+
+// terminated returns the state of a step exiting with the code.
+func terminated(name string, code int32, reason string) v1beta1.StepState {
+	return v1beta1.StepState{
+		Name:          name,
+		ContainerName: "step-" + name,
+		ContainerState: corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{ExitCode: code, Reason: reason},
+		},
+	}
+}
+
+// taskRun returns the status of a task run started at the offset.
+func taskRun(task string, offset time.Duration, condition *apis.Condition, steps ...v1beta1.StepState) *v1beta1.PipelineRunTaskRunStatus {
+	tr := &v1beta1.PipelineRunTaskRunStatus{
+		PipelineTaskName: task,
+		Status:           &v1beta1.TaskRunStatus{},
+	}
+	tr.Status.StartTime = &metav1.Time{Time: time.Unix(1000, 0).Add(offset)}
+	tr.Status.PodName = task + "-pod"
+	tr.Status.Steps = steps
+	if condition != nil {
+		tr.Status.Conditions = append(tr.Status.Conditions, *condition)
+	}
+	return tr
+}
+
+// pipelineRun returns a run of the strategy, with the task runs.
+func pipelineRun(strategy string, condition *apis.Condition, taskRuns ...*v1beta1.PipelineRunTaskRunStatus) *v1beta1.PipelineRun {
+	pr := &v1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				models.EpinioStageIDLabel:         "s-1",
+				models.EpinioStagingStrategyLabel: strategy,
+			},
+		},
+	}
+	pr.Status.StartTime = &metav1.Time{Time: time.Unix(1000, 0)}
+	if condition != nil {
+		pr.Status.Conditions = append(pr.Status.Conditions, *condition)
+	}
+	pr.Status.TaskRuns = map[string]*v1beta1.PipelineRunTaskRunStatus{}
+	for _, tr := range taskRuns {
+		pr.Status.TaskRuns[tr.PipelineTaskName+"-run"] = tr
+	}
+	return pr
+}
+
+var _ = Describe("PipelineRunStatus", func() {
+	done := succeeded(corev1.ConditionTrue, "Succeeded", "")
+
+	It("reports a run without verdict as running", func() {
+		status := staging.PipelineRunStatus(pipelineRun(models.StagingBuildpacks, nil))
+		Expect(status.ID).To(Equal("s-1"))
+		Expect(status.Strategy).To(Equal(models.StagingBuildpacks))
+		Expect(status.Phase).To(Equal(models.StageRunning))
+		Expect(status.FailedStep).To(BeNil())
+		Expect(status.Hint).To(BeEmpty())
+	})
+
+	It("reports a run not started yet as pending", func() {
+		pr := pipelineRun(models.StagingBuildpacks, nil)
+		pr.Status.StartTime = nil
+		Expect(staging.PipelineRunStatus(pr).Phase).To(Equal(models.StagePending))
+	})
+
+	It("orders the tasks by their start", func() {
+		status := staging.PipelineRunStatus(pipelineRun(models.StagingBuildpacks, &done,
+			taskRun("stage", 3*time.Second, &done, terminated("create", 0, "Completed")),
+			taskRun("cleanup", 0, &done, terminated("remove", 0, "Completed")),
+			taskRun("fetch-sources", time.Second, &done, terminated("download", 0, "Completed")),
+		))
+		Expect(status.Phase).To(Equal(models.StageSucceeded))
+		Expect(status.Tasks).To(HaveLen(3))
+		Expect(status.Tasks[0].Name).To(Equal("cleanup"))
+		Expect(status.Tasks[1].Name).To(Equal("fetch-sources"))
+		Expect(status.Tasks[2].Name).To(Equal("stage"))
+		Expect(status.Tasks[2].Phase).To(Equal(models.StageSucceeded))
+		Expect(status.Tasks[2].Steps).To(HaveLen(1))
+		Expect(*status.Tasks[2].Steps[0].ExitCode).To(Equal(int32(0)))
+	})
+
+	It("reports the first failed step of a failed run", func() {
+		failed := succeeded(corev1.ConditionFalse, "Failed", "Tasks Completed: 2 (Failed: 1)")
+		status := staging.PipelineRunStatus(pipelineRun(models.StagingDockerfile, &failed,
+			taskRun("cleanup", 0, &done, terminated("remove", 0, "Completed")),
+			taskRun("stage", time.Second, &failed,
+				terminated("build", 2, "Error"),
+				terminated("digest", 1, "Error")),
+		))
+		Expect(status.Phase).To(Equal(models.StageFailed))
+		Expect(status.Reason).To(Equal("Failed"))
+		Expect(status.Message).To(Equal("Tasks Completed: 2 (Failed: 1)"))
+		Expect(status.FailedStep).ToNot(BeNil())
+		Expect(status.FailedStep.Task).To(Equal("stage"))
+		Expect(status.FailedStep.Name).To(Equal("build"))
+		Expect(*status.FailedStep.ExitCode).To(Equal(int32(2)))
+		Expect(status.Hint).To(ContainSubstring("Dockerfile"))
+		Expect(staging.Summary(status)).To(Equal("staging failed in step 'build' of task 'stage', exit code 2"))
+	})
+
+	It("hints at memory for a killed step", func() {
+		failed := succeeded(corev1.ConditionFalse, "Failed", "")
+		status := staging.PipelineRunStatus(pipelineRun(models.StagingBuildpacks, &failed,
+			taskRun("stage", 0, &failed, terminated("create", 137, "OOMKilled")),
+		))
+		Expect(status.Hint).To(ContainSubstring("memory"))
+		Expect(staging.Summary(status)).To(Equal("staging failed in step 'create' of task 'stage', exit code 137 (OOMKilled)"))
+	})
+
+	It("reports a cancelled run as such", func() {
+		cancelled := succeeded(corev1.ConditionFalse, "PipelineRunCancelled", "PipelineRun \"s-1\" was cancelled")
+		status := staging.PipelineRunStatus(pipelineRun(models.StagingBuildpacks, &cancelled,
+			taskRun("stage", 0, &cancelled, terminated("create", 1, "Error")),
+		))
+		Expect(status.Phase).To(Equal(models.StageCancelled))
+		Expect(status.FailedStep).To(BeNil())
+		Expect(status.Hint).To(BeEmpty())
+		Expect(staging.Summary(status)).To(Equal("staging cancelled"))
+	})
+
+	It("summarizes a failure without failed step by its message", func() {
+		failed := succeeded(corev1.ConditionFalse, "PipelineRunTimeout", "timed out")
+		status := staging.PipelineRunStatus(pipelineRun(models.StagingImage, &failed))
+		Expect(status.FailedStep).To(BeNil())
+		Expect(status.Hint).ToNot(BeEmpty())
+		Expect(staging.Summary(status)).To(Equal("staging failed: timed out"))
+	})
+})
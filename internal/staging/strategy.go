@@ -0,0 +1,115 @@
+// Package staging implements the choice of the strategy to stage an
+// application with, see models.StageRequest. Each strategy has its own
+// tekton pipeline.
+package staging
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"path"
+
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+var (
+	// ErrUnknownStrategy is returned by Resolve for strategies it does not know.
+	ErrUnknownStrategy = errors.New("unknown staging strategy")
+	// ErrNoSourceImage is returned by Resolve when the image strategy lacks its image.
+	ErrNoSourceImage = errors.New("staging a prebuilt image requires the image")
+	// ErrBadSourceImage is returned by Resolve when the image of the image strategy is no valid reference.
+	ErrBadSourceImage = errors.New("the image to stage is not a valid image reference")
+	// ErrNoSources is returned by Resolve when a strategy building sources lacks them.
+	ErrNoSources = errors.New("staging requires uploaded sources")
+)
+
+// Pipelines maps the staging strategies to the names of their tekton pipelines.
+var Pipelines = map[string]string{
+	models.StagingBuildpacks: "staging-pipeline",
+	models.StagingDockerfile: "staging-pipeline-dockerfile",
+	models.StagingImage:      "staging-pipeline-image",
+}
+
+// Blob is the function through which Resolve reads the uploaded sources, if
+// it has to inspect them.
+type Blob func() (io.ReadCloser, error)
+
+// Resolve returns the strategy to stage the request with, and, for
+// buildpacks, the builder image to use. A strategy given by the request is
+// validated. Otherwise a source image selects models.StagingImage, and a
+// builder image models.StagingBuildpacks. Else the uploaded sources are
+// inspected, see Detect.
+func Resolve(req models.StageRequest, blob Blob) (string, string, error) {
+	strategy := req.Strategy
+	if strategy == "" {
+		switch {
+		case req.SourceImage != "":
+			strategy = models.StagingImage
+		case req.BuilderImage != "":
+			strategy = models.StagingBuildpacks
+		default:
+			if req.BlobUID == "" {
+				return "", "", ErrNoSources
+			}
+
+			reader, err := blob()
+			if err != nil {
+				return "", "", err
+			}
+			defer reader.Close()
+
+			strategy, err = Detect(reader)
+			if err != nil {
+				return "", "", err
+			}
+		}
+	}
+
+	switch strategy {
+	case models.StagingBuildpacks:
+		if req.BlobUID == "" {
+			return "", "", ErrNoSources
+		}
+		builderImage := req.BuilderImage
+		if builderImage == "" {
+			builderImage = models.DefaultBuilderImage
+		}
+		return strategy, builderImage, nil
+	case models.StagingDockerfile:
+		if req.BlobUID == "" {
+			return "", "", ErrNoSources
+		}
+		return strategy, "", nil
+	case models.StagingImage:
+		if req.SourceImage == "" {
+			return "", "", ErrNoSourceImage
+		}
+		if _, err := name.ParseReference(req.SourceImage); err != nil {
+			return "", "", ErrBadSourceImage
+		}
+		return strategy, "", nil
+	}
+
+	return "", "", ErrUnknownStrategy
+}
+
+// Detect returns the strategy for staging the sources in the tarball.
+// Sources with a Dockerfile at their top are built from it, everything
+// else with buildpacks.
+func Detect(tarball io.Reader) (string, error) {
+	reader := tar.NewReader(tarball)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return models.StagingBuildpacks, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if header.Typeflag == tar.TypeReg && path.Clean(header.Name) == "Dockerfile" {
+			return models.StagingDockerfile, nil
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package staging_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/epinio/epinio/internal/staging"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// tarball returns a tarball holding empty files of the given names.
+func tarball(names ...string) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	writer := tar.NewWriter(buf)
+	for _, name := range names {
+		err := writer.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644})
+		Expect(err).ToNot(HaveOccurred())
+	}
+	Expect(writer.Close()).To(Succeed())
+	return buf
+}
+
+// blobOf returns a staging.Blob reading the tarball of the given files.
+func blobOf(names ...string) staging.Blob {
+	return func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(tarball(names...)), nil
+	}
+}
+
+var noBlob staging.Blob = func() (io.ReadCloser, error) {
+	return nil, errors.New("the blob must not be read")
+}
+
+var _ = Describe("Detect", func() {
+	It("picks the Dockerfile strategy for sources with a Dockerfile", func() {
+		Expect(staging.Detect(tarball("main.go", "Dockerfile"))).To(Equal(models.StagingDockerfile))
+		Expect(staging.Detect(tarball("./Dockerfile"))).To(Equal(models.StagingDockerfile))
+	})
+
+	It("picks buildpacks for everything else", func() {
+		Expect(staging.Detect(tarball("main.go", "go.mod"))).To(Equal(models.StagingBuildpacks))
+		Expect(staging.Detect(tarball("sub/Dockerfile"))).To(Equal(models.StagingBuildpacks))
+		Expect(staging.Detect(tarball())).To(Equal(models.StagingBuildpacks))
+	})
+
+	It("fails for data which is not a tarball", func() {
+		_, err := staging.Detect(bytes.NewBufferString("not a tarball, but long enough to be read as a header of one"))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Resolve", func() {
+	It("defaults the builder image of the buildpacks strategy", func() {
+		strategy, builder, err := staging.Resolve(models.StageRequest{
+			BlobUID:  "blob",
+			Strategy: models.StagingBuildpacks,
+		}, noBlob)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strategy).To(Equal(models.StagingBuildpacks))
+		Expect(builder).To(Equal(models.DefaultBuilderImage))
+	})
+
+	It("uses buildpacks for a builder image, without looking at the sources", func() {
+		strategy, builder, err := staging.Resolve(models.StageRequest{
+			BlobUID:      "blob",
+			BuilderImage: "my/builder",
+		}, noBlob)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strategy).To(Equal(models.StagingBuildpacks))
+		Expect(builder).To(Equal("my/builder"))
+	})
+
+	It("imports a source image", func() {
+		strategy, _, err := staging.Resolve(models.StageRequest{SourceImage: "my/app"}, noBlob)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strategy).To(Equal(models.StagingImage))
+
+		_, _, err = staging.Resolve(models.StageRequest{Strategy: models.StagingImage}, noBlob)
+		Expect(err).To(Equal(staging.ErrNoSourceImage))
+	})
+
+	It("rejects source images which are no image reference", func() {
+		_, _, err := staging.Resolve(models.StageRequest{SourceImage: "my/app; rm -rf /"}, noBlob)
+		Expect(err).To(Equal(staging.ErrBadSourceImage))
+
+		_, _, err = staging.Resolve(models.StageRequest{SourceImage: "$(cat /etc/passwd)"}, noBlob)
+		Expect(err).To(Equal(staging.ErrBadSourceImage))
+
+		_, _, err = staging.Resolve(models.StageRequest{SourceImage: "registry.example.com:5000/my/app:v1"}, noBlob)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("inspects the sources when nothing else decides", func() {
+		strategy, builder, err := staging.Resolve(models.StageRequest{BlobUID: "blob"}, blobOf("Dockerfile"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strategy).To(Equal(models.StagingDockerfile))
+		Expect(builder).To(BeEmpty())
+
+		strategy, builder, err = staging.Resolve(models.StageRequest{BlobUID: "blob"}, blobOf("main.go"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strategy).To(Equal(models.StagingBuildpacks))
+		Expect(builder).To(Equal(models.DefaultBuilderImage))
+	})
+
+	It("requires sources for building", func() {
+		_, _, err := staging.Resolve(models.StageRequest{Strategy: models.StagingDockerfile}, noBlob)
+		Expect(err).To(Equal(staging.ErrNoSources))
+
+		_, _, err = staging.Resolve(models.StageRequest{}, noBlob)
+		Expect(err).To(Equal(staging.ErrNoSources))
+	})
+
+	It("rejects unknown strategies", func() {
+		_, _, err := staging.Resolve(models.StageRequest{BlobUID: "blob", Strategy: "magic"}, noBlob)
+		Expect(err).To(Equal(staging.ErrUnknownStrategy))
+	})
+})
@@ -0,0 +1,190 @@
+// Package tokens implements the issuing, verification and revocation of
+// the bearer tokens used to access the API server. Tokens are signed with
+// a key stored in a kube secret in epinio's namespace, which is created on
+// first use. The IDs of revoked tokens are kept in another secret, until
+// the tokens expire.
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/auth"
+	"github.com/epinio/epinio/internal/duration"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	signingKeySecret  = "epinio-token-signing-key"
+	revocationsSecret = "epinio-token-revocations"
+	signingKeyKey     = "key"
+	signingKeySize    = 32
+)
+
+// ErrTokenRevoked is returned by Verify for revoked tokens.
+var ErrTokenRevoked = errors.New("token revoked")
+
+var (
+	keyMu      sync.Mutex
+	signingKey []byte
+)
+
+// Issue returns a new token for the named user, and its claims. The
+// token expires after duration.TokenLifetime.
+func Issue(ctx context.Context, cluster *kubernetes.Cluster, username string) (string, auth.TokenClaims, error) {
+	key, err := getSigningKey(ctx, cluster)
+	if err != nil {
+		return "", auth.TokenClaims{}, err
+	}
+
+	claims := auth.TokenClaims{
+		ID:       uuid.New().String(),
+		Username: username,
+		Expires:  time.Now().Add(duration.TokenLifetime()).Unix(),
+	}
+
+	token, err := auth.SignToken(key, claims)
+	if err != nil {
+		return "", auth.TokenClaims{}, err
+	}
+
+	return token, claims, nil
+}
+
+// Verify checks that the token is properly signed, not expired, and not
+// revoked, and returns its claims.
+func Verify(ctx context.Context, cluster *kubernetes.Cluster, token string) (auth.TokenClaims, error) {
+	key, err := getSigningKey(ctx, cluster)
+	if err != nil {
+		return auth.TokenClaims{}, err
+	}
+
+	claims, err := auth.VerifyToken(key, token, time.Now())
+	if err != nil {
+		return auth.TokenClaims{}, err
+	}
+
+	secret, err := cluster.Kubectl.CoreV1().Secrets(deployments.EpinioDeploymentID).Get(
+		ctx, revocationsSecret, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return claims, nil
+		}
+		return auth.TokenClaims{}, err
+	}
+
+	if _, ok := secret.Data[claims.ID]; ok {
+		return auth.TokenClaims{}, ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+// Revoke records the token as revoked, until it expires. Expired
+// revocations are dropped at the same time.
+func Revoke(ctx context.Context, cluster *kubernetes.Cluster, claims auth.TokenClaims) error {
+	secrets := cluster.Kubectl.CoreV1().Secrets(deployments.EpinioDeploymentID)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := secrets.Get(ctx, revocationsSecret, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+
+			secret, err = secrets.Create(ctx, &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: revocationsSecret,
+					Labels: map[string]string{
+						"app.kubernetes.io/managed-by": "epinio",
+					},
+				},
+			}, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// Created concurrently. Retry to load it.
+				return apierrors.NewConflict(v1.Resource("secrets"), revocationsSecret, err)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+
+		now := time.Now().Unix()
+		for id, expires := range secret.Data {
+			if exp, err := strconv.ParseInt(string(expires), 10, 64); err != nil || exp < now {
+				delete(secret.Data, id)
+			}
+		}
+
+		secret.Data[claims.ID] = []byte(strconv.FormatInt(claims.Expires, 10))
+
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// getSigningKey returns the key to sign tokens with. The key is loaded
+// from its secret, created if necessary, and cached.
+func getSigningKey(ctx context.Context, cluster *kubernetes.Cluster) ([]byte, error) {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+
+	if signingKey != nil {
+		return signingKey, nil
+	}
+
+	secrets := cluster.Kubectl.CoreV1().Secrets(deployments.EpinioDeploymentID)
+
+	secret, err := secrets.Get(ctx, signingKeySecret, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		key := make([]byte, signingKeySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, errors.Wrap(err, "generating the token signing key")
+		}
+
+		secret, err = secrets.Create(ctx, &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: signingKeySecret,
+				Labels: map[string]string{
+					"app.kubernetes.io/managed-by": "epinio",
+				},
+			},
+			Data: map[string][]byte{
+				signingKeyKey: key,
+			},
+		}, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			// Another server instance won the race. Use its key.
+			secret, err = secrets.Get(ctx, signingKeySecret, metav1.GetOptions{})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	key := secret.Data[signingKeyKey]
+	if len(key) == 0 {
+		return nil, errors.New("the token signing key is empty")
+	}
+
+	signingKey = key
+	return signingKey, nil
+}
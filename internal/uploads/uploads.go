@@ -0,0 +1,293 @@
+// Package uploads implements the server side of chunked source uploads.
+// The parts of an upload are kept in a directory on the server's disk,
+// until the upload is completed, i.e. the parts are assembled into the
+// tarball, and checked against its SHA-256.
+package uploads
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"github.com/pkg/errors"
+)
+
+// PartSize is the size of the parts of chunked uploads. Only the last part may be smaller.
+const PartSize = 8 * 1024 * 1024
+
+// MaxAge is the time after the last change of an upload, after which it is removed by Sweep.
+const MaxAge = 24 * time.Hour
+
+const (
+	stateFile  = "state.json"
+	partPrefix = "part-"
+)
+
+var (
+	// ErrUploadNotFound is returned for uploads which were not initiated, or were removed already.
+	ErrUploadNotFound = errors.New("upload not found")
+	// ErrChecksumMismatch is returned by Assemble when the parts do not add up to the announced tarball.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrBadPart is wrapped by the errors of WritePart and Assemble for parts not matching the upload.
+	ErrBadPart = errors.New("bad part")
+)
+
+// Upload is a chunked upload in progress
+type Upload struct {
+	models.UploadState
+	App models.AppRef `json:"app"`
+	dir string
+}
+
+// Dir is the directory holding the uploads in progress. It is a variable, for the tests.
+var Dir = filepath.Join(os.TempDir(), "epinio-uploads")
+
+// ID returns the id of the upload of the tarball with the given size and
+// checksum, for the application. Initiating the same upload again yields
+// the same id, i.e. resumes it.
+func ID(app models.AppRef, size int64, checksum string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%s/%d", app.Org, app.Name, checksum, size)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Open initiates the upload of a tarball with the given size and checksum
+// for the application, or returns the upload in progress for it.
+func Open(app models.AppRef, size int64, checksum string) (*Upload, error) {
+	if size < 0 {
+		return nil, errors.New("size must not be negative")
+	}
+	checksum = strings.ToLower(checksum)
+	if raw, err := hex.DecodeString(checksum); err != nil || len(raw) != sha256.Size {
+		return nil, errors.New("checksum is not a hex encoded SHA-256")
+	}
+
+	id := ID(app, size, checksum)
+	upload, err := Load(app, id)
+	if err == nil {
+		return upload, nil
+	}
+	if err != ErrUploadNotFound {
+		return nil, err
+	}
+
+	upload = &Upload{
+		UploadState: models.UploadState{
+			ID:       id,
+			Size:     size,
+			SHA256:   checksum,
+			PartSize: PartSize,
+		},
+		App: app,
+		dir: filepath.Join(Dir, id),
+	}
+
+	if err := os.MkdirAll(upload.dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "creating the upload directory")
+	}
+
+	state, err := json.Marshal(upload)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAtomic(filepath.Join(upload.dir, stateFile), strings.NewReader(string(state))); err != nil {
+		return nil, errors.Wrap(err, "writing the upload state")
+	}
+
+	upload.Parts = []int{}
+	return upload, nil
+}
+
+// Load returns the upload with the given id, of the application.
+func Load(app models.AppRef, id string) (*Upload, error) {
+	// The id is part of a path. Reject anything which could escape the directory.
+	if id == "" || filepath.Base(id) != id || strings.HasPrefix(id, ".") {
+		return nil, ErrUploadNotFound
+	}
+
+	dir := filepath.Join(Dir, id)
+	state, err := ioutil.ReadFile(filepath.Join(dir, stateFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, errors.Wrap(err, "reading the upload state")
+	}
+
+	upload := &Upload{dir: dir}
+	if err := json.Unmarshal(state, upload); err != nil {
+		return nil, errors.Wrap(err, "reading the upload state")
+	}
+	if upload.App != app {
+		return nil, ErrUploadNotFound
+	}
+
+	upload.Parts, err = upload.received()
+	if err != nil {
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// PartCount returns the number of parts of the upload.
+func (u *Upload) PartCount() int {
+	return int((u.Size + u.PartSize - 1) / u.PartSize)
+}
+
+// WritePart stores the numbered part of the upload. All parts but the last
+// must have the full part size. A part is acknowledged only when it was
+// written completely. Writing a part again replaces it.
+func (u *Upload) WritePart(n int, r io.Reader) error {
+	if n < 0 || n >= u.PartCount() {
+		return fmt.Errorf("%w: part %d is out of range, the upload has %d parts", ErrBadPart, n, u.PartCount())
+	}
+
+	expected := u.PartSize
+	if n == u.PartCount()-1 {
+		expected = u.Size - int64(n)*u.PartSize
+	}
+
+	tmp, err := ioutil.TempFile(u.dir, ".part")
+	if err != nil {
+		return errors.Wrap(err, "creating the part")
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, io.LimitReader(r, expected+1))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.Wrap(err, "writing the part")
+	}
+	if written != expected {
+		return fmt.Errorf("%w: part %d has %d bytes, expected %d", ErrBadPart, n, written, expected)
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(u.dir, partPrefix+strconv.Itoa(n)))
+}
+
+// Assemble concatenates the parts of the upload into the tarball, and
+// checks its size and checksum. The result is the path of the tarball,
+// inside the upload's directory.
+func (u *Upload) Assemble() (string, error) {
+	parts, err := u.received()
+	if err != nil {
+		return "", err
+	}
+	if len(parts) != u.PartCount() {
+		return "", fmt.Errorf("%w: upload is incomplete, received %d of %d parts", ErrBadPart, len(parts), u.PartCount())
+	}
+
+	tarball, err := ioutil.TempFile(u.dir, ".blob")
+	if err != nil {
+		return "", errors.Wrap(err, "creating the tarball")
+	}
+	defer tarball.Close()
+
+	hash := sha256.New()
+	out := io.MultiWriter(tarball, hash)
+	for n := 0; n < u.PartCount(); n++ {
+		if err := appendFile(out, filepath.Join(u.dir, partPrefix+strconv.Itoa(n))); err != nil {
+			os.Remove(tarball.Name())
+			return "", err
+		}
+	}
+
+	if hex.EncodeToString(hash.Sum(nil)) != u.SHA256 {
+		os.Remove(tarball.Name())
+		return "", ErrChecksumMismatch
+	}
+
+	return tarball.Name(), nil
+}
+
+// Remove deletes the upload, with its parts.
+func (u *Upload) Remove() error {
+	return os.RemoveAll(u.dir)
+}
+
+// Sweep removes the uploads not changed for longer than MaxAge.
+func Sweep() error {
+	entries, err := ioutil.ReadDir(Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() && time.Since(entry.ModTime()) > MaxAge {
+			if err := os.RemoveAll(filepath.Join(Dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// received returns the numbers of the parts received, in order.
+func (u *Upload) received() ([]int, error) {
+	entries, err := ioutil.ReadDir(u.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading the upload directory")
+	}
+
+	parts := []int{}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), partPrefix) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), partPrefix))
+		if err != nil {
+			continue
+		}
+		parts = append(parts, n)
+	}
+	sort.Ints(parts)
+
+	return parts, nil
+}
+
+// writeAtomic writes the file, such that readers see either no file or the complete file.
+func writeAtomic(path string, r io.Reader) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// appendFile copies the contents of the file to the writer.
+func appendFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "reading a part")
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return errors.Wrap(err, "reading a part")
+}
@@ -0,0 +1,13 @@
+package uploads_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestUploads(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Uploads Suite")
+}
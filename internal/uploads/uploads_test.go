@@ -0,0 +1,134 @@
+package uploads_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/epinio/epinio/internal/uploads"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// checksum returns the hex encoded SHA-256 of the data.
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+var _ = Describe("Upload", func() {
+	var (
+		dir  string
+		app  models.AppRef
+		data []byte
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "epinio-uploads-test")
+		Expect(err).ToNot(HaveOccurred())
+		uploads.Dir = dir
+
+		app = models.NewAppRef("app", "org")
+		// Two full parts, and a short last part
+		data = bytes.Repeat([]byte("x"), 2*uploads.PartSize+10)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	// part returns the numbered part of the data.
+	part := func(n int) *bytes.Reader {
+		end := (n + 1) * uploads.PartSize
+		if end > len(data) {
+			end = len(data)
+		}
+		return bytes.NewReader(data[n*uploads.PartSize : end])
+	}
+
+	It("assembles the parts into the tarball", func() {
+		upload, err := uploads.Open(app, int64(len(data)), checksum(data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(upload.PartCount()).To(Equal(3))
+
+		for _, n := range []int{2, 0, 1} {
+			Expect(upload.WritePart(n, part(n))).To(Succeed())
+		}
+
+		tarball, err := upload.Assemble()
+		Expect(err).ToNot(HaveOccurred())
+		assembled, err := ioutil.ReadFile(tarball)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(assembled).To(Equal(data))
+	})
+
+	It("resumes an upload initiated again", func() {
+		upload, err := uploads.Open(app, int64(len(data)), checksum(data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(upload.WritePart(1, part(1))).To(Succeed())
+
+		again, err := uploads.Open(app, int64(len(data)), checksum(data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(again.ID).To(Equal(upload.ID))
+		Expect(again.Parts).To(Equal([]int{1}))
+	})
+
+	It("does not acknowledge a short part", func() {
+		upload, err := uploads.Open(app, int64(len(data)), checksum(data))
+		Expect(err).ToNot(HaveOccurred())
+
+		err = upload.WritePart(0, strings.NewReader("short"))
+		Expect(err).To(MatchError(ContainSubstring("part 0 has 5 bytes")))
+
+		upload, err = uploads.Load(app, upload.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(upload.Parts).To(BeEmpty())
+	})
+
+	It("rejects parts out of range", func() {
+		upload, err := uploads.Open(app, int64(len(data)), checksum(data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(upload.WritePart(3, part(2))).To(MatchError(ContainSubstring("out of range")))
+	})
+
+	It("rejects an incomplete upload", func() {
+		upload, err := uploads.Open(app, int64(len(data)), checksum(data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(upload.WritePart(0, part(0))).To(Succeed())
+
+		_, err = upload.Assemble()
+		Expect(err).To(MatchError(ContainSubstring("received 1 of 3 parts")))
+	})
+
+	It("detects a checksum mismatch", func() {
+		upload, err := uploads.Open(app, int64(len(data)), checksum([]byte("other")))
+		Expect(err).ToNot(HaveOccurred())
+		for n := 0; n < 3; n++ {
+			Expect(upload.WritePart(n, part(n))).To(Succeed())
+		}
+
+		_, err = upload.Assemble()
+		Expect(err).To(Equal(uploads.ErrChecksumMismatch))
+	})
+
+	It("does not find the uploads of other applications", func() {
+		upload, err := uploads.Open(app, int64(len(data)), checksum(data))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = uploads.Load(models.NewAppRef("other", "org"), upload.ID)
+		Expect(err).To(Equal(uploads.ErrUploadNotFound))
+		_, err = uploads.Load(app, "../"+upload.ID)
+		Expect(err).To(Equal(uploads.ErrUploadNotFound))
+	})
+
+	It("rejects a bad checksum", func() {
+		_, err := uploads.Open(app, 10, "not-a-checksum")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,356 @@
+// Package users implements the management of the users of the API
+// server, and of the roles granted to them. Each user is stored as a kube
+// secret in epinio's namespace, labeled with the name of the user. The
+// secret holds the bcrypt hash of the user's password, whether it is an
+// admin, and a key per namespace the user has a role in.
+package users
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/auth"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	"golang.org/x/crypto/bcrypt"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// UserLabel marks the secrets holding the API users.
+	// See also assets/embedded-files/epinio/server.yaml, for the admin user created by the installer.
+	UserLabel = "epinio.suse.org/api-user-credentials"
+	// UsernameLabel holds the name of the user stored in a secret.
+	UsernameLabel = "epinio.suse.org/username"
+
+	passwordKey  = "password"
+	adminKey     = "admin"
+	namespaceKey = "namespace."
+)
+
+var (
+	// ErrUserNotFound is returned for operations on unknown users.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUserExists is returned when creating a user which exists already.
+	ErrUserExists = errors.New("user already exists")
+	// ErrBadCredentials is returned by Authenticate for an unknown user or a wrong password.
+	ErrBadCredentials = errors.New("bad credentials")
+)
+
+// SecretName returns the name of the kube secret created for the named user.
+// Note that lookup is by label, as the secret of the initial admin user has
+// a fixed name.
+func SecretName(username string) string {
+	return "epinio-user-" + username
+}
+
+// ValidateUsername checks that the name is usable for a user. User names
+// are used in the names of kube resources, and have to be DNS labels.
+func ValidateUsername(username string) error {
+	if errs := validation.IsDNS1123Label(username); len(errs) > 0 {
+		return fmt.Errorf("bad user name '%s': %s", username, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// ValidateRole checks that the role can be granted in a namespace.
+func ValidateRole(role string) error {
+	if role != models.RoleDeveloper && role != models.RoleViewer {
+		return fmt.Errorf("bad role '%s', expected one of %s, %s", role, models.RoleDeveloper, models.RoleViewer)
+	}
+	return nil
+}
+
+// List returns all users, sorted by name.
+func List(ctx context.Context, cluster *kubernetes.Cluster) (models.UserList, error) {
+	secrets, err := cluster.Kubectl.CoreV1().Secrets(deployments.EpinioDeploymentID).List(ctx, metav1.ListOptions{
+		LabelSelector: UserLabel + "=true",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := models.UserList{}
+	for i := range secrets.Items {
+		result = append(result, fromSecret(&secrets.Items[i]))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Username < result[j].Username
+	})
+
+	return result, nil
+}
+
+// Get returns the named user.
+func Get(ctx context.Context, cluster *kubernetes.Cluster, username string) (models.User, error) {
+	secret, err := userLoad(ctx, cluster, username)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return fromSecret(secret), nil
+}
+
+// Authenticate returns the named user, if the password matches.
+func Authenticate(ctx context.Context, cluster *kubernetes.Cluster, username, password string) (models.User, error) {
+	secret, err := userLoad(ctx, cluster, username)
+	if err != nil {
+		if err == ErrUserNotFound {
+			return models.User{}, ErrBadCredentials
+		}
+		return models.User{}, err
+	}
+
+	hash := secret.Data[passwordKey]
+	if !verifiedRecently(hash, password) {
+		err = bcrypt.CompareHashAndPassword(hash, []byte(password))
+		if err != nil {
+			return models.User{}, ErrBadCredentials
+		}
+		verified(hash, password)
+	}
+
+	return fromSecret(secret), nil
+}
+
+// verifiedTTL is the time for which verified passwords are remembered.
+// Clients using basic auth send the password with every request, and the
+// bcrypt comparison is slow by design.
+const verifiedTTL = time.Minute
+
+var (
+	verifiedMu sync.Mutex
+	// verifiedPasswords maps the keys of verified passwords to the time of
+	// the verification, see verifiedKey.
+	verifiedPasswords = map[[sha256.Size]byte]time.Time{}
+)
+
+// verifiedKey identifies the password, together with the stored hash it
+// was verified against. A changed password, i.e. hash, does not match the
+// remembered key anymore. The hash is salted, and so is the key.
+func verifiedKey(hash []byte, password string) [sha256.Size]byte {
+	return sha256.Sum256(append(append([]byte{}, hash...), password...))
+}
+
+// verifiedRecently returns true if the password was verified against the
+// hash within the verifiedTTL.
+func verifiedRecently(hash []byte, password string) bool {
+	verifiedMu.Lock()
+	defer verifiedMu.Unlock()
+
+	at, ok := verifiedPasswords[verifiedKey(hash, password)]
+	return ok && time.Since(at) < verifiedTTL
+}
+
+// verified remembers the password as verified against the hash. Expired
+// entries are dropped.
+func verified(hash []byte, password string) {
+	verifiedMu.Lock()
+	defer verifiedMu.Unlock()
+
+	now := time.Now()
+	for key, at := range verifiedPasswords {
+		if now.Sub(at) >= verifiedTTL {
+			delete(verifiedPasswords, key)
+		}
+	}
+	verifiedPasswords[verifiedKey(hash, password)] = now
+}
+
+// Create creates a new user with the given password.
+func Create(ctx context.Context, cluster *kubernetes.Cluster, username, password string, admin bool) error {
+	_, err := userLoad(ctx, cluster, username)
+	if err == nil {
+		return ErrUserExists
+	}
+	if err != ErrUserNotFound {
+		return err
+	}
+
+	hash, err := auth.HashBcrypt(password)
+	if err != nil {
+		return err
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: SecretName(username),
+			Labels: map[string]string{
+				UserLabel:                      "true",
+				UsernameLabel:                  username,
+				"app.kubernetes.io/managed-by": "epinio",
+			},
+		},
+		Data: map[string][]byte{
+			passwordKey: []byte(hash),
+			adminKey:    []byte(fmt.Sprintf("%t", admin)),
+		},
+	}
+
+	_, err = cluster.Kubectl.CoreV1().Secrets(deployments.EpinioDeploymentID).Create(
+		ctx, secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return ErrUserExists
+	}
+
+	return err
+}
+
+// Delete removes the named user.
+func Delete(ctx context.Context, cluster *kubernetes.Cluster, username string) error {
+	secret, err := userLoad(ctx, cluster, username)
+	if err != nil {
+		return err
+	}
+
+	err = cluster.Kubectl.CoreV1().Secrets(deployments.EpinioDeploymentID).Delete(
+		ctx, secret.Name, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return ErrUserNotFound
+	}
+
+	return err
+}
+
+// Grant gives the named user the specified role in the namespace,
+// replacing any role it had there before.
+func Grant(ctx context.Context, cluster *kubernetes.Cluster, username, namespace, role string) error {
+	return userUpdate(ctx, cluster, username, func(secret *v1.Secret) {
+		secret.Data[namespaceKey+namespace] = []byte(role)
+	})
+}
+
+// Revoke removes the role of the named user in the namespace.
+func Revoke(ctx context.Context, cluster *kubernetes.Cluster, username, namespace string) error {
+	return userUpdate(ctx, cluster, username, func(secret *v1.Secret) {
+		delete(secret.Data, namespaceKey+namespace)
+	})
+}
+
+// RevokeNamespace removes the roles of all users in the namespace. It is
+// used when the namespace is deleted.
+func RevokeNamespace(ctx context.Context, cluster *kubernetes.Cluster, namespace string) error {
+	users, err := List(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if _, ok := user.Namespaces[namespace]; !ok {
+			continue
+		}
+		err := Revoke(ctx, cluster, user.Username, namespace)
+		if err != nil && err != ErrUserNotFound {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Allowed returns true if the user may access the namespace. Read access
+// is granted to viewers and developers of the namespace, write access to
+// developers only. Admins are allowed everything.
+func Allowed(user models.User, namespace string, write bool) bool {
+	if user.Admin {
+		return true
+	}
+
+	switch user.Namespaces[namespace] {
+	case models.RoleDeveloper:
+		return true
+	case models.RoleViewer:
+		return !write
+	default:
+		return false
+	}
+}
+
+// userKey is the type of the key under which the user of a request is
+// stored in the request's context.
+type userKey struct{}
+
+// WithUser returns a copy of the context carrying the user.
+func WithUser(ctx context.Context, user models.User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// FromContext returns the user carried by the context, if any.
+func FromContext(ctx context.Context) (models.User, bool) {
+	user, ok := ctx.Value(userKey{}).(models.User)
+	return user, ok
+}
+
+// userUpdate is a helper for the public functions. It encapsulates the read/modify/write cycle
+// necessary to update the secret holding the named user.
+func userUpdate(ctx context.Context, cluster *kubernetes.Cluster, username string, modify func(*v1.Secret)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := userLoad(ctx, cluster, username)
+		if err != nil {
+			return err
+		}
+
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+
+		modify(secret)
+
+		_, err = cluster.Kubectl.CoreV1().Secrets(deployments.EpinioDeploymentID).Update(
+			ctx, secret, metav1.UpdateOptions{})
+
+		return err
+	})
+}
+
+// userLoad returns the kube secret holding the named user.
+func userLoad(ctx context.Context, cluster *kubernetes.Cluster, username string) (*v1.Secret, error) {
+	if ValidateUsername(username) != nil {
+		return nil, ErrUserNotFound
+	}
+
+	secrets, err := cluster.Kubectl.CoreV1().Secrets(deployments.EpinioDeploymentID).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=true,%s=%s", UserLabel, UsernameLabel, username),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(secrets.Items) == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	return &secrets.Items[0], nil
+}
+
+// fromSecret decodes the user held by the kube secret.
+func fromSecret(secret *v1.Secret) models.User {
+	user := models.User{
+		Username: secret.Labels[UsernameLabel],
+		Admin:    string(secret.Data[adminKey]) == "true",
+	}
+
+	for key, value := range secret.Data {
+		if !strings.HasPrefix(key, namespaceKey) {
+			continue
+		}
+		if user.Namespaces == nil {
+			user.Namespaces = map[string]string{}
+		}
+		user.Namespaces[strings.TrimPrefix(key, namespaceKey)] = string(value)
+	}
+
+	return user
+}
@@ -0,0 +1,65 @@
+package users_test
+
+import (
+	"context"
+
+	"github.com/epinio/epinio/internal/users"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Users", func() {
+	Describe("Allowed", func() {
+		user := models.User{
+			Username: "someone",
+			Namespaces: map[string]string{
+				"dev":  models.RoleDeveloper,
+				"view": models.RoleViewer,
+			},
+		}
+
+		It("allows developers to read and write", func() {
+			Expect(users.Allowed(user, "dev", false)).To(BeTrue())
+			Expect(users.Allowed(user, "dev", true)).To(BeTrue())
+		})
+
+		It("allows viewers to read only", func() {
+			Expect(users.Allowed(user, "view", false)).To(BeTrue())
+			Expect(users.Allowed(user, "view", true)).To(BeFalse())
+		})
+
+		It("denies access to other namespaces", func() {
+			Expect(users.Allowed(user, "other", false)).To(BeFalse())
+			Expect(users.Allowed(user, "other", true)).To(BeFalse())
+		})
+
+		It("allows admins everything", func() {
+			admin := models.User{Username: "admin", Admin: true}
+			Expect(users.Allowed(admin, "other", true)).To(BeTrue())
+		})
+	})
+
+	Describe("validation", func() {
+		It("accepts DNS labels as user names", func() {
+			Expect(users.ValidateUsername("dev-1")).To(Succeed())
+			Expect(users.ValidateUsername("Dev@example")).ToNot(Succeed())
+		})
+
+		It("accepts namespace roles only", func() {
+			Expect(users.ValidateRole(models.RoleDeveloper)).To(Succeed())
+			Expect(users.ValidateRole(models.RoleViewer)).To(Succeed())
+			Expect(users.ValidateRole(models.RoleAdmin)).ToNot(Succeed())
+		})
+	})
+
+	It("carries the user in the context", func() {
+		_, ok := users.FromContext(context.Background())
+		Expect(ok).To(BeFalse())
+
+		ctx := users.WithUser(context.Background(), models.User{Username: "someone"})
+		user, ok := users.FromContext(ctx)
+		Expect(ok).To(BeTrue())
+		Expect(user.Username).To(Equal("someone"))
+	})
+})
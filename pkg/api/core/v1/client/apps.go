@@ -1,16 +1,21 @@
 package client
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/avast/retry-go"
+	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
 
 	"github.com/epinio/epinio/helpers"
@@ -125,10 +130,168 @@ func (c *Client) AppDelete(org string, name string) (models.ApplicationDeleteRes
 	return resp, nil
 }
 
-// AppUpload uploads a tarball for the named app, which is later used in staging
+// AppUpload uploads a tarball for the named app, which is later used in staging.
+// The tarball is sent in parts, each retried on failure, and an interrupted
+// upload of the same tarball is resumed. When the server has identical
+// sources for the app already nothing is sent, and the response is marked
+// as skipped. Servers without chunked uploads receive the tarball in a
+// single request.
 func (c *Client) AppUpload(org string, name string, tarball string) (models.UploadResponse, error) {
 	resp := models.UploadResponse{}
 
+	size, checksum, err := fileChecksum(tarball)
+	if err != nil {
+		return resp, errors.Wrap(err, "can't read archive")
+	}
+
+	for restarted := false; ; restarted = true {
+		init, err := c.appUploadInit(org, name, size, checksum)
+		if statusCode(err) == http.StatusNotFound {
+			// The server does not know about chunked uploads
+			return c.appUploadSingle(org, name, tarball)
+		}
+		if err != nil {
+			return resp, errors.Wrap(err, "can't initiate upload")
+		}
+
+		if init.BlobUID != "" {
+			return models.UploadResponse{BlobUID: init.BlobUID, Skipped: true}, nil
+		}
+
+		resp, err = c.appUploadParts(org, name, tarball, init.Upload)
+		if err != nil {
+			code := statusCode(err)
+			if !restarted && (code == http.StatusNotFound || code == http.StatusUnprocessableEntity) {
+				// The server lost the upload, or the parts did not
+				// match the checksum. Start over, once.
+				c.log.Info("restarting upload", "error", err.Error())
+				continue
+			}
+			return resp, errors.Wrap(err, "can't upload archive")
+		}
+
+		return resp, nil
+	}
+}
+
+// appUploadInit initiates, or resumes, the chunked upload of the tarball
+func (c *Client) appUploadInit(org, name string, size int64, checksum string) (models.UploadInitResponse, error) {
+	resp := models.UploadInitResponse{}
+
+	b, err := json.Marshal(models.UploadInitRequest{Size: size, SHA256: checksum})
+	if err != nil {
+		return resp, err
+	}
+
+	data, err := c.post(api.Routes.Path("AppUploadInit", org, name), string(b))
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, errors.Wrap(err, "response body is not JSON")
+	}
+	if resp.BlobUID == "" && resp.Upload == nil {
+		return resp, errors.New("response carries neither blob nor upload")
+	}
+
+	return resp, nil
+}
+
+// appUploadParts sends the parts of the tarball not received by the server
+// yet, and completes the upload
+func (c *Client) appUploadParts(org, name, tarball string, upload *models.UploadState) (models.UploadResponse, error) {
+	resp := models.UploadResponse{}
+
+	if upload.PartSize <= 0 {
+		return resp, errors.New("upload has no part size")
+	}
+
+	file, err := os.Open(tarball)
+	if err != nil {
+		return resp, errors.Wrap(err, "failed to open tarball")
+	}
+	defer file.Close()
+
+	received := map[int]bool{}
+	for _, n := range upload.Parts {
+		received[n] = true
+	}
+
+	count := int((upload.Size + upload.PartSize - 1) / upload.PartSize)
+	chunk := make([]byte, upload.PartSize)
+	for n := 0; n < count; n++ {
+		if received[n] {
+			continue
+		}
+
+		length, err := file.ReadAt(chunk, int64(n)*upload.PartSize)
+		if err != nil && err != io.EOF {
+			return resp, errors.Wrapf(err, "failed to read part %d", n)
+		}
+
+		err = c.appUploadPart(org, name, upload.ID, n, chunk[:length])
+		if err != nil {
+			return resp, err
+		}
+	}
+
+	data, err := c.post(api.Routes.Path("AppUploadComplete", org, name, upload.ID), "")
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, errors.Wrap(err, "response body is not JSON")
+	}
+
+	return resp, nil
+}
+
+// appUploadPart sends a part of the tarball, retrying on failure
+func (c *Client) appUploadPart(org, name, id string, n int, chunk []byte) error {
+	details := c.log.V(1)
+	var lastErr error
+
+	err := retry.Do(
+		func() error {
+			_, lastErr = c.put(api.Routes.Path("AppUploadPart", org, name, id, strconv.Itoa(n)), string(chunk))
+			return lastErr
+		},
+		retry.RetryIf(func(err error) bool {
+			if code := statusCode(err); code != 0 {
+				// A rejected part, or a lost upload, is final.
+				if code == http.StatusBadRequest || code == http.StatusNotFound {
+					return false
+				}
+				return helpers.RetryableCode(code)
+			}
+			return helpers.Retryable(err.Error())
+		}),
+		retry.OnRetry(func(try uint, err error) {
+			details.WithValues(
+				"part", n,
+				"tries", fmt.Sprintf("%d/%d", try, duration.RetryMax),
+				"error", err.Error(),
+			).Info("Retrying upload of part")
+		}),
+		retry.Delay(time.Second),
+		retry.Attempts(duration.RetryMax),
+	)
+	if err != nil {
+		if lastErr != nil {
+			return errors.Wrapf(lastErr, "failed to upload part %d", n)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// appUploadSingle uploads the tarball in a single request
+func (c *Client) appUploadSingle(org, name, tarball string) (models.UploadResponse, error) {
+	resp := models.UploadResponse{}
+
 	data, err := c.upload(api.Routes.Path("AppUpload", org, name), tarball)
 	if err != nil {
 		return resp, errors.Wrap(err, "can't upload archive")
@@ -141,18 +304,48 @@ func (c *Client) AppUpload(org string, name string, tarball string) (models.Uplo
 	return resp, nil
 }
 
-// AppImportGit asks the server to import a git repo and put in into the blob store
+// fileChecksum returns the size and the hex encoded SHA-256 of the file
+func fileChecksum(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// statusCode returns the HTTP status code of a failed request, or 0 if
+// the error is not about a response
+func statusCode(err error) int {
+	var r interface{ StatusCode() int }
+	if errors.As(err, &r) {
+		return r.StatusCode()
+	}
+	return 0
+}
+
+// AppImportGit asks the server to import a git repo and put in into the blob store.
+// The import runs in the background on the server. The method waits for its job to
+// finish, and returns the blob UID of the imported sources.
 func (c *Client) AppImportGit(app models.AppRef, gitRef models.GitRef) (*models.ImportGitResponse, error) {
 	data := url.Values{}
 	data.Set("giturl", gitRef.URL)
 	data.Set("gitrev", gitRef.Revision)
+	data.Set("gitcredentials", gitRef.Credentials)
 
 	url := fmt.Sprintf("%s/%s", c.URL, api.Routes.Path("AppImportGit", app.Org, app.Name))
 	request, err := http.NewRequest("POST", url, strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, errors.Wrap(err, "constructing the request")
 	}
-	request.SetBasicAuth(c.user, c.password)
+	c.authorize(request)
 	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	request.Header.Add("Content-Length", strconv.Itoa(len(data.Encode())))
 
@@ -166,7 +359,9 @@ func (c *Client) AppImportGit(app models.AppRef, gitRef models.GitRef) (*models.
 	if err != nil {
 		return nil, errors.Wrap(err, "reading the response body")
 	}
-	if response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusOK {
+	if response.StatusCode != http.StatusAccepted &&
+		response.StatusCode != http.StatusCreated &&
+		response.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("unexpected server status code: %s\n%s", http.StatusText(response.StatusCode),
 			string(bodyBytes))
 	}
@@ -176,6 +371,13 @@ func (c *Client) AppImportGit(app models.AppRef, gitRef models.GitRef) (*models.
 		return nil, err
 	}
 
+	job, err := c.JobWait(app.Org, resp.JobID, duration.ToGitImport())
+	if err != nil {
+		return nil, errors.Wrap(err, "importing git")
+	}
+	resp.BlobUID = job.BlobUID
+	resp.Commit = job.Commit
+
 	return resp, nil
 }
 
@@ -221,22 +423,274 @@ func (c *Client) AppDeploy(req models.DeployRequest) (*models.DeployResponse, er
 	return resp, nil
 }
 
+// AppReleases returns the release history of an app
+func (c *Client) AppReleases(org string, appName string) (models.AppReleaseList, error) {
+	var resp models.AppReleaseList
+
+	data, err := c.get(api.Routes.Path("AppReleases", org, appName))
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// AppRollback redeploys a previous release of an app
+func (c *Client) AppRollback(req models.RollbackRequest, org string, appName string) (*models.RollbackResponse, error) {
+	out, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't marshal rollback request")
+	}
+
+	b, err := c.post(api.Routes.Path("AppRollback", org, appName), string(out))
+	if err != nil {
+		return nil, errors.Wrap(err, "can't roll back app")
+	}
+
+	resp := &models.RollbackResponse{}
+	if err := json.Unmarshal(b, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// AppInstances returns the instances of an app, with their status
+func (c *Client) AppInstances(org string, appName string) (models.AppInstanceList, error) {
+	resp := models.AppInstanceList{}
+
+	data, err := c.get(api.Routes.Path("AppInstances", org, appName))
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// AppLogs opens the stream of the logs of an app, or, with a stage id,
+// of its staging. The text messages of the connection are the
+// tailer.ContainerLogLine objects, in JSON.
+func (c *Client) AppLogs(org string, appName string, stageID string, query url.Values) (*websocket.Conn, error) {
+	endpoint := api.Routes.Path("AppLogs", org, appName)
+	if stageID != "" {
+		endpoint = api.Routes.Path("StagingLogs", org, stageID)
+	}
+
+	return c.dial(endpoint, query)
+}
+
+// AppExec opens an exec session in an instance of an app, running the
+// command, or a shell if there is none. A negative instance selects the
+// first ready instance. See models.ExecControl for the protocol of the
+// session.
+func (c *Client) AppExec(org string, appName string, instance int, command []string) (*websocket.Conn, error) {
+	query := url.Values{}
+	if instance >= 0 {
+		query.Set("instance", strconv.Itoa(instance))
+	}
+	for _, arg := range command {
+		query.Add("command", arg)
+	}
+
+	return c.dial(api.Routes.Path("AppExec", org, appName), query)
+}
+
+// AppPortForward opens a tunnel to the port of an instance of an app. A
+// negative instance selects the first ready instance. The binary messages
+// of the connection carry the data of the tunnel, see wsstream.Stream.
+func (c *Client) AppPortForward(org string, appName string, instance int, port int) (*websocket.Conn, error) {
+	query := url.Values{}
+	query.Set("port", strconv.Itoa(port))
+	if instance >= 0 {
+		query.Set("instance", strconv.Itoa(instance))
+	}
+
+	return c.dial(api.Routes.Path("AppPortForward", org, appName), query)
+}
+
+// AppRestart restarts the pods of an app, without redeploying it
+func (c *Client) AppRestart(org string, appName string) (models.Response, error) {
+	resp := models.Response{}
+
+	data, err := c.post(api.Routes.Path("AppRestart", org, appName), "")
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// AppStop scales an app to zero instances, keeping its desired instances
+func (c *Client) AppStop(org string, appName string) (models.Response, error) {
+	resp := models.Response{}
+
+	data, err := c.post(api.Routes.Path("AppStop", org, appName), "")
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// AppStart scales a stopped app back to its desired instances
+func (c *Client) AppStart(org string, appName string) (models.Response, error) {
+	resp := models.Response{}
+
+	data, err := c.post(api.Routes.Path("AppStart", org, appName), "")
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// AppCache returns the settings of an app's build cache, and the claim holding it
+func (c *Client) AppCache(org string, appName string) (models.AppCache, error) {
+	var resp models.AppCache
+
+	data, err := c.get(api.Routes.Path("AppCache", org, appName))
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// AppCacheUpdate replaces the settings of an app's build cache
+func (c *Client) AppCacheUpdate(req models.AppCacheConfig, org string, appName string) (models.AppCache, error) {
+	var resp models.AppCache
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+
+	data, err := c.patch(api.Routes.Path("AppCacheUpdate", org, appName), string(b))
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// AppCacheClear removes the contents of an app's build cache
+func (c *Client) AppCacheClear(org string, appName string) (models.Response, error) {
+	resp := models.Response{}
+
+	data, err := c.delete(api.Routes.Path("AppCacheClear", org, appName))
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// AppAutoscale replaces the autoscaling settings of an app
+func (c *Client) AppAutoscale(req models.AppAutoscale, org string, appName string) (models.Response, error) {
+	resp := models.Response{}
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+
+	data, err := c.put(api.Routes.Path("AppAutoscale", org, appName), string(b))
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// AppAutoscaleDisable disables the autoscaling of an app
+func (c *Client) AppAutoscaleDisable(org string, appName string) (models.Response, error) {
+	resp := models.Response{}
+
+	data, err := c.delete(api.Routes.Path("AppAutoscaleDisable", org, appName))
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// Caches returns the claims holding the build caches of all apps
+func (c *Client) Caches() (models.CacheClaimList, error) {
+	resp := models.CacheClaimList{}
+
+	data, err := c.get(api.Routes.Path("Caches"))
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
 // StagingComplete checks if the staging process is complete
 func (c *Client) StagingComplete(org string, id string) (models.Response, error) {
 	resp := models.Response{}
 
 	details := c.log.V(1)
 	var (
-		data []byte
-		err  error
+		data    []byte
+		err     error
+		lastErr error
 	)
 	err = retry.Do(
 		func() error {
-			data, err = c.get(api.Routes.Path("StagingComplete", org, id))
-			return err
+			data, lastErr = c.get(api.Routes.Path("StagingComplete", org, id))
+			return lastErr
 		},
 		retry.RetryIf(func(err error) bool {
 			if r, ok := err.(interface{ StatusCode() int }); ok {
+				// A failed staging is final.
+				if r.StatusCode() == http.StatusUnprocessableEntity {
+					return false
+				}
 				return helpers.RetryableCode(r.StatusCode())
 			}
 			retry := helpers.Retryable(err.Error())
@@ -253,17 +707,80 @@ func (c *Client) StagingComplete(org string, id string) (models.Response, error)
 		retry.Delay(time.Second),
 		retry.Attempts(duration.RetryMax),
 	)
+	if err != nil {
+		if r, ok := lastErr.(interface{ StatusCode() int }); ok && r.StatusCode() == http.StatusUnprocessableEntity {
+			return resp, newStagingError(data, lastErr)
+		}
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// StagingStatus returns the status of the staging run, with the state of
+// its tasks and steps
+func (c *Client) StagingStatus(org string, id string) (models.StageStatus, error) {
+	resp := models.StageStatus{}
+
+	data, err := c.get(api.Routes.Path("StagingStatus", org, id))
 	if err != nil {
 		return resp, err
 	}
 
 	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, errors.Wrap(err, "response body is not JSON")
+	}
+
+	return resp, nil
+}
+
+// StagingCancel cancels the staging run
+func (c *Client) StagingCancel(org string, id string) (models.Response, error) {
+	resp := models.Response{}
+
+	data, err := c.delete(api.Routes.Path("StagingCancel", org, id))
+	if err != nil {
 		return resp, err
 	}
 
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, errors.Wrap(err, "response body is not JSON")
+	}
+
 	return resp, nil
 }
 
+// StagingError is returned by StagingComplete for a failed staging. It
+// carries the status of the staging run, as reported by the server.
+type StagingError struct {
+	Status models.StageStatus
+	err    error
+}
+
+func (e *StagingError) Error() string { return e.err.Error() }
+func (e *StagingError) Unwrap() error { return e.err }
+
+// newStagingError decodes the staging run's status from the details of
+// the server's error response. Without a decodable status the server's
+// error is returned as is.
+func newStagingError(data []byte, err error) error {
+	var eResponse api.ErrorResponse
+	if jerr := json.Unmarshal(data, &eResponse); jerr != nil || len(eResponse.Errors) == 0 {
+		return err
+	}
+
+	serr := &StagingError{err: err}
+	if jerr := json.Unmarshal([]byte(eResponse.Errors[0].Details), &serr.Status); jerr != nil {
+		return err
+	}
+
+	return serr
+}
+
 // AppRunning checks if the app is running
 func (c *Client) AppRunning(app models.AppRef) (models.Response, error) {
 	resp := models.Response{}
@@ -280,6 +797,10 @@ func (c *Client) AppRunning(app models.AppRef) (models.Response, error) {
 		},
 		retry.RetryIf(func(err error) bool {
 			if r, ok := err.(interface{ StatusCode() int }); ok {
+				// A failed rollout is final.
+				if r.StatusCode() == http.StatusUnprocessableEntity {
+					return false
+				}
 				return helpers.RetryableCode(r.StatusCode())
 			}
 			retry := helpers.Retryable(err.Error())
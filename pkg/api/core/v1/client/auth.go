@@ -0,0 +1,63 @@
+package client
+
+import (
+	"encoding/json"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+)
+
+// AuthToken requests a new token for the credentials
+func (c *Client) AuthToken(req models.AuthTokenRequest) (models.AuthTokenResponse, error) {
+	resp := models.AuthTokenResponse{}
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+
+	data, err := c.post(api.Routes.Path("AuthToken"), string(b))
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// AuthTokenRefresh requests a new token in exchange for the token of the
+// client. The server revokes the old token, the caller has to switch to
+// the new one, see SetToken.
+func (c *Client) AuthTokenRefresh() (models.AuthTokenResponse, error) {
+	resp := models.AuthTokenResponse{}
+
+	data, err := c.post(api.Routes.Path("AuthTokenRefresh"), "")
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// AuthTokenRevoke revokes the token of the client
+func (c *Client) AuthTokenRevoke() (models.Response, error) {
+	resp := models.Response{}
+
+	data, err := c.delete(api.Routes.Path("AuthTokenRevoke"))
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"encoding/json"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+)
+
+// Blobs returns a list of the source blobs in the S3 storage
+func (c *Client) Blobs() (models.BlobList, error) {
+	resp := models.BlobList{}
+
+	data, err := c.get(api.Routes.Path("Blobs"))
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// BlobsPrune removes the expired and orphaned source blobs from the S3 storage
+func (c *Client) BlobsPrune(req models.BlobPruneRequest) (models.BlobPruneResponse, error) {
+	resp := models.BlobPruneResponse{}
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+
+	data, err := c.post(api.Routes.Path("BlobsPrune"), string(b))
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
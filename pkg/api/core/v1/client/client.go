@@ -2,6 +2,10 @@
 package client
 
 import (
+	"encoding/base64"
+	"net/http"
+	"sync"
+
 	"github.com/go-logr/logr"
 )
 
@@ -10,12 +14,62 @@ import (
 type Client struct {
 	log      logr.Logger
 	URL      string
-	WsURL    string // only stored here for the memo, the websocket client is not part of the epinioapi, yet.
+	WsURL    string // for the websocket endpoints, see dial
 	user     string
 	password string
+	token    string
+
+	refreshMu sync.Mutex
+	refresh   func() // pending refresh of the token, see SetTokenRefresh
 }
 
 // New returns a new Epinio API client
 func New(log logr.Logger, url string, wsURL string, user string, password string) *Client {
 	return &Client{log: log, URL: url, WsURL: wsURL, user: user, password: password}
 }
+
+// SetToken makes the client authenticate with the bearer token, instead
+// of the user and password.
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// SetTokenRefresh registers the function refreshing the token. It is
+// called once, before the first request of the client. Thus only the
+// commands talking to the API server refresh the token.
+func (c *Client) SetTokenRefresh(refresh func()) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	c.refresh = refresh
+}
+
+// authorization returns the value of the Authorization header for the
+// next request, after the pending refresh of the token, if any.
+func (c *Client) authorization() string {
+	c.refreshMu.Lock()
+	refresh := c.refresh
+	// Cleared before the call, as the refresh is a request itself
+	c.refresh = nil
+	c.refreshMu.Unlock()
+
+	if refresh != nil {
+		refresh()
+	}
+
+	return c.AuthorizationHeader()
+}
+
+// AuthorizationHeader returns the value of the Authorization header for
+// requests to the API server, i.e. the bearer token if set, else the
+// basic auth credentials.
+func (c *Client) AuthorizationHeader() string {
+	if c.token != "" {
+		return "Bearer " + c.token
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.user+":"+c.password))
+}
+
+// authorize adds the credentials of the client to the request
+func (c *Client) authorize(request *http.Request) {
+	request.Header.Set("Authorization", c.authorization())
+}
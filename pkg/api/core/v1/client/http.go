@@ -15,6 +15,7 @@ import (
 
 	api "github.com/epinio/epinio/internal/api/v1"
 	"github.com/go-logr/logr"
+	"github.com/gorilla/websocket"
 
 	"github.com/pkg/errors"
 )
@@ -43,6 +44,10 @@ func (c *Client) patch(endpoint string, data string) ([]byte, error) {
 	return c.do(endpoint, "PATCH", data)
 }
 
+func (c *Client) put(endpoint string, data string) ([]byte, error) {
+	return c.do(endpoint, "PUT", data)
+}
+
 func (c *Client) delete(endpoint string) ([]byte, error) {
 	return c.do(endpoint, "DELETE", "")
 }
@@ -82,7 +87,7 @@ func (c *Client) upload(endpoint string, path string) ([]byte, error) {
 		return nil, errors.Wrap(err, "failed to build request")
 	}
 
-	request.SetBasicAuth(c.user, c.password)
+	c.authorize(request)
 	request.Header.Add("Content-Type", writer.FormDataContentType())
 
 	response, err := (&http.Client{}).Do(request)
@@ -118,7 +123,7 @@ func (c *Client) do(endpoint, method, requestBody string) ([]byte, error) {
 		return []byte{}, err
 	}
 
-	request.SetBasicAuth(c.user, c.password)
+	c.authorize(request)
 
 	response, err := (&http.Client{}).Do(request)
 	if err != nil {
@@ -146,7 +151,7 @@ func (c *Client) do(endpoint, method, requestBody string) ([]byte, error) {
 
 	respLog.V(1).Info("response received")
 
-	if response.StatusCode == http.StatusCreated {
+	if response.StatusCode == http.StatusCreated || response.StatusCode == http.StatusAccepted {
 		return bodyBytes, nil
 	}
 
@@ -182,7 +187,7 @@ func (c *Client) doWithCustomErrorHandling(endpoint, method, requestBody string,
 		return []byte{}, err
 	}
 
-	request.SetBasicAuth(c.user, c.password)
+	c.authorize(request)
 
 	response, err := (&http.Client{}).Do(request)
 	if err != nil {
@@ -202,7 +207,7 @@ func (c *Client) doWithCustomErrorHandling(endpoint, method, requestBody string,
 
 	respLog.V(1).Info("response received")
 
-	if response.StatusCode == http.StatusCreated {
+	if response.StatusCode == http.StatusCreated || response.StatusCode == http.StatusAccepted {
 		return bodyBytes, nil
 	}
 
@@ -245,6 +250,29 @@ func responseLogger(l logr.Logger, response *http.Response) logr.Logger {
 	return log
 }
 
+// dial opens a websocket connection to the endpoint, with the query
+func (c *Client) dial(endpoint string, query url.Values) (*websocket.Conn, error) {
+	headers := http.Header{
+		"Authorization": {c.authorization()},
+	}
+
+	conn, response, err := websocket.DefaultDialer.Dial(fmt.Sprintf("%s/%s?%s", c.WsURL, endpoint, query.Encode()), headers)
+	if err != nil {
+		if response == nil {
+			return nil, errors.Wrap(err, "failed to connect to websockets endpoint")
+		}
+		defer response.Body.Close()
+
+		bodyBytes, rerr := ioutil.ReadAll(response.Body)
+		if rerr != nil {
+			return nil, wrapResponseError(rerr, response.StatusCode)
+		}
+		return nil, wrapResponseError(formatError(bodyBytes, response), response.StatusCode)
+	}
+
+	return conn, nil
+}
+
 func formatError(bodyBytes []byte, response *http.Response) error {
 	t := "response body is empty"
 	if len(bodyBytes) > 0 {
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/duration"
+	"github.com/epinio/epinio/pkg/api/core/v1/models"
+)
+
+// Job returns the state of a background job
+func (c *Client) Job(org string, id string) (models.JobResponse, error) {
+	var resp models.JobResponse
+
+	data, err := c.get(api.Routes.Path("JobShow", org, id))
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// JobWait polls the state of a background job until it is done, or the
+// timeout has passed. A failed job is reported as error.
+func (c *Client) JobWait(org string, id string, timeout time.Duration) (models.JobResponse, error) {
+	details := c.log.V(1)
+	deadline := time.Now().Add(timeout)
+
+	for {
+		job, err := c.Job(org, id)
+		if err != nil {
+			return job, err
+		}
+
+		details.Info("job state", "id", id, "status", job.Status, "progress", job.Progress)
+
+		switch job.Status {
+		case models.JobSucceeded:
+			return job, nil
+		case models.JobFailed:
+			return job, fmt.Errorf("job %s failed: %s", id, job.Error)
+		}
+
+		if time.Now().After(deadline) {
+			return job, fmt.Errorf("timed out waiting for job %s, last progress: %s", id, job.Progress)
+		}
+
+		time.Sleep(duration.PollInterval())
+	}
+}
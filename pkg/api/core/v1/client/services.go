@@ -25,6 +25,22 @@ func (c *Client) Services(org string) (models.ServiceResponseList, error) {
 	return resp, nil
 }
 
+// ServiceCatalog returns the service classes and plans offered by the brokers
+func (c *Client) ServiceCatalog() (models.CatalogServiceList, error) {
+	resp := models.CatalogServiceList{}
+
+	data, err := c.get(api.Routes.Path("ServiceCatalog"))
+	if err != nil {
+		return resp, err
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
 // ServiceBindingCreate creates a binding from an app to a serviceclass
 func (c *Client) ServiceBindingCreate(req models.BindRequest, org string, appName string) (models.BindResponse, error) {
 	resp := models.BindResponse{}
@@ -115,6 +131,31 @@ func (c *Client) ServiceCreate(req models.ServiceCreateRequest, org string) (mod
 	return resp, nil
 }
 
+// ServiceUpdate changes the parameters of a service by invoking the associated API endpoint
+func (c *Client) ServiceUpdate(req models.ServiceUpdateRequest, org string, name string) (models.ServiceUpdateResponse, error) {
+	resp := models.ServiceUpdateResponse{}
+
+	c.log.V(5).WithValues("request", req, "org", org, "name", name).Info("requesting ServiceUpdate")
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return resp, nil
+	}
+
+	data, err := c.patch(api.Routes.Path("ServiceUpdate", org, name), string(b))
+	if err != nil {
+		return resp, err
+	}
+
+	c.log.V(5).WithValues("response", string(data), "org", org, "name", name).Info("received ServiceUpdate")
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, errors.Wrap(err, "response body is not JSON")
+	}
+
+	return resp, nil
+}
+
 // ServiceShow shows a service
 func (c *Client) ServiceShow(org string, name string) (models.ServiceShowResponse, error) {
 	var resp models.ServiceShowResponse
@@ -5,13 +5,39 @@ import (
 )
 
 const (
-	EpinioStageIDLabel      = "epinio.suse.org/stage-id"
-	EpinioStageBlobUIDLabel = "epinio.suse.org/blob-uid"
+	EpinioStageIDLabel         = "epinio.suse.org/stage-id"
+	EpinioStageBlobUIDLabel    = "epinio.suse.org/blob-uid"
+	EpinioStagingStrategyLabel = "epinio.suse.org/staging-strategy"
+
+	EpinioGitURLAnnotation    = "epinio.suse.org/git-url"
+	EpinioGitCommitAnnotation = "epinio.suse.org/git-commit"
+
+	EpinioRestartedAtAnnotation = "epinio.suse.org/restarted-at"
 )
 
+// GitRef references a revision of a git repository. The revision is a
+// branch, tag, or commit SHA. The optional credentials are the name of
+// the git credentials for accessing the repository, see
+// GitCredentialsCreateRequest.
 type GitRef struct {
-	Revision string `json:"revision"`
-	URL      string `json:"url"`
+	Revision    string `json:"revision"`
+	URL         string `json:"url"`
+	Credentials string `json:"credentials,omitempty"`
+}
+
+// GitCredentialsCreateRequest represents and contains the data needed to
+// create git credentials, for the access to private repositories. HTTPS
+// access uses the username (optional) and either the password or the
+// token. SSH access uses the private key, and the known hosts to verify
+// the host key with. Skipping the verification has to be requested.
+type GitCredentialsCreateRequest struct {
+	Name                string `json:"name"`
+	Username            string `json:"username,omitempty"`
+	Password            string `json:"password,omitempty"`
+	Token               string `json:"token,omitempty"`
+	SSHKey              string `json:"ssh_key,omitempty"`
+	KnownHosts          string `json:"known_hosts,omitempty"`
+	InsecureSkipHostKey bool   `json:"insecure_skip_host_key,omitempty"`
 }
 
 // App has all the application's properties, for at rest (Configuration), and active (Workload).
@@ -21,16 +47,24 @@ type App struct {
 	Meta          AppRef                   `json:"meta"`
 	Configuration ApplicationUpdateRequest `json:"configuration"`
 	Workload      *AppDeployment           `json:"deployment,omitempty"`
+	StagingID     string                   `json:"staging_id,omitempty"` // staging run in flight, if any. Only set by AppShow
 }
 
 // AppDeployment contains all the information specific to an active
 // application, i.e. one with a deployment in the cluster.
 type AppDeployment struct {
-	Active   bool   `json:"active,omitempty"`   // app is > 0 replicas
-	Username string `json:"username,omitempty"` // app creator
-	StageID  string `json:"stage_id,omitempty"` // tekton staging id
-	Status   string `json:"status,omitempty"`   // app replica status
-	Route    string `json:"route,omitempty"`    // app route
+	Active          bool            `json:"active,omitempty"`           // app is > 0 replicas
+	Username        string          `json:"username,omitempty"`         // app creator
+	StageID         string          `json:"stage_id,omitempty"`         // tekton staging id
+	Status          string          `json:"status,omitempty"`           // app replica status
+	Route           string          `json:"route,omitempty"`            // app route, the first of the routes
+	Routes          []string        `json:"routes,omitempty"`           // app routes, HOST[/PATH] per path of the ingress
+	Git             *GitRef         `json:"git,omitempty"`              // git commit the app was built from, if any
+	Stopped         bool            `json:"stopped,omitempty"`          // app was stopped by a user, and scaled to zero
+	CurrentReplicas int32           `json:"current_replicas,omitempty"` // app replicas, ready or not
+	DesiredReplicas int32           `json:"desired_replicas,omitempty"` // app replicas wanted by the deployment, or the autoscaler
+	Autoscale       *AppAutoscale   `json:"autoscale,omitempty"`        // bounds of the active autoscaler, if any
+	Instances       AppInstanceList `json:"instances,omitempty"`        // app pods, with their status, for a single app only
 }
 
 // NewApp returns a new app for name and org
@@ -108,6 +142,30 @@ func (ar *AppRef) MakeScaleSecretName() string {
 	return names.GenerateResourceName(ar.Name + "-scale")
 }
 
+// MakeRoutesSecretName returns the name of the kube secret holding the
+// routes of the referenced application
+func (ar *AppRef) MakeRoutesSecretName() string {
+	return names.GenerateResourceName(ar.Name + "-routes")
+}
+
+// MakeReleasesSecretName returns the name of the kube secret holding the
+// release history of the referenced application
+func (ar *AppRef) MakeReleasesSecretName() string {
+	return names.GenerateResourceName(ar.Name + "-releases")
+}
+
+// MakeCacheSecretName returns the name of the kube secret holding the
+// settings of the build cache of the referenced application
+func (ar *AppRef) MakeCacheSecretName() string {
+	return names.GenerateResourceName(ar.Name + "-cache")
+}
+
+// MakeHealthSecretName returns the name of the kube secret holding the
+// health check and rollout settings of the referenced application
+func (ar *AppRef) MakeHealthSecretName() string {
+	return names.GenerateResourceName(ar.Name + "-health")
+}
+
 // MakePVCName returns the name of the kube pvc to use with/for the referenced application.
 func (ar *AppRef) MakePVCName() string {
 	return names.GenerateResourceName(ar.Org, ar.Name)
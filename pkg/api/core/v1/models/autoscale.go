@@ -0,0 +1,24 @@
+package models
+
+// DefaultAutoscaleCPUPercent is the target CPU utilization of autoscaled
+// applications which do not specify one
+const DefaultAutoscaleCPUPercent = 80
+
+// AppAutoscale represents the autoscaling settings of an application.
+// The number of instances is kept between Min and Max, adding instances
+// when their average CPU utilization, relative to their CPU request, is
+// above CPUPercent, and removing instances when it is below. A zero
+// CPUPercent selects DefaultAutoscaleCPUPercent.
+type AppAutoscale struct {
+	Min        int32 `json:"min"`
+	Max        int32 `json:"max"`
+	CPUPercent int32 `json:"cpu_percent,omitempty"`
+}
+
+// WithDefaults returns the settings with the zero values replaced by the defaults.
+func (a AppAutoscale) WithDefaults() AppAutoscale {
+	if a.CPUPercent == 0 {
+		a.CPUPercent = DefaultAutoscaleCPUPercent
+	}
+	return a
+}
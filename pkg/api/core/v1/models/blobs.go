@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// DefaultBlobRetention is the number of source blobs kept per application, if not configured otherwise
+const DefaultBlobRetention = 3
+
+// DefaultMaxUploadSize is the maximal size of uploaded sources in bytes, if not configured otherwise
+const DefaultMaxUploadSize = 1024 * 1024 * 1024
+
+// The states of source blobs, per the retention policy. Pruning removes
+// the expired and orphaned blobs.
+const (
+	// BlobStaged blobs are used by a staging run of their application
+	BlobStaged = "staged"
+	// BlobRetained blobs are among the newest of their application
+	BlobRetained = "retained"
+	// BlobExpired blobs are older than the retained blobs of their application
+	BlobExpired = "expired"
+	// BlobOrphaned blobs belong to applications or namespaces which do not exist anymore
+	BlobOrphaned = "orphaned"
+	// BlobUnknown blobs lack the metadata naming their application. They are left alone.
+	BlobUnknown = "unknown"
+)
+
+// Blob represents a source blob in the S3 storage, as created by the
+// upload of application sources, or the import of a git repository.
+type Blob struct {
+	ID       string    `json:"id"`
+	App      AppRef    `json:"app"`
+	Username string    `json:"username,omitempty"`
+	Size     int64     `json:"size"`
+	Created  time.Time `json:"created"`
+	State    string    `json:"state"`
+}
+
+// BlobList is a collection of blobs
+type BlobList []Blob
+
+// BlobPruneRequest represents the request of the BlobsPrune endpoint.
+// Keep overrides the server's retention, if positive.
+type BlobPruneRequest struct {
+	Keep int `json:"keep,omitempty"`
+}
+
+// BlobPruneResponse represents the response of the BlobsPrune endpoint.
+// It lists the removed blobs.
+type BlobPruneResponse struct {
+	Removed BlobList `json:"removed"`
+	Freed   int64    `json:"freed"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// DefaultCacheSize is the size of the build cache of applications which do not specify one
+const DefaultCacheSize = "1Gi"
+
+// AppCacheConfig represents the settings of the build cache of an
+// application. An empty Size selects DefaultCacheSize, an empty
+// StorageClass the cluster's default storage class.
+type AppCacheConfig struct {
+	Size         string `json:"size,omitempty"`
+	StorageClass string `json:"storageclass,omitempty"`
+}
+
+// AppCache represents the build cache of an application, i.e. its
+// settings, and the claim holding it. The Claim is nil until the
+// application is staged.
+type AppCache struct {
+	Config AppCacheConfig `json:"config"`
+	Claim  *CacheClaim    `json:"claim,omitempty"`
+}
+
+// CacheClaim represents the persistent volume claim holding the build
+// cache of an application. Orphaned claims belong to applications or
+// namespaces which do not exist anymore. The App of claims missing the
+// labels naming it is unknown, i.e. empty. The Capacity is the size of
+// the claim's volume, not the space used by the cache.
+type CacheClaim struct {
+	Name         string     `json:"name"`
+	App          AppRef     `json:"app"`
+	StorageClass string     `json:"storageclass,omitempty"`
+	Capacity     string     `json:"capacity,omitempty"` // size of the volume, not its usage
+	Created      time.Time  `json:"created"`
+	LastUsed     *time.Time `json:"lastused,omitempty"`
+	Orphaned     bool       `json:"orphaned,omitempty"`
+}
+
+// CacheClaimList is a collection of cache claims
+type CacheClaimList []CacheClaim
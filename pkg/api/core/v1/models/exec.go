@@ -0,0 +1,12 @@
+package models
+
+// ExecControl is a control message of an exec session, sent by the client
+// as a websocket text message. Binary messages carry the input of the
+// session in one direction, and the output of the TTY in the other.
+// A non-zero Width and Height resize the TTY. CloseStdin signals the end
+// of the input.
+type ExecControl struct {
+	Width      uint16 `json:"width,omitempty"`
+	Height     uint16 `json:"height,omitempty"`
+	CloseStdin bool   `json:"close_stdin,omitempty"`
+}
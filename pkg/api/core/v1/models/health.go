@@ -0,0 +1,87 @@
+package models
+
+// The types of health checks
+const (
+	HealthCheckHTTP = "http"
+	HealthCheckTCP  = "tcp"
+	HealthCheckNone = "none"
+)
+
+// Defaults of the health check and rollout settings
+const (
+	DefaultHealthCheckType    = HealthCheckTCP
+	DefaultHealthCheckPort    = 8080
+	DefaultHealthCheckPath    = "/"
+	DefaultHealthCheckTimeout = 1  // seconds
+	DefaultHealthCheckPeriod  = 10 // seconds
+
+	DefaultRolloutMaxSurge       = "25%"
+	DefaultRolloutMaxUnavailable = "0"
+)
+
+// HealthCheck represents the health check settings of an application.
+// They are rendered as the readiness and liveness probes of the
+// application's pods. An HTTP check requests the Path from the Port,
+// a TCP check connects to the Port. The liveness probe starts after the
+// InitialDelay. Times are in seconds. Zero values select the defaults.
+// The empty health check is rendered as a readiness probe only.
+type HealthCheck struct {
+	Type         string `json:"type,omitempty"`
+	Path         string `json:"path,omitempty"`
+	Port         int32  `json:"port,omitempty"`
+	InitialDelay int32  `json:"initial_delay,omitempty"`
+	Timeout      int32  `json:"timeout,omitempty"`
+	Period       int32  `json:"period,omitempty"`
+}
+
+// WithDefaults returns the health check with the zero values replaced by
+// the defaults. The settings not used by the type of check are cleared.
+func (h HealthCheck) WithDefaults() HealthCheck {
+	if h.Type == "" {
+		h.Type = DefaultHealthCheckType
+	}
+
+	switch h.Type {
+	case HealthCheckNone:
+		return HealthCheck{Type: HealthCheckNone}
+	case HealthCheckTCP:
+		h.Path = ""
+	case HealthCheckHTTP:
+		if h.Path == "" {
+			h.Path = DefaultHealthCheckPath
+		}
+	}
+
+	if h.Port == 0 {
+		h.Port = DefaultHealthCheckPort
+	}
+	if h.Timeout == 0 {
+		h.Timeout = DefaultHealthCheckTimeout
+	}
+	if h.Period == 0 {
+		h.Period = DefaultHealthCheckPeriod
+	}
+
+	return h
+}
+
+// Rollout represents the strategy for replacing the pods of an
+// application, e.g. on deployment or restart. Both settings are a number
+// of pods, or a percentage of the instances, like "25%". MaxSurge is how
+// many pods may be created above the number of instances, MaxUnavailable
+// how many pods may be unavailable. Empty values select the defaults.
+type Rollout struct {
+	MaxSurge       string `json:"max_surge,omitempty"`
+	MaxUnavailable string `json:"max_unavailable,omitempty"`
+}
+
+// WithDefaults returns the rollout with the empty values replaced by the defaults.
+func (r Rollout) WithDefaults() Rollout {
+	if r.MaxSurge == "" {
+		r.MaxSurge = DefaultRolloutMaxSurge
+	}
+	if r.MaxUnavailable == "" {
+		r.MaxUnavailable = DefaultRolloutMaxUnavailable
+	}
+	return r
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// AppInstance represents an instance, i.e. a pod, of an application.
+// Reason is why the application's container is waiting, e.g.
+// CrashLoopBackOff. The Events are the recent warning events of
+// instances which are not ready, oldest first.
+type AppInstance struct {
+	Name            string               `json:"name"`
+	Phase           string               `json:"phase"`
+	Ready           bool                 `json:"ready"`
+	Reason          string               `json:"reason,omitempty"`
+	Restarts        int32                `json:"restarts"`
+	LastTermination *InstanceTermination `json:"last_termination,omitempty"`
+	Node            string               `json:"node,omitempty"`
+	Created         time.Time            `json:"created"`
+	Events          []string             `json:"events,omitempty"`
+}
+
+// InstanceTermination represents the last termination of the
+// application's container in an instance
+type InstanceTermination struct {
+	Reason   string    `json:"reason"`
+	ExitCode int32     `json:"exit_code"`
+	Finished time.Time `json:"finished"`
+}
+
+// AppInstanceList is a collection of application instances
+type AppInstanceList []AppInstance
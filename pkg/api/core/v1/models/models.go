@@ -14,22 +14,48 @@ var ResponseOK = Response{"ok"}
 type Request struct {
 }
 
-// ServiceResponse represents the data of a single service instance
+// ServiceResponse represents the data of a single service instance.
+// Class and Plan are empty for custom services.
 type ServiceResponse struct {
 	Name      string   `json:"name"`
 	BoundApps []string `json:"boundapps"`
+	Class     string   `json:"class,omitempty"`
+	Plan      string   `json:"plan,omitempty"`
 }
 
 // ServiceResponseList represents a collection of service instance
 type ServiceResponseList []ServiceResponse
 
 // ServiceCreateRequest represents and contains the data needed to
-// create a service instance
+// create a service instance. Custom services are created from the Data.
+// Catalog services are provisioned by a broker, from the Class, Plan,
+// and optional Params.
 type ServiceCreateRequest struct {
-	Name string            `json:"name"`
-	Data map[string]string `json:"data"`
+	Name   string                 `json:"name"`
+	Data   map[string]string      `json:"data,omitempty"`
+	Class  string                 `json:"class,omitempty"`
+	Plan   string                 `json:"plan,omitempty"`
+	Params map[string]interface{} `json:"params,omitempty"`
 }
 
+// CatalogServicePlan represents a plan of a service class offered by a broker
+type CatalogServicePlan struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Free        bool   `json:"free"`
+}
+
+// CatalogService represents a service class offered by a broker, with its plans
+type CatalogService struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description,omitempty"`
+	Broker      string               `json:"broker,omitempty"`
+	Plans       []CatalogServicePlan `json:"plans"`
+}
+
+// CatalogServiceList represents a collection of service classes
+type CatalogServiceList []CatalogService
+
 // ServiceDeleteRequest represents and contains the data needed to delete a service
 type ServiceDeleteRequest struct {
 	Unbind bool `json:"unbind"`
@@ -40,6 +66,20 @@ type ServiceDeleteResponse struct {
 	BoundApps []string `json:"boundapps"`
 }
 
+// ServiceUpdateRequest represents and contains the data needed to update a service.
+// The keys of Set are added or replaced, the keys in Unset removed. With Sequential
+// the bound applications are restarted one after the other, instead of all at once.
+type ServiceUpdateRequest struct {
+	Set        map[string]string `json:"set,omitempty"`
+	Unset      []string          `json:"unset,omitempty"`
+	Sequential bool              `json:"sequential,omitempty"`
+}
+
+// ServiceUpdateResponse represents the server's response to a successful service update
+type ServiceUpdateResponse struct {
+	RestartedApps []string `json:"restartedapps"`
+}
+
 // BindRequest represents and contains the data needed to bind services to an application.
 type BindRequest struct {
 	Names []string `json:"names"`
@@ -59,45 +99,126 @@ type ApplicationCreateRequest struct {
 
 // ApplicationUpdateRequest represents and contains the data needed to update
 // an application. Specifically to modify the number of replicas to
-// run, and the services bound to it.
+// run, the services bound to it, the routes it is reachable under, how
+// its pods are checked and replaced, and their compute resources.
 // Note: Instances is a pointer to give us a nil value separate from
 // actual integers, as means of communicating `default`/`no change`.
+// Ditto for the nil Routes, versus an empty list restoring the default
+// route, and the nil HealthCheck, Rollout, and Resources.
 
 type ApplicationUpdateRequest struct {
 	Instances   *int32          `json:"instances"`
 	Services    []string        `json:"services"`
 	Environment EnvVariableList `json:"environment"`
+	Routes      []string        `json:"routes"`
+	HealthCheck *HealthCheck    `json:"healthcheck"`
+	Rollout     *Rollout        `json:"rollout"`
+	Resources   *AppResources   `json:"resources"`
 }
 
+// ImportGitResponse represents the server's response to a request to import
+// application sources from git. The import runs in the background. The job
+// is queried for progress, the blob UID of the imported sources, and the SHA
+// of the imported commit.
 type ImportGitResponse struct {
+	JobID   string `json:"jobid,omitempty"`
 	BlobUID string `json:"blobuid,omitempty"`
+	Commit  string `json:"commit,omitempty"`
+}
+
+// Possible states of a background job
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// JobResponse represents the state of a background job, like the import of
+// application sources from a git repository.
+type JobResponse struct {
+	ID        string `json:"id"`
+	Namespace string `json:"namespace"`
+	App       string `json:"app,omitempty"`
+	Status    string `json:"status"`
+	Progress  string `json:"progress,omitempty"`
+	Error     string `json:"error,omitempty"`
+	BlobUID   string `json:"blobuid,omitempty"`
+	Commit    string `json:"commit,omitempty"`
 }
 
 // TODO: CreateOrgRequest
 
 // UploadRequest is a multipart form
 
-// UploadResponse represents the server's response to a successful app sources upload
+// UploadResponse represents the server's response to a successful app sources upload.
+// Skipped is set when the identical sources were stored already, and not uploaded again.
 type UploadResponse struct {
 	BlobUID string `json:"blobuid,omitempty"`
+	Skipped bool   `json:"skipped,omitempty"`
+}
+
+// UploadInitRequest represents the request to initiate a chunked upload
+// of app sources. It announces the size and SHA-256 of the tarball.
+type UploadInitRequest struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// UploadInitResponse represents the server's response to the initiation
+// of a chunked upload. It carries the state of the upload, or, when the
+// identical sources were stored already, their blob UID.
+type UploadInitResponse struct {
+	Upload  *UploadState `json:"upload,omitempty"`
+	BlobUID string       `json:"blobuid,omitempty"`
+}
+
+// UploadState represents a chunked upload in progress. Parts lists the
+// numbers of the parts received, i.e. acknowledged, by the server.
+type UploadState struct {
+	ID       string `json:"id"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	PartSize int64  `json:"partsize"`
+	Parts    []int  `json:"parts"`
 }
 
-// StageRequest represents and contains the data needed to stage an application
+// The staging strategies, see StageRequest.
+const (
+	// StagingBuildpacks builds the sources with the buildpacks of the builder image
+	StagingBuildpacks = "buildpacks"
+	// StagingDockerfile builds the sources with their Dockerfile
+	StagingDockerfile = "dockerfile"
+	// StagingImage imports the prebuilt SourceImage
+	StagingImage = "image"
+
+	// DefaultBuilderImage is the builder image used by StagingBuildpacks when none is given
+	DefaultBuilderImage = "paketobuildpacks/builder:full"
+)
+
+// StageRequest represents and contains the data needed to stage an application.
+// Without Strategy it is chosen by the server: StagingImage for a SourceImage,
+// StagingBuildpacks for a BuilderImage, else per the uploaded sources.
 type StageRequest struct {
 	App          AppRef `json:"app,omitempty"`
 	BlobUID      string `json:"blobuid,omitempty"`
 	BuilderImage string `json:"builderimage,omitempty"`
+	Strategy     string `json:"strategy,omitempty"`
+	SourceImage  string `json:"sourceimage,omitempty"`
 }
 
 // StageResponse represents the server's response to a successful app staging
 type StageResponse struct {
 	Stage    StageRef `json:"stage,omitempty"`
 	ImageURL string   `json:"image,omitempty"`
+	Strategy string   `json:"strategy,omitempty"`
 }
 
 // DeployRequest represents and contains the data needed to deploy an application
 // Note that the overall application configuration (instances, services, EVs) is
 // already known server side, through AppCreate/AppUpdate requests.
+// The git commit the application was built from is known from the
+// staging run, if it was imported from git.
 type DeployRequest struct {
 	App      AppRef   `json:"app,omitempty"`
 	Stage    StageRef `json:"stage,omitempty"`
@@ -106,7 +227,8 @@ type DeployRequest struct {
 
 // DeployResponse represents the server's response to a successful app deployment
 type DeployResponse struct {
-	Route string `json:"route,omitempty"`
+	Route   string `json:"route,omitempty"`
+	Release int    `json:"release,omitempty"`
 }
 
 // ApplicationDeleteResponse represents the server's response to a successful app deletion
@@ -123,6 +245,8 @@ type EnvMatchResponse struct {
 type ServiceShowResponse struct {
 	Username string            `json:"user"`
 	Details  map[string]string `json:"details,omitempty"`
+	Class    string            `json:"class,omitempty"`
+	Plan     string            `json:"plan,omitempty"`
 }
 
 // InfoResponse contains information about Epinio and its components
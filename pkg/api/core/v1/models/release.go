@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// This subsection of models provides structures related to the
+// release history of applications.
+
+// AppRelease records a successful deployment of an application, with
+// everything needed to redeploy it later, without staging.
+type AppRelease struct {
+	Number      int             `json:"number"`
+	ImageURL    string          `json:"image"`
+	StageID     string          `json:"stage_id,omitempty"`
+	Environment EnvVariableList `json:"environment,omitempty"`
+	Services    []string        `json:"services,omitempty"`
+	Username    string          `json:"username,omitempty"`
+	Created     time.Time       `json:"created"`
+	Git         *GitRef         `json:"git,omitempty"`
+	RollbackOf  int             `json:"rollback_of,omitempty"` // release restored by this release, if any
+}
+
+// AppReleaseList is a collection of releases, ordered by release number
+type AppReleaseList []AppRelease
+
+// RollbackRequest represents and contains the data needed to roll an
+// application back to a previous release. The zero release refers to
+// the release before the current one.
+type RollbackRequest struct {
+	Release int `json:"release,omitempty"`
+}
+
+// RollbackResponse represents the server's response to a successful rollback
+type RollbackResponse struct {
+	Release AppRelease `json:"release"`
+	Route   string     `json:"route,omitempty"`
+}
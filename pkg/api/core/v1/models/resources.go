@@ -0,0 +1,33 @@
+package models
+
+// AppResources represents the compute resources of each instance of an
+// application, as kubernetes quantities, like "512Mi" for memory, or
+// "250m" for CPU. Empty values mean no request, respectively no limit.
+type AppResources struct {
+	MemoryRequest string `json:"memory_request,omitempty"`
+	MemoryLimit   string `json:"memory_limit,omitempty"`
+	CPURequest    string `json:"cpu_request,omitempty"`
+	CPULimit      string `json:"cpu_limit,omitempty"`
+}
+
+// Merge returns the resources with the values set by the update. Empty
+// values of the update keep the current value, "0" removes it.
+func (r AppResources) Merge(update AppResources) AppResources {
+	merge := func(current, value string) string {
+		switch value {
+		case "":
+			return current
+		case "0":
+			return ""
+		default:
+			return value
+		}
+	}
+
+	return AppResources{
+		MemoryRequest: merge(r.MemoryRequest, update.MemoryRequest),
+		MemoryLimit:   merge(r.MemoryLimit, update.MemoryLimit),
+		CPURequest:    merge(r.CPURequest, update.CPURequest),
+		CPULimit:      merge(r.CPULimit, update.CPULimit),
+	}
+}
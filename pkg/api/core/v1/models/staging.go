@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// The phases of a staging run, see StageStatus.
+const (
+	StagePending   = "pending"
+	StageRunning   = "running"
+	StageSucceeded = "succeeded"
+	StageFailed    = "failed"
+	StageCancelled = "cancelled"
+)
+
+// StageStatus represents the progress of a staging run, i.e. of the tekton
+// pipeline run staging an application. For a failed run Reason and Message
+// explain the failure, FailedStep, if known, names the step at fault, and
+// Hint suggests what to do about it.
+type StageStatus struct {
+	ID         string      `json:"id"`
+	Strategy   string      `json:"strategy,omitempty"`
+	Phase      string      `json:"phase"`
+	Reason     string      `json:"reason,omitempty"`
+	Message    string      `json:"message,omitempty"`
+	Started    *time.Time  `json:"started,omitempty"`
+	Finished   *time.Time  `json:"finished,omitempty"`
+	Tasks      []StageTask `json:"tasks,omitempty"`
+	FailedStep *StageStep  `json:"failedstep,omitempty"`
+	Hint       string      `json:"hint,omitempty"`
+}
+
+// StageTask represents the progress of a single task of a staging run.
+type StageTask struct {
+	Name     string      `json:"name"`
+	Phase    string      `json:"phase"`
+	Started  *time.Time  `json:"started,omitempty"`
+	Finished *time.Time  `json:"finished,omitempty"`
+	Steps    []StageStep `json:"steps,omitempty"`
+}
+
+// StageStep represents the state of a single step of a staging task. The
+// ExitCode is set for terminated steps. Logs holds the last lines of the
+// step's log, and is only filled for the failed step.
+type StageStep struct {
+	Task     string   `json:"task"`
+	Name     string   `json:"name"`
+	Phase    string   `json:"phase"`
+	ExitCode *int32   `json:"exitcode,omitempty"`
+	Reason   string   `json:"reason,omitempty"`
+	Logs     []string `json:"logs,omitempty"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Roles of API users. Admins have access to everything. The other
+// roles are granted per namespace. Developers can read and modify the
+// namespace's applications and services, viewers can only read them.
+const (
+	RoleAdmin     = "admin"
+	RoleDeveloper = "developer"
+	RoleViewer    = "viewer"
+)
+
+// User is an API user, with the roles granted to it. The Namespaces
+// map namespace names to the role of the user in that namespace.
+type User struct {
+	Username   string            `json:"username"`
+	Admin      bool              `json:"admin"`
+	Namespaces map[string]string `json:"namespaces,omitempty"`
+}
+
+// UserList is a collection of users
+type UserList []User
+
+// UserCreateRequest represents and contains the data needed to create
+// a user.
+type UserCreateRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Admin    bool   `json:"admin,omitempty"`
+}
+
+// UserGrantRequest represents and contains the data needed to grant a
+// role in a namespace to a user.
+type UserGrantRequest struct {
+	Namespace string `json:"namespace"`
+	Role      string `json:"role"`
+}
+
+// AuthTokenRequest represents and contains the credentials needed to
+// obtain an API token.
+type AuthTokenRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// AuthTokenResponse contains an API token, and the time it expires.
+type AuthTokenResponse struct {
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}